@@ -2,72 +2,224 @@ package catalog
 
 import (
 	"errors"
+	"fmt"
+	"garakutadb/expression"
 	"garakutadb/storage"
+	"garakutadb/types"
+	"maps"
+	"slices"
+	"sync"
 )
 
+// Catalog holds the schemas of a database. The catalog returned by LoadCatalog is shared by the sessions, and a
+// session reads a Snapshot of it, whose lists are never changed by other sessions. The lists are replaced instead of
+// being changed in place, so a snapshot is a copy of the references.
+// The changes made by a transaction are seen only by the snapshots of the transaction until it is committed.
 type Catalog struct {
 	TableSchemas TableSchemas
+	Sequences    SequenceSchemas
+	Views        ViewSchemas
+	Namespaces   NamespaceSchemas
 	storage      *storage.Storage
+	// the functions which can be called in SQL. They belong to the DB and are not saved in the catalog.
+	functions *expression.Functions
+
+	// incremented by every change of the shared catalog or its snapshots, so that a version is never given twice
+	version uint64
+	// the version in which each relation or sequence was changed last. It is replaced like the lists.
+	relationVersions map[string]uint64
+	// the shared catalog, which is changed by the mutators of its snapshots. It refers to itself.
+	root *Catalog
+	// the transaction whose changes are seen by the snapshot. nil for the shared catalog.
+	tx *storage.Transaction
+	// protects the lists and the versions of the shared catalog
+	rwMutex sync.RWMutex
+
+	// protects committed and pending (see persist.go)
+	mutex     sync.Mutex
+	committed catalogFile
+	pending   map[*storage.Transaction]*pendingChanges
+	// held by a transaction from preparing its changes until it is committed or aborted
+	commitMutex sync.Mutex
 }
 
 func NewEmptyCatalog(st *storage.Storage) *Catalog {
-	return &Catalog{
-		storage:      st,
-		TableSchemas: TableSchemas{},
+	return newCatalog(st, catalogFile{})
+}
+
+func LoadCatalog(st *storage.Storage) (*Catalog, error) {
+	file, err := readCatalogFile(st)
+	if err != nil {
+		return nil, err
 	}
+	return newCatalog(st, file), nil
 }
 
-const catalogPath = "table_schema.json"
+func newCatalog(st *storage.Storage, file catalogFile) *Catalog {
+	ct := &Catalog{
+		storage:          st,
+		relationVersions: make(map[string]uint64),
+		committed:        file.clone(),
+		pending:          make(map[*storage.Transaction]*pendingChanges),
+	}
+	ct.setLists(file.clone())
+	ct.root = ct
+	return ct
+}
 
-func LoadCatalog(storage *storage.Storage) (*Catalog, error) {
-	tableSchemas := TableSchemas{}
-	if err := storage.ReadJson(catalogPath, &tableSchemas); err != nil {
-		return nil, err
+// Snapshot returns a copy of the shared catalog with the changes made by tx, which is not changed by other sessions.
+// The changes made through the snapshot must be made by tx. They are applied to the snapshot at once, and to the
+// shared catalog when tx is committed.
+func (ct *Catalog) Snapshot(tx *storage.Transaction) *Catalog {
+	root := ct.root
+	root.rwMutex.RLock()
+	defer root.rwMutex.RUnlock()
+
+	snapshot := &Catalog{
+		storage: root.storage,
+		root:    root,
+		tx:      tx,
 	}
+	snapshot.copyFrom(root)
 
-	return &Catalog{
-		TableSchemas: tableSchemas,
-		storage:      storage,
-	}, nil
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+	if pending, ok := root.pending[tx]; ok {
+		lists := snapshot.lists()
+		for _, change := range pending.changes {
+			change(&lists)
+		}
+		snapshot.setLists(lists)
+		versions := maps.Clone(snapshot.relationVersions)
+		for name, version := range pending.versions {
+			versions[name] = version
+			snapshot.version = max(snapshot.version, version)
+		}
+		snapshot.relationVersions = versions
+	}
+	return snapshot
 }
 
-func (ct *Catalog) Add(ts *TableSchema) error {
-	ct.TableSchemas = append(ct.TableSchemas, *ts)
-	return ct.save()
+func (ct *Catalog) copyFrom(root *Catalog) {
+	ct.TableSchemas = root.TableSchemas
+	ct.Sequences = root.Sequences
+	ct.Views = root.Views
+	ct.Namespaces = root.Namespaces
+	ct.functions = root.functions
+	ct.version = root.version
+	ct.relationVersions = root.relationVersions
 }
 
-func (ct *Catalog) Update(ts *TableSchema) error {
-	for i, t := range ct.TableSchemas {
-		if t.Name == ts.Name {
-			ct.TableSchemas[i] = *ts
-			return ct.save()
-		}
+// lists returns the lists of the catalog in the form of the file, to which changes are applied
+func (ct *Catalog) lists() catalogFile {
+	return catalogFile{
+		Tables:     ct.TableSchemas,
+		Sequences:  ct.Sequences,
+		Views:      ct.Views,
+		Namespaces: ct.Namespaces,
 	}
+}
+
+func (ct *Catalog) setLists(lists catalogFile) {
+	ct.TableSchemas = lists.Tables
+	ct.Sequences = lists.Sequences
+	ct.Views = lists.Views
+	ct.Namespaces = lists.Namespaces
+}
+
+// SetFunctions sets the functions of the DB. It must be called before the catalog is shared.
+func (ct *Catalog) SetFunctions(functions *expression.Functions) {
+	ct.functions = functions
+}
+
+// Functions returns the functions which can be called in SQL. nil means only the built-in functions.
+func (ct *Catalog) Functions() *expression.Functions {
+	return ct.functions
+}
+
+// Version returns the version of the catalog, which is incremented by every change
+func (ct *Catalog) Version() uint64 {
+	return ct.version
+}
+
+// RelationVersion returns the version in which the relation or the sequence was created, changed or dropped last.
+// It is 0 if it has not been changed since the catalog was loaded.
+func (ct *Catalog) RelationVersion(name string) uint64 {
+	return ct.relationVersions[name]
+}
+
+// touch gives a new version to the relation. The lock of the shared catalog must be held.
+func (ct *Catalog) touch(name string) {
+	ct.version++
+	versions := maps.Clone(ct.relationVersions)
+	versions[name] = ct.version
+	ct.relationVersions = versions
+}
+
+// change applies a change of tx to the snapshot, and keeps it until tx is committed or aborted.
+// name is the relation or the sequence which is changed, or empty for a schema. The relation gets a new version in
+// the snapshots of tx, so that the plans made before the change are invalidated.
+func (ct *Catalog) change(tx *storage.Transaction, name string, change catalogChange) {
+	root := ct.root
+	root.rwMutex.Lock()
+	root.version++
+	version := root.version
+	root.rwMutex.Unlock()
+
+	root.record(tx, name, ct.relationVersions[name], version, change)
+
+	lists := ct.lists()
+	change(&lists)
+	ct.setLists(lists)
+	ct.version = version
+	if name != "" {
+		versions := maps.Clone(ct.relationVersions)
+		versions[name] = version
+		ct.relationVersions = versions
+	}
+}
+
+// Add adds a table schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) Add(ts *TableSchema, tx *storage.Transaction) error {
+	ts = ts.Clone()
+	ct.change(tx, ts.Name, func(file *catalogFile) {
+		file.Tables = putByName(file.Tables, *ts, tableSchemaName)
+	})
 	return nil
 }
 
-func (ct *Catalog) Delete(name string) error {
-	for i, t := range ct.TableSchemas {
-		if t.Name == name {
-			ct.TableSchemas = append(ct.TableSchemas[:i], ct.TableSchemas[i+1:]...)
-			return ct.save()
-		}
+// Update replaces a table schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) Update(ts *TableSchema, tx *storage.Transaction) error {
+	if _, err := ct.TableSchemas.Get(ts.Name); err != nil {
+		return nil
 	}
+	ts = ts.Clone()
+	ct.change(tx, ts.Name, func(file *catalogFile) {
+		file.Tables = putByName(file.Tables, *ts, tableSchemaName)
+	})
 	return nil
 }
 
-func (ct *Catalog) save() error {
-	return ct.storage.WriteJson(catalogPath, &ct.TableSchemas)
+// Delete deletes a table schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) Delete(name string, tx *storage.Transaction) error {
+	if _, err := ct.TableSchemas.Get(name); err != nil {
+		return nil
+	}
+	ct.change(tx, name, func(file *catalogFile) {
+		file.Tables = deleteByName(file.Tables, name, tableSchemaName)
+	})
+	return nil
 }
 
 type TableSchemas []TableSchema
 
 var TableSchemaNotFoundError = errors.New("table schema not found")
 
+// Get returns the table schema in the list. It must not be changed, because the list is shared by snapshots. Use Clone to change it.
 func (t TableSchemas) Get(name string) (*TableSchema, error) {
-	for _, ts := range t {
-		if ts.Name == name {
-			return &ts, nil
+	for i := range t {
+		if t[i].Name == name {
+			return &t[i], nil
 		}
 	}
 	return nil, TableSchemaNotFoundError
@@ -76,7 +228,200 @@ func (t TableSchemas) Get(name string) (*TableSchema, error) {
 type TableSchema struct {
 	Name    string        `json:"name"`
 	Columns ColumnSchemas `json:"columns"`
-	PK      string        `json:"pk"`
+	// columns of the primary key. A table without primary key is a heap table, whose rows are identified by their locations.
+	PK     []string `json:"pk,omitempty"`
+	PKName string   `json:"pkName,omitempty"`
+
+	// incremented by ALTER TABLE
+	Version uint32 `json:"version,omitempty"`
+	// the slot of the tuple assigned to the next added column. Slots of dropped columns are not reused.
+	NextSlot uint32 `json:"nextSlot"`
+
+	Uniques     []UniqueConstraint     `json:"uniques,omitempty"`
+	Checks      []CheckConstraint      `json:"checks,omitempty"`
+	ForeignKeys []ForeignKeyConstraint `json:"foreignKeys,omitempty"`
+
+	// the query of a materialized view, whose rows are stored in the table. nil for ordinary tables.
+	Materialized *MaterializedView `json:"materialized,omitempty"`
+}
+
+// UniqueConstraint is enforced by the index named Name
+type UniqueConstraint struct {
+	Name   string `json:"name"`
+	Column string `json:"column"`
+}
+
+// CheckConstraint is satisfied unless Expression is false. Expression is kept as SQL text.
+type CheckConstraint struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// ForeignKeyConstraint requires a non-null Column to match RefColumn of a row in RefTable.
+// RefColumn is the primary key or a unique column, so the parent row is found with its index.
+type ForeignKeyConstraint struct {
+	Name      string            `json:"name"`
+	Column    string            `json:"column"`
+	RefTable  string            `json:"refTable"`
+	RefColumn string            `json:"refColumn"`
+	OnDelete  ReferentialAction `json:"onDelete,omitempty"`
+	OnUpdate  ReferentialAction `json:"onUpdate,omitempty"`
+}
+
+// ReferentialAction is what happens to child rows when the referenced parent row is deleted or updated
+type ReferentialAction string
+
+const (
+	// NoAction is the same as Restrict, because constraints are not deferrable
+	NoAction ReferentialAction = ""
+	Restrict ReferentialAction = "RESTRICT"
+	Cascade  ReferentialAction = "CASCADE"
+	SetNull  ReferentialAction = "SET NULL"
+)
+
+// IsNotNull reports whether the column can't be NULL. Primary key is always NOT NULL.
+func (ts *TableSchema) IsNotNull(column *ColumnSchema) bool {
+	return column.NotNull || slices.Contains(ts.PK, column.Name)
+}
+
+func (ts *TableSchema) HasPK() bool {
+	return len(ts.PK) > 0
+}
+
+// PKOrders returns the orders of the primary key columns
+func (ts *TableSchema) PKOrders() []uint64 {
+	orders := make([]uint64, len(ts.PK))
+	for i, column := range ts.PK {
+		orders[i], _ = ts.Columns.Contains(column)
+	}
+	return orders
+}
+
+// PKConstraintName is the name of the primary key constraint, which is also the name of its index
+func (ts *TableSchema) PKConstraintName() string {
+	if ts.PKName != "" {
+		return ts.PKName
+	}
+	_, name := SplitRelationName(ts.Name)
+	return name + "_pkey"
+}
+
+// IndexNames returns the names of all indexes of the table
+func (ts *TableSchema) IndexNames() []string {
+	names := make([]string, 0)
+	if ts.HasPK() {
+		names = append(names, ts.PKConstraintName())
+	}
+	for _, unique := range ts.Uniques {
+		names = append(names, unique.Name)
+	}
+	return names
+}
+
+// IndexNameOf returns the name of the index whose key is column
+func (ts *TableSchema) IndexNameOf(column string) (string, bool) {
+	if len(ts.PK) == 1 && ts.PK[0] == column {
+		return ts.PKConstraintName(), true
+	}
+	for _, unique := range ts.Uniques {
+		if unique.Column == column {
+			return unique.Name, true
+		}
+	}
+	return "", false
+}
+
+// ForeignKeyReference is a foreign key of Table
+type ForeignKeyReference struct {
+	Table      *TableSchema
+	ForeignKey ForeignKeyConstraint
+}
+
+// ReferencedBy returns the foreign keys referencing the table
+func (t TableSchemas) ReferencedBy(name string) []ForeignKeyReference {
+	references := make([]ForeignKeyReference, 0)
+	for i := range t {
+		for _, fk := range t[i].ForeignKeys {
+			if fk.RefTable == name {
+				references = append(references, ForeignKeyReference{
+					Table:      &t[i],
+					ForeignKey: fk,
+				})
+			}
+		}
+	}
+	return references
+}
+
+// Clone returns a deep copy, which can be changed without changing the catalog
+func (ts *TableSchema) Clone() *TableSchema {
+	clone := *ts
+	clone.Columns = slices.Clone(ts.Columns)
+	clone.PK = slices.Clone(ts.PK)
+	clone.Uniques = slices.Clone(ts.Uniques)
+	clone.Checks = slices.Clone(ts.Checks)
+	clone.ForeignKeys = slices.Clone(ts.ForeignKeys)
+	if ts.Materialized != nil {
+		materialized := *ts.Materialized
+		clone.Materialized = &materialized
+	}
+	return &clone
+}
+
+// Validate checks the columns and the names of the constraints
+func (ts *TableSchema) Validate() error {
+	if len(ts.Columns) == 0 {
+		return fmt.Errorf("columns is empty")
+	}
+	for i, column := range ts.Columns {
+		if _, found := ts.Columns[:i].Contains(column.Name); found {
+			return fmt.Errorf("column specified more than once: %s", column.Name)
+		}
+	}
+	for i, column := range ts.PK {
+		if _, found := ts.Columns.Contains(column); !found {
+			return fmt.Errorf("column not found: %s", column)
+		}
+		if slices.Contains(ts.PK[:i], column) {
+			return fmt.Errorf("column %s appears twice in primary key constraint", column)
+		}
+	}
+
+	names := make(map[string]struct{})
+	addName := func(name string) error {
+		if _, found := names[name]; found {
+			return fmt.Errorf("constraint already exists: %s", name)
+		}
+		names[name] = struct{}{}
+		return nil
+	}
+	if ts.HasPK() {
+		if err := addName(ts.PKConstraintName()); err != nil {
+			return err
+		}
+	}
+	for _, unique := range ts.Uniques {
+		if _, found := ts.Columns.Contains(unique.Column); !found {
+			return fmt.Errorf("column not found: %s", unique.Column)
+		}
+		if err := addName(unique.Name); err != nil {
+			return err
+		}
+	}
+	for _, check := range ts.Checks {
+		if err := addName(check.Name); err != nil {
+			return err
+		}
+	}
+	for _, fk := range ts.ForeignKeys {
+		if _, found := ts.Columns.Contains(fk.Column); !found {
+			return fmt.Errorf("column not found: %s", fk.Column)
+		}
+		if err := addName(fk.Name); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type ColumnSchemas []ColumnSchema
@@ -91,13 +436,32 @@ func (c ColumnSchemas) Contains(name string) (uint64, bool) {
 }
 
 type ColumnSchema struct {
-	Name string     `json:"name"`
-	Type ColumnType `json:"type"`
+	Name    string     `json:"name"`
+	Type    ColumnType `json:"type"`
+	NotNull bool       `json:"notNull,omitempty"`
+	// the precision and the scale of a decimal. nil means any.
+	Typmod *types.Typmod `json:"typmod,omitempty"`
+	// SQL text of the default value. nil means NULL.
+	Default *string `json:"default,omitempty"`
+
+	// the index of the value in tuples, which doesn't change when other columns are added or dropped
+	Slot uint32 `json:"slot"`
+	// the value of the tuples written before the column was added, in the text representation of the type. nil means NULL.
+	Missing *string `json:"missing,omitempty"`
+
+	// the sequence owned by a SERIAL, AUTO_INCREMENT or identity column, which is dropped with the column.
+	// Default is nextval of the sequence.
+	Sequence string   `json:"sequence,omitempty"`
+	Identity Identity `json:"identity,omitempty"`
 }
 
-type ColumnType uint8
+// Identity is the kind of an identity column. A value can't be given to a GENERATED ALWAYS column.
+type Identity string
 
 const (
-	Unknown ColumnType = iota
-	String
+	NotIdentity       Identity = ""
+	IdentityAlways    Identity = "ALWAYS"
+	IdentityByDefault Identity = "BY DEFAULT"
 )
+
+type ColumnType = types.Type
@@ -0,0 +1,221 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"garakutadb/storage"
+	"os"
+)
+
+// The catalog is saved in catalogPath as a whole. The changes made by a transaction are kept as pending changes, which
+// are applied to the snapshots of the transaction, until the transaction is committed. While committing, they are
+// applied to the committed catalog and written to a prepared file, which replaces catalogPath when the transaction is
+// committed (see storage.ReplaceJsonOnCommit). Then the shared catalog in memory is replaced with the committed one.
+// So neither the file nor the shared catalog has uncommitted changes, and the file is consistent with the tables after
+// recovery.
+
+const catalogPath = "catalog.json"
+
+// the files which were used before catalogPath. They are read only when catalogPath doesn't exist.
+const (
+	tableSchemaPath    = "table_schema.json"
+	sequenceSchemaPath = "sequence_schema.json"
+	viewSchemaPath     = "view_schema.json"
+)
+
+type catalogFile struct {
+	Tables    TableSchemas    `json:"tables"`
+	Sequences SequenceSchemas `json:"sequences"`
+	Views     ViewSchemas     `json:"views"`
+	// omitted by the files written before schemas were added
+	Namespaces NamespaceSchemas `json:"schemas"`
+}
+
+// clone copies the lists, which are never nil
+func (f *catalogFile) clone() catalogFile {
+	return catalogFile{
+		Tables:     append(TableSchemas{}, f.Tables...),
+		Sequences:  append(SequenceSchemas{}, f.Sequences...),
+		Views:      append(ViewSchemas{}, f.Views...),
+		Namespaces: append(NamespaceSchemas{}, f.Namespaces...),
+	}
+}
+
+func readCatalogFile(st *storage.Storage) (catalogFile, error) {
+	var file catalogFile
+	err := st.ReadJson(catalogPath, &file)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return file.clone(), err
+	}
+
+	if err := st.ReadJson(tableSchemaPath, &file.Tables); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return file, err
+	}
+	if err := st.ReadJson(sequenceSchemaPath, &file.Sequences); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return file, err
+	}
+	if err := st.ReadJson(viewSchemaPath, &file.Views); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return file, err
+	}
+	return file.clone(), nil
+}
+
+// catalogChange applies a change of a transaction to the committed catalog or a snapshot
+type catalogChange func(file *catalogFile)
+
+// pendingChanges are the changes of a transaction which has not been committed
+type pendingChanges struct {
+	changes []catalogChange
+	// the versions of the changed relations on which the changes were made. If another transaction has changed one of
+	// them meanwhile, the transaction fails to commit instead of overwriting the change.
+	base map[string]uint64
+	// the versions of the changed relations in the snapshots of the transaction
+	versions map[string]uint64
+}
+
+// record keeps the change until tx is committed or aborted. base is the version of the relation before the change,
+// and version is its version after the change.
+func (ct *Catalog) record(tx *storage.Transaction, name string, base uint64, version uint64, change catalogChange) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	pending, ok := ct.pending[tx]
+	if !ok {
+		tx.AddUndoRecord(&discardChangesUndoRecord{
+			catalog: ct,
+			tx:      tx,
+		})
+		tx.AddPrepareAction(&prepareCatalogAction{
+			catalog: ct,
+			tx:      tx,
+		})
+		pending = &pendingChanges{
+			base:     make(map[string]uint64),
+			versions: make(map[string]uint64),
+		}
+		ct.pending[tx] = pending
+	}
+	pending.changes = append(pending.changes, change)
+	if name != "" {
+		if _, ok := pending.base[name]; !ok {
+			pending.base[name] = base
+		}
+		pending.versions[name] = version
+	}
+}
+
+// prepareCatalogAction writes the committed catalog with the changes of tx.
+// Transactions commit their changes one by one, so that no change of another transaction is lost.
+type prepareCatalogAction struct {
+	catalog *Catalog
+	tx      *storage.Transaction
+}
+
+func (a *prepareCatalogAction) Run(_ *storage.TransactionManager) error {
+	ct := a.catalog
+	ct.commitMutex.Lock()
+	a.tx.AddUndoRecord(&unlockCommitUndoRecord{
+		catalog: ct,
+	})
+
+	// the versions of the shared catalog are changed only by the transaction holding commitMutex
+	ct.rwMutex.RLock()
+	versions := ct.relationVersions
+	ct.rwMutex.RUnlock()
+
+	ct.mutex.Lock()
+	pending := ct.pending[a.tx]
+	next := ct.committed.clone()
+	for _, change := range pending.changes {
+		change(&next)
+	}
+	ct.mutex.Unlock()
+
+	for name, base := range pending.base {
+		if versions[name] != base {
+			return fmt.Errorf("could not serialize access due to concurrent change of \"%s\"", name)
+		}
+	}
+
+	if err := ct.storage.ReplaceJsonOnCommit(catalogPath, &next, a.tx); err != nil {
+		return err
+	}
+	a.tx.AddCommitAction(&commitCatalogAction{
+		catalog: ct,
+		tx:      a.tx,
+		next:    next,
+	})
+	return nil
+}
+
+// commitCatalogAction publishes the changes of tx to the shared catalog
+type commitCatalogAction struct {
+	catalog *Catalog
+	tx      *storage.Transaction
+	next    catalogFile
+}
+
+func (a *commitCatalogAction) Run(_ *storage.TransactionManager) error {
+	ct := a.catalog
+	ct.rwMutex.Lock()
+	ct.mutex.Lock()
+	pending := ct.pending[a.tx]
+	ct.committed = a.next
+	delete(ct.pending, a.tx)
+	ct.mutex.Unlock()
+
+	ct.setLists(a.next.clone())
+	ct.version++
+	for name := range pending.base {
+		ct.touch(name)
+	}
+	ct.rwMutex.Unlock()
+
+	ct.commitMutex.Unlock()
+	return nil
+}
+
+// putByName returns a copy of the list in which the element of the same name is replaced, or the element is appended
+func putByName[S ~[]T, T any](list S, elem T, name func(*T) string) S {
+	result := make(S, 0, len(list)+1)
+	found := false
+	for i := range list {
+		if name(&list[i]) == name(&elem) {
+			result = append(result, elem)
+			found = true
+		} else {
+			result = append(result, list[i])
+		}
+	}
+	if !found {
+		result = append(result, elem)
+	}
+	return result
+}
+
+// deleteByName returns a copy of the list without the element of the name
+func deleteByName[S ~[]T, T any](list S, target string, name func(*T) string) S {
+	result := make(S, 0, len(list))
+	for i := range list {
+		if name(&list[i]) != target {
+			result = append(result, list[i])
+		}
+	}
+	return result
+}
+
+func tableSchemaName(ts *TableSchema) string {
+	return ts.Name
+}
+
+func sequenceSchemaName(seq *SequenceSchema) string {
+	return seq.Name
+}
+
+func viewSchemaName(view *ViewSchema) string {
+	return view.Name
+}
+
+func namespaceSchemaName(namespace *NamespaceSchema) string {
+	return namespace.Name
+}
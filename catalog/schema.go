@@ -0,0 +1,156 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"garakutadb/storage"
+	"strings"
+)
+
+// Relations and sequences in a schema other than public are named <schema>.<name> in the catalog and the storage,
+// and the ones in public are named without the schema. Unqualified names in statements are resolved with a search path.
+
+// NamespaceSchema is a schema created by CREATE SCHEMA. public and the system schemas always exist.
+type NamespaceSchema struct {
+	Name string `json:"name"`
+}
+
+type NamespaceSchemas []NamespaceSchema
+
+var NamespaceSchemaNotFoundError = errors.New("namespace schema not found")
+
+func (n NamespaceSchemas) Get(name string) (*NamespaceSchema, error) {
+	for i := range n {
+		if n[i].Name == name {
+			return &n[i], nil
+		}
+	}
+	return nil, NamespaceSchemaNotFoundError
+}
+
+func IsSystemSchema(name string) bool {
+	return name == InformationSchemaSchemaName || name == SystemSchemaName
+}
+
+// SchemaExists reports whether the schema exists, including public and the system schemas
+func (ct *Catalog) SchemaExists(name string) bool {
+	if name == PublicSchemaName || IsSystemSchema(name) {
+		return true
+	}
+	_, err := ct.Namespaces.Get(name)
+	return err == nil
+}
+
+// SchemaNames returns public, the system schemas and the created schemas
+func (ct *Catalog) SchemaNames() []string {
+	names := []string{PublicSchemaName, InformationSchemaSchemaName, SystemSchemaName}
+	for _, namespace := range ct.Namespaces {
+		names = append(names, namespace.Name)
+	}
+	return names
+}
+
+// ObjectsInSchema returns the names of the tables, the views and the sequences in the schema
+func (ct *Catalog) ObjectsInSchema(schema string) []string {
+	names := make([]string, 0)
+	add := func(name string) {
+		if s, _ := SplitRelationName(name); s == schema {
+			names = append(names, name)
+		}
+	}
+	for _, ts := range ct.TableSchemas {
+		add(ts.Name)
+	}
+	for _, view := range ct.Views {
+		add(view.Name)
+	}
+	for _, seq := range ct.Sequences {
+		add(seq.Name)
+	}
+	return names
+}
+
+// AddNamespace adds a schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) AddNamespace(namespace *NamespaceSchema, tx *storage.Transaction) error {
+	added := *namespace
+	ct.change(tx, "", func(file *catalogFile) {
+		file.Namespaces = putByName(file.Namespaces, added, namespaceSchemaName)
+	})
+	return nil
+}
+
+// DeleteNamespace deletes a schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) DeleteNamespace(name string, tx *storage.Transaction) error {
+	if _, err := ct.Namespaces.Get(name); err != nil {
+		return nil
+	}
+	ct.change(tx, "", func(file *catalogFile) {
+		file.Namespaces = deleteByName(file.Namespaces, name, namespaceSchemaName)
+	})
+	return nil
+}
+
+// QualifyRelationName returns the name in the catalog of a relation in the schema. It is the reverse of SplitRelationName.
+func QualifyRelationName(schema string, name string) string {
+	if schema == PublicSchemaName || schema == SystemSchemaName && IsSystemView(name) {
+		return name
+	}
+	return schema + "." + name
+}
+
+// SearchPath is the schemas in which unqualified names are searched in order
+type SearchPath []string
+
+var DefaultSearchPath = SearchPath{PublicSchemaName}
+
+// ResolveRelation returns the name in the catalog of a table, a view or a system view.
+// An unqualified name is searched in pg_catalog and the schemas of the search path. It is returned as it is if it is not found.
+func (ct *Catalog) ResolveRelation(name string, path SearchPath) string {
+	return ct.resolve(name, path, func(name string) bool {
+		_, err := ct.GetRelation(name)
+		return err == nil
+	})
+}
+
+// ResolveSequence returns the name in the catalog of a sequence like ResolveRelation
+func (ct *Catalog) ResolveSequence(name string, path SearchPath) string {
+	return ct.resolve(name, path, func(name string) bool {
+		_, err := ct.Sequences.Get(name)
+		return err == nil
+	})
+}
+
+func (ct *Catalog) resolve(name string, path SearchPath, exists func(name string) bool) string {
+	if schema, relation, found := strings.Cut(name, "."); found {
+		return QualifyRelationName(schema, relation)
+	}
+	if IsSystemView(name) {
+		return name
+	}
+	for _, schema := range path {
+		if qualified := QualifyRelationName(schema, name); exists(qualified) {
+			return qualified
+		}
+	}
+	return name
+}
+
+// CreationName returns the name in the catalog of a new relation or sequence.
+// An unqualified name is created in the first schema of the search path which exists.
+func (ct *Catalog) CreationName(name string, path SearchPath) (string, error) {
+	if schema, relation, found := strings.Cut(name, "."); found {
+		if !ct.SchemaExists(schema) {
+			return "", fmt.Errorf("schema \"%s\" does not exist", schema)
+		}
+		if IsSystemSchema(schema) {
+			return "", fmt.Errorf("cannot create relation in system schema \"%s\"", schema)
+		}
+		return QualifyRelationName(schema, relation), nil
+	}
+	for _, schema := range path {
+		if ct.SchemaExists(schema) && !IsSystemSchema(schema) {
+			return QualifyRelationName(schema, name), nil
+		}
+	}
+	return "", fmt.Errorf("no schema has been selected to create in")
+}
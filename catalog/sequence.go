@@ -0,0 +1,67 @@
+package catalog
+
+import (
+	"errors"
+	"garakutadb/storage"
+)
+
+// SequenceSchema is a definition of a sequence. Its counter is kept by the storage.
+type SequenceSchema struct {
+	Name string `json:"name"`
+	storage.SequenceOptions
+}
+
+type SequenceSchemas []SequenceSchema
+
+var SequenceSchemaNotFoundError = errors.New("sequence schema not found")
+
+// Get returns the sequence schema in the list, which must not be changed
+func (s SequenceSchemas) Get(name string) (*SequenceSchema, error) {
+	for i := range s {
+		if s[i].Name == name {
+			return &s[i], nil
+		}
+	}
+	return nil, SequenceSchemaNotFoundError
+}
+
+// AddSequence adds a sequence schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) AddSequence(seq *SequenceSchema, tx *storage.Transaction) error {
+	added := *seq
+	ct.change(tx, seq.Name, func(file *catalogFile) {
+		file.Sequences = putByName(file.Sequences, added, sequenceSchemaName)
+	})
+	return nil
+}
+
+// DeleteSequence deletes a sequence schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) DeleteSequence(name string, tx *storage.Transaction) error {
+	if _, err := ct.Sequences.Get(name); err != nil {
+		return nil
+	}
+	ct.change(tx, name, func(file *catalogFile) {
+		file.Sequences = deleteByName(file.Sequences, name, sequenceSchemaName)
+	})
+	return nil
+}
+
+// SequenceOwner returns the table and the column which own the sequence
+func (t TableSchemas) SequenceOwner(name string) (*TableSchema, *ColumnSchema, bool) {
+	for i := range t {
+		for j := range t[i].Columns {
+			if t[i].Columns[j].Sequence == name {
+				return &t[i], &t[i].Columns[j], true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// RelationExists reports whether a table, a system view, a view or a sequence has the name. They share the namespace.
+func (ct *Catalog) RelationExists(name string) bool {
+	if _, err := ct.GetRelation(name); err == nil {
+		return true
+	}
+	_, err := ct.Sequences.Get(name)
+	return err == nil
+}
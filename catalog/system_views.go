@@ -0,0 +1,133 @@
+package catalog
+
+import (
+	"garakutadb/types"
+	"strings"
+)
+
+// System views are virtual tables whose rows are generated by the executor on every scan.
+
+const (
+	SysLocksViewName        = "sys_locks"
+	SysTransactionsViewName = "sys_transactions"
+
+	// the views of information_schema describe the catalog
+	InformationSchemaTablesViewName           = "information_schema.tables"
+	InformationSchemaColumnsViewName          = "information_schema.columns"
+	InformationSchemaTableConstraintsViewName = "information_schema.table_constraints"
+	InformationSchemaKeyColumnUsageViewName   = "information_schema.key_column_usage"
+	InformationSchemaStatisticsViewName       = "information_schema.statistics"
+	InformationSchemaSchemataViewName         = "information_schema.schemata"
+)
+
+// the schemas which always exist. Tables and views are created in public unless another schema is specified.
+const (
+	PublicSchemaName            = "public"
+	InformationSchemaSchemaName = "information_schema"
+	SystemSchemaName            = "pg_catalog"
+)
+
+var SystemViews = TableSchemas{
+	{
+		Name: SysLocksViewName,
+		Columns: ColumnSchemas{
+			{Name: "locktype", Type: types.Text},
+			{Name: "resource", Type: types.Text},
+			{Name: "table_name", Type: types.Text},
+			{Name: "mode", Type: types.Text},
+			{Name: "holder", Type: types.Text},
+			{Name: "waiters", Type: types.Text},
+		},
+	},
+	{
+		Name: SysTransactionsViewName,
+		Columns: ColumnSchemas{
+			{Name: "id", Type: types.Text},
+			{Name: "state", Type: types.Text},
+			{Name: "isolation_level", Type: types.Text},
+			{Name: "start_time", Type: types.Text},
+			{Name: "statement", Type: types.Text},
+			{Name: "locks_held", Type: types.Text},
+		},
+	},
+	{
+		Name: InformationSchemaTablesViewName,
+		Columns: ColumnSchemas{
+			{Name: "table_schema", Type: types.Text},
+			{Name: "table_name", Type: types.Text},
+			// BASE TABLE, VIEW, MATERIALIZED VIEW or SYSTEM VIEW
+			{Name: "table_type", Type: types.Text},
+		},
+	},
+	{
+		Name: InformationSchemaColumnsViewName,
+		Columns: ColumnSchemas{
+			{Name: "table_schema", Type: types.Text},
+			{Name: "table_name", Type: types.Text},
+			{Name: "column_name", Type: types.Text},
+			{Name: "ordinal_position", Type: types.Int},
+			{Name: "column_default", Type: types.Text},
+			// YES or NO
+			{Name: "is_nullable", Type: types.Text},
+			{Name: "data_type", Type: types.Text},
+			// YES or NO
+			{Name: "is_identity", Type: types.Text},
+			// ALWAYS, BY DEFAULT or NULL
+			{Name: "identity_generation", Type: types.Text},
+		},
+	},
+	{
+		Name: InformationSchemaTableConstraintsViewName,
+		Columns: ColumnSchemas{
+			{Name: "constraint_name", Type: types.Text},
+			{Name: "table_schema", Type: types.Text},
+			{Name: "table_name", Type: types.Text},
+			// PRIMARY KEY, UNIQUE, FOREIGN KEY or CHECK
+			{Name: "constraint_type", Type: types.Text},
+		},
+	},
+	{
+		Name: InformationSchemaKeyColumnUsageViewName,
+		Columns: ColumnSchemas{
+			{Name: "constraint_name", Type: types.Text},
+			{Name: "table_schema", Type: types.Text},
+			{Name: "table_name", Type: types.Text},
+			{Name: "column_name", Type: types.Text},
+			{Name: "ordinal_position", Type: types.Int},
+		},
+	},
+	{
+		// the indexes, one row for each column of an index like MySQL
+		Name: InformationSchemaStatisticsViewName,
+		Columns: ColumnSchemas{
+			{Name: "table_schema", Type: types.Text},
+			{Name: "table_name", Type: types.Text},
+			{Name: "index_name", Type: types.Text},
+			{Name: "non_unique", Type: types.Int},
+			{Name: "seq_in_index", Type: types.Int},
+			{Name: "column_name", Type: types.Text},
+		},
+	},
+	{
+		Name: InformationSchemaSchemataViewName,
+		Columns: ColumnSchemas{
+			{Name: "schema_name", Type: types.Text},
+		},
+	},
+}
+
+func IsSystemView(name string) bool {
+	_, err := SystemViews.Get(name)
+	return err == nil
+}
+
+// SplitRelationName returns the schema and the name in the schema of a relation, e.g. information_schema and tables
+func SplitRelationName(name string) (string, string) {
+	if schema, table, found := strings.Cut(name, "."); found {
+		return schema, table
+	}
+	if IsSystemView(name) {
+		return SystemSchemaName, name
+	}
+	return PublicSchemaName, name
+}
@@ -0,0 +1,29 @@
+package catalog
+
+import "garakutadb/storage"
+
+// The changes of a transaction are seen only by its snapshots until it is committed, so they are reverted by
+// discarding them.
+
+// discardChangesUndoRecord forgets the pending changes of the aborted transaction
+type discardChangesUndoRecord struct {
+	catalog *Catalog
+	tx      *storage.Transaction
+}
+
+func (r *discardChangesUndoRecord) Undo(_ *storage.TransactionManager) error {
+	r.catalog.mutex.Lock()
+	delete(r.catalog.pending, r.tx)
+	r.catalog.mutex.Unlock()
+	return nil
+}
+
+// unlockCommitUndoRecord lets other transactions commit after the transaction fails to commit
+type unlockCommitUndoRecord struct {
+	catalog *Catalog
+}
+
+func (r *unlockCommitUndoRecord) Undo(_ *storage.TransactionManager) error {
+	r.catalog.commitMutex.Unlock()
+	return nil
+}
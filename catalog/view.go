@@ -0,0 +1,133 @@
+package catalog
+
+import (
+	"errors"
+	"garakutadb/storage"
+	"slices"
+)
+
+// ViewSchema is a view, which is expanded to its query by the planner
+type ViewSchema struct {
+	Name string `json:"name"`
+	// the SELECT statement in SQL
+	Query string `json:"query"`
+	// the relation which the query reads: a table, a system view or another view
+	From string `json:"from"`
+	// the columns of the view, which are resolved when the view is created.
+	// The i-th column is the i-th column selected by the query, and SELECT * means the columns of From at that time.
+	Columns ColumnSchemas `json:"columns"`
+}
+
+func (v *ViewSchema) clone() ViewSchema {
+	clone := *v
+	clone.Columns = slices.Clone(v.Columns)
+	return clone
+}
+
+// TableSchema returns a table schema which has the name and the columns of the view
+func (v *ViewSchema) TableSchema() *TableSchema {
+	return &TableSchema{
+		Name:    v.Name,
+		Columns: v.Columns,
+	}
+}
+
+// MaterializedView is the query of a materialized view. The rows of the query are stored in a heap table,
+// and they are recomputed by REFRESH MATERIALIZED VIEW.
+type MaterializedView struct {
+	// the SELECT statement in SQL
+	Query string `json:"query"`
+	// the relation which the query reads
+	From string `json:"from"`
+	// created WITH NO DATA and not refreshed yet. The view can't be read.
+	Unpopulated bool `json:"unpopulated,omitempty"`
+}
+
+func (ts *TableSchema) IsMaterializedView() bool {
+	return ts.Materialized != nil
+}
+
+type ViewSchemas []ViewSchema
+
+var ViewSchemaNotFoundError = errors.New("view schema not found")
+
+// Get returns the view schema in the list, which must not be changed
+func (v ViewSchemas) Get(name string) (*ViewSchema, error) {
+	for i := range v {
+		if v[i].Name == name {
+			return &v[i], nil
+		}
+	}
+	return nil, ViewSchemaNotFoundError
+}
+
+// DependingOn returns the views which read the relation directly
+func (v ViewSchemas) DependingOn(name string) []ViewSchema {
+	views := make([]ViewSchema, 0)
+	for _, view := range v {
+		if view.From == name {
+			views = append(views, view)
+		}
+	}
+	return views
+}
+
+// AddView adds a view schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) AddView(view *ViewSchema, tx *storage.Transaction) error {
+	added := view.clone()
+	ct.change(tx, view.Name, func(file *catalogFile) {
+		file.Views = putByName(file.Views, added, viewSchemaName)
+	})
+	return nil
+}
+
+// UpdateView replaces a view schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) UpdateView(view *ViewSchema, tx *storage.Transaction) error {
+	if _, err := ct.Views.Get(view.Name); err != nil {
+		return nil
+	}
+	updated := view.clone()
+	ct.change(tx, view.Name, func(file *catalogFile) {
+		file.Views = putByName(file.Views, updated, viewSchemaName)
+	})
+	return nil
+}
+
+// DeleteView deletes a view schema. The change is saved when tx is committed, and reverted when tx is aborted.
+func (ct *Catalog) DeleteView(name string, tx *storage.Transaction) error {
+	if _, err := ct.Views.Get(name); err != nil {
+		return nil
+	}
+	ct.change(tx, name, func(file *catalogFile) {
+		file.Views = deleteByName(file.Views, name, viewSchemaName)
+	})
+	return nil
+}
+
+// DependentViews returns the names of the views and the materialized views which read the relation directly
+func (ct *Catalog) DependentViews(name string) []string {
+	names := make([]string, 0)
+	for _, view := range ct.Views.DependingOn(name) {
+		names = append(names, view.Name)
+	}
+	for _, ts := range ct.TableSchemas {
+		if ts.IsMaterializedView() && ts.Materialized.From == name {
+			names = append(names, ts.Name)
+		}
+	}
+	return names
+}
+
+// GetRelation returns the schema of a table, a system view or a view, whose rows can be read by SELECT
+func (ct *Catalog) GetRelation(name string) (*TableSchema, error) {
+	if tableSchema, err := ct.TableSchemas.Get(name); err == nil {
+		return tableSchema, nil
+	}
+	if viewSchema, err := SystemViews.Get(name); err == nil {
+		return viewSchema, nil
+	}
+	if view, err := ct.Views.Get(name); err == nil {
+		return view.TableSchema(), nil
+	}
+	return nil, TableSchemaNotFoundError
+}
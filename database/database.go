@@ -0,0 +1,248 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/parser/statements"
+	"garakutadb/planner"
+	"garakutadb/storage"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultDatabaseName is the database which new sessions use. It is stored in the base directory.
+const DefaultDatabaseName = "default"
+
+// the other databases are stored in the directories under databasesDir. Tables are never named with a leading dot.
+const databasesDir = ".databases"
+
+// DB holds the databases. Each database has its own directory, catalog and transactions, and a session uses one of them at a time.
+type DB struct {
+	basePath string
+
+	// protects databases and the numbers of their sessions
+	mutex *sync.Mutex
+	// the databases which have been opened
+	databases map[string]*instance
+	// the functions registered with CreateFunction, which all the databases of this DB can call
+	functions *expression.Functions
+}
+
+// instance holds the components of a database shared by the sessions using it.
+// Each statement is planned and executed with a snapshot of the catalog.
+type instance struct {
+	name    string
+	catalog *catalog.Catalog
+	storage *storage.Storage
+	txMgr   *storage.TransactionManager
+
+	// the number of sessions using the database
+	sessions int
+}
+
+func Open(basePath string) (*DB, error) {
+	db := &DB{
+		basePath:  basePath,
+		mutex:     new(sync.Mutex),
+		databases: make(map[string]*instance),
+		functions: expression.NewFunctions(),
+	}
+	if _, err := db.open(DefaultDatabaseName); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) NewSession() *Session {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	database := db.databases[DefaultDatabaseName]
+	database.sessions++
+	return &Session{
+		db:             db,
+		database:       database,
+		searchPath:     catalog.DefaultSearchPath,
+		sequenceValues: make(map[string]int64),
+		plans:          make(map[string]*cachedPlan),
+	}
+}
+
+// Function is a function defined in Go for SQL. A scalar function has Call, and an aggregate function has NewAggregator.
+type Function = expression.Function
+
+// Aggregator computes an aggregate function over a group of rows
+type Aggregator = expression.Aggregator
+
+// CreateFunction registers a function which can be called in SQL like the built-in functions, e.g.
+// db.CreateFunction(&Function{Name: "double", Params: []types.Type{types.BigInt}, Returns: types.BigInt, Call: ...}).
+// A function of the same name, including a built-in function, is replaced only for the sessions of this DB. Other DBs
+// in the process keep their own functions.
+func (db *DB) CreateFunction(f *Function) error {
+	name := strings.ToLower(f.Name)
+	if name == planner.FunctionNextval || name == planner.FunctionCurrval {
+		return fmt.Errorf("function %s cannot be replaced", name)
+	}
+	// the caller may change f later
+	copied := *f
+	copied.Params = slices.Clone(f.Params)
+	return db.functions.Register(&copied)
+}
+
+// databasePath returns the directory of the database. The name is checked again so that no path outside the base
+// directory is ever used.
+func (db *DB) databasePath(name string) (string, error) {
+	if name == DefaultDatabaseName {
+		return db.basePath, nil
+	}
+	if err := statements.CheckDatabaseName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(db.basePath, databasesDir, name), nil
+}
+
+// open returns the database, which is opened and recovered when it is used first. db.mutex must be held except in Open.
+func (db *DB) open(name string) (*instance, error) {
+	if database, ok := db.databases[name]; ok {
+		return database, nil
+	}
+	path, err := db.databasePath(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("database \"%s\" does not exist", name)
+		}
+		return nil, err
+	}
+
+	st := storage.NewStorage(storage.NewDiskManager(path))
+	// the transaction manager recovers the files, including the catalog, before it is loaded
+	txMgr, err := storage.NewTransactionManager(st)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := catalog.LoadCatalog(st)
+	if err != nil {
+		return nil, err
+	}
+	ct.SetFunctions(db.functions)
+
+	database := &instance{
+		name:    name,
+		catalog: ct,
+		storage: st,
+		txMgr:   txMgr,
+	}
+	db.databases[name] = database
+	return database, nil
+}
+
+// use moves the session to the database
+func (db *DB) use(s *Session, name string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	database, err := db.open(name)
+	if err != nil {
+		return err
+	}
+	s.database.sessions--
+	database.sessions++
+	s.database = database
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// createDatabase creates the directory of an empty database
+func (db *DB) createDatabase(name string, ifNotExists bool) (string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	path, err := db.databasePath(name)
+	if err != nil {
+		return "", err
+	}
+	if exists(path) {
+		if ifNotExists {
+			return fmt.Sprintf("database \"%s\" already exists, skipping", name), nil
+		}
+		return "", fmt.Errorf("database \"%s\" already exists", name)
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", err
+	}
+	return "successfully created database!", nil
+}
+
+// dropDatabase removes the directory of a database which no session uses. The directory is moved aside first,
+// so that a crash never leaves a broken database.
+func (db *DB) dropDatabase(s *Session, name string, ifExists bool) (string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if name == DefaultDatabaseName {
+		return "", fmt.Errorf("cannot drop the default database")
+	}
+	path, err := db.databasePath(name)
+	if err != nil {
+		return "", err
+	}
+	if !exists(path) {
+		if ifExists {
+			return fmt.Sprintf("database \"%s\" does not exist, skipping", name), nil
+		}
+		return "", fmt.Errorf("database \"%s\" does not exist", name)
+	}
+	if database, ok := db.databases[name]; ok {
+		if s.database == database {
+			return "", fmt.Errorf("cannot drop the currently open database")
+		}
+		if database.sessions > 0 {
+			return "", fmt.Errorf("database \"%s\" is being accessed by other users", name)
+		}
+		if err := database.txMgr.Close(); err != nil {
+			return "", err
+		}
+		delete(db.databases, name)
+	}
+
+	trashPath := filepath.Join(db.basePath, databasesDir, "."+name)
+	if err := os.RemoveAll(trashPath); err != nil {
+		return "", err
+	}
+	if err := os.Rename(path, trashPath); err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(trashPath); err != nil {
+		return "", err
+	}
+	return "successfully dropped database!", nil
+}
+
+// databaseNames returns the names of all databases sorted
+func (db *DB) databaseNames() ([]string, error) {
+	names := []string{DefaultDatabaseName}
+	entries, err := os.ReadDir(filepath.Join(db.basePath, databasesDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		// dropped databases which have not been removed
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
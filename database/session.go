@@ -0,0 +1,345 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/executor"
+	"garakutadb/parser"
+	"garakutadb/parser/statements"
+	"garakutadb/planner"
+	"garakutadb/storage"
+	"strings"
+)
+
+var (
+	NoTransactionError         = errors.New("there is no transaction in progress")
+	TransactionInProgressError = errors.New("there is already a transaction in progress")
+	TransactionAbortedError    = errors.New("current transaction is aborted, commands ignored until end of transaction block")
+)
+
+// Session runs statements of one client.
+// Statements outside of BEGIN ... COMMIT run in their own transaction (autocommit).
+type Session struct {
+	db *DB
+	// the database used by USE
+	database *instance
+	// the schemas in which unqualified names are searched
+	searchPath catalog.SearchPath
+
+	// explicit transaction started by BEGIN
+	transaction *storage.Transaction
+
+	// the last values returned by nextval in the session
+	sequenceValues map[string]int64
+
+	// the plans of the statements executed in the session, keyed by planCacheKey
+	plans map[string]*cachedPlan
+}
+
+// the number of plans kept by a session. The cache is cleared when it is full.
+const planCacheSize = 128
+
+// cachedPlan is reused while the relations which it depends on and the functions are not changed
+type cachedPlan struct {
+	plan         planner.Plan
+	dependencies planner.Dependencies
+	// the version of the registered functions, whose results can be in the plan
+	functionsVersion uint64
+}
+
+func (s *Session) Execute(sql string) (*executor.ResultSet, error) {
+	stmt, err := parser.NewSimpleParser().Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	switch st := stmt.(type) {
+	case *statements.BeginStmt:
+		return s.begin(st)
+	case *statements.CommitStmt:
+		return s.commit()
+	case *statements.RollbackStmt:
+		return s.rollback()
+	case *statements.SetTransactionStmt:
+		return s.setTransaction(st)
+	case *statements.CreateDatabaseStmt:
+		return s.createDatabase(st)
+	case *statements.DropDatabaseStmt:
+		return s.dropDatabase(st)
+	case *statements.UseStmt:
+		return s.use(st)
+	case *statements.ShowDatabasesStmt:
+		return s.showDatabases()
+	case *statements.SetSearchPathStmt:
+		return s.setSearchPath(st)
+	case *statements.ShowSearchPathStmt:
+		return s.showSearchPath()
+	}
+
+	// KILL aborts the transaction of the session immediately only while no statement is running
+	if tx := s.transaction; tx != nil {
+		s.database.txMgr.StartStatement(tx)
+		defer s.database.txMgr.EndStatement(tx)
+	}
+
+	if s.transaction != nil {
+		if err := s.database.txMgr.AbortIfKilled(s.transaction); err != nil {
+			return nil, err
+		}
+		if s.transaction.GetState() != storage.ACTIVE {
+			return nil, TransactionAbortedError
+		}
+		rs, err := s.execute(s.transaction, sql, stmt)
+		if err != nil {
+			// killed while running the statement
+			if killErr := s.database.txMgr.AbortIfKilled(s.transaction); killErr != nil {
+				return nil, killErr
+			}
+			return nil, err
+		}
+		return rs, nil
+	}
+
+	tx, err := s.database.txMgr.Begin()
+	if err != nil {
+		return nil, err
+	}
+	tx.ShareSequenceValues(s.sequenceValues)
+	rs, err := s.execute(tx, sql, stmt)
+	if err != nil {
+		if tx.GetState() == storage.ACTIVE {
+			_ = s.database.txMgr.Abort(tx)
+		}
+		return nil, err
+	}
+	if err := s.database.txMgr.Commit(tx); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// execute plans and executes the statement with a snapshot of the catalog, which has the changes made by tx,
+// so that DDL of other sessions doesn't change the schemas while the statement runs
+func (s *Session) execute(tx *storage.Transaction, sql string, stmt parser.Stmt) (*executor.ResultSet, error) {
+	s.database.txMgr.SetStatement(tx, sql)
+
+	ct := s.database.catalog.Snapshot(tx)
+	pl, err := s.plan(ct, sql, stmt)
+	if err != nil {
+		return nil, err
+	}
+	return executor.NewSimpleExecutor(ct, s.database.storage).Execute(pl, tx, s.database.txMgr)
+}
+
+// plan returns the cached plan of the sql, or makes a new one when the relations which the cached plan depends on
+// have been changed. Only the plans of SELECT, INSERT, UPDATE and DELETE are cached.
+func (s *Session) plan(ct *catalog.Catalog, sql string, stmt parser.Stmt) (planner.Plan, error) {
+	switch stmt.(type) {
+	case *statements.SelectStmt, *statements.InsertStmt, *statements.UpdateStmt, *statements.DeleteStmt:
+	default:
+		return planner.NewSimplePlannerWithSearchPath(ct, s.searchPath).MakePlan(stmt)
+	}
+
+	key := planCacheKey(sql, s.searchPath)
+	functionsVersion := s.db.functions.Version()
+	if cached, ok := s.plans[key]; ok && cached.dependencies.IsValid(ct) && cached.functionsVersion == functionsVersion {
+		return cached.plan, nil
+	}
+	pl, deps, err := planner.NewSimplePlannerWithSearchPath(ct, s.searchPath).MakePlanWithDependencies(stmt)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.plans) >= planCacheSize {
+		s.plans = make(map[string]*cachedPlan)
+	}
+	s.plans[key] = &cachedPlan{
+		plan:             pl,
+		dependencies:     deps,
+		functionsVersion: functionsVersion,
+	}
+	return pl, nil
+}
+
+func planCacheKey(sql string, path catalog.SearchPath) string {
+	return strings.Join(path, ",") + "\x00" + sql
+}
+
+func (s *Session) begin(stmt *statements.BeginStmt) (*executor.ResultSet, error) {
+	if s.transaction != nil {
+		return nil, TransactionInProgressError
+	}
+
+	tx, err := s.database.txMgr.BeginWithOptions(storage.TransactionOptions{
+		IsolationLevel: isolationLevel(stmt.IsolationLevel),
+		ReadOnly:       stmt.ReadOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tx.ShareSequenceValues(s.sequenceValues)
+	s.transaction = tx
+	// idle until the next statement
+	s.database.txMgr.EndStatement(tx)
+
+	return &executor.ResultSet{
+		Message: "BEGIN",
+	}, nil
+}
+
+// setTransaction changes the isolation level of the explicit transaction, which must not have run any statement yet
+func (s *Session) setTransaction(stmt *statements.SetTransactionStmt) (*executor.ResultSet, error) {
+	if s.transaction == nil {
+		return nil, fmt.Errorf("SET TRANSACTION can only be used in transaction blocks")
+	}
+	if s.transaction.GetState() != storage.ACTIVE {
+		return nil, TransactionAbortedError
+	}
+	if err := s.database.txMgr.SetIsolationLevel(s.transaction, isolationLevel(stmt.IsolationLevel)); err != nil {
+		return nil, err
+	}
+	return &executor.ResultSet{
+		Message: "SET",
+	}, nil
+}
+
+func isolationLevel(level statements.IsolationLevel) storage.IsolationLevel {
+	if level == statements.Serializable {
+		return storage.Serializable
+	}
+	return storage.RepeatableRead
+}
+
+func (s *Session) commit() (*executor.ResultSet, error) {
+	if s.transaction == nil {
+		return nil, NoTransactionError
+	}
+
+	tx := s.transaction
+	s.transaction = nil
+	if err := s.database.txMgr.AbortIfKilled(tx); err != nil && err != storage.TransactionKilledError {
+		return nil, err
+	}
+	if tx.GetState() != storage.ACTIVE {
+		// killed by another session
+		return &executor.ResultSet{
+			Message: "ROLLBACK",
+		}, nil
+	}
+	if err := s.database.txMgr.Commit(tx); err != nil {
+		return nil, err
+	}
+
+	return &executor.ResultSet{
+		Message: "COMMIT",
+	}, nil
+}
+
+func (s *Session) rollback() (*executor.ResultSet, error) {
+	if s.transaction == nil {
+		return nil, NoTransactionError
+	}
+
+	tx := s.transaction
+	s.transaction = nil
+	if tx.GetState() == storage.ACTIVE {
+		if err := s.database.txMgr.Abort(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	return &executor.ResultSet{
+		Message: "ROLLBACK",
+	}, nil
+}
+
+// Close releases the database used by the session. The transaction in progress is rolled back.
+func (s *Session) Close() error {
+	if tx := s.transaction; tx != nil {
+		s.database.txMgr.StartStatement(tx)
+		_, err := s.rollback()
+		s.database.txMgr.EndStatement(tx)
+		if err != nil {
+			return err
+		}
+	}
+	s.db.mutex.Lock()
+	s.database.sessions--
+	s.db.mutex.Unlock()
+	return nil
+}
+
+func (s *Session) createDatabase(stmt *statements.CreateDatabaseStmt) (*executor.ResultSet, error) {
+	if s.transaction != nil {
+		return nil, fmt.Errorf("CREATE DATABASE cannot run inside a transaction block")
+	}
+	message, err := s.db.createDatabase(stmt.DatabaseName, stmt.IfNotExists)
+	if err != nil {
+		return nil, err
+	}
+	return &executor.ResultSet{
+		Message: message,
+	}, nil
+}
+
+func (s *Session) dropDatabase(stmt *statements.DropDatabaseStmt) (*executor.ResultSet, error) {
+	if s.transaction != nil {
+		return nil, fmt.Errorf("DROP DATABASE cannot run inside a transaction block")
+	}
+	message, err := s.db.dropDatabase(s, stmt.DatabaseName, stmt.IfExists)
+	if err != nil {
+		return nil, err
+	}
+	return &executor.ResultSet{
+		Message: message,
+	}, nil
+}
+
+// use switches the database. The values of nextval and the plans are forgotten, because they belong to the database.
+func (s *Session) use(stmt *statements.UseStmt) (*executor.ResultSet, error) {
+	if s.transaction != nil {
+		return nil, fmt.Errorf("USE cannot run inside a transaction block")
+	}
+	if err := s.db.use(s, stmt.DatabaseName); err != nil {
+		return nil, err
+	}
+	s.sequenceValues = make(map[string]int64)
+	s.plans = make(map[string]*cachedPlan)
+
+	return &executor.ResultSet{
+		Message: "USE",
+	}, nil
+}
+
+func (s *Session) showDatabases() (*executor.ResultSet, error) {
+	names, err := s.db.databaseNames()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, []string{name})
+	}
+	return &executor.ResultSet{
+		Header: []string{"database"},
+		Rows:   rows,
+	}, nil
+}
+
+// setSearchPath changes the search path of the session. The schemas which don't exist are ignored like PostgreSQL.
+func (s *Session) setSearchPath(stmt *statements.SetSearchPathStmt) (*executor.ResultSet, error) {
+	s.searchPath = catalog.DefaultSearchPath
+	if stmt.SearchPath != nil {
+		s.searchPath = stmt.SearchPath
+	}
+	return &executor.ResultSet{
+		Message: "SET",
+	}, nil
+}
+
+func (s *Session) showSearchPath() (*executor.ResultSet, error) {
+	return &executor.ResultSet{
+		Header: []string{"search_path"},
+		Rows:   [][]string{{strings.Join(s.searchPath, ", ")}},
+	}, nil
+}
@@ -0,0 +1,639 @@
+package database_test
+
+import (
+	"garakutadb/database"
+	"garakutadb/executor"
+	"garakutadb/storage"
+	"garakutadb/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSystemViewsAndKill(t *testing.T) {
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+
+	s1 := db.NewSession()
+	s2 := db.NewSession()
+	_, err = s1.Execute("CREATE TABLE users (id text PRIMARY KEY, name text)")
+	assert.Nil(t, err)
+
+	_, err = s1.Execute("BEGIN")
+	assert.Nil(t, err)
+	_, err = s1.Execute("INSERT INTO users VALUES ('1', 'alice')")
+	assert.Nil(t, err)
+
+	rs, err := s2.Execute("SELECT id, statement FROM sys_transactions WHERE state = 'active'")
+	assert.Nil(t, err)
+	// s1 and the transaction running this select
+	assert.Len(t, rs.Rows, 2)
+	assert.Equal(t, "INSERT INTO users VALUES ('1', 'alice')", rs.Rows[0][1])
+	txId := rs.Rows[0][0]
+
+	rs, err = s2.Execute("SELECT mode FROM sys_locks WHERE holder = '" + txId + "' AND locktype = 'tuple'")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"X"}}, rs.Rows)
+
+	// s1 is idle, so its transaction is aborted immediately, which releases the locks
+	_, err = s2.Execute("KILL " + txId)
+	assert.Nil(t, err)
+	_, err = s2.Execute("KILL " + txId)
+	assert.NotNil(t, err)
+	rs, err = s2.Execute("SELECT mode FROM sys_locks WHERE holder = '" + txId + "'")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 0)
+	rs, err = s2.Execute("SELECT * FROM users")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 0)
+
+	_, err = s1.Execute("SELECT * FROM users")
+	assert.Equal(t, storage.TransactionKilledError, err)
+	_, err = s1.Execute("SELECT * FROM users")
+	assert.Equal(t, database.TransactionAbortedError, err)
+	_, err = s1.Execute("ROLLBACK")
+	assert.Nil(t, err)
+
+	rs, err = s1.Execute("SELECT * FROM users")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 0)
+
+	_, err = s2.Execute("KILL " + txId)
+	assert.NotNil(t, err)
+}
+
+func TestReadOnlyTransactionsShareSnapshot(t *testing.T) {
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+
+	writer := db.NewSession()
+	reader1 := db.NewSession()
+	reader2 := db.NewSession()
+	_, err = writer.Execute("CREATE TABLE users (id text PRIMARY KEY, name text)")
+	assert.Nil(t, err)
+	_, err = writer.Execute("INSERT INTO users VALUES ('1', 'alice')")
+	assert.Nil(t, err)
+	_, err = writer.Execute("CREATE SEQUENCE users_seq")
+	assert.Nil(t, err)
+
+	_, err = reader1.Execute("BEGIN READ ONLY")
+	assert.Nil(t, err)
+	rs, err := reader1.Execute("EXPORT SNAPSHOT")
+	assert.Nil(t, err)
+	snapshotId := rs.Rows[0][0]
+
+	// readers never take locks, so writers are not blocked
+	_, err = writer.Execute("INSERT INTO users VALUES ('2', 'bob')")
+	assert.Nil(t, err)
+	_, err = writer.Execute("UPDATE users SET name = 'carol' WHERE id = '1'")
+	assert.Nil(t, err)
+
+	_, err = reader2.Execute("BEGIN READ ONLY")
+	assert.Nil(t, err)
+	_, err = reader2.Execute("SET TRANSACTION SNAPSHOT '" + snapshotId + "'")
+	assert.Nil(t, err)
+
+	for _, reader := range []*database.Session{reader1, reader2} {
+		rs, err = reader.Execute("SELECT * FROM users")
+		assert.Nil(t, err)
+		assert.Equal(t, [][]string{{"1", "alice"}}, rs.Rows)
+		rs, err = reader.Execute("SELECT name FROM users WHERE id = '1'")
+		assert.Nil(t, err)
+		assert.Equal(t, [][]string{{"alice"}}, rs.Rows)
+	}
+
+	_, err = reader1.Execute("INSERT INTO users VALUES ('3', 'dave')")
+	assert.Equal(t, storage.ReadOnlyTransactionError, err)
+	_, err = reader1.Execute("SELECT nextval('users_seq')")
+	assert.Equal(t, storage.ReadOnlyTransactionError, err)
+	_, err = reader1.Execute("SELECT currval('users_seq')")
+	assert.NotEqual(t, storage.ReadOnlyTransactionError, err)
+
+	_, err = reader1.Execute("COMMIT")
+	assert.Nil(t, err)
+	_, err = reader2.Execute("COMMIT")
+	assert.Nil(t, err)
+
+	// the snapshot is released when the exporting transaction finishes
+	_, err = reader2.Execute("BEGIN READ ONLY")
+	assert.Nil(t, err)
+	_, err = reader2.Execute("SET TRANSACTION SNAPSHOT '" + snapshotId + "'")
+	assert.NotNil(t, err)
+	_, err = reader2.Execute("ROLLBACK")
+	assert.Nil(t, err)
+
+	// the snapshot is taken by the first query, even if it reads nothing
+	_, err = writer.Execute("CREATE TABLE empty (id text PRIMARY KEY)")
+	assert.Nil(t, err)
+	_, err = reader1.Execute("BEGIN READ ONLY")
+	assert.Nil(t, err)
+	rs, err = reader1.Execute("EXPORT SNAPSHOT")
+	assert.Nil(t, err)
+	_, err = reader2.Execute("BEGIN READ ONLY")
+	assert.Nil(t, err)
+	rs2, err := reader2.Execute("SELECT * FROM empty")
+	assert.Nil(t, err)
+	assert.Len(t, rs2.Rows, 0)
+	_, err = reader2.Execute("SET TRANSACTION SNAPSHOT '" + rs.Rows[0][0] + "'")
+	assert.NotNil(t, err)
+}
+
+func TestSerializableTransactionsRejectWriteSkew(t *testing.T) {
+	for _, begin := range [][]string{
+		{"BEGIN ISOLATION LEVEL SERIALIZABLE"},
+		{"START TRANSACTION READ WRITE, ISOLATION LEVEL SERIALIZABLE"},
+		{"BEGIN", "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE"},
+	} {
+		db, err := database.Open(t.TempDir())
+		assert.Nil(t, err)
+
+		s1 := db.NewSession()
+		s2 := db.NewSession()
+		_, err = s1.Execute("CREATE TABLE doctors (name text PRIMARY KEY, on_call text)")
+		assert.Nil(t, err)
+		_, err = s1.Execute("INSERT INTO doctors VALUES ('alice', 'yes')")
+		assert.Nil(t, err)
+		_, err = s1.Execute("INSERT INTO doctors VALUES ('bob', 'yes')")
+		assert.Nil(t, err)
+
+		// each doctor goes off call after checking that the other one is on call
+		for _, s := range []*database.Session{s1, s2} {
+			for _, sql := range begin {
+				_, err = s.Execute(sql)
+				assert.Nil(t, err)
+			}
+			rs, err := s.Execute("SELECT name FROM doctors WHERE on_call = 'yes'")
+			assert.Nil(t, err)
+			assert.Len(t, rs.Rows, 2)
+		}
+		_, err = s1.Execute("UPDATE doctors SET on_call = 'no' WHERE name = 'alice'")
+		assert.Nil(t, err)
+		// the second write completes the write skew
+		_, err = s2.Execute("UPDATE doctors SET on_call = 'no' WHERE name = 'bob'")
+		assert.Equal(t, storage.SerializationFailureError, err)
+		_, err = s2.Execute("COMMIT")
+		assert.Equal(t, storage.SerializationFailureError, err)
+		_, err = s1.Execute("COMMIT")
+		assert.Nil(t, err)
+
+		rs, err := s1.Execute("SELECT name FROM doctors WHERE on_call = 'yes'")
+		assert.Nil(t, err)
+		assert.Equal(t, [][]string{{"bob"}}, rs.Rows)
+	}
+
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+	s := db.NewSession()
+	_, err = s.Execute("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE")
+	assert.NotNil(t, err)
+	_, err = s.Execute("BEGIN ISOLATION LEVEL READ COMMITTED")
+	assert.NotNil(t, err)
+	_, err = s.Execute("BEGIN")
+	assert.Nil(t, err)
+	_, err = s.Execute("SELECT 1")
+	assert.Nil(t, err)
+	_, err = s.Execute("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE")
+	assert.NotNil(t, err)
+}
+
+func TestSerializableTransactionsRejectPhantoms(t *testing.T) {
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+	s1 := db.NewSession()
+	s2 := db.NewSession()
+	mustExecute := func(s *database.Session, sql string) *executor.ResultSet {
+		rs, err := s.Execute(sql)
+		assert.Nil(t, err, sql)
+		return rs
+	}
+	mustExecute(s1, "CREATE TABLE items (id int PRIMARY KEY)")
+	mustExecute(s1, "INSERT INTO items VALUES (10)")
+
+	mustExecute(s1, "BEGIN ISOLATION LEVEL SERIALIZABLE")
+	rs := mustExecute(s1, "SELECT id FROM items WHERE id > 5")
+	assert.Equal(t, [][]string{{"10"}}, rs.Rows)
+
+	mustExecute(s2, "BEGIN ISOLATION LEVEL SERIALIZABLE")
+	rs = mustExecute(s2, "SELECT id FROM items WHERE id > 5")
+	assert.Equal(t, [][]string{{"10"}}, rs.Rows)
+	mustExecute(s2, "INSERT INTO items VALUES (7)")
+	mustExecute(s2, "COMMIT")
+
+	// s1 keeps reading from its snapshot
+	rs = mustExecute(s1, "SELECT id FROM items WHERE id > 5")
+	assert.Equal(t, [][]string{{"10"}}, rs.Rows)
+	rs = mustExecute(s1, "SELECT id FROM items WHERE id = 7")
+	assert.Len(t, rs.Rows, 0)
+	// s1 and s2 have each missed the write of the other
+	_, err = s1.Execute("INSERT INTO items VALUES (8)")
+	assert.Equal(t, storage.SerializationFailureError, err)
+	_, err = s1.Execute("COMMIT")
+	assert.NotNil(t, err)
+
+	rs = mustExecute(s1, "SELECT id FROM items")
+	assert.Equal(t, [][]string{{"10"}, {"7"}}, rs.Rows)
+
+	// a row deleted since the snapshot was taken can't be deleted again
+	mustExecute(s1, "BEGIN ISOLATION LEVEL SERIALIZABLE")
+	mustExecute(s1, "SELECT id FROM items")
+	mustExecute(s2, "DELETE FROM items WHERE id = 7")
+	_, err = s1.Execute("DELETE FROM items WHERE id = 7")
+	assert.Equal(t, storage.ConcurrentUpdateError, err)
+	mustExecute(s1, "ROLLBACK")
+
+	// a key which a serializable transaction has looked up and not found can't be inserted by others
+	mustExecute(s1, "BEGIN ISOLATION LEVEL SERIALIZABLE")
+	rs = mustExecute(s1, "SELECT id FROM items WHERE id = 9")
+	assert.Len(t, rs.Rows, 0)
+	_, err = s2.Execute("INSERT INTO items VALUES (9)")
+	assert.ErrorContains(t, err, "could not insert into index items_pkey of table items: the key range is locked by transaction")
+}
+
+func TestCurrvalIsKeptInSession(t *testing.T) {
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+
+	s1 := db.NewSession()
+	s2 := db.NewSession()
+	_, err = s1.Execute("CREATE TABLE items (id serial PRIMARY KEY, name text)")
+	assert.Nil(t, err)
+	_, err = s1.Execute("INSERT INTO items (name) VALUES ('a')")
+	assert.Nil(t, err)
+	_, err = s2.Execute("INSERT INTO items (name) VALUES ('b')")
+	assert.Nil(t, err)
+
+	rs, err := s1.Execute("SELECT currval('items_id_seq')")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+	rs, err = s2.Execute("SELECT currval('items_id_seq')")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"2"}}, rs.Rows)
+}
+
+func TestDatabasesAndSchemas(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.Open(dir)
+	assert.Nil(t, err)
+
+	s1 := db.NewSession()
+	s2 := db.NewSession()
+	mustExecute := func(s *database.Session, sql string) *executor.ResultSet {
+		rs, err := s.Execute(sql)
+		assert.Nil(t, err, sql)
+		return rs
+	}
+
+	mustExecute(s1, "CREATE DATABASE team")
+	mustExecute(s1, "USE team")
+	mustExecute(s1, "CREATE TABLE users (id int PRIMARY KEY, name text)")
+	mustExecute(s1, "INSERT INTO users VALUES (1, 'alice')")
+	// the databases have their own tables
+	_, err = s2.Execute("SELECT * FROM users")
+	assert.NotNil(t, err)
+	rs := mustExecute(s2, "SHOW DATABASES")
+	assert.Equal(t, [][]string{{"default"}, {"team"}}, rs.Rows)
+
+	mustExecute(s1, "CREATE SCHEMA billing")
+	mustExecute(s1, "CREATE TABLE billing.invoices (id serial PRIMARY KEY, user_id int REFERENCES users)")
+	mustExecute(s1, "INSERT INTO billing.invoices (user_id) VALUES (1)")
+	_, err = s1.Execute("SELECT * FROM invoices")
+	assert.NotNil(t, err)
+
+	mustExecute(s1, "SET search_path TO billing, public")
+	rs = mustExecute(s1, "SELECT * FROM invoices")
+	assert.Equal(t, [][]string{{"1", "1"}}, rs.Rows)
+	rs = mustExecute(s1, "SELECT currval('invoices_id_seq')")
+	assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+	// a new table is created in the first schema
+	mustExecute(s1, "CREATE TABLE items (name text PRIMARY KEY)")
+	rs = mustExecute(s1, "SHOW TABLES")
+	assert.Equal(t, [][]string{{"invoices", "BASE TABLE"}, {"items", "BASE TABLE"}}, rs.Rows)
+	rs = mustExecute(s1, "SELECT constraint_name, constraint_type FROM information_schema.table_constraints WHERE table_schema = 'billing' AND table_name = 'invoices'")
+	assert.Equal(t, [][]string{{"invoices_pkey", "PRIMARY KEY"}, {"invoices_user_id_fkey", "FOREIGN KEY"}}, rs.Rows)
+
+	_, err = s1.Execute("DROP SCHEMA billing")
+	assert.NotNil(t, err)
+	mustExecute(s1, "DROP TABLE items")
+	mustExecute(s1, "DROP TABLE invoices")
+	mustExecute(s1, "DROP SCHEMA billing")
+	_, err = s1.Execute("CREATE TABLE billing.invoices (id int PRIMARY KEY)")
+	assert.EqualError(t, err, "schema \"billing\" does not exist")
+
+	_, err = s2.Execute("DROP DATABASE team")
+	assert.EqualError(t, err, "database \"team\" is being accessed by other users")
+	assert.Nil(t, s1.Close())
+	mustExecute(s2, "DROP DATABASE team")
+	_, err = s2.Execute("USE team")
+	assert.EqualError(t, err, "database \"team\" does not exist")
+
+	// a database is a directory, so a name must not reach outside the base directory
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "victim"), 0755))
+	for _, sql := range []string{
+		"CREATE DATABASE `../evil`",
+		"DROP DATABASE `../victim`",
+		"DROP DATABASE IF EXISTS `..`",
+		"USE `../victim`",
+		"CREATE DATABASE `team/data`",
+	} {
+		_, err := s2.Execute(sql)
+		assert.ErrorContains(t, err, "invalid database name", sql)
+	}
+	_, err = os.Stat(filepath.Join(dir, "victim"))
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "evil"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSchemasAreKeptAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.Open(dir)
+	assert.Nil(t, err)
+	s := db.NewSession()
+	for _, sql := range []string{
+		"CREATE DATABASE team",
+		"USE team",
+		"CREATE SCHEMA hr",
+		"CREATE TABLE hr.staff (id int PRIMARY KEY, name text)",
+		"INSERT INTO hr.staff VALUES (1, 'alice')",
+		"CREATE VIEW hr.names AS SELECT name FROM hr.staff",
+	} {
+		_, err := s.Execute(sql)
+		assert.Nil(t, err, sql)
+	}
+
+	db, err = database.Open(dir)
+	assert.Nil(t, err)
+	s = db.NewSession()
+	_, err = s.Execute("USE team")
+	assert.Nil(t, err)
+	_, err = s.Execute("SET search_path = hr")
+	assert.Nil(t, err)
+	rs, err := s.Execute("SELECT * FROM names")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"alice"}}, rs.Rows)
+	rs, err = s.Execute("SELECT schema_name FROM information_schema.schemata")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"hr"}, {"information_schema"}, {"pg_catalog"}, {"public"}}, rs.Rows)
+}
+
+func TestPlanIsInvalidatedBySchemaChange(t *testing.T) {
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+
+	s1 := db.NewSession()
+	s2 := db.NewSession()
+	_, err = s1.Execute("CREATE TABLE users (id text PRIMARY KEY, name text)")
+	assert.Nil(t, err)
+	_, err = s1.Execute("INSERT INTO users VALUES ('1', 'alice')")
+	assert.Nil(t, err)
+
+	rs, err := s1.Execute("SELECT * FROM users")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "name"}, rs.Header)
+
+	_, err = s2.Execute("ALTER TABLE users ADD COLUMN age int")
+	assert.Nil(t, err)
+	rs, err = s1.Execute("SELECT * FROM users")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "name", "age"}, rs.Header)
+
+	// a table created in a schema before public in the search path hides the cached one
+	_, err = s2.Execute("CREATE SCHEMA app")
+	assert.Nil(t, err)
+	_, err = s1.Execute("SET search_path TO app, public")
+	assert.Nil(t, err)
+	rs, err = s1.Execute("SELECT * FROM users")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 1)
+	_, err = s2.Execute("CREATE TABLE app.users (id text PRIMARY KEY)")
+	assert.Nil(t, err)
+	rs, err = s1.Execute("SELECT * FROM users")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id"}, rs.Header)
+	assert.Len(t, rs.Rows, 0)
+
+	_, err = s2.Execute("DROP TABLE app.users")
+	assert.Nil(t, err)
+	rs, err = s1.Execute("SELECT * FROM users")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 1)
+}
+
+func TestDDLIsSeenByOtherSessionsAfterCommit(t *testing.T) {
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+
+	s1 := db.NewSession()
+	s2 := db.NewSession()
+	_, err = s1.Execute("CREATE TABLE users (id text PRIMARY KEY, name text)")
+	assert.Nil(t, err)
+
+	_, err = s1.Execute("BEGIN")
+	assert.Nil(t, err)
+	_, err = s1.Execute("CREATE TABLE items (id text PRIMARY KEY)")
+	assert.Nil(t, err)
+	_, err = s1.Execute("CREATE SEQUENCE items_seq")
+	assert.Nil(t, err)
+	_, err = s1.Execute("INSERT INTO items VALUES ('1')")
+	assert.Nil(t, err)
+	_, err = s2.Execute("SELECT * FROM items")
+	assert.NotNil(t, err)
+
+	// a sequence created by both transactions
+	_, err = s2.Execute("CREATE SEQUENCE items_seq")
+	assert.Nil(t, err)
+	_, err = s1.Execute("COMMIT")
+	assert.NotNil(t, err)
+	_, err = s2.Execute("SELECT * FROM items")
+	assert.NotNil(t, err)
+
+	_, err = s1.Execute("BEGIN")
+	assert.Nil(t, err)
+	_, err = s1.Execute("CREATE TABLE items (id text PRIMARY KEY)")
+	assert.Nil(t, err)
+	_, err = s1.Execute("COMMIT")
+	assert.Nil(t, err)
+	rs, err := s2.Execute("SELECT * FROM items")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 0)
+
+	// the plan of the other session is made again after the commit
+	_, err = s1.Execute("BEGIN")
+	assert.Nil(t, err)
+	_, err = s1.Execute("DROP TABLE items")
+	assert.Nil(t, err)
+	rs, err = s2.Execute("SELECT * FROM items")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 0)
+	_, err = s1.Execute("COMMIT")
+	assert.Nil(t, err)
+	_, err = s2.Execute("SELECT * FROM items")
+	assert.NotNil(t, err)
+}
+
+func TestConcurrentDDLAndQueries(t *testing.T) {
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+
+	s := db.NewSession()
+	_, err = s.Execute("CREATE TABLE users (id text PRIMARY KEY, name text)")
+	assert.Nil(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ddl := db.NewSession()
+		for i := 0; i < 20; i++ {
+			_, _ = ddl.Execute("CREATE TABLE t (id text PRIMARY KEY)")
+			_, _ = ddl.Execute("DROP TABLE t")
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		_, err := s.Execute("SELECT * FROM users")
+		assert.Nil(t, err)
+	}
+	<-done
+}
+
+// sumAggregator sums the arguments of the rows
+type sumAggregator struct {
+	sum   int64
+	found bool
+}
+
+func (a *sumAggregator) Step(args []types.Value) error {
+	a.sum += args[0].Int()
+	a.found = true
+	return nil
+}
+
+func (a *sumAggregator) Result() (types.Value, error) {
+	if !a.found {
+		return types.NewNull(types.BigInt), nil
+	}
+	return types.NewBigInt(a.sum), nil
+}
+
+func TestUserDefinedFunctions(t *testing.T) {
+	db, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+
+	calls := 0
+	assert.Nil(t, db.CreateFunction(&database.Function{
+		Name:    "test_scale",
+		Params:  []types.Type{types.BigInt, types.BigInt},
+		Returns: types.BigInt,
+		Call: func(args []types.Value) (types.Value, error) {
+			calls++
+			return types.NewBigInt(args[0].Int() * args[1].Int()), nil
+		},
+		Deterministic: true,
+	}))
+	assert.Nil(t, db.CreateFunction(&database.Function{
+		Name:          "test_sum",
+		Params:        []types.Type{types.BigInt},
+		Returns:       types.BigInt,
+		NewAggregator: func() database.Aggregator { return &sumAggregator{} },
+	}))
+
+	s := db.NewSession()
+	_, err = s.Execute("CREATE TABLE items (id int PRIMARY KEY, quantity int)")
+	assert.Nil(t, err)
+	for _, values := range []string{"1, 2", "2, NULL", "3, 5"} {
+		_, err = s.Execute("INSERT INTO items VALUES (" + values + ")")
+		assert.Nil(t, err)
+	}
+
+	rs, err := s.Execute("SELECT id, test_scale(quantity, 10) FROM items WHERE quantity IS NOT NULL")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"1", "20"}, {"3", "50"}}, rs.Rows)
+
+	// computed once in planning, and the plan is reused
+	calls = 0
+	for i := 0; i < 2; i++ {
+		rs, err = s.Execute("SELECT id FROM items WHERE quantity < test_scale(2, 2)")
+		assert.Nil(t, err)
+		assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+	}
+	assert.Equal(t, 1, calls)
+
+	rs, err = s.Execute("SELECT test_sum(quantity), test_sum(quantity * 2) + 1 FROM items")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"7", "15"}}, rs.Rows)
+	rs, err = s.Execute("SELECT test_sum(quantity) FROM items WHERE id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"NULL"}}, rs.Rows)
+
+	// a replaced function is used by the cached plan
+	assert.Nil(t, db.CreateFunction(&database.Function{
+		Name:    "test_scale",
+		Params:  []types.Type{types.BigInt, types.BigInt},
+		Returns: types.BigInt,
+		Call: func(args []types.Value) (types.Value, error) {
+			return types.NewBigInt(args[0].Int() + args[1].Int()), nil
+		},
+		Deterministic: true,
+	}))
+	rs, err = s.Execute("SELECT id FROM items WHERE quantity < test_scale(2, 2)")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+	rs, err = s.Execute("SELECT id, test_scale(quantity, 10) FROM items WHERE quantity IS NOT NULL")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"1", "12"}, {"3", "15"}}, rs.Rows)
+
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT id, test_sum(quantity) FROM items", `column "id" must appear in the GROUP BY clause or be used in an aggregate function`},
+		{"SELECT test_sum(test_sum(quantity)) FROM items", "aggregate function calls cannot be nested"},
+		{"SELECT id FROM items WHERE test_sum(quantity) > 1", "aggregate functions are not allowed in WHERE"},
+		{"SELECT test_scale(id) FROM items", "function test_scale(int) does not exist"},
+	}
+	for _, tt := range tests {
+		_, err := s.Execute(tt.sql)
+		assert.EqualError(t, err, tt.err, tt.sql)
+	}
+	assert.NotNil(t, db.CreateFunction(&database.Function{Name: "nextval", Call: func(args []types.Value) (types.Value, error) {
+		return types.Value{}, nil
+	}}))
+}
+
+func TestFunctionsAreScopedToDB(t *testing.T) {
+	db1, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+	db2, err := database.Open(t.TempDir())
+	assert.Nil(t, err)
+
+	assert.Nil(t, db1.CreateFunction(&database.Function{
+		Name:    "test_twice",
+		Params:  []types.Type{types.BigInt},
+		Returns: types.BigInt,
+		Call: func(args []types.Value) (types.Value, error) {
+			return types.NewBigInt(args[0].Int() * 2), nil
+		},
+		Deterministic: true,
+	}))
+	// a built-in function is replaced only in db1
+	assert.Nil(t, db1.CreateFunction(&database.Function{
+		Name:    "abs",
+		Params:  []types.Type{types.BigInt},
+		Returns: types.BigInt,
+		Call: func(args []types.Value) (types.Value, error) {
+			return types.NewBigInt(0), nil
+		},
+		Deterministic: true,
+	}))
+
+	rs, err := db1.NewSession().Execute("SELECT test_twice(21), abs(-3)")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"42", "0"}}, rs.Rows)
+
+	s2 := db2.NewSession()
+	_, err = s2.Execute("SELECT test_twice(21)")
+	assert.EqualError(t, err, "function test_twice does not exist")
+	rs, err = s2.Execute("SELECT abs(-3)")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"3"}}, rs.Rows)
+}
@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/types"
+)
+
+// applyTypmods rounds the values of a new row to the scales of the decimal columns, or fails if they overflow the precision
+func applyTypmods(tableSchema *catalog.TableSchema, row []types.Value) error {
+	for order, column := range tableSchema.Columns {
+		value, err := column.Typmod.Apply(row[order])
+		if err != nil {
+			return err
+		}
+		row[order] = value
+	}
+	return nil
+}
+
+// checkConstraints validates a new row against NOT NULL and CHECK constraints.
+// Unique constraints are checked with the indexes.
+func checkConstraints(tableSchema *catalog.TableSchema, row []types.Value, functions *expression.Functions) error {
+	for order, column := range tableSchema.Columns {
+		if tableSchema.IsNotNull(&column) && row[order].IsNull() {
+			return fmt.Errorf("null value in column \"%s\" violates not-null constraint", column.Name)
+		}
+	}
+
+	if len(tableSchema.Checks) == 0 {
+		return nil
+	}
+	columnNameAndOrderMap := columnNameAndOrderMap(tableSchema)
+	for _, check := range tableSchema.Checks {
+		expr, err := expression.ParseCondition(check.Expression)
+		if err != nil {
+			return err
+		}
+		// unknown (NULL) satisfies the constraint
+		result, err := evalCondition(expr, row, columnNameAndOrderMap, functions)
+		if err != nil {
+			return err
+		}
+		if result == ternaryFalse {
+			return fmt.Errorf("new row for relation \"%s\" violates check constraint \"%s\"", tableSchema.Name, check.Name)
+		}
+	}
+	return nil
+}
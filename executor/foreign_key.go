@@ -0,0 +1,150 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/storage"
+	"garakutadb/types"
+)
+
+// checkReferences fails if a foreign key of row references a parent row which doesn't exist.
+// oldRow is nil on insert. Foreign keys which are not changed by update are not checked.
+func (w *rowWriter) checkReferences(tableSchema *catalog.TableSchema, oldRow []types.Value, row []types.Value) error {
+	for _, fk := range tableSchema.ForeignKeys {
+		order, _ := tableSchema.Columns.Contains(fk.Column)
+		value := row[order]
+		if value.IsNull() {
+			continue
+		}
+		if oldRow != nil && !oldRow[order].IsNull() && types.EncodeKey(oldRow[order]) == types.EncodeKey(value) {
+			continue
+		}
+
+		found, err := w.parentExists(fk, value)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("insert or update on table \"%s\" violates foreign key constraint \"%s\"", tableSchema.Name, fk.Name)
+		}
+	}
+	return nil
+}
+
+// parentExists searches the index of the referenced column. The parent row is locked, so that it isn't deleted until the transaction ends.
+func (w *rowWriter) parentExists(fk catalog.ForeignKeyConstraint, value types.Value) (bool, error) {
+	parent, err := w.catalog.TableSchemas.Get(fk.RefTable)
+	if err != nil {
+		return false, err
+	}
+	indexName, _ := parent.IndexNameOf(fk.RefColumn)
+	refOrder, _ := parent.Columns.Contains(fk.RefColumn)
+	key := types.EncodeKey(value)
+
+	btree, err := w.storage.ReadIndex(parent.Name, indexName)
+	if err != nil {
+		return false, err
+	}
+	item, found := btree.Search(&storage.StringItem{
+		Value: key,
+	})
+	if !found {
+		return false, nil
+	}
+
+	_, err = w.storage.GetTupleFromPage(parent.Name, item.GetPageId(), func(tuple *storage.Tuple) bool {
+		parentRow, err := decodeTuple(parent, tuple)
+		return err == nil && types.EncodeKey(parentRow[refOrder]) == key
+	}, w.transaction, w.transactionMgr)
+	if err == storage.TupleNotFoundError {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// applyReferentialActions runs ON DELETE or ON UPDATE actions of the foreign keys referencing a changed parent row.
+// newRow is nil on delete.
+func (w *rowWriter) applyReferentialActions(tableSchema *catalog.TableSchema, oldRow []types.Value, newRow []types.Value) error {
+	for _, reference := range w.catalog.TableSchemas.ReferencedBy(tableSchema.Name) {
+		fk := reference.ForeignKey
+		refOrder, _ := tableSchema.Columns.Contains(fk.RefColumn)
+		oldValue := oldRow[refOrder]
+		if oldValue.IsNull() {
+			continue
+		}
+		action := fk.OnDelete
+		if newRow != nil {
+			if !newRow[refOrder].IsNull() && types.EncodeKey(newRow[refOrder]) == types.EncodeKey(oldValue) {
+				continue
+			}
+			action = fk.OnUpdate
+		}
+
+		child := reference.Table
+		order, _ := child.Columns.Contains(fk.Column)
+		children, err := w.referencingRows(child, order, oldValue)
+		if err != nil {
+			return err
+		}
+		for _, c := range children {
+			updated := make([]types.Value, len(c.row))
+			copy(updated, c.row)
+
+			switch {
+			case action == catalog.Cascade && newRow == nil:
+				err = w.delete(child, c.tupleId, c.row)
+			case action == catalog.Cascade:
+				updated[order] = newRow[refOrder]
+				err = w.update(child, c.tupleId, c.row, updated)
+			case action == catalog.SetNull:
+				updated[order] = types.NewNull(child.Columns[order].Type)
+				err = w.update(child, c.tupleId, c.row, updated)
+			default:
+				err = fmt.Errorf("update or delete on table \"%s\" violates foreign key constraint \"%s\" on table \"%s\"", tableSchema.Name, fk.Name, child.Name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type storedRow struct {
+	tupleId *storage.TupleId
+	row     []types.Value
+}
+
+// referencingRows scans the child table for rows whose column at order is value.
+// Foreign key columns don't have indexes, so the whole table is read.
+func (w *rowWriter) referencingRows(child *catalog.TableSchema, order uint64, value types.Value) ([]storedRow, error) {
+	key := types.EncodeKey(value)
+	rows := make([]storedRow, 0)
+
+	w.transactionMgr.PredicateLockRelation(w.transaction, child.Name)
+	it := w.storage.NewTupleIterator(child.Name, w.transaction)
+	for {
+		tuple, found := it.Next(w.transactionMgr)
+		if !found {
+			if err := it.Err(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if len(tuple.Data) == 0 {
+			continue
+		}
+
+		row, err := decodeTuple(child, tuple)
+		if err != nil {
+			return nil, err
+		}
+		if !row[order].IsNull() && types.EncodeKey(row[order]) == key {
+			rows = append(rows, storedRow{
+				tupleId: it.GetTupleId(),
+				row:     row,
+			})
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/storage"
+	"garakutadb/types"
+)
+
+// indexEntry is a key of a row in the primary key index or a unique index
+type indexEntry struct {
+	indexName      string
+	constraintName string
+	key            string
+}
+
+// indexEntries returns the index keys of row. NULL is not indexed, so that unique columns can have many NULLs.
+func indexEntries(tableSchema *catalog.TableSchema, row []types.Value) map[string]indexEntry {
+	entries := make(map[string]indexEntry)
+	if tableSchema.HasPK() {
+		entries[tableSchema.PKConstraintName()] = indexEntry{
+			indexName:      tableSchema.PKConstraintName(),
+			constraintName: tableSchema.PKConstraintName(),
+			key:            primaryKey(tableSchema, row),
+		}
+	}
+	for _, unique := range tableSchema.Uniques {
+		order, _ := tableSchema.Columns.Contains(unique.Column)
+		if row[order].IsNull() {
+			continue
+		}
+		entries[unique.Name] = indexEntry{
+			indexName:      unique.Name,
+			constraintName: unique.Name,
+			key:            types.EncodeKey(row[order]),
+		}
+	}
+	return entries
+}
+
+type indexWriter struct {
+	storage        *storage.Storage
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
+	tableName      string
+}
+
+// checkInsert fails if the key is already used, or a concurrent transaction has read the gap
+func (w *indexWriter) checkInsert(entry indexEntry) error {
+	btree, err := w.storage.ReadIndex(w.tableName, entry.indexName)
+	if err != nil {
+		return err
+	}
+
+	if _, found := btree.Search(&storage.StringItem{
+		Value: entry.key,
+	}); found {
+		return fmt.Errorf("duplicate key value violates unique constraint \"%s\"", entry.constraintName)
+	}
+	// the key may have been deleted by a transaction which has not finished
+	if !w.transactionMgr.LockKey(w.transaction, btree, entry.key) {
+		return fmt.Errorf("failed to lock key of index: %s", entry.indexName)
+	}
+
+	if err := w.transactionMgr.CheckKeyRangeForInsert(w.transaction, btree, entry.key); err != nil {
+		return err
+	}
+
+	return w.transactionMgr.CheckForSerializableConflictIn(w.transaction, &storage.PredicateLockTarget{
+		TableName: w.tableName,
+		IndexName: entry.indexName,
+		Key:       entry.key,
+	})
+}
+
+func (w *indexWriter) insert(entry indexEntry, pageId storage.PageId) error {
+	btree, err := w.storage.ReadIndex(w.tableName, entry.indexName)
+	if err != nil {
+		return err
+	}
+	return w.storage.InsertIndexItem(btree, &storage.StringItem{
+		Value:  entry.key,
+		PageId: pageId,
+	}, w.transaction)
+}
+
+func (w *indexWriter) delete(entry indexEntry) error {
+	if err := w.transactionMgr.CheckForSerializableConflictIn(w.transaction, &storage.PredicateLockTarget{
+		TableName: w.tableName,
+		IndexName: entry.indexName,
+		Key:       entry.key,
+	}); err != nil {
+		return err
+	}
+	btree, err := w.storage.ReadIndex(w.tableName, entry.indexName)
+	if err != nil {
+		return err
+	}
+	if !w.transactionMgr.LockKey(w.transaction, btree, entry.key) {
+		return fmt.Errorf("failed to lock key of index: %s", entry.indexName)
+	}
+	w.transactionMgr.InheritKeyRangeLocks(btree, entry.key)
+	return w.storage.DeleteIndexItem(btree, entry.key, w.transaction)
+}
+
+func (w *indexWriter) updatePageId(entry indexEntry, pageId storage.PageId) error {
+	btree, err := w.storage.ReadIndex(w.tableName, entry.indexName)
+	if err != nil {
+		return err
+	}
+	if !w.transactionMgr.LockKey(w.transaction, btree, entry.key) {
+		return fmt.Errorf("failed to lock key of index: %s", entry.indexName)
+	}
+	return w.storage.UpdateIndexItemPageId(btree, entry.key, pageId, w.transaction)
+}
@@ -0,0 +1,211 @@
+package executor
+
+import (
+	"cmp"
+	"garakutadb/catalog"
+	"garakutadb/types"
+	"slices"
+)
+
+// The rows of information_schema are generated from the catalog in the orders of catalog.SystemViews.
+
+const (
+	tableTypeBaseTable        = "BASE TABLE"
+	tableTypeView             = "VIEW"
+	tableTypeMaterializedView = "MATERIALIZED VIEW"
+	tableTypeSystemView       = "SYSTEM VIEW"
+)
+
+// relation is a table, a view or a system view shown in information_schema
+type relation struct {
+	schemaName string
+	name       string
+	tableType  string
+	schema     *catalog.TableSchema
+}
+
+// relations returns all relations sorted by the schema and the name
+func (e *SystemViewScanExecutor) relations() []relation {
+	relations := make([]relation, 0)
+	add := func(ts *catalog.TableSchema, tableType string) {
+		schemaName, name := catalog.SplitRelationName(ts.Name)
+		relations = append(relations, relation{
+			schemaName: schemaName,
+			name:       name,
+			tableType:  tableType,
+			schema:     ts,
+		})
+	}
+
+	for i := range e.catalog.TableSchemas {
+		ts := &e.catalog.TableSchemas[i]
+		if ts.IsMaterializedView() {
+			add(ts, tableTypeMaterializedView)
+		} else {
+			add(ts, tableTypeBaseTable)
+		}
+	}
+	for _, view := range e.catalog.Views {
+		add(view.TableSchema(), tableTypeView)
+	}
+	for i := range catalog.SystemViews {
+		add(&catalog.SystemViews[i], tableTypeSystemView)
+	}
+
+	slices.SortFunc(relations, func(a relation, b relation) int {
+		if c := cmp.Compare(a.schemaName, b.schemaName); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+	return relations
+}
+
+// tables returns the tables and the materialized views, which have constraints and indexes
+func (e *SystemViewScanExecutor) tables() []relation {
+	return slices.DeleteFunc(e.relations(), func(r relation) bool {
+		return r.tableType != tableTypeBaseTable && r.tableType != tableTypeMaterializedView
+	})
+}
+
+// tablesRows returns rows of (table_schema, table_name, table_type)
+func (e *SystemViewScanExecutor) tablesRows() [][]types.Value {
+	rows := make([][]types.Value, 0)
+	for _, r := range e.relations() {
+		rows = append(rows, []types.Value{
+			types.NewText(r.schemaName),
+			types.NewText(r.name),
+			types.NewText(r.tableType),
+		})
+	}
+	return rows
+}
+
+// columnsRows returns rows of (table_schema, table_name, column_name, ordinal_position, column_default, is_nullable,
+// data_type, is_identity, identity_generation)
+func (e *SystemViewScanExecutor) columnsRows() [][]types.Value {
+	rows := make([][]types.Value, 0)
+	for _, r := range e.relations() {
+		for i, column := range r.schema.Columns {
+			// the default of an identity column is not shown like PostgreSQL
+			columnDefault := types.NewNull(types.Text)
+			if column.Default != nil && column.Identity == catalog.NotIdentity {
+				columnDefault = types.NewText(*column.Default)
+			}
+			identityGeneration := types.NewNull(types.Text)
+			if column.Identity != catalog.NotIdentity {
+				identityGeneration = types.NewText(string(column.Identity))
+			}
+			rows = append(rows, []types.Value{
+				types.NewText(r.schemaName),
+				types.NewText(r.name),
+				types.NewText(column.Name),
+				types.NewInt(int64(i + 1)),
+				columnDefault,
+				yesOrNo(!r.schema.IsNotNull(&column)),
+				types.NewText(column.Type.String()),
+				yesOrNo(column.Identity != catalog.NotIdentity),
+				identityGeneration,
+			})
+		}
+	}
+	return rows
+}
+
+// tableConstraintsRows returns rows of (constraint_name, table_schema, table_name, constraint_type)
+func (e *SystemViewScanExecutor) tableConstraintsRows() [][]types.Value {
+	rows := make([][]types.Value, 0)
+	for _, r := range e.tables() {
+		add := func(name string, constraintType string) {
+			rows = append(rows, []types.Value{
+				types.NewText(name),
+				types.NewText(r.schemaName),
+				types.NewText(r.name),
+				types.NewText(constraintType),
+			})
+		}
+		if r.schema.HasPK() {
+			add(r.schema.PKConstraintName(), "PRIMARY KEY")
+		}
+		for _, unique := range r.schema.Uniques {
+			add(unique.Name, "UNIQUE")
+		}
+		for _, fk := range r.schema.ForeignKeys {
+			add(fk.Name, "FOREIGN KEY")
+		}
+		for _, check := range r.schema.Checks {
+			add(check.Name, "CHECK")
+		}
+	}
+	return rows
+}
+
+// keyColumnUsageRows returns rows of (constraint_name, table_schema, table_name, column_name, ordinal_position)
+// for the columns of primary keys, unique constraints and foreign keys
+func (e *SystemViewScanExecutor) keyColumnUsageRows() [][]types.Value {
+	rows := make([][]types.Value, 0)
+	for _, r := range e.tables() {
+		add := func(name string, column string, position int) {
+			rows = append(rows, []types.Value{
+				types.NewText(name),
+				types.NewText(r.schemaName),
+				types.NewText(r.name),
+				types.NewText(column),
+				types.NewInt(int64(position)),
+			})
+		}
+		for i, column := range r.schema.PK {
+			add(r.schema.PKConstraintName(), column, i+1)
+		}
+		for _, unique := range r.schema.Uniques {
+			add(unique.Name, unique.Column, 1)
+		}
+		for _, fk := range r.schema.ForeignKeys {
+			add(fk.Name, fk.Column, 1)
+		}
+	}
+	return rows
+}
+
+// statisticsRows returns rows of (table_schema, table_name, index_name, non_unique, seq_in_index, column_name).
+// All indexes are unique, because they are created for primary keys and unique constraints.
+func (e *SystemViewScanExecutor) statisticsRows() [][]types.Value {
+	rows := make([][]types.Value, 0)
+	for _, r := range e.tables() {
+		add := func(name string, column string, seq int) {
+			rows = append(rows, []types.Value{
+				types.NewText(r.schemaName),
+				types.NewText(r.name),
+				types.NewText(name),
+				types.NewInt(0),
+				types.NewInt(int64(seq)),
+				types.NewText(column),
+			})
+		}
+		for i, column := range r.schema.PK {
+			add(r.schema.PKConstraintName(), column, i+1)
+		}
+		for _, unique := range r.schema.Uniques {
+			add(unique.Name, unique.Column, 1)
+		}
+	}
+	return rows
+}
+
+// schemataRows returns rows of (schema_name) sorted by the name
+func (e *SystemViewScanExecutor) schemataRows() [][]types.Value {
+	names := e.catalog.SchemaNames()
+	slices.Sort(names)
+	rows := make([][]types.Value, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, []types.Value{types.NewText(name)})
+	}
+	return rows
+}
+
+func yesOrNo(b bool) types.Value {
+	if b {
+		return types.NewText("YES")
+	}
+	return types.NewText("NO")
+}
@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/storage"
+	"garakutadb/types"
+	"slices"
+)
+
+// decodeTuple converts a stored tuple to typed values in the order of the columns.
+// Tuples written before a column was added don't have its slot, and the missing value of the column is used.
+func decodeTuple(tableSchema *catalog.TableSchema, tuple *storage.Tuple) ([]types.Value, error) {
+	if len(tuple.Data) > int(tableSchema.NextSlot) {
+		return nil, fmt.Errorf("tuple of %s has %d slots, but the table has %d", tableSchema.Name, len(tuple.Data), tableSchema.NextSlot)
+	}
+
+	row := make([]types.Value, len(tableSchema.Columns))
+	for order, column := range tableSchema.Columns {
+		slot := int(column.Slot)
+		if slot >= len(tuple.Data) {
+			value, err := missingValue(&column)
+			if err != nil {
+				return nil, err
+			}
+			row[order] = value
+			continue
+		}
+		if isNullAt(tuple.Nulls, slot) {
+			row[order] = types.NewNull(column.Type)
+			continue
+		}
+		value, err := types.Decode(column.Type, tuple.Data[slot].Value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column.Name, err)
+		}
+		row[order] = value
+	}
+	return row, nil
+}
+
+func missingValue(column *catalog.ColumnSchema) (types.Value, error) {
+	if column.Missing == nil {
+		return types.NewNull(column.Type), nil
+	}
+	return types.Parse(column.Type, *column.Missing)
+}
+
+// encodeTuple stores the values of row in the slots of the columns. The slots of dropped columns are NULL.
+func encodeTuple(tableSchema *catalog.TableSchema, row []types.Value) *storage.Tuple {
+	data := make([]*storage.TupleValue, tableSchema.NextSlot)
+	nulls := make([]byte, (tableSchema.NextSlot+7)/8)
+	for slot := range data {
+		data[slot] = &storage.TupleValue{}
+		nulls[slot/8] |= 1 << (slot % 8)
+	}
+
+	for order, column := range tableSchema.Columns {
+		slot := column.Slot
+		if row[order].IsNull() {
+			continue
+		}
+		data[slot].Value = types.Encode(row[order])
+		nulls[slot/8] &^= 1 << (slot % 8)
+	}
+	if !slices.ContainsFunc(nulls, func(b byte) bool { return b != 0 }) {
+		nulls = nil
+	}
+	return &storage.Tuple{
+		Data:  data,
+		Nulls: nulls,
+	}
+}
+
+// isNullAt reports whether the i-th bit of the null bitmap is set
+func isNullAt(nulls []byte, i int) bool {
+	return i/8 < len(nulls) && nulls[i/8]&(1<<(i%8)) != 0
+}
+
+// projectRow returns the columns of row in columnOrders
+func projectRow(row []types.Value, columnOrders []uint64) []types.Value {
+	projected := make([]types.Value, 0, len(columnOrders))
+	for _, columnOrder := range columnOrders {
+		projected = append(projected, row[columnOrder])
+	}
+	return projected
+}
+
+// selectRow returns the columns of row in columnOrders, or the values of projections computed from row
+func selectRow(row []types.Value, columnOrders []uint64, projections []expression.Expression, columnNameAndOrderMap map[string]uint64, functions *expression.Functions) ([]types.Value, error) {
+	if projections == nil {
+		return projectRow(row, columnOrders), nil
+	}
+	selected := make([]types.Value, 0, len(projections))
+	for _, projection := range projections {
+		value, err := expression.Eval(projection, &columnRow{values: row, orders: columnNameAndOrderMap}, functions)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, value)
+	}
+	return selected, nil
+}
+
+// formatRows returns the values of rows as strings
+func formatRows(rows [][]types.Value) [][]string {
+	formatted := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		columns := make([]string, 0, len(row))
+		for _, value := range row {
+			columns = append(columns, value.String())
+		}
+		formatted = append(formatted, columns)
+	}
+	return formatted
+}
+
+func columnNameAndOrderMap(tableSchema *catalog.TableSchema) map[string]uint64 {
+	m := make(map[string]uint64)
+	for order, col := range tableSchema.Columns {
+		m[col.Name] = uint64(order)
+	}
+	return m
+}
+
+// primaryKey returns the index key of the primary key of row
+func primaryKey(tableSchema *catalog.TableSchema, row []types.Value) string {
+	orders := tableSchema.PKOrders()
+	values := make([]types.Value, len(orders))
+	for i, order := range orders {
+		values[i] = row[order]
+	}
+	return types.EncodeKeys(values...)
+}
+
+// evalExpressions computes the expressions which replace the values at the same index, converting them to the types of
+// the values
+func evalExpressions(exprs map[int]expression.Expression, values []types.Value, functions *expression.Functions) ([]types.Value, error) {
+	if len(exprs) == 0 {
+		return values, nil
+	}
+
+	evaluated := make([]types.Value, len(values))
+	copy(evaluated, values)
+	for i, expr := range exprs {
+		value, err := expression.Eval(expr, nil, functions)
+		if err != nil {
+			return nil, err
+		}
+		if evaluated[i], err = types.Cast(value, values[i].Type()); err != nil {
+			return nil, err
+		}
+	}
+	return evaluated, nil
+}
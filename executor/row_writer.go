@@ -0,0 +1,155 @@
+package executor
+
+import (
+	"garakutadb/catalog"
+	"garakutadb/storage"
+	"garakutadb/types"
+)
+
+// rowWriter inserts, deletes and updates rows keeping the indexes and the constraints.
+// Insert, update and delete executors and the referential actions of foreign keys share it.
+type rowWriter struct {
+	catalog        *catalog.Catalog
+	storage        *storage.Storage
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
+}
+
+func newRowWriter(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *rowWriter {
+	return &rowWriter{
+		catalog:        ct,
+		storage:        st,
+		transaction:    tx,
+		transactionMgr: txMgr,
+	}
+}
+
+func (w *rowWriter) indexWriter(tableName string) *indexWriter {
+	return &indexWriter{
+		storage:        w.storage,
+		transaction:    w.transaction,
+		transactionMgr: w.transactionMgr,
+		tableName:      tableName,
+	}
+}
+
+func (w *rowWriter) insert(tableSchema *catalog.TableSchema, row []types.Value) error {
+	if err := w.save(tableSchema, row); err != nil {
+		return err
+	}
+	// checked after the row is saved, so that a row can reference itself
+	return w.checkReferences(tableSchema, nil, row)
+}
+
+// save inserts row without checking its foreign keys
+func (w *rowWriter) save(tableSchema *catalog.TableSchema, row []types.Value) error {
+	if err := applyTypmods(tableSchema, row); err != nil {
+		return err
+	}
+	if err := checkConstraints(tableSchema, row, w.catalog.Functions()); err != nil {
+		return err
+	}
+
+	// check indexes before saving the row, so that a failed insert leaves nothing behind
+	indexWriter := w.indexWriter(tableSchema.Name)
+	entries := indexEntries(tableSchema, row)
+	for _, indexName := range tableSchema.IndexNames() {
+		if entry, ok := entries[indexName]; ok {
+			if err := indexWriter.checkInsert(entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	// save row
+	page, err := w.storage.InsertTuple(tableSchema.Name, encodeTuple(tableSchema, row), w.transaction, w.transactionMgr)
+	if err != nil {
+		return err
+	}
+
+	// save index
+	for _, indexName := range tableSchema.IndexNames() {
+		if entry, ok := entries[indexName]; ok {
+			if err := indexWriter.insert(entry, page.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *rowWriter) delete(tableSchema *catalog.TableSchema, tupleId *storage.TupleId, row []types.Value) error {
+	// delete tuple
+	if err := w.storage.DeleteTuple(tableSchema.Name, tupleId, w.transaction, w.transactionMgr); err != nil {
+		return err
+	}
+
+	// delete index entries
+	indexWriter := w.indexWriter(tableSchema.Name)
+	for _, entry := range indexEntries(tableSchema, row) {
+		if err := indexWriter.delete(entry); err != nil {
+			return err
+		}
+	}
+
+	return w.applyReferentialActions(tableSchema, row, nil)
+}
+
+// update replaces oldRow at tupleId with newRow. The new version of the tuple is appended to the table.
+func (w *rowWriter) update(tableSchema *catalog.TableSchema, tupleId *storage.TupleId, oldRow []types.Value, newRow []types.Value) error {
+	if err := applyTypmods(tableSchema, newRow); err != nil {
+		return err
+	}
+	if err := checkConstraints(tableSchema, newRow, w.catalog.Functions()); err != nil {
+		return err
+	}
+	indexWriter := w.indexWriter(tableSchema.Name)
+	oldEntries := indexEntries(tableSchema, oldRow)
+	newEntries := indexEntries(tableSchema, newRow)
+	for _, indexName := range tableSchema.IndexNames() {
+		newEntry, ok := newEntries[indexName]
+		if ok && newEntry != oldEntries[indexName] {
+			if err := indexWriter.checkInsert(newEntry); err != nil {
+				return err
+			}
+		}
+	}
+
+	// update tuple
+	tuple := encodeTuple(tableSchema, newRow)
+	w.transactionMgr.UnlockSharedByTupleId(w.transaction, tupleId)
+	if err := w.storage.DeleteTuple(tableSchema.Name, tupleId, w.transaction, w.transactionMgr); err != nil {
+		return err
+	}
+	insertedTuplePage, err := w.storage.InsertTuple(tableSchema.Name, tuple, w.transaction, w.transactionMgr)
+	if err != nil {
+		return err
+	}
+
+	// update index entries
+	for _, indexName := range tableSchema.IndexNames() {
+		oldEntry, hadOld := oldEntries[indexName]
+		newEntry, hasNew := newEntries[indexName]
+		if hadOld && hasNew && oldEntry == newEntry {
+			if err := indexWriter.updatePageId(newEntry, insertedTuplePage.Id); err != nil {
+				return err
+			}
+			continue
+		}
+		if hadOld {
+			if err := indexWriter.delete(oldEntry); err != nil {
+				return err
+			}
+		}
+		if hasNew {
+			if err := indexWriter.insert(newEntry, insertedTuplePage.Id); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := w.checkReferences(tableSchema, oldRow, newRow); err != nil {
+		return err
+	}
+	return w.applyReferentialActions(tableSchema, oldRow, newRow)
+}
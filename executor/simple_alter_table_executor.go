@@ -0,0 +1,168 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+	"garakutadb/types"
+)
+
+type AlterTableExecutor struct {
+	storage        *storage.Storage
+	catalog        *catalog.Catalog
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
+}
+
+func NewAlterTableExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *AlterTableExecutor {
+	return &AlterTableExecutor{
+		storage:        st,
+		catalog:        ct,
+		transaction:    tx,
+		transactionMgr: txMgr,
+	}
+}
+
+func (e *AlterTableExecutor) Execute(pl planner.AlterTablePlan) (*ResultSet, error) {
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("column \"%s\" of relation \"%s\" does not exist, skipping", pl.ColumnName, pl.TableName),
+		}, nil
+	}
+
+	newName := pl.NewSchema.Name
+	for _, tableName := range []string{pl.TableName, newName} {
+		if !e.transactionMgr.LockTable(e.transaction, tableName, storage.Exclusive) {
+			return nil, fmt.Errorf("failed to lock table: %s", tableName)
+		}
+	}
+	oldSchema, err := e.catalog.TableSchemas.Get(pl.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case pl.Rewrite:
+		if err := e.rewrite(oldSchema, pl.NewSchema.Clone()); err != nil {
+			return nil, err
+		}
+	case newName != pl.TableName:
+		if err := e.storage.RenameTable(pl.TableName, newName, e.transaction); err != nil {
+			return nil, err
+		}
+		if err := e.catalog.Delete(pl.TableName, e.transaction); err != nil {
+			return nil, err
+		}
+		if err := e.catalog.Add(pl.NewSchema, e.transaction); err != nil {
+			return nil, err
+		}
+	default:
+		for _, indexName := range pl.DroppedIndexNames {
+			if err := e.storage.DropIndex(pl.TableName, indexName, e.transaction); err != nil {
+				return nil, err
+			}
+		}
+		if err := e.catalog.Update(pl.NewSchema, e.transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, referencing := range pl.ReferencingSchemas {
+		if err := e.catalog.Update(referencing, e.transaction); err != nil {
+			return nil, err
+		}
+	}
+	if pl.DroppedSequenceName != "" {
+		if err := dropSequence(e.catalog, e.storage, e.transaction, pl.DroppedSequenceName); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ResultSet{
+		Message: "successfully altered table!",
+	}, nil
+}
+
+// rewrite rebuilds the table with newSchema. The rows are converted to the new types and inserted into the empty table,
+// so that the constraints are checked and the indexes are built again. The columns of the new table have consecutive slots.
+func (e *AlterTableExecutor) rewrite(oldSchema *catalog.TableSchema, newSchema *catalog.TableSchema) error {
+	rows := make([][]types.Value, 0)
+	it := e.storage.NewTupleIterator(oldSchema.Name, e.transaction)
+	for {
+		tuple, found := it.Next(e.transactionMgr)
+		if !found {
+			if err := it.Err(); err != nil {
+				return err
+			}
+			break
+		}
+		if len(tuple.Data) == 0 {
+			continue
+		}
+		oldRow, err := decodeTuple(oldSchema, tuple)
+		if err != nil {
+			return err
+		}
+		row, err := convertRow(oldSchema, newSchema, oldRow)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	for i := range newSchema.Columns {
+		newSchema.Columns[i].Slot = uint32(i)
+		newSchema.Columns[i].Missing = nil
+	}
+	newSchema.NextSlot = uint32(len(newSchema.Columns))
+
+	if err := e.storage.TruncateTable(newSchema.Name, newSchema.IndexNames(), e.transaction); err != nil {
+		return err
+	}
+	if err := e.catalog.Update(newSchema, e.transaction); err != nil {
+		return err
+	}
+
+	// foreign keys are checked after all rows are inserted, because a row can reference a row of the same table
+	writer := newRowWriter(e.catalog, e.storage, e.transaction, e.transactionMgr)
+	for _, row := range rows {
+		if err := writer.save(newSchema, row); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		if err := writer.checkReferences(newSchema, nil, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertRow maps a row of oldSchema to newSchema. Columns are identified by their slots, and added columns have their missing values.
+func convertRow(oldSchema *catalog.TableSchema, newSchema *catalog.TableSchema, oldRow []types.Value) ([]types.Value, error) {
+	row := make([]types.Value, len(newSchema.Columns))
+	for order, column := range newSchema.Columns {
+		oldOrder := -1
+		for i, oldColumn := range oldSchema.Columns {
+			if oldColumn.Slot == column.Slot {
+				oldOrder = i
+			}
+		}
+		if oldOrder < 0 {
+			value, err := missingValue(&column)
+			if err != nil {
+				return nil, err
+			}
+			row[order] = value
+			continue
+		}
+
+		value, err := types.Cast(oldRow[oldOrder], column.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column \"%s\" cannot be cast automatically to type %s: %w", column.Name, column.Type, err)
+		}
+		row[order] = value
+	}
+	return row, nil
+}
@@ -1,27 +1,45 @@
 package executor
 
 import (
+	"fmt"
 	"garakutadb/catalog"
 	"garakutadb/planner"
 	"garakutadb/storage"
 )
 
 type CreateTableExecutor struct {
-	storage *storage.Storage
-	catalog *catalog.Catalog
+	storage        *storage.Storage
+	catalog        *catalog.Catalog
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
 }
 
-func NewCreateTableExecutor(ct *catalog.Catalog, st *storage.Storage) *CreateTableExecutor {
+func NewCreateTableExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *CreateTableExecutor {
 	return &CreateTableExecutor{
-		storage: st,
-		catalog: ct,
+		storage:        st,
+		catalog:        ct,
+		transaction:    tx,
+		transactionMgr: txMgr,
 	}
 }
 
 func (e *CreateTableExecutor) Execute(pl planner.CreateTablePlan) (*ResultSet, error) {
-	if err := e.catalog.Add(pl.TableSchema); err != nil {
+	if !e.transactionMgr.LockTable(e.transaction, pl.TableSchema.Name, storage.Exclusive) {
+		return nil, fmt.Errorf("failed to lock table: %s", pl.TableSchema.Name)
+	}
+
+	if err := e.storage.CreateTable(pl.TableSchema.Name, pl.TableSchema.IndexNames(), e.transaction); err != nil {
+		return nil, err
+	}
+
+	if err := e.catalog.Add(pl.TableSchema, e.transaction); err != nil {
 		return nil, err
 	}
+	for _, sequence := range pl.Sequences {
+		if err := createSequence(e.catalog, e.storage, e.transaction, &sequence); err != nil {
+			return nil, err
+		}
+	}
 
 	return &ResultSet{
 		Message: "successfully created table!",
@@ -1,7 +1,6 @@
 package executor
 
 import (
-	"fmt"
 	"garakutadb/catalog"
 	"garakutadb/planner"
 	"garakutadb/storage"
@@ -24,25 +23,23 @@ func NewDeleteExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Tra
 }
 
 func (e *DeleteExecutor) Execute(pl planner.DeletePlan) (*ResultSet, error) {
-	isDeleteAll := pl.WhereExpression == nil
-	if isDeleteAll {
-		return nil, fmt.Errorf("deleting all is not supported yet")
-	}
-
 	tableSchema, err := e.catalog.TableSchemas.Get(pl.TableName)
 	if err != nil {
 		return nil, err
 	}
 
-	columnNameAndOrderMap := make(map[string]uint64)
-	for order, col := range tableSchema.Columns {
-		columnNameAndOrderMap[col.Name] = uint64(order)
-	}
+	columnNameAndOrderMap := columnNameAndOrderMap(tableSchema)
+
+	writer := newRowWriter(e.catalog, e.storage, e.transaction, e.transactionMgr)
 
+	e.transactionMgr.PredicateLockRelation(e.transaction, pl.TableName)
 	it := e.storage.NewTupleIterator(pl.TableName, e.transaction)
 	for true {
 		tuple, found := it.Next(e.transactionMgr)
 		if !found {
+			if err := it.Err(); err != nil {
+				return nil, err
+			}
 			break
 		}
 
@@ -50,33 +47,25 @@ func (e *DeleteExecutor) Execute(pl planner.DeletePlan) (*ResultSet, error) {
 			continue
 		}
 
-		row := make([]string, len(columnNameAndOrderMap))
-		for order := range tableSchema.Columns {
-			row[order] = tuple.Data[order].Value
-		}
-
-		evalResult, err := evalWhere(pl.WhereExpression, row, columnNameAndOrderMap)
+		row, err := decodeTuple(tableSchema, tuple)
 		if err != nil {
 			return nil, err
 		}
-		if evalResult {
-			// delete tuple
-			if err := e.storage.DeleteTuple(pl.TableName, it.GetTupleId(), e.transaction, e.transactionMgr); err != nil {
-				return nil, err
-			}
 
-			// delete index entry
-			btree, err := e.storage.ReadIndex(pl.TableName, tableSchema.PK)
+		// all rows are deleted without WHERE
+		if pl.WhereExpression != nil {
+			evalResult, err := evalWhere(pl.WhereExpression, row, columnNameAndOrderMap, e.catalog.Functions())
 			if err != nil {
 				return nil, err
 			}
-			btree.Delete(&storage.StringItem{
-				Value: tuple.Data[0].Value,
-			})
-			if err := e.storage.WriteIndex(btree); err != nil {
-				return nil, err
+			if !evalResult {
+				continue
 			}
 		}
+
+		if err := writer.delete(tableSchema, it.GetTupleId(), row); err != nil {
+			return nil, err
+		}
 	}
 
 	return &ResultSet{
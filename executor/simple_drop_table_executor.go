@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+)
+
+type DropTableExecutor struct {
+	storage        *storage.Storage
+	catalog        *catalog.Catalog
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
+}
+
+func NewDropTableExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *DropTableExecutor {
+	return &DropTableExecutor{
+		storage:        st,
+		catalog:        ct,
+		transaction:    tx,
+		transactionMgr: txMgr,
+	}
+}
+
+func (e *DropTableExecutor) Execute(pl planner.DropTablePlan) (*ResultSet, error) {
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("table %s does not exist, skipping", pl.TableName),
+		}, nil
+	}
+
+	if !e.transactionMgr.LockTable(e.transaction, pl.TableName, storage.Exclusive) {
+		return nil, fmt.Errorf("failed to lock table: %s", pl.TableName)
+	}
+
+	// the files are removed when the transaction is committed
+	if err := e.storage.DropTable(pl.TableName, e.transaction); err != nil {
+		return nil, err
+	}
+
+	if err := e.catalog.Delete(pl.TableName, e.transaction); err != nil {
+		return nil, err
+	}
+	for _, name := range pl.SequenceNames {
+		if err := dropSequence(e.catalog, e.storage, e.transaction, name); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ResultSet{
+		Message: "successfully dropped table!",
+	}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"garakutadb/expression"
 	"garakutadb/planner"
 	"garakutadb/storage"
+	"garakutadb/types"
 )
 
 type SimpleExecutor struct {
@@ -30,11 +31,18 @@ type ResultSet struct {
 }
 
 func (e *SimpleExecutor) Execute(pl planner.Plan, tx *storage.Transaction, txMgr *storage.TransactionManager) (*ResultSet, error) {
+	if tx.IsReadOnly() && isWritePlan(pl) {
+		return nil, storage.ReadOnlyTransactionError
+	}
+	if _, ok := pl.(*planner.ImportSnapshotPlan); !ok {
+		txMgr.TakeSnapshot(tx)
+	}
+
 	switch p := pl.(type) {
 	case *planner.SeqScanPlan:
-		return NewSeqScanExecutor(e.storage, tx, txMgr).Execute(*p)
+		return NewSeqScanExecutor(e.catalog, e.storage, tx, txMgr).Execute(*p)
 	case *planner.IndexScanPlan:
-		return NewIndexScanExecutor(e.storage, tx, txMgr).Execute(*p)
+		return NewIndexScanExecutor(e.catalog, e.storage, tx, txMgr).Execute(*p)
 	case *planner.InsertPlan:
 		return NewInsertExecutor(e.catalog, e.storage, tx, txMgr).Execute(*p)
 	case *planner.DeletePlan:
@@ -42,28 +50,112 @@ func (e *SimpleExecutor) Execute(pl planner.Plan, tx *storage.Transaction, txMgr
 	case *planner.UpdatePlan:
 		return NewUpdateExecutor(e.catalog, e.storage, tx, txMgr).Execute(*p)
 	case *planner.CreateTablePlan:
-		return NewCreateTableExecutor(e.catalog, e.storage).Execute(*p)
+		return NewCreateTableExecutor(e.catalog, e.storage, tx, txMgr).Execute(*p)
+	case *planner.AlterTablePlan:
+		return NewAlterTableExecutor(e.catalog, e.storage, tx, txMgr).Execute(*p)
+	case *planner.DropTablePlan:
+		return NewDropTableExecutor(e.catalog, e.storage, tx, txMgr).Execute(*p)
+	case *planner.TruncateTablePlan:
+		return NewTruncateTableExecutor(e.catalog, e.storage, tx, txMgr).Execute(*p)
+	case *planner.ResultPlan:
+		return NewResultExecutor(e.catalog, e.storage, tx).Execute(*p)
+	case *planner.CreateSequencePlan:
+		return NewSequenceExecutor(e.catalog, e.storage, tx).Create(*p)
+	case *planner.DropSequencePlan:
+		return NewSequenceExecutor(e.catalog, e.storage, tx).Drop(*p)
+	case *planner.CreateViewPlan:
+		return NewViewExecutor(e.catalog, tx).Create(*p)
+	case *planner.DropViewPlan:
+		return NewViewExecutor(e.catalog, tx).Drop(*p)
+	case *planner.CreateMaterializedViewPlan:
+		return NewMaterializedViewExecutor(e.catalog, e.storage, tx, txMgr).Create(*p)
+	case *planner.RefreshMaterializedViewPlan:
+		return NewMaterializedViewExecutor(e.catalog, e.storage, tx, txMgr).Refresh(*p)
+	case *planner.DropMaterializedViewPlan:
+		return NewMaterializedViewExecutor(e.catalog, e.storage, tx, txMgr).Drop(*p)
+	case *planner.CreateSchemaPlan:
+		return NewSchemaExecutor(e.catalog, tx).Create(*p)
+	case *planner.DropSchemaPlan:
+		return NewSchemaExecutor(e.catalog, tx).Drop(*p)
+	case *planner.SystemViewScanPlan:
+		return NewSystemViewScanExecutor(e.catalog, txMgr).Execute(*p)
+	case *planner.KillPlan:
+		return NewKillExecutor(tx, txMgr).Execute(*p)
+	case *planner.ExportSnapshotPlan:
+		return NewSnapshotExecutor(tx, txMgr).Export(*p)
+	case *planner.ImportSnapshotPlan:
+		return NewSnapshotExecutor(tx, txMgr).Import(*p)
 	default:
 		return nil, fmt.Errorf("not supported plan type: %T", p)
 	}
 }
 
-func evalWhere(expr expression.Expression, row []string, columnNameNadOrderMap map[string]uint64) (bool, error) {
-	switch e := expr.(type) {
-	case *expression.AndExpression:
-		leftResult, err := evalWhere(e.Left, row, columnNameNadOrderMap)
-		if err != nil {
-			return false, err
-		}
-		rightResult, err := evalWhere(e.Right, row, columnNameNadOrderMap)
-		if err != nil {
-			return false, err
+// isWritePlan reports whether a plan changes the database. Calling nextval changes the sequence.
+func isWritePlan(pl planner.Plan) bool {
+	switch p := pl.(type) {
+	case *planner.ResultPlan:
+		for _, call := range p.SequenceCalls {
+			if call.Function == planner.FunctionNextval {
+				return true
+			}
 		}
-		return leftResult && rightResult, nil
-	case *expression.ComparisonExpression:
-		return row[columnNameNadOrderMap[e.Left.(*expression.ValueExpression).Value]] ==
-			e.Right.(*expression.ValueExpression).Value, nil
+		return false
+	case *planner.InsertPlan, *planner.DeletePlan, *planner.UpdatePlan,
+		*planner.CreateTablePlan, *planner.AlterTablePlan, *planner.DropTablePlan, *planner.TruncateTablePlan,
+		*planner.CreateSequencePlan, *planner.DropSequencePlan, *planner.CreateViewPlan, *planner.DropViewPlan,
+		*planner.CreateMaterializedViewPlan, *planner.RefreshMaterializedViewPlan, *planner.DropMaterializedViewPlan,
+		*planner.CreateSchemaPlan, *planner.DropSchemaPlan:
+		return true
 	default:
-		return false, fmt.Errorf("not supported expression type: %T", expr)
+		return false
+	}
+}
+
+// ternary is a truth value of three-valued logic. Comparisons with NULL are unknown.
+type ternary int8
+
+const (
+	ternaryFalse ternary = iota
+	ternaryTrue
+	ternaryUnknown
+)
+
+// evalWhere reports whether expr is true for row. Rows for which expr is unknown are filtered out as well as false.
+func evalWhere(expr expression.Expression, row []types.Value, columnNameNadOrderMap map[string]uint64, functions *expression.Functions) (bool, error) {
+	result, err := evalCondition(expr, row, columnNameNadOrderMap, functions)
+	if err != nil {
+		return false, err
+	}
+	return result == ternaryTrue, nil
+}
+
+func evalCondition(expr expression.Expression, row []types.Value, columnNameNadOrderMap map[string]uint64, functions *expression.Functions) (ternary, error) {
+	result, err := expression.Eval(expr, &columnRow{values: row, orders: columnNameNadOrderMap}, functions)
+	if err != nil {
+		return ternaryUnknown, err
+	}
+	if result.IsNull() {
+		return ternaryUnknown, nil
+	}
+	if result.Type() != types.Boolean {
+		return ternaryUnknown, fmt.Errorf("argument of WHERE must be type boolean, not type %s", result.Type())
+	}
+	if result.Bool() {
+		return ternaryTrue, nil
+	}
+	return ternaryFalse, nil
+}
+
+// columnRow gives the columns of a row to expressions
+type columnRow struct {
+	values []types.Value
+	orders map[string]uint64
+}
+
+func (r *columnRow) Column(name string) (types.Value, error) {
+	order, ok := r.orders[name]
+	if !ok {
+		return types.Value{}, fmt.Errorf("column not found: %s", name)
 	}
+	return r.values[order], nil
 }
@@ -0,0 +1,1071 @@
+package executor_test
+
+import (
+	"garakutadb/catalog"
+	"garakutadb/executor"
+	"garakutadb/parser"
+	"garakutadb/planner"
+	"garakutadb/storage"
+	"garakutadb/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+type testDB struct {
+	t       *testing.T
+	catalog *catalog.Catalog
+	storage *storage.Storage
+	txMgr   *storage.TransactionManager
+}
+
+func newTestDB(t *testing.T) *testDB {
+	return openTestDB(t, t.TempDir())
+}
+
+func openTestDB(t *testing.T, dir string) *testDB {
+	st := storage.NewStorage(storage.NewDiskManager(dir))
+	txMgr, err := storage.NewTransactionManager(st)
+	assert.Nil(t, err)
+	ct, err := catalog.LoadCatalog(st)
+	assert.Nil(t, err)
+	return &testDB{
+		t:       t,
+		catalog: ct,
+		storage: st,
+		txMgr:   txMgr,
+	}
+}
+
+func (db *testDB) begin(opts storage.TransactionOptions) *storage.Transaction {
+	tx, err := db.txMgr.BeginWithOptions(opts)
+	assert.Nil(db.t, err)
+	return tx
+}
+
+func (db *testDB) execute(tx *storage.Transaction, sql string) (*executor.ResultSet, error) {
+	stmt, err := parser.NewSimpleParser().Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+	ct := db.catalog.Snapshot(tx)
+	pl, err := planner.NewSimplePlanner(ct).MakePlan(stmt)
+	if err != nil {
+		return nil, err
+	}
+	return executor.NewSimpleExecutor(ct, db.storage).Execute(pl, tx, db.txMgr)
+}
+
+// mustExecute executes sql in a new transaction and commits it
+func (db *testDB) mustExecute(sql string) *executor.ResultSet {
+	tx := db.begin(storage.TransactionOptions{})
+	rs, err := db.execute(tx, sql)
+	assert.Nil(db.t, err)
+	assert.Nil(db.t, db.txMgr.Commit(tx))
+	return rs
+}
+
+func (db *testDB) setup() {
+	db.mustExecute("CREATE TABLE users (id text PRIMARY KEY, name text)")
+	db.mustExecute("INSERT INTO users VALUES ('1', 'alice')")
+	db.mustExecute("INSERT INTO users VALUES ('2', 'bob')")
+}
+
+func TestAbortAfterInsert(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "INSERT INTO users VALUES ('3', 'carol')")
+	assert.Nil(t, err)
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	rs := db.mustExecute("SELECT * FROM users")
+	assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob"}}, rs.Rows)
+
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '3'")
+	assert.Len(t, rs.Rows, 0)
+
+	// index entry is also reverted, so the same key can be inserted again
+	db.mustExecute("INSERT INTO users VALUES ('3', 'dave')")
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '3'")
+	assert.Equal(t, [][]string{{"3", "dave"}}, rs.Rows)
+}
+
+func TestAbortAfterUpdate(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "UPDATE users SET name = 'eve' WHERE id = '1'")
+	assert.Nil(t, err)
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	rs := db.mustExecute("SELECT * FROM users")
+	assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob"}}, rs.Rows)
+
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '1'")
+	assert.Equal(t, [][]string{{"1", "alice"}}, rs.Rows)
+}
+
+func TestAbortAfterDelete(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "DELETE FROM users WHERE id = '2'")
+	assert.Nil(t, err)
+	// the deleted key is locked, so that it can be put back when tx is aborted
+	other := db.begin(storage.TransactionOptions{})
+	_, err = db.execute(other, "INSERT INTO users VALUES ('2', 'carol')")
+	assert.EqualError(t, err, "failed to lock key of index: users_pkey")
+	assert.Nil(t, db.txMgr.Abort(other))
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	rs := db.mustExecute("SELECT * FROM users")
+	assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob"}}, rs.Rows)
+
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '2'")
+	assert.Equal(t, [][]string{{"2", "bob"}}, rs.Rows)
+}
+
+func TestAbortAfterCreateTable(t *testing.T) {
+	db := newTestDB(t)
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "CREATE TABLE users (id text PRIMARY KEY, name text)")
+	assert.Nil(t, err)
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	_, err = db.catalog.TableSchemas.Get("users")
+	assert.ErrorIs(t, err, catalog.TableSchemaNotFoundError)
+
+	// the table can be created again
+	db.setup()
+}
+
+func TestAbortAfterDropTable(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "DROP TABLE users")
+	assert.Nil(t, err)
+	_, err = db.execute(tx, "CREATE TABLE users (id text PRIMARY KEY)")
+	assert.Nil(t, err)
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	rs := db.mustExecute("SELECT * FROM users")
+	assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob"}}, rs.Rows)
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '2'")
+	assert.Equal(t, [][]string{{"2", "bob"}}, rs.Rows)
+}
+
+func TestAbortAfterTruncate(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "TRUNCATE TABLE users")
+	assert.Nil(t, err)
+	rs, err := db.execute(tx, "SELECT * FROM users")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 0)
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	rs = db.mustExecute("SELECT * FROM users")
+	assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob"}}, rs.Rows)
+}
+
+func TestDropTable(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir)
+	db.setup()
+	db.mustExecute("CREATE TABLE posts (id int PRIMARY KEY, user_id text REFERENCES users)")
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "DROP TABLE users")
+	assert.EqualError(t, err, "cannot drop table users because constraint posts_user_id_fkey on table posts depends on it")
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	db.mustExecute("DROP TABLE posts")
+	db.mustExecute("DROP TABLE users")
+	rs := db.mustExecute("DROP TABLE IF EXISTS users")
+	assert.Equal(t, "table users does not exist, skipping", rs.Message)
+
+	// the files are removed on commit
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	for _, entry := range entries {
+		assert.False(t, entry.IsDir(), entry.Name())
+	}
+
+	// a table with the same name is empty
+	db.mustExecute("CREATE TABLE users (id text PRIMARY KEY, name text)")
+	rs = db.mustExecute("SELECT * FROM users")
+	assert.Len(t, rs.Rows, 0)
+}
+
+func TestDeleteAll(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	db.mustExecute("DELETE FROM users")
+	rs := db.mustExecute("SELECT * FROM users")
+	assert.Len(t, rs.Rows, 0)
+
+	// index entries are also deleted
+	db.mustExecute("INSERT INTO users VALUES ('1', 'alice')")
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '1'")
+	assert.Equal(t, [][]string{{"1", "alice"}}, rs.Rows)
+}
+
+func TestInsertIntoRangeReadBySerializableTransaction(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	reader := db.begin(storage.TransactionOptions{IsolationLevel: storage.Serializable})
+	rs, err := db.execute(reader, "SELECT * FROM users WHERE id = '3'")
+	assert.Nil(t, err)
+	assert.Len(t, rs.Rows, 0)
+
+	writer := db.begin(storage.TransactionOptions{})
+	_, err = db.execute(writer, "INSERT INTO users VALUES ('3', 'carol')")
+	assert.Error(t, err)
+	assert.Nil(t, db.txMgr.Abort(writer))
+
+	// keys outside of the locked range can be inserted
+	db.mustExecute("INSERT INTO users VALUES ('0', 'dave')")
+
+	assert.Nil(t, db.txMgr.Commit(reader))
+	db.mustExecute("INSERT INTO users VALUES ('3', 'carol')")
+}
+
+func TestUncommittedChangesAreInvisibleAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir)
+	db.setup()
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "INSERT INTO users VALUES ('3', 'carol')")
+	assert.Nil(t, err)
+	_, err = db.execute(tx, "DELETE FROM users WHERE id = '1'")
+	assert.Nil(t, err)
+	_, err = db.execute(tx, "UPDATE users SET name = 'eve' WHERE id = '2'")
+	assert.Nil(t, err)
+
+	// restart without committing tx
+	db = openTestDB(t, dir)
+	rs := db.mustExecute("SELECT * FROM users")
+	assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob"}}, rs.Rows)
+
+	// the index entries of tx are reverted too
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '1'")
+	assert.Equal(t, [][]string{{"1", "alice"}}, rs.Rows)
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '2'")
+	assert.Equal(t, [][]string{{"2", "bob"}}, rs.Rows)
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '3'")
+	assert.Len(t, rs.Rows, 0)
+	db.mustExecute("INSERT INTO users VALUES ('3', 'dave')")
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '3'")
+	assert.Equal(t, [][]string{{"3", "dave"}}, rs.Rows)
+}
+
+func TestUncommittedDDLIsRevertedAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir)
+	db.setup()
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "DROP TABLE users")
+	assert.Nil(t, err)
+	_, err = db.execute(tx, "CREATE TABLE posts (id int PRIMARY KEY)")
+	assert.Nil(t, err)
+	// the catalog committed by another transaction doesn't have the changes of tx
+	db.mustExecute("CREATE TABLE tags (name text PRIMARY KEY)")
+
+	// restart without committing tx
+	db = openTestDB(t, dir)
+	rs := db.mustExecute("SELECT * FROM users")
+	assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob"}}, rs.Rows)
+	rs = db.mustExecute("SELECT * FROM users WHERE id = '2'")
+	assert.Equal(t, [][]string{{"2", "bob"}}, rs.Rows)
+	_, err = db.catalog.TableSchemas.Get("tags")
+	assert.Nil(t, err)
+	_, err = db.catalog.TableSchemas.Get("posts")
+	assert.ErrorIs(t, err, catalog.TableSchemaNotFoundError)
+
+	// the directory of posts has been removed
+	db.mustExecute("CREATE TABLE posts (id int PRIMARY KEY)")
+	db.mustExecute("INSERT INTO posts VALUES (1)")
+	rs = db.mustExecute("SELECT * FROM posts")
+	assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+}
+
+func TestTypedColumns(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE items (id int PRIMARY KEY, price decimal(10, 2), rate double, active boolean, released date, updated_at timestamp, data bytea)")
+	db.mustExecute("INSERT INTO items VALUES (10, 12.50, 0.5, true, '2024-01-02', '2024-01-02 03:04:05', X'0a0b')")
+	db.mustExecute("INSERT INTO items VALUES (-3, 1, -1.5, false, '1999-12-31', '1999-12-31 23:59:59', '')")
+
+	rs := db.mustExecute("SELECT * FROM items WHERE id = 10")
+	assert.Equal(t, [][]string{{"10", "12.50", "0.5", "true", "2024-01-02", "2024-01-02 03:04:05", `\x0a0b`}}, rs.Rows)
+
+	// values are compared by their types
+	rs = db.mustExecute("SELECT id FROM items WHERE price = 12.5")
+	assert.Equal(t, [][]string{{"10"}}, rs.Rows)
+	rs = db.mustExecute("SELECT id FROM items WHERE active = false AND rate = -1.50")
+	assert.Equal(t, [][]string{{"-3"}}, rs.Rows)
+
+	db.mustExecute("UPDATE items SET price = 99.99 WHERE id = -3")
+	rs = db.mustExecute("SELECT price FROM items WHERE id = -3")
+	assert.Equal(t, [][]string{{"99.99"}}, rs.Rows)
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "INSERT INTO items VALUES ('abc', 1, 1, true, '2024-01-01', '2024-01-01', '')")
+	assert.EqualError(t, err, `invalid input syntax for type int: "abc"`)
+	_, err = db.execute(tx, "UPDATE items SET active = 'maybe' WHERE id = 10")
+	assert.EqualError(t, err, `invalid input syntax for type boolean: "maybe"`)
+	assert.Nil(t, db.txMgr.Abort(tx))
+}
+
+func TestUpdateStoresStringWithoutQuotes(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	db.mustExecute("UPDATE users SET name = 'eve' WHERE id = '1'")
+	rs := db.mustExecute("SELECT name FROM users WHERE id = '1'")
+	assert.Equal(t, [][]string{{"eve"}}, rs.Rows)
+}
+
+func TestNull(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE items (id int PRIMARY KEY, name text, price int)")
+	db.mustExecute("INSERT INTO items VALUES (1, NULL, 100)")
+	db.mustExecute("INSERT INTO items (id, name) VALUES (2, 'NULL')")
+	db.mustExecute("INSERT INTO items VALUES (3, 'pen', NULL)")
+
+	rs := db.mustExecute("SELECT * FROM items")
+	assert.Equal(t, [][]string{{"1", "NULL", "100"}, {"2", "NULL", "NULL"}, {"3", "pen", "NULL"}}, rs.Rows)
+
+	// the text 'NULL' is not NULL
+	rs = db.mustExecute("SELECT id FROM items WHERE name IS NULL")
+	assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+	rs = db.mustExecute("SELECT id FROM items WHERE name IS NOT NULL AND price IS NULL")
+	assert.Equal(t, [][]string{{"2"}, {"3"}}, rs.Rows)
+
+	// comparisons with NULL are unknown
+	rs = db.mustExecute("SELECT id FROM items WHERE price = NULL")
+	assert.Len(t, rs.Rows, 0)
+	rs = db.mustExecute("SELECT id FROM items WHERE name = 'NULL'")
+	assert.Equal(t, [][]string{{"2"}}, rs.Rows)
+
+	db.mustExecute("UPDATE items SET price = NULL WHERE id = 1")
+	rs = db.mustExecute("SELECT id FROM items WHERE price IS NULL")
+	assert.Len(t, rs.Rows, 3)
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "INSERT INTO items VALUES (NULL, 'cup', 1)")
+	assert.EqualError(t, err, `null value in column "id" violates not-null constraint`)
+	assert.Nil(t, db.txMgr.Abort(tx))
+}
+
+func TestWhereOperators(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE items (id int PRIMARY KEY, name text, price decimal(10, 2))")
+	db.mustExecute("INSERT INTO items VALUES (1, 'Pen', 1.50)")
+	db.mustExecute("INSERT INTO items VALUES (2, 'pencil', 0.80)")
+	db.mustExecute("INSERT INTO items VALUES (3, 'cup_100%', 12)")
+	db.mustExecute("INSERT INTO items VALUES (4, NULL, NULL)")
+
+	ids := func(where string) [][]string {
+		return db.mustExecute("SELECT id FROM items WHERE " + where).Rows
+	}
+	assert.Equal(t, [][]string{{"1"}, {"3"}}, ids("price > 1"))
+	assert.Equal(t, [][]string{{"2"}}, ids("1 > price"))
+	assert.Equal(t, [][]string{{"2"}, {"3"}}, ids("id <> 1 AND id != 4"))
+	assert.Equal(t, [][]string{{"1"}, {"2"}}, ids("price <= 1.5 OR price >= 100"))
+	assert.Equal(t, [][]string{{"1"}, {"4"}}, ids("id = 1 OR (id >= 3 AND price IS NULL)"))
+	assert.Equal(t, [][]string{{"2"}, {"3"}}, ids("NOT price = 1.5"))
+	assert.Equal(t, [][]string{{"1"}, {"3"}}, ids("id IN (1, 3, NULL)"))
+	// NOT IN with NULL is never true
+	assert.Len(t, ids("id NOT IN (1, NULL)"), 0)
+	assert.Equal(t, [][]string{{"2"}, {"4"}}, ids("id NOT IN (1, 3)"))
+	assert.Equal(t, [][]string{{"1"}, {"2"}}, ids("price BETWEEN 0.8 AND 1.5"))
+	assert.Equal(t, [][]string{{"3"}}, ids("price NOT BETWEEN 0.8 AND 1.5"))
+
+	assert.Equal(t, [][]string{{"2"}}, ids("name LIKE 'pen%'"))
+	assert.Equal(t, [][]string{{"1"}, {"2"}}, ids("name ILIKE 'pen%'"))
+	assert.Equal(t, [][]string{{"1"}}, ids("name ILIKE '_EN'"))
+	assert.Equal(t, [][]string{{"3"}}, ids("name NOT ILIKE 'pen%'"))
+	assert.Equal(t, [][]string{{"3"}}, ids("name LIKE '%!%' ESCAPE '!'"))
+	assert.Equal(t, [][]string{{"3"}}, ids("name LIKE 'cup!_%' ESCAPE '!'"))
+
+	// conditions of views and CHECK constraints use the same operators
+	db.mustExecute("CREATE VIEW cheap AS SELECT id, name FROM items WHERE price < 2 OR name ILIKE 'cup%'")
+	rs := db.mustExecute("SELECT id FROM cheap WHERE name NOT LIKE 'pen%'")
+	assert.Equal(t, [][]string{{"1"}, {"3"}}, rs.Rows)
+	db.mustExecute("CREATE TABLE orders (id int PRIMARY KEY, quantity int CHECK (quantity BETWEEN 1 AND 10))")
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "INSERT INTO orders VALUES (1, 11)")
+	assert.EqualError(t, err, `new row for relation "orders" violates check constraint "orders_quantity_check"`)
+	_, err = db.execute(tx, "SELECT id FROM items WHERE price LIKE '1%'")
+	assert.EqualError(t, err, "operator does not exist: decimal LIKE text")
+	assert.Nil(t, db.txMgr.Abort(tx))
+}
+
+func TestExpressions(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE items (id int PRIMARY KEY, name text, price decimal(10, 2), quantity int)")
+	db.mustExecute("INSERT INTO items VALUES (1, 'pen', 1.50, 4)")
+	db.mustExecute("INSERT INTO items VALUES (2, 'cup', 12, NULL)")
+	db.mustExecute("INSERT INTO items VALUES (1 + 2, 'ink' || '-' || 'black', CAST('0.25' AS decimal) * 2, 7 / 2)")
+
+	rs := db.mustExecute("SELECT id, price * quantity AS total, name || '!', -id FROM items")
+	assert.Equal(t, []string{"id", "total", "?column?", "?column?"}, rs.Header)
+	assert.Equal(t, [][]string{{"1", "6.00", "pen!", "-1"}, {"2", "NULL", "cup!", "-2"}, {"3", "1.50", "ink-black!", "-3"}}, rs.Rows)
+
+	rs = db.mustExecute(`SELECT id, CASE WHEN price >= 10 THEN 'high' WHEN price >= 1 THEN 'mid' ELSE 'low' END AS band,
+		CASE quantity WHEN 4 THEN 'four' END, CAST(price AS int) FROM items WHERE (id + 1) % 2 = 0`)
+	assert.Equal(t, [][]string{{"1", "mid", "four", "2"}, {"3", "low", "NULL", "1"}}, rs.Rows)
+	rs = db.mustExecute("SELECT id FROM items WHERE price * quantity > 5 OR quantity IS NULL")
+	assert.Equal(t, [][]string{{"1"}, {"2"}}, rs.Rows)
+
+	db.mustExecute("UPDATE items SET quantity = quantity * 2 + 1, name = name || '2' WHERE id = 1")
+	rs = db.mustExecute("SELECT quantity, name FROM items WHERE id = 1")
+	assert.Equal(t, [][]string{{"9", "pen2"}}, rs.Rows)
+	// a number of another type is compared by its value, also in the lookup of the primary key
+	rs = db.mustExecute("SELECT name FROM items WHERE id = 1.0")
+	assert.Equal(t, [][]string{{"pen2"}}, rs.Rows)
+	rs = db.mustExecute("SELECT name FROM items WHERE id = 1.5")
+	assert.Len(t, rs.Rows, 0)
+
+	rs = db.mustExecute("SELECT 1 + 2 * 3, 7 % 3, 1.5 + 1, 'a' || NULL")
+	assert.Equal(t, [][]string{{"7", "1", "2.5", "NULL"}}, rs.Rows)
+
+	db.mustExecute("CREATE VIEW priced AS SELECT id, price FROM items")
+	rs = db.mustExecute("SELECT id, price + 1 FROM priced WHERE id = 2")
+	assert.Equal(t, [][]string{{"2", "13.00"}}, rs.Rows)
+
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT 1 / 0", "division by zero"},
+		{"SELECT name + 1 FROM items", "operator does not exist: text + text"},
+		{"SELECT missing * 2 FROM items", "column not found: missing"},
+		{"SELECT id FROM items WHERE price + 1", "argument of WHERE must be type boolean, not type decimal"},
+		{"INSERT INTO items VALUES (id, 'x', 1, 1)", `column reference "id" is not allowed here`},
+		{"UPDATE items SET quantity = 2147483647 + quantity WHERE id = 1", "int out of range"},
+		{"CREATE VIEW totals AS SELECT price * quantity FROM items", "a view of computed columns is not supported"},
+	}
+	for _, tt := range tests {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, tt.sql)
+		assert.EqualError(t, err, tt.err, tt.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+}
+
+func TestDecimalPrecisionAndScale(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE prices (id int PRIMARY KEY, amount decimal(10, 2), ratio numeric(3))")
+	db.mustExecute("INSERT INTO prices VALUES (1, 12345678.125, 12.5)")
+	db.mustExecute("INSERT INTO prices VALUES (2, -0.005, NULL)")
+	rs := db.mustExecute("SELECT amount, ratio FROM prices")
+	assert.Equal(t, [][]string{{"12345678.13", "13"}, {"-0.01", "NULL"}}, rs.Rows)
+
+	db.mustExecute("UPDATE prices SET amount = amount / 3 WHERE id = 1")
+	rs = db.mustExecute("SELECT amount FROM prices WHERE id = 1")
+	assert.Equal(t, [][]string{{"4115226.04"}}, rs.Rows)
+	rs = db.mustExecute("SELECT CAST(amount AS decimal(4, 1)), CAST('1.25' AS decimal(3, 1)) FROM prices WHERE id = 2")
+	assert.Equal(t, [][]string{{"0.0", "1.3"}}, rs.Rows)
+
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"INSERT INTO prices VALUES (3, 12345678901.5, 1)", "numeric field overflow: a field with precision 10, scale 2 must round to an absolute value less than 10^8"},
+		{"INSERT INTO prices VALUES (3, 1, 999.5)", "numeric field overflow: a field with precision 3, scale 0 must round to an absolute value less than 10^3"},
+		{"UPDATE prices SET amount = amount * 100 WHERE id = 1", "numeric field overflow: a field with precision 10, scale 2 must round to an absolute value less than 10^8"},
+		{"SELECT CAST(123.45 AS decimal(4, 2))", "numeric field overflow: a field with precision 4, scale 2 must round to an absolute value less than 10^2"},
+		{"ALTER TABLE prices ALTER COLUMN amount TYPE decimal(3, 1)", "numeric field overflow: a field with precision 3, scale 1 must round to an absolute value less than 10^2"},
+		{"CREATE TABLE bad (amount decimal(0))", "NUMERIC precision 0 must be between 1 and 1000"},
+		{"CREATE TABLE bad (amount decimal(2, 3))", "NUMERIC scale 3 must be between 0 and precision 2"},
+		{"SELECT CAST(1 AS decimal(2, 3))", "NUMERIC scale 3 must be between 0 and precision 2"},
+	}
+	for _, tt := range tests {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, tt.sql)
+		assert.EqualError(t, err, tt.err, tt.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+
+	// the stored values are rounded to the new scale
+	db.mustExecute("ALTER TABLE prices ALTER COLUMN amount TYPE decimal(8, 1)")
+	rs = db.mustExecute("SELECT id, amount FROM prices")
+	assert.ElementsMatch(t, [][]string{{"1", "4115226.0"}, {"2", "0.0"}}, rs.Rows)
+}
+
+func TestFunctions(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE events (id int PRIMARY KEY, name text, amount decimal(10, 2), at timestamp, created timestamp DEFAULT now())")
+	db.mustExecute("INSERT INTO events (id, name, amount, at) VALUES (1, ' Launch ', -12.345, '2024-05-17 13:45:30')")
+	db.mustExecute("INSERT INTO events (id, name, amount, at) VALUES (2, NULL, 7, '2023-12-31 23:59:59')")
+
+	rs := db.mustExecute("SELECT id, upper(trim(name)), length(name), substr(trim(name), 2, 3), coalesce(name, 'none') FROM events")
+	assert.Equal(t, [][]string{{"1", "LAUNCH", "8", "aun", " Launch "}, {"2", "NULL", "NULL", "NULL", "none"}}, rs.Rows)
+
+	rs = db.mustExecute("SELECT abs(amount), round(amount, 1), round(amount), nullif(id, 2) FROM events")
+	// the amounts are stored rounded to the scale of the column
+	assert.Equal(t, [][]string{{"12.35", "-12.4", "-12", "1"}, {"7.00", "7.0", "7", "NULL"}}, rs.Rows)
+
+	rs = db.mustExecute("SELECT date_trunc('month', at), EXTRACT(year FROM at), extract(quarter from at) FROM events WHERE id = 1")
+	assert.Equal(t, [][]string{{"2024-05-01 00:00:00", "2024", "2"}}, rs.Rows)
+
+	rs = db.mustExecute("SELECT id FROM events WHERE lower(name) LIKE '%launch%' AND created <= now()")
+	assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+
+	db.mustExecute("UPDATE events SET name = replace(coalesce(name, 'new year'), ' ', '_') WHERE id = 2")
+	rs = db.mustExecute("SELECT name FROM events WHERE id = 2")
+	assert.Equal(t, [][]string{{"new_year"}}, rs.Rows)
+
+	rs = db.mustExecute("SELECT lower('ABC'), round(2.5), abs(-3), length(''), coalesce(NULL, 1.5, 2)")
+	assert.Equal(t, [][]string{{"abc", "3", "3", "0", "1.5"}}, rs.Rows)
+
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT abs(name) FROM events", "function abs(text) does not exist"},
+		{"SELECT lower(id) FROM events", "function lower(int) does not exist"},
+		{"SELECT substr('abc', 1, 2, 3)", "function substr(unknown, int, int, int) does not exist"},
+		{"SELECT missing(1)", "function missing does not exist"},
+		{"SELECT substr(name, 1, -1) FROM events", "negative substring length not allowed"},
+		{"SELECT date_trunc('era', at) FROM events", `unit "era" not recognized for type timestamp`},
+	}
+	for _, tt := range tests {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, tt.sql)
+		assert.EqualError(t, err, tt.err, tt.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute(`CREATE TABLE items (
+		id int PRIMARY KEY,
+		code text UNIQUE,
+		name text NOT NULL,
+		currency text DEFAULT 'JPY' CONSTRAINT currency_is_jpy CHECK (currency = 'JPY'),
+		price int DEFAULT -1
+	)`)
+	db.mustExecute("INSERT INTO items (id, code, name) VALUES (1, 'a', 'pen')")
+	db.mustExecute("INSERT INTO items (id, name) VALUES (2, 'cup')")
+	db.mustExecute("INSERT INTO items (id, name) VALUES (3, 'box')")
+
+	rs := db.mustExecute("SELECT * FROM items")
+	assert.Equal(t, [][]string{
+		{"1", "a", "pen", "JPY", "-1"},
+		{"2", "NULL", "cup", "JPY", "-1"},
+		{"3", "NULL", "box", "JPY", "-1"},
+	}, rs.Rows)
+
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"INSERT INTO items (id, code) VALUES (4, 'b')", `null value in column "name" violates not-null constraint`},
+		{"INSERT INTO items (id, name, currency) VALUES (4, 'ink', 'USD')", `new row for relation "items" violates check constraint "currency_is_jpy"`},
+		{"INSERT INTO items (id, code, name) VALUES (4, 'a', 'ink')", `duplicate key value violates unique constraint "items_code_key"`},
+		{"INSERT INTO items (id, name) VALUES (1, 'ink')", `duplicate key value violates unique constraint "items_pkey"`},
+		{"UPDATE items SET name = NULL WHERE id = 1", `null value in column "name" violates not-null constraint`},
+		{"UPDATE items SET code = 'a' WHERE id = 2", `duplicate key value violates unique constraint "items_code_key"`},
+	}
+	for _, tt := range tests {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, tt.sql)
+		assert.EqualError(t, err, tt.err, tt.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+
+	// the unique key is released by update and delete
+	db.mustExecute("UPDATE items SET code = 'b' WHERE id = 1")
+	db.mustExecute("UPDATE items SET code = 'a' WHERE id = 2")
+	db.mustExecute("DELETE FROM items WHERE id = 2")
+	db.mustExecute("INSERT INTO items (id, code, name) VALUES (4, 'a', 'ink')")
+	rs = db.mustExecute("SELECT id, code FROM items")
+	assert.ElementsMatch(t, [][]string{{"1", "b"}, {"3", "NULL"}, {"4", "a"}}, rs.Rows)
+}
+
+func TestForeignKeys(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE authors (id int PRIMARY KEY, name text UNIQUE)")
+	db.mustExecute("CREATE TABLE books (id int PRIMARY KEY, author_id int REFERENCES authors ON DELETE CASCADE ON UPDATE CASCADE)")
+	db.mustExecute(`CREATE TABLE reviews (
+		id int PRIMARY KEY,
+		book_id int,
+		author_name text,
+		CONSTRAINT reviews_book FOREIGN KEY (book_id) REFERENCES books (id),
+		FOREIGN KEY (author_name) REFERENCES authors (name) ON UPDATE SET NULL
+	)`)
+	db.mustExecute("INSERT INTO authors VALUES (1, 'alice')")
+	db.mustExecute("INSERT INTO authors VALUES (2, 'bob')")
+	db.mustExecute("INSERT INTO books VALUES (10, 1)")
+	db.mustExecute("INSERT INTO books VALUES (11, 2)")
+	db.mustExecute("INSERT INTO books VALUES (12, NULL)")
+	db.mustExecute("INSERT INTO reviews VALUES (100, 11, 'bob')")
+
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"INSERT INTO books VALUES (13, 3)", `insert or update on table "books" violates foreign key constraint "books_author_id_fkey"`},
+		{"UPDATE reviews SET book_id = 13 WHERE id = 100", `insert or update on table "reviews" violates foreign key constraint "reviews_book"`},
+		{"DELETE FROM books WHERE id = 11", `update or delete on table "books" violates foreign key constraint "reviews_book" on table "reviews"`},
+		// the cascade to books is restricted by reviews
+		{"DELETE FROM authors WHERE id = 2", `update or delete on table "books" violates foreign key constraint "reviews_book" on table "reviews"`},
+	}
+	for _, tt := range tests {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, tt.sql)
+		assert.EqualError(t, err, tt.err, tt.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+
+	// ON UPDATE CASCADE and SET NULL
+	db.mustExecute("UPDATE authors SET id = 20 WHERE id = 2")
+	db.mustExecute("UPDATE authors SET name = 'robert' WHERE id = 20")
+	rs := db.mustExecute("SELECT * FROM books WHERE id = 11")
+	assert.Equal(t, [][]string{{"11", "20"}}, rs.Rows)
+	rs = db.mustExecute("SELECT * FROM reviews")
+	assert.Equal(t, [][]string{{"100", "11", "NULL"}}, rs.Rows)
+
+	// ON DELETE CASCADE
+	db.mustExecute("DELETE FROM authors WHERE id = 1")
+	rs = db.mustExecute("SELECT id FROM books")
+	assert.ElementsMatch(t, [][]string{{"11"}, {"12"}}, rs.Rows)
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "CREATE TABLE notes (id int PRIMARY KEY, book_id text REFERENCES books)")
+	assert.EqualError(t, err, `foreign key constraint "notes_book_id_fkey" cannot be implemented: key columns "book_id" and "id" are of incompatible types`)
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	// a table can reference itself
+	db.mustExecute("CREATE TABLE employees (id int PRIMARY KEY, manager_id int REFERENCES employees ON DELETE SET NULL)")
+	db.mustExecute("INSERT INTO employees VALUES (1, 1)")
+	db.mustExecute("INSERT INTO employees VALUES (2, 1)")
+	db.mustExecute("DELETE FROM employees WHERE id = 1")
+	rs = db.mustExecute("SELECT * FROM employees")
+	assert.Equal(t, [][]string{{"2", "NULL"}}, rs.Rows)
+}
+
+func TestCompositePrimaryKeyAndHeapTable(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE stocks (shop text, item int, amount int, PRIMARY KEY (shop, item))")
+	db.mustExecute("INSERT INTO stocks VALUES ('a', 1, 10)")
+	db.mustExecute("INSERT INTO stocks VALUES ('a', 2, 20)")
+	db.mustExecute("INSERT INTO stocks VALUES ('b', 1, 30)")
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "INSERT INTO stocks VALUES ('a', 2, 40)")
+	assert.EqualError(t, err, `duplicate key value violates unique constraint "stocks_pkey"`)
+	assert.Nil(t, db.txMgr.Abort(tx))
+	tx = db.begin(storage.TransactionOptions{})
+	_, err = db.execute(tx, "INSERT INTO stocks (shop, amount) VALUES ('c', 1)")
+	assert.EqualError(t, err, `null value in column "item" violates not-null constraint`)
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	rs := db.mustExecute("SELECT amount FROM stocks WHERE item = 1 AND shop = 'b'")
+	assert.Equal(t, [][]string{{"30"}}, rs.Rows)
+	db.mustExecute("UPDATE stocks SET item = 3 WHERE shop = 'a' AND item = 2")
+	rs = db.mustExecute("SELECT amount FROM stocks WHERE shop = 'a' AND item = 3")
+	assert.Equal(t, [][]string{{"20"}}, rs.Rows)
+
+	// rows of a table without primary key can be duplicated
+	db.mustExecute("CREATE TABLE logs (level text, message text)")
+	db.mustExecute("INSERT INTO logs VALUES ('info', 'started')")
+	db.mustExecute("INSERT INTO logs VALUES ('info', 'started')")
+	db.mustExecute("INSERT INTO logs VALUES ('error', 'failed')")
+	db.mustExecute("UPDATE logs SET level = 'warn' WHERE level = 'info'")
+	db.mustExecute("DELETE FROM logs WHERE level = 'error'")
+	rs = db.mustExecute("SELECT * FROM logs")
+	assert.Equal(t, [][]string{{"warn", "started"}, {"warn", "started"}}, rs.Rows)
+}
+
+func TestAlterTable(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE TABLE items (id text PRIMARY KEY, price int CHECK (price IS NOT NULL), memo text)")
+	db.mustExecute("CREATE TABLE orders (id int PRIMARY KEY, item_id text REFERENCES items)")
+	db.mustExecute("INSERT INTO items VALUES ('1', 100, 'a')")
+	db.mustExecute("INSERT INTO items VALUES ('2', 200, 'b')")
+	db.mustExecute("INSERT INTO orders VALUES (1, '2')")
+
+	// the existing rows have the default value without rewriting
+	db.mustExecute("ALTER TABLE items ADD COLUMN currency text NOT NULL DEFAULT 'JPY'")
+	db.mustExecute("ALTER TABLE items DROP COLUMN memo")
+	db.mustExecute("INSERT INTO items VALUES ('3', 300, 'USD')")
+	rs := db.mustExecute("SELECT * FROM items")
+	assert.Equal(t, [][]string{{"1", "100", "JPY"}, {"2", "200", "JPY"}, {"3", "300", "USD"}}, rs.Rows)
+
+	// a column with the same name is a new column
+	db.mustExecute("ALTER TABLE items ADD memo text")
+	rs = db.mustExecute("SELECT memo FROM items WHERE id = '1'")
+	assert.Equal(t, [][]string{{"NULL"}}, rs.Rows)
+
+	db.mustExecute("ALTER TABLE items RENAME COLUMN price TO amount")
+	db.mustExecute("ALTER TABLE items RENAME COLUMN id TO code")
+	db.mustExecute("ALTER TABLE items RENAME TO products")
+	rs = db.mustExecute("SELECT amount FROM products WHERE code = '2'")
+	assert.Equal(t, [][]string{{"200"}}, rs.Rows)
+
+	tests := []struct {
+		sql string
+		err string
+	}{
+		// the check constraint and the foreign key follow the renames
+		{"INSERT INTO products (code) VALUES ('4')", `new row for relation "products" violates check constraint "items_price_check"`},
+		{"DELETE FROM products WHERE code = '2'", `update or delete on table "products" violates foreign key constraint "orders_item_id_fkey" on table "orders"`},
+		{"ALTER TABLE products ALTER COLUMN currency TYPE int", `default for column "currency" cannot be cast automatically to type int`},
+		{"ALTER TABLE products ALTER COLUMN amount TYPE date", `column "amount" cannot be cast automatically to type date: invalid input syntax for type date: "100"`},
+		{"ALTER TABLE products ALTER COLUMN code TYPE int", "cannot alter type of column code because it is used in a foreign key constraint"},
+		{"ALTER TABLE products ADD COLUMN currency text", `column "currency" of relation "products" already exists`},
+		{"ALTER TABLE products ADD COLUMN rank int NOT NULL", `null value in column "rank" violates not-null constraint`},
+		{"ALTER TABLE products DROP COLUMN code", "cannot drop column code because it is a part of the primary key of table products"},
+	}
+	for _, tt := range tests {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, tt.sql)
+		assert.EqualError(t, err, tt.err, tt.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+
+	// int to bigint reads the stored values, and the others rewrite the table
+	db.mustExecute("ALTER TABLE products ALTER COLUMN amount TYPE bigint")
+	db.mustExecute("ALTER TABLE products ALTER COLUMN amount SET DATA TYPE text")
+	rs = db.mustExecute("SELECT * FROM products WHERE code = '3'")
+	assert.Equal(t, [][]string{{"3", "300", "USD", "NULL"}}, rs.Rows)
+	schema, err := db.catalog.TableSchemas.Get("products")
+	assert.Nil(t, err)
+	assert.Equal(t, types.Text, schema.Columns[1].Type)
+	assert.Equal(t, uint32(8), schema.Version)
+}
+
+func TestAbortAfterAlterTable(t *testing.T) {
+	db := newTestDB(t)
+	db.setup()
+
+	for _, sql := range []string{
+		"ALTER TABLE users ADD COLUMN age int DEFAULT 20",
+		"ALTER TABLE users DROP COLUMN name",
+		"ALTER TABLE users RENAME TO members",
+		"ALTER TABLE users ALTER COLUMN id TYPE int",
+		"ALTER TABLE users ADD COLUMN email text UNIQUE",
+	} {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, sql)
+		assert.Nil(t, err, sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+
+		rs := db.mustExecute("SELECT * FROM users")
+		assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob"}}, rs.Rows, sql)
+		rs = db.mustExecute("SELECT name FROM users WHERE id = '2'")
+		assert.Equal(t, [][]string{{"bob"}}, rs.Rows, sql)
+	}
+}
+
+func TestSequences(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir)
+	db.mustExecute("CREATE SEQUENCE s START WITH 10 INCREMENT BY 5 CACHE 2")
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "SELECT currval('s')")
+	assert.EqualError(t, err, "currval of sequence \"s\" is not yet defined in this session")
+	rs, err := db.execute(tx, "SELECT nextval('s'), nextval('s') AS n")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nextval", "n"}, rs.Header)
+	assert.ElementsMatch(t, []string{"10", "15"}, rs.Rows[0])
+	rs, err = db.execute(tx, "SELECT nextval('s')")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"20"}}, rs.Rows)
+	rs, err = db.execute(tx, "SELECT currval('s')")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"20"}}, rs.Rows)
+	// values are not returned again even if the transaction is aborted
+	assert.Nil(t, db.txMgr.Abort(tx))
+	rs = db.mustExecute("SELECT nextval('s')")
+	assert.Equal(t, [][]string{{"25"}}, rs.Rows)
+
+	// values are reserved two at a time, so 30 is the first one which is not reserved by the previous process
+	db = openTestDB(t, dir)
+	rs = db.mustExecute("SELECT nextval('s')")
+	assert.Equal(t, [][]string{{"30"}}, rs.Rows)
+
+	db.mustExecute("CREATE TABLE items (id serial PRIMARY KEY, name text, n bigint DEFAULT nextval('s'))")
+	db.mustExecute("INSERT INTO items (name) VALUES ('a')")
+	db.mustExecute("INSERT INTO items (name) VALUES ('b')")
+	db.mustExecute("INSERT INTO items (id, name) VALUES (10, 'c')")
+	rs = db.mustExecute("SELECT * FROM items")
+	assert.Equal(t, [][]string{{"1", "a", "35"}, {"2", "b", "40"}, {"10", "c", "45"}}, rs.Rows)
+
+	db.mustExecute("CREATE TABLE events (id bigint GENERATED ALWAYS AS IDENTITY (START WITH 100), name text)")
+	db.mustExecute("CREATE TABLE logs (id int AUTO_INCREMENT, body text)")
+	db.mustExecute("INSERT INTO events (name) VALUES ('e')")
+	rs = db.mustExecute("SELECT * FROM events")
+	assert.Equal(t, [][]string{{"100", "e"}}, rs.Rows)
+
+	for _, c := range []struct {
+		sql string
+		err string
+	}{
+		{"CREATE SEQUENCE s", "relation \"s\" already exists"},
+		{"CREATE TABLE s (id int)", "relation \"s\" already exists"},
+		{"CREATE SEQUENCE t MINVALUE 10 START 5", "START value (5) cannot be less than MINVALUE (10)"},
+		{"SELECT nextval('missing')", "relation \"missing\" does not exist"},
+		{"SELECT nextval('s') + 1", "nextval is not allowed in an expression"},
+		{"SELECT id, nextval('s') FROM items", "nextval is not allowed in SELECT with FROM"},
+		{"DELETE FROM items WHERE id = currval('s')", "currval is not allowed in WHERE"},
+		{"UPDATE items SET n = nextval('s') WHERE id = 1", "nextval is not allowed in UPDATE SET"},
+		{"INSERT INTO events (id, name) VALUES (1, 'f')", "cannot insert a non-DEFAULT value into column \"id\""},
+		{"UPDATE events SET id = 1 WHERE name = 'e'", "column \"id\" can only be updated to DEFAULT"},
+		{"DROP SEQUENCE items_id_seq", "cannot drop sequence items_id_seq because column id of table items requires it"},
+		{"CREATE TABLE u (name text AUTO_INCREMENT)", "identity column type must be integer or bigint: name"},
+		{"CREATE TABLE u (id serial DEFAULT 1)", "multiple default values specified for column \"id\" of table \"u\""},
+	} {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, c.sql)
+		assert.EqualError(t, err, c.err, c.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+
+	// the owned sequences are dropped with the table and the column
+	db.mustExecute("DROP TABLE items")
+	db.mustExecute("ALTER TABLE logs DROP COLUMN id")
+	db.mustExecute("DROP SEQUENCE s")
+	rs = db.mustExecute("DROP SEQUENCE IF EXISTS s")
+	assert.Equal(t, "sequence s does not exist, skipping", rs.Message)
+	assert.Equal(t, []string{"events_id_seq"}, sequenceNames(db.catalog))
+
+	// a sequence created again starts from the beginning
+	db.mustExecute("CREATE SEQUENCE s")
+	rs = db.mustExecute("SELECT nextval('s')")
+	assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+}
+
+func TestAbortAfterDropSequence(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExecute("CREATE SEQUENCE s")
+	db.mustExecute("SELECT nextval('s')")
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "DROP SEQUENCE s")
+	assert.Nil(t, err)
+	_, err = db.execute(tx, "CREATE SEQUENCE s")
+	assert.Nil(t, err)
+	rs, err := db.execute(tx, "SELECT nextval('s')")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"1"}}, rs.Rows)
+	assert.Nil(t, db.txMgr.Abort(tx))
+
+	rs = db.mustExecute("SELECT nextval('s')")
+	assert.Equal(t, [][]string{{"33"}}, rs.Rows)
+}
+
+func sequenceNames(ct *catalog.Catalog) []string {
+	names := make([]string, 0)
+	for _, sequence := range ct.Sequences {
+		names = append(names, sequence.Name)
+	}
+	return names
+}
+
+func TestViews(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir)
+	db.mustExecute("CREATE TABLE users (id int PRIMARY KEY, name text, active boolean)")
+	db.mustExecute("INSERT INTO users VALUES (1, 'alice', true)")
+	db.mustExecute("INSERT INTO users VALUES (2, 'bob', false)")
+	db.mustExecute("INSERT INTO users VALUES (3, 'carol', true)")
+
+	db.mustExecute("CREATE VIEW active_users (user_id, user_name) AS SELECT id, name FROM users WHERE active = true")
+	db.mustExecute("CREATE VIEW all_users AS SELECT * FROM users")
+	db.mustExecute("CREATE VIEW carol AS SELECT user_id FROM active_users WHERE user_name = 'carol'")
+
+	rs := db.mustExecute("SELECT * FROM active_users")
+	assert.Equal(t, []string{"user_id", "user_name"}, rs.Header)
+	assert.Equal(t, [][]string{{"1", "alice"}, {"3", "carol"}}, rs.Rows)
+	rs = db.mustExecute("SELECT user_name FROM active_users WHERE user_id = 1")
+	assert.Equal(t, [][]string{{"alice"}}, rs.Rows)
+	rs = db.mustExecute("SELECT user_name FROM active_users WHERE user_id = 2")
+	assert.Len(t, rs.Rows, 0)
+	rs = db.mustExecute("SELECT * FROM carol")
+	assert.Equal(t, [][]string{{"3"}}, rs.Rows)
+
+	// SELECT * of a view keeps the columns at the creation
+	db.mustExecute("ALTER TABLE users ADD COLUMN age int")
+	rs = db.mustExecute("SELECT * FROM all_users WHERE id = 2")
+	assert.Equal(t, []string{"id", "name", "active"}, rs.Header)
+	assert.Equal(t, [][]string{{"2", "bob", "false"}}, rs.Rows)
+
+	// views remain after restart
+	db = openTestDB(t, dir)
+	db.mustExecute("CREATE OR REPLACE VIEW all_users AS SELECT * FROM users")
+	rs = db.mustExecute("SELECT * FROM all_users WHERE id = 2")
+	assert.Equal(t, []string{"id", "name", "active", "age"}, rs.Header)
+
+	for _, c := range []struct {
+		sql string
+		err string
+	}{
+		{"CREATE VIEW users AS SELECT * FROM all_users", "relation \"users\" already exists"},
+		{"CREATE VIEW v AS SELECT missing FROM users", "column not found: missing"},
+		{"CREATE VIEW v (a, b) AS SELECT id FROM users", "CREATE VIEW specifies more column names than columns"},
+		{"CREATE OR REPLACE VIEW active_users AS SELECT id FROM users", "cannot drop columns from view"},
+		{"CREATE OR REPLACE VIEW active_users AS SELECT * FROM carol", "infinite recursion detected in view active_users"},
+		{"SELECT missing FROM active_users", "column not found: missing"},
+		{"SELECT * FROM active_users WHERE id = 1", "column not found: id"},
+		{"INSERT INTO active_users VALUES (4, 'dave')", "cannot insert into view \"active_users\""},
+		{"DELETE FROM active_users", "cannot delete from view \"active_users\""},
+		{"DROP VIEW active_users", "cannot drop view active_users because view carol depends on it"},
+		{"DROP TABLE users", "cannot drop table users because view active_users depends on it"},
+		{"ALTER TABLE users RENAME COLUMN name TO full_name", "cannot alter table users because view active_users depends on it"},
+	} {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, c.sql)
+		assert.EqualError(t, err, c.err, c.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "DROP VIEW carol")
+	assert.Nil(t, err)
+	assert.Nil(t, db.txMgr.Abort(tx))
+	rs = db.mustExecute("SELECT * FROM carol")
+	assert.Equal(t, [][]string{{"3"}}, rs.Rows)
+
+	db.mustExecute("DROP VIEW carol")
+	db.mustExecute("DROP VIEW active_users")
+	rs = db.mustExecute("DROP VIEW IF EXISTS active_users")
+	assert.Equal(t, "view active_users does not exist, skipping", rs.Message)
+}
+
+func TestMaterializedViews(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir)
+	db.mustExecute("CREATE TABLE orders (id int PRIMARY KEY, item text, shipped boolean)")
+	db.mustExecute("INSERT INTO orders VALUES (1, 'apple', true)")
+	db.mustExecute("INSERT INTO orders VALUES (2, 'banana', false)")
+
+	db.mustExecute("CREATE MATERIALIZED VIEW shipped (order_id, item) AS SELECT id, item FROM orders WHERE shipped = true")
+	db.mustExecute("CREATE MATERIALIZED VIEW empty AS SELECT * FROM orders WITH NO DATA")
+	db.mustExecute("CREATE VIEW shipped_items AS SELECT item FROM shipped")
+
+	// the rows are computed when the view is created
+	db.mustExecute("INSERT INTO orders VALUES (3, 'cherry', true)")
+	rs := db.mustExecute("SELECT * FROM shipped")
+	assert.Equal(t, []string{"order_id", "item"}, rs.Header)
+	assert.Equal(t, [][]string{{"1", "apple"}}, rs.Rows)
+
+	// snapshots read the old rows until the refresh is committed
+	tx := db.begin(storage.TransactionOptions{})
+	_, err := db.execute(tx, "REFRESH MATERIALIZED VIEW shipped")
+	assert.Nil(t, err)
+	reader := db.begin(storage.TransactionOptions{ReadOnly: true})
+	rs, err = db.execute(reader, "SELECT * FROM shipped_items")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"apple"}}, rs.Rows)
+	assert.Nil(t, db.txMgr.Commit(reader))
+	assert.Nil(t, db.txMgr.Commit(tx))
+	rs = db.mustExecute("SELECT * FROM shipped_items")
+	assert.Equal(t, [][]string{{"apple"}, {"cherry"}}, rs.Rows)
+
+	// an aborted refresh keeps the old rows
+	db.mustExecute("UPDATE orders SET shipped = true WHERE id = 2")
+	tx = db.begin(storage.TransactionOptions{})
+	_, err = db.execute(tx, "REFRESH MATERIALIZED VIEW shipped")
+	assert.Nil(t, err)
+	assert.Nil(t, db.txMgr.Abort(tx))
+	rs = db.mustExecute("SELECT order_id FROM shipped")
+	assert.Equal(t, [][]string{{"1"}, {"3"}}, rs.Rows)
+
+	// materialized views remain after restart
+	db = openTestDB(t, dir)
+	db.mustExecute("REFRESH MATERIALIZED VIEW empty")
+	rs = db.mustExecute("SELECT item FROM empty WHERE id = 2")
+	assert.Equal(t, [][]string{{"banana"}}, rs.Rows)
+	db.mustExecute("REFRESH MATERIALIZED VIEW empty WITH NO DATA")
+
+	for _, c := range []struct {
+		sql string
+		err string
+	}{
+		{"SELECT * FROM empty", "materialized view \"empty\" has not been populated"},
+		{"CREATE MATERIALIZED VIEW orders AS SELECT * FROM shipped", "relation \"orders\" already exists"},
+		{"INSERT INTO shipped VALUES (4, 'durian')", "cannot change materialized view \"shipped\""},
+		{"DELETE FROM shipped", "cannot change materialized view \"shipped\""},
+		{"TRUNCATE shipped", "\"shipped\" is not a table"},
+		{"DROP TABLE shipped", "\"shipped\" is not a table"},
+		{"DROP VIEW shipped", "\"shipped\" is not a view"},
+		{"REFRESH MATERIALIZED VIEW orders", "\"orders\" is not a materialized view"},
+		{"DROP MATERIALIZED VIEW shipped", "cannot drop materialized view shipped because view shipped_items depends on it"},
+		{"DROP TABLE orders", "cannot drop table orders because view shipped depends on it"},
+	} {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, c.sql)
+		assert.EqualError(t, err, c.err, c.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+
+	db.mustExecute("DROP VIEW shipped_items")
+	db.mustExecute("DROP MATERIALIZED VIEW shipped")
+	rs = db.mustExecute("DROP MATERIALIZED VIEW IF EXISTS shipped")
+	assert.Equal(t, "materialized view shipped does not exist, skipping", rs.Message)
+	db.mustExecute("CREATE MATERIALIZED VIEW IF NOT EXISTS empty AS SELECT id FROM orders")
+}
+
+func TestInformationSchema(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+	db.mustExecute("CREATE TABLE users (id int PRIMARY KEY, email text NOT NULL UNIQUE, age int DEFAULT 20)")
+	db.mustExecute("CREATE TABLE posts (id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY, user_id int REFERENCES users, CHECK (user_id IS NOT NULL))")
+	db.mustExecute("CREATE VIEW adults AS SELECT id FROM users WHERE age = 20")
+	db.mustExecute("CREATE MATERIALIZED VIEW emails AS SELECT email FROM users")
+
+	rs := db.mustExecute("SHOW TABLES")
+	assert.Equal(t, []string{"table_name", "table_type"}, rs.Header)
+	assert.Equal(t, [][]string{{"adults", "VIEW"}, {"emails", "MATERIALIZED VIEW"}, {"posts", "BASE TABLE"}, {"users", "BASE TABLE"}}, rs.Rows)
+
+	rs = db.mustExecute("DESCRIBE users")
+	assert.Equal(t, []string{"column_name", "data_type", "is_nullable", "column_default"}, rs.Header)
+	assert.Equal(t, [][]string{{"id", "int", "NO", "NULL"}, {"email", "text", "NO", "NULL"}, {"age", "int", "YES", "20"}}, rs.Rows)
+	rs = db.mustExecute("DESC information_schema.tables")
+	assert.Equal(t, [][]string{{"table_schema", "text", "YES", "NULL"}, {"table_name", "text", "YES", "NULL"}, {"table_type", "text", "YES", "NULL"}}, rs.Rows)
+
+	rs = db.mustExecute("SHOW INDEXES FROM users")
+	assert.Equal(t, [][]string{{"users", "users_pkey", "0", "1", "id"}, {"users", "users_email_key", "0", "1", "email"}}, rs.Rows)
+
+	rs = db.mustExecute("SELECT constraint_name, constraint_type FROM information_schema.table_constraints WHERE table_name = 'posts'")
+	assert.Equal(t, [][]string{{"posts_pkey", "PRIMARY KEY"}, {"posts_user_id_fkey", "FOREIGN KEY"}, {"posts_check", "CHECK"}}, rs.Rows)
+	rs = db.mustExecute("SELECT constraint_name, column_name FROM information_schema.key_column_usage WHERE table_name = 'posts'")
+	assert.Equal(t, [][]string{{"posts_pkey", "id"}, {"posts_user_id_fkey", "user_id"}}, rs.Rows)
+	rs = db.mustExecute("SELECT is_identity, identity_generation FROM information_schema.columns WHERE table_name = 'posts' AND ordinal_position = 1")
+	assert.Equal(t, [][]string{{"YES", "ALWAYS"}}, rs.Rows)
+	rs = db.mustExecute("SELECT table_name FROM information_schema.tables WHERE table_schema = 'pg_catalog'")
+	assert.Equal(t, [][]string{{"sys_locks"}, {"sys_transactions"}}, rs.Rows)
+
+	for _, c := range []struct {
+		sql string
+		err string
+	}{
+		{"DESCRIBE missing", "table not found: missing"},
+		{"SHOW INDEXES FROM adults", "table not found: adults"},
+		{"SELECT table_name FROM information_schema.tables WHERE missing = 1", "column not found: missing"},
+		{"SELECT table_name FROM information_schema.tables WHERE abs(table_name) = 1", "function abs(text) does not exist"},
+	} {
+		tx := db.begin(storage.TransactionOptions{})
+		_, err := db.execute(tx, c.sql)
+		assert.EqualError(t, err, c.err, c.sql)
+		assert.Nil(t, db.txMgr.Abort(tx))
+	}
+}
@@ -1,51 +1,130 @@
 package executor
 
 import (
+	"fmt"
+	"garakutadb/catalog"
 	"garakutadb/planner"
 	"garakutadb/storage"
+	"garakutadb/types"
 )
 
 type IndexScanExecutor struct {
 	storage        *storage.Storage
+	catalog        *catalog.Catalog
 	transaction    *storage.Transaction
 	transactionMgr *storage.TransactionManager
 }
 
-func NewIndexScanExecutor(st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *IndexScanExecutor {
+func NewIndexScanExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *IndexScanExecutor {
 	return &IndexScanExecutor{
 		storage:        st,
+		catalog:        ct,
 		transaction:    tx,
 		transactionMgr: txMgr,
 	}
 }
 
 func (e *IndexScanExecutor) Execute(pl planner.IndexScanPlan) (*ResultSet, error) {
+	rows, err := e.rows(pl)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return &ResultSet{
+			Message: "rows was not found",
+		}, nil
+	}
+
+	return &ResultSet{
+		Header: pl.ColumnNames,
+		Rows:   formatRows(rows),
+	}, nil
+}
+
+// rows returns the selected columns of the row whose key is the search key. It is empty if the row is not found.
+func (e *IndexScanExecutor) rows(pl planner.IndexScanPlan) ([][]types.Value, error) {
+	tableSchema, err := e.catalog.TableSchemas.Get(pl.TableName)
+	if err != nil {
+		return nil, err
+	}
+	searchKey := types.EncodeKeys(pl.SearchKeys...)
+
+	// the index only has the latest versions, which may not be in the snapshot
+	if e.transaction.IsReadOnly() {
+		return e.scanSnapshot(pl, tableSchema, searchKey)
+	}
+
 	btree, err := e.storage.ReadIndex(pl.TableName, pl.IndexName)
 	if err != nil {
 		return nil, err
 	}
 
 	item, found := btree.Search(&storage.StringItem{
-		Value: pl.SearchKey,
+		Value: searchKey,
 	})
 
+	if e.transaction.IsSerializable() {
+		if !found {
+			// lock the gap so that the key is not inserted by other transactions (phantom)
+			if !e.transactionMgr.LockKeyRange(e.transaction, btree, searchKey) {
+				return nil, fmt.Errorf("failed to lock key range of %s", pl.TableName)
+			}
+		}
+		// serializable transactions read from their snapshots too. The table is read with a SIREAD lock, so that
+		// the versions which the snapshot doesn't see are detected as rw-antidependencies.
+		e.transactionMgr.PredicateLockRelation(e.transaction, pl.TableName)
+		return e.scanSnapshot(pl, tableSchema, searchKey)
+	}
 	if !found {
-		return &ResultSet{
-			Message: "rows was not found",
-		}, nil
+		return nil, nil
 	}
 
-	tuple, err := e.storage.GetTupleFromPage(pl.TableName, item.GetPageId(), pl.SearchKey, e.transaction, e.transactionMgr)
+	tuple, err := e.storage.GetTupleFromPage(pl.TableName, item.GetPageId(), func(tuple *storage.Tuple) bool {
+		row, err := decodeTuple(tableSchema, tuple)
+		return err == nil && primaryKey(tableSchema, row) == searchKey
+	}, e.transaction, e.transactionMgr)
 	if err != nil {
 		return nil, err
 	}
-	row := make([]string, 0)
-	for _, columnOrder := range pl.ColumnOrders {
-		row = append(row, tuple.Data[columnOrder].Value)
+	row, err := decodeTuple(tableSchema, tuple)
+	if err != nil {
+		return nil, err
 	}
 
-	return &ResultSet{
-		Header: pl.ColumnNames,
-		Rows:   [][]string{row},
-	}, nil
+	selected, err := selectRow(row, pl.ColumnOrders, pl.Projections, columnNameAndOrderMap(tableSchema), e.catalog.Functions())
+	if err != nil {
+		return nil, err
+	}
+	return [][]types.Value{selected}, nil
+}
+
+func (e *IndexScanExecutor) scanSnapshot(pl planner.IndexScanPlan, tableSchema *catalog.TableSchema, searchKey string) ([][]types.Value, error) {
+	it := e.storage.NewTupleIterator(pl.TableName, e.transaction)
+	for true {
+		tuple, found := it.Next(e.transactionMgr)
+		if !found {
+			if err := it.Err(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if len(tuple.Data) == 0 {
+			continue
+		}
+
+		row, err := decodeTuple(tableSchema, tuple)
+		if err != nil {
+			return nil, err
+		}
+		if primaryKey(tableSchema, row) != searchKey {
+			continue
+		}
+		selected, err := selectRow(row, pl.ColumnOrders, pl.Projections, columnNameAndOrderMap(tableSchema), e.catalog.Functions())
+		if err != nil {
+			return nil, err
+		}
+		return [][]types.Value{selected}, nil
+	}
+
+	return nil, nil
 }
@@ -1,10 +1,10 @@
 package executor
 
 import (
-	"fmt"
 	"garakutadb/catalog"
 	"garakutadb/planner"
 	"garakutadb/storage"
+	"garakutadb/types"
 )
 
 type InsertExecutor struct {
@@ -29,49 +29,23 @@ func (e *InsertExecutor) Execute(pl planner.InsertPlan) (*ResultSet, error) {
 		return nil, err
 	}
 
-	// save index
-	btree, err := e.storage.ReadIndex(pl.Into, tableSchema.PK)
-	if err != nil {
-		return nil, err
-	}
-
-	if _, found := btree.Search(&storage.StringItem{
-		Value: pl.PKValue,
-	}); found {
-		return nil, fmt.Errorf("duplicate key value violates unique constraint")
-	}
-
-	// save row
-	tupleValues := make([]*storage.TupleValue, pl.ColumnNum)
-	for i := uint64(0); i < pl.ColumnNum; i++ {
-		tupleValues[i] = &storage.TupleValue{
-			Value: "NULL", // TODO: support NULL
-		}
+	row := make([]types.Value, pl.ColumnNum)
+	for i, column := range tableSchema.Columns {
+		row[i] = types.NewNull(column.Type)
 	}
 
-	for _, order := range pl.ColumnOrders {
-		tupleValues[order] = &storage.TupleValue{
-			Value: pl.Values[order],
-		}
-	}
-
-	page, err := e.storage.InsertTuple(pl.Into, &storage.Tuple{
-		Data: tupleValues,
-	}, e.transaction, e.transactionMgr)
+	values, err := evalSequenceCalls(e.catalog, e.storage, e.transaction, pl.SequenceCalls, pl.Values)
 	if err != nil {
 		return nil, err
 	}
-
-	if err := btree.Insert(&storage.StringItem{
-		Value:  pl.PKValue,
-		PageId: page.Id,
-	}); err != nil {
+	if values, err = evalExpressions(pl.Expressions, values, e.catalog.Functions()); err != nil {
 		return nil, err
 	}
+	for i, order := range pl.ColumnOrders {
+		row[order] = values[i]
+	}
 
-	btree.PrintTree()
-
-	if err := e.storage.WriteIndex(btree); err != nil {
+	if err := newRowWriter(e.catalog, e.storage, e.transaction, e.transactionMgr).insert(tableSchema, row); err != nil {
 		return nil, err
 	}
 
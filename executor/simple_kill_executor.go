@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/planner"
+	"garakutadb/storage"
+)
+
+type KillExecutor struct {
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
+}
+
+func NewKillExecutor(tx *storage.Transaction, txMgr *storage.TransactionManager) *KillExecutor {
+	return &KillExecutor{
+		transaction:    tx,
+		transactionMgr: txMgr,
+	}
+}
+
+func (e *KillExecutor) Execute(pl planner.KillPlan) (*ResultSet, error) {
+	if pl.TransactionId == e.transaction.GetId() {
+		return nil, fmt.Errorf("cannot kill the current transaction: %d", pl.TransactionId)
+	}
+
+	if err := e.transactionMgr.Kill(pl.TransactionId); err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Message: fmt.Sprintf("killed transaction %d", pl.TransactionId),
+	}, nil
+}
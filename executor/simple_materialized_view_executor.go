@@ -0,0 +1,164 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+	"garakutadb/types"
+)
+
+// MaterializedViewExecutor runs CREATE, REFRESH and DROP MATERIALIZED VIEW.
+// The rows of a materialized view are stored in a heap table, which is read like other tables.
+type MaterializedViewExecutor struct {
+	storage        *storage.Storage
+	catalog        *catalog.Catalog
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
+}
+
+func NewMaterializedViewExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *MaterializedViewExecutor {
+	return &MaterializedViewExecutor{
+		storage:        st,
+		catalog:        ct,
+		transaction:    tx,
+		transactionMgr: txMgr,
+	}
+}
+
+func (e *MaterializedViewExecutor) Create(pl planner.CreateMaterializedViewPlan) (*ResultSet, error) {
+	name := pl.TableSchema.Name
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("relation \"%s\" already exists, skipping", name),
+		}, nil
+	}
+
+	if !e.transactionMgr.LockTable(e.transaction, name, storage.Exclusive) {
+		return nil, fmt.Errorf("failed to lock table: %s", name)
+	}
+	if err := e.storage.CreateTable(name, pl.TableSchema.IndexNames(), e.transaction); err != nil {
+		return nil, err
+	}
+	if err := e.catalog.Add(pl.TableSchema, e.transaction); err != nil {
+		return nil, err
+	}
+
+	if pl.Query != nil {
+		if err := e.populate(pl.TableSchema, pl.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ResultSet{
+		Message: "successfully created materialized view!",
+	}, nil
+}
+
+// Refresh replaces the rows with the current result of the query in the transaction.
+// The old rows are deleted instead of truncating the table, so snapshots of other transactions keep reading them.
+func (e *MaterializedViewExecutor) Refresh(pl planner.RefreshMaterializedViewPlan) (*ResultSet, error) {
+	tableSchema, err := e.catalog.TableSchemas.Get(pl.ViewName)
+	if err != nil {
+		return nil, err
+	}
+
+	// concurrent refreshes would leave the rows of both
+	if !e.transactionMgr.LockTable(e.transaction, pl.ViewName, storage.Exclusive) {
+		return nil, fmt.Errorf("failed to lock table: %s", pl.ViewName)
+	}
+
+	writer := newRowWriter(e.catalog, e.storage, e.transaction, e.transactionMgr)
+	it := e.storage.NewTupleIterator(pl.ViewName, e.transaction)
+	for true {
+		tuple, found := it.Next(e.transactionMgr)
+		if !found {
+			if err := it.Err(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if len(tuple.Data) == 0 {
+			continue
+		}
+
+		row, err := decodeTuple(tableSchema, tuple)
+		if err != nil {
+			return nil, err
+		}
+		if err := writer.delete(tableSchema, it.GetTupleId(), row); err != nil {
+			return nil, err
+		}
+	}
+
+	if pl.Query != nil {
+		if err := e.populate(tableSchema, pl.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	if unpopulated := pl.Query == nil; tableSchema.Materialized.Unpopulated != unpopulated {
+		tableSchema = tableSchema.Clone()
+		tableSchema.Materialized.Unpopulated = unpopulated
+		if err := e.catalog.Update(tableSchema, e.transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ResultSet{
+		Message: "successfully refreshed materialized view!",
+	}, nil
+}
+
+func (e *MaterializedViewExecutor) Drop(pl planner.DropMaterializedViewPlan) (*ResultSet, error) {
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("materialized view %s does not exist, skipping", pl.ViewName),
+		}, nil
+	}
+
+	if !e.transactionMgr.LockTable(e.transaction, pl.ViewName, storage.Exclusive) {
+		return nil, fmt.Errorf("failed to lock table: %s", pl.ViewName)
+	}
+	if err := e.storage.DropTable(pl.ViewName, e.transaction); err != nil {
+		return nil, err
+	}
+	if err := e.catalog.Delete(pl.ViewName, e.transaction); err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Message: "successfully dropped materialized view!",
+	}, nil
+}
+
+// populate inserts the rows of the query
+func (e *MaterializedViewExecutor) populate(tableSchema *catalog.TableSchema, query planner.Plan) error {
+	rows, err := e.queryRows(query)
+	if err != nil {
+		return err
+	}
+
+	writer := newRowWriter(e.catalog, e.storage, e.transaction, e.transactionMgr)
+	for _, row := range rows {
+		// SELECT * selects the columns added to the relation after the view was created
+		if err := writer.save(tableSchema, row[:len(tableSchema.Columns)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryRows runs the plan of a query and returns the typed values
+func (e *MaterializedViewExecutor) queryRows(query planner.Plan) ([][]types.Value, error) {
+	switch p := query.(type) {
+	case *planner.SeqScanPlan:
+		return NewSeqScanExecutor(e.catalog, e.storage, e.transaction, e.transactionMgr).rows(*p)
+	case *planner.IndexScanPlan:
+		return NewIndexScanExecutor(e.catalog, e.storage, e.transaction, e.transactionMgr).rows(*p)
+	case *planner.SystemViewScanPlan:
+		return NewSystemViewScanExecutor(e.catalog, e.transactionMgr).rows(*p)
+	default:
+		return nil, fmt.Errorf("not supported plan of materialized view: %T", query)
+	}
+}
@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+)
+
+// ResultExecutor returns the row of SELECT without FROM
+type ResultExecutor struct {
+	storage     *storage.Storage
+	catalog     *catalog.Catalog
+	transaction *storage.Transaction
+}
+
+func NewResultExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction) *ResultExecutor {
+	return &ResultExecutor{
+		storage:     st,
+		catalog:     ct,
+		transaction: tx,
+	}
+}
+
+func (e *ResultExecutor) Execute(pl planner.ResultPlan) (*ResultSet, error) {
+	values, err := evalSequenceCalls(e.catalog, e.storage, e.transaction, pl.SequenceCalls, pl.Values)
+	if err != nil {
+		return nil, err
+	}
+	if values, err = evalExpressions(pl.Expressions, values, e.catalog.Functions()); err != nil {
+		return nil, err
+	}
+
+	row := make([]string, 0, len(values))
+	for _, value := range values {
+		row = append(row, value.String())
+	}
+	return &ResultSet{
+		Header: pl.ColumnNames,
+		Rows:   [][]string{row},
+	}, nil
+}
@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+)
+
+// SchemaExecutor runs CREATE SCHEMA and DROP SCHEMA. A schema is only a namespace in the catalog.
+type SchemaExecutor struct {
+	catalog     *catalog.Catalog
+	transaction *storage.Transaction
+}
+
+func NewSchemaExecutor(ct *catalog.Catalog, tx *storage.Transaction) *SchemaExecutor {
+	return &SchemaExecutor{
+		catalog:     ct,
+		transaction: tx,
+	}
+}
+
+func (e *SchemaExecutor) Create(pl planner.CreateSchemaPlan) (*ResultSet, error) {
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("schema \"%s\" already exists, skipping", pl.SchemaName),
+		}, nil
+	}
+
+	if err := e.catalog.AddNamespace(&catalog.NamespaceSchema{Name: pl.SchemaName}, e.transaction); err != nil {
+		return nil, err
+	}
+	return &ResultSet{
+		Message: "successfully created schema!",
+	}, nil
+}
+
+func (e *SchemaExecutor) Drop(pl planner.DropSchemaPlan) (*ResultSet, error) {
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("schema %s does not exist, skipping", pl.SchemaName),
+		}, nil
+	}
+
+	if err := e.catalog.DeleteNamespace(pl.SchemaName, e.transaction); err != nil {
+		return nil, err
+	}
+	return &ResultSet{
+		Message: "successfully dropped schema!",
+	}, nil
+}
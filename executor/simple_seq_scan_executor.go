@@ -1,36 +1,68 @@
 package executor
 
 import (
+	"garakutadb/catalog"
+	"garakutadb/expression"
 	"garakutadb/planner"
 	"garakutadb/storage"
+	"garakutadb/types"
 )
 
 type SeqScanExecutor struct {
 	storage        *storage.Storage
+	catalog        *catalog.Catalog
 	transaction    *storage.Transaction
 	transactionMgr *storage.TransactionManager
 }
 
-func NewSeqScanExecutor(st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *SeqScanExecutor {
+func NewSeqScanExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *SeqScanExecutor {
 	return &SeqScanExecutor{
 		storage:        st,
+		catalog:        ct,
 		transaction:    tx,
 		transactionMgr: txMgr,
 	}
 }
 
 func (e *SeqScanExecutor) Execute(pl planner.SeqScanPlan) (*ResultSet, error) {
+	rows, err := e.rows(pl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Header: pl.ColumnNames,
+		Rows:   formatRows(rows),
+	}, nil
+}
+
+// rows returns the selected columns of the rows which satisfy the condition
+func (e *SeqScanExecutor) rows(pl planner.SeqScanPlan) ([][]types.Value, error) {
+	tableSchema, err := e.catalog.TableSchemas.Get(pl.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	e.transactionMgr.PredicateLockRelation(e.transaction, pl.TableName)
 	it := e.storage.NewTupleIterator(pl.TableName, e.transaction)
 
-	columnNameAndOrderMap := make(map[string]uint64)
-	for order, columnName := range pl.ColumnNames {
-		columnNameAndOrderMap[columnName] = uint64(order)
+	// where expression can refer to columns which are not selected
+	columnNameAndOrderMap := columnNameAndOrderMap(tableSchema)
+
+	var aggregation *expression.Aggregation
+	if pl.Aggregate {
+		if aggregation, err = expression.NewAggregation(pl.Projections, e.catalog.Functions()); err != nil {
+			return nil, err
+		}
 	}
 
-	filteredRows := make([][]string, 0)
+	filteredRows := make([][]types.Value, 0)
 	for true {
 		tuple, found := it.Next(e.transactionMgr)
 		if !found {
+			if err := it.Err(); err != nil {
+				return nil, err
+			}
 			break
 		}
 
@@ -38,26 +70,40 @@ func (e *SeqScanExecutor) Execute(pl planner.SeqScanPlan) (*ResultSet, error) {
 			continue
 		}
 
-		row := make([]string, 0)
-		for _, columnOrder := range pl.ColumnOrders {
-			row = append(row, tuple.Data[columnOrder].Value)
+		row, err := decodeTuple(tableSchema, tuple)
+		if err != nil {
+			return nil, err
 		}
 
 		if pl.WhereExpression != nil {
-			evalResult, err := evalWhere(pl.WhereExpression, row, columnNameAndOrderMap)
+			evalResult, err := evalWhere(pl.WhereExpression, row, columnNameAndOrderMap, e.catalog.Functions())
 			if err != nil {
 				return nil, err
 			}
-			if evalResult {
-				filteredRows = append(filteredRows, row)
+			if !evalResult {
+				continue
+			}
+		}
+		if aggregation != nil {
+			if err := aggregation.Step(&columnRow{values: row, orders: columnNameAndOrderMap}); err != nil {
+				return nil, err
 			}
-		} else {
-			filteredRows = append(filteredRows, row)
+			continue
+		}
+		selected, err := selectRow(row, pl.ColumnOrders, pl.Projections, columnNameAndOrderMap, e.catalog.Functions())
+		if err != nil {
+			return nil, err
 		}
+		filteredRows = append(filteredRows, selected)
 	}
 
-	return &ResultSet{
-		Header: pl.ColumnNames,
-		Rows:   filteredRows,
-	}, nil
+	if aggregation != nil {
+		// one row even for no rows, e.g. a count is 0
+		selected, err := aggregation.Results()
+		if err != nil {
+			return nil, err
+		}
+		return [][]types.Value{selected}, nil
+	}
+	return filteredRows, nil
 }
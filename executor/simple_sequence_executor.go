@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+	"garakutadb/types"
+	"strconv"
+)
+
+// SequenceExecutor runs CREATE SEQUENCE and DROP SEQUENCE
+type SequenceExecutor struct {
+	storage     *storage.Storage
+	catalog     *catalog.Catalog
+	transaction *storage.Transaction
+}
+
+func NewSequenceExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction) *SequenceExecutor {
+	return &SequenceExecutor{
+		storage:     st,
+		catalog:     ct,
+		transaction: tx,
+	}
+}
+
+func (e *SequenceExecutor) Create(pl planner.CreateSequencePlan) (*ResultSet, error) {
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("relation \"%s\" already exists, skipping", pl.Sequence.Name),
+		}, nil
+	}
+
+	if err := createSequence(e.catalog, e.storage, e.transaction, pl.Sequence); err != nil {
+		return nil, err
+	}
+	return &ResultSet{
+		Message: "successfully created sequence!",
+	}, nil
+}
+
+func (e *SequenceExecutor) Drop(pl planner.DropSequencePlan) (*ResultSet, error) {
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("sequence %s does not exist, skipping", pl.SequenceName),
+		}, nil
+	}
+
+	if err := dropSequence(e.catalog, e.storage, e.transaction, pl.SequenceName); err != nil {
+		return nil, err
+	}
+	return &ResultSet{
+		Message: "successfully dropped sequence!",
+	}, nil
+}
+
+func createSequence(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, sequence *catalog.SequenceSchema) error {
+	if err := st.CreateSequence(sequence.Name, tx); err != nil {
+		return err
+	}
+	return ct.AddSequence(sequence, tx)
+}
+
+func dropSequence(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, name string) error {
+	if err := st.DropSequence(name, tx); err != nil {
+		return err
+	}
+	return ct.DeleteSequence(name, tx)
+}
+
+// evalSequenceCalls replaces values with the results of nextval and currval. The results are converted to the types of the values.
+func evalSequenceCalls(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, calls map[int]*planner.SequenceCall, values []types.Value) ([]types.Value, error) {
+	if len(calls) == 0 {
+		return values, nil
+	}
+
+	evaluated := make([]types.Value, len(values))
+	copy(evaluated, values)
+	for i, call := range calls {
+		n, err := evalSequenceCall(ct, st, tx, call)
+		if err != nil {
+			return nil, err
+		}
+		value, err := types.Parse(values[i].Type(), strconv.FormatInt(n, 10))
+		if err != nil {
+			return nil, err
+		}
+		evaluated[i] = value
+	}
+	return evaluated, nil
+}
+
+func evalSequenceCall(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, call *planner.SequenceCall) (int64, error) {
+	switch call.Function {
+	case planner.FunctionNextval:
+		sequence, err := ct.Sequences.Get(call.SequenceName)
+		if err != nil {
+			return 0, fmt.Errorf("relation \"%s\" does not exist", call.SequenceName)
+		}
+		n, err := st.NextSequenceValue(sequence.Name, sequence.SequenceOptions)
+		if err != nil {
+			return 0, err
+		}
+		tx.SetSequenceValue(sequence.Name, n)
+		return n, nil
+	case planner.FunctionCurrval:
+		n, ok := tx.SequenceValue(call.SequenceName)
+		if !ok {
+			return 0, fmt.Errorf("currval of sequence \"%s\" is not yet defined in this session", call.SequenceName)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("function %s does not exist", call.Function)
+	}
+}
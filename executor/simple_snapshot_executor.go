@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"garakutadb/planner"
+	"garakutadb/storage"
+)
+
+type SnapshotExecutor struct {
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
+}
+
+func NewSnapshotExecutor(tx *storage.Transaction, txMgr *storage.TransactionManager) *SnapshotExecutor {
+	return &SnapshotExecutor{
+		transaction:    tx,
+		transactionMgr: txMgr,
+	}
+}
+
+func (e *SnapshotExecutor) Export(pl planner.ExportSnapshotPlan) (*ResultSet, error) {
+	id, err := e.transactionMgr.ExportSnapshot(e.transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Header: []string{"snapshot_id"},
+		Rows:   [][]string{{id}},
+	}, nil
+}
+
+func (e *SnapshotExecutor) Import(pl planner.ImportSnapshotPlan) (*ResultSet, error) {
+	if err := e.transactionMgr.ImportSnapshot(e.transaction, pl.SnapshotId); err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Message: "SET",
+	}, nil
+}
@@ -0,0 +1,146 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+	"garakutadb/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SystemViewScanExecutor struct {
+	catalog        *catalog.Catalog
+	transactionMgr *storage.TransactionManager
+}
+
+func NewSystemViewScanExecutor(ct *catalog.Catalog, txMgr *storage.TransactionManager) *SystemViewScanExecutor {
+	return &SystemViewScanExecutor{
+		catalog:        ct,
+		transactionMgr: txMgr,
+	}
+}
+
+func (e *SystemViewScanExecutor) Execute(pl planner.SystemViewScanPlan) (*ResultSet, error) {
+	rows, err := e.rows(pl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Header: pl.ColumnNames,
+		Rows:   formatRows(rows),
+	}, nil
+}
+
+// rows returns the selected columns of the rows which satisfy the condition
+func (e *SystemViewScanExecutor) rows(pl planner.SystemViewScanPlan) ([][]types.Value, error) {
+	viewSchema, err := catalog.SystemViews.Get(pl.ViewName)
+	if err != nil {
+		return nil, fmt.Errorf("system view not found: %s", pl.ViewName)
+	}
+
+	var rows [][]types.Value
+	switch pl.ViewName {
+	case catalog.SysLocksViewName:
+		rows = textRows(e.lockRows())
+	case catalog.SysTransactionsViewName:
+		rows = textRows(e.transactionRows())
+	case catalog.InformationSchemaTablesViewName:
+		rows = e.tablesRows()
+	case catalog.InformationSchemaColumnsViewName:
+		rows = e.columnsRows()
+	case catalog.InformationSchemaTableConstraintsViewName:
+		rows = e.tableConstraintsRows()
+	case catalog.InformationSchemaKeyColumnUsageViewName:
+		rows = e.keyColumnUsageRows()
+	case catalog.InformationSchemaStatisticsViewName:
+		rows = e.statisticsRows()
+	case catalog.InformationSchemaSchemataViewName:
+		rows = e.schemataRows()
+	default:
+		return nil, fmt.Errorf("system view not found: %s", pl.ViewName)
+	}
+
+	// where expression refers to any column of the view, not only the selected ones
+	columnNameAndOrderMap := make(map[string]uint64)
+	for order, column := range viewSchema.Columns {
+		columnNameAndOrderMap[column.Name] = uint64(order)
+	}
+
+	filteredRows := make([][]types.Value, 0)
+	for _, row := range rows {
+		if pl.WhereExpression != nil {
+			evalResult, err := evalWhere(pl.WhereExpression, row, columnNameAndOrderMap, e.catalog.Functions())
+			if err != nil {
+				return nil, err
+			}
+			if !evalResult {
+				continue
+			}
+		}
+
+		selected, err := selectRow(row, pl.ColumnOrders, pl.Projections, columnNameAndOrderMap, e.catalog.Functions())
+		if err != nil {
+			return nil, err
+		}
+		filteredRows = append(filteredRows, selected)
+	}
+
+	return filteredRows, nil
+}
+
+// lockRows returns rows in the order of catalog.SystemViews (locktype, resource, table_name, mode, holder, waiters)
+func (e *SystemViewScanExecutor) lockRows() [][]string {
+	rows := make([][]string, 0)
+	for _, info := range e.transactionMgr.GetLockInfos() {
+		waiters := make([]string, 0)
+		for _, id := range info.Waiters {
+			waiters = append(waiters, formatTransactionId(id))
+		}
+		rows = append(rows, []string{
+			info.Resource.Type.String(),
+			info.Resource.String(),
+			info.Resource.TableName,
+			info.Mode.String(),
+			formatTransactionId(info.Holder),
+			strings.Join(waiters, ","),
+		})
+	}
+	return rows
+}
+
+// transactionRows returns rows in the order of catalog.SystemViews (id, state, isolation_level, start_time, statement, locks_held)
+func (e *SystemViewScanExecutor) transactionRows() [][]string {
+	rows := make([][]string, 0)
+	for _, info := range e.transactionMgr.GetTransactionInfos() {
+		rows = append(rows, []string{
+			formatTransactionId(info.Id),
+			info.State.String(),
+			info.IsolationLevel.String(),
+			info.StartTime.Format(time.RFC3339),
+			info.Statement,
+			strconv.Itoa(info.LocksHeld),
+		})
+	}
+	return rows
+}
+
+// textRows converts the columns of rows to text values
+func textRows(rows [][]string) [][]types.Value {
+	values := make([][]types.Value, 0, len(rows))
+	for _, columns := range rows {
+		row := make([]types.Value, len(columns))
+		for i, column := range columns {
+			row[i] = types.NewText(column)
+		}
+		values = append(values, row)
+	}
+	return values
+}
+
+func formatTransactionId(id storage.TransactionId) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+)
+
+type TruncateTableExecutor struct {
+	storage        *storage.Storage
+	catalog        *catalog.Catalog
+	transaction    *storage.Transaction
+	transactionMgr *storage.TransactionManager
+}
+
+func NewTruncateTableExecutor(ct *catalog.Catalog, st *storage.Storage, tx *storage.Transaction, txMgr *storage.TransactionManager) *TruncateTableExecutor {
+	return &TruncateTableExecutor{
+		storage:        st,
+		catalog:        ct,
+		transaction:    tx,
+		transactionMgr: txMgr,
+	}
+}
+
+func (e *TruncateTableExecutor) Execute(pl planner.TruncateTablePlan) (*ResultSet, error) {
+	tableSchema, err := e.catalog.TableSchemas.Get(pl.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.transactionMgr.LockTable(e.transaction, pl.TableName, storage.Exclusive) {
+		return nil, fmt.Errorf("failed to lock table: %s", pl.TableName)
+	}
+
+	if err := e.storage.TruncateTable(pl.TableName, tableSchema.IndexNames(), e.transaction); err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Message: "successfully truncated table!",
+	}, nil
+}
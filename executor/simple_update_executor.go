@@ -3,10 +3,10 @@ package executor
 import (
 	"fmt"
 	"garakutadb/catalog"
+	"garakutadb/expression"
 	"garakutadb/planner"
 	"garakutadb/storage"
-	"log"
-	"slices"
+	"garakutadb/types"
 )
 
 type UpdateExecutor struct {
@@ -36,73 +36,67 @@ func (e *UpdateExecutor) Execute(pl planner.UpdatePlan) (*ResultSet, error) {
 		return nil, err
 	}
 
-	columnNameAndOrderMap := make(map[string]uint64)
-	for order, col := range tableSchema.Columns {
-		columnNameAndOrderMap[col.Name] = uint64(order)
-	}
+	columnNameAndOrderMap := columnNameAndOrderMap(tableSchema)
+
+	writer := newRowWriter(e.catalog, e.storage, e.transaction, e.transactionMgr)
 
+	// the matched rows are collected first, so that the new versions appended to the table are not updated again
+	e.transactionMgr.PredicateLockRelation(e.transaction, pl.TableName)
 	it := e.storage.NewTupleIterator(pl.TableName, e.transaction)
-	updatedTupleIds := make([]string, 0)
+	matched := make([]storedRow, 0)
 	for true {
 		tuple, found := it.Next(e.transactionMgr)
 		if !found {
+			if err := it.Err(); err != nil {
+				return nil, err
+			}
 			break
 		}
 
-		if len(tuple.Data) == 0 || slices.Contains(updatedTupleIds, tuple.Data[0].Value) {
+		if len(tuple.Data) == 0 {
 			continue
 		}
 
-		row := make([]string, len(columnNameAndOrderMap))
-		for order := range tableSchema.Columns {
-			row[order] = tuple.Data[order].Value
+		row, err := decodeTuple(tableSchema, tuple)
+		if err != nil {
+			return nil, err
 		}
 
-		evalResult, err := evalWhere(pl.WhereExpression, row, columnNameAndOrderMap)
+		evalResult, err := evalWhere(pl.WhereExpression, row, columnNameAndOrderMap, e.catalog.Functions())
 		if err != nil {
 			return nil, err
 		}
 		if evalResult {
-			// update tuple
-			for i, newValue := range pl.ColumnValues {
-				tuple.Data[pl.ColumnOrders[i]].Value = newValue
-			}
-			e.transactionMgr.UnlockSharedByTupleId(e.transaction, it.GetTupleId())
-			if err := e.storage.DeleteTuple(pl.TableName, it.GetTupleId(), e.transaction, e.transactionMgr); err != nil {
-				return nil, err
-			}
-			log.Printf("deleted tuple: %v", tuple)
-			insertedTuplePage, err := e.storage.InsertTuple(pl.TableName, tuple, e.transaction, e.transactionMgr)
-			if err != nil {
-				return nil, err
-			}
-			log.Printf("inserted tuple: %v", tuple)
-
-			// update index entry
-			btree, err := e.storage.ReadIndex(pl.TableName, tableSchema.PK)
-			if err != nil {
-				return nil, err
-			}
-			item, found := btree.SearchAndUpdatePageId(&storage.StringItem{
-				Value:  tuple.Data[0].Value,
-				PageId: insertedTuplePage.Id,
+			matched = append(matched, storedRow{
+				tupleId: it.GetTupleId(),
+				row:     row,
 			})
-			if !found {
-				return nil, fmt.Errorf("index entry not found")
-			}
-
-			item.Value = tuple.Data[0].Value
-
-			if err := e.storage.WriteIndex(btree); err != nil {
-				return nil, err
-			}
-
-			updatedTupleIds = append(updatedTupleIds, tuple.Data[0].Value)
 		} else {
 			e.transactionMgr.UnlockSharedByTupleId(e.transaction, it.GetTupleId())
 		}
 	}
 
+	for _, m := range matched {
+		newRow := make([]types.Value, len(m.row))
+		copy(newRow, m.row)
+		for i, newValue := range pl.ColumnValues {
+			if expr := pl.ColumnExpressions[i]; expr != nil {
+				// computed from the current values
+				value, err := expression.Eval(expr, &columnRow{values: m.row, orders: columnNameAndOrderMap}, e.catalog.Functions())
+				if err != nil {
+					return nil, err
+				}
+				if newValue, err = types.Cast(value, newValue.Type()); err != nil {
+					return nil, err
+				}
+			}
+			newRow[pl.ColumnOrders[i]] = newValue
+		}
+		if err := writer.update(tableSchema, m.tupleId, m.row, newRow); err != nil {
+			return nil, err
+		}
+	}
+
 	return &ResultSet{
 		Message: "updated!",
 	}, nil
@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/planner"
+	"garakutadb/storage"
+)
+
+// ViewExecutor runs CREATE VIEW and DROP VIEW. Views have only their schemas in the catalog.
+type ViewExecutor struct {
+	catalog     *catalog.Catalog
+	transaction *storage.Transaction
+}
+
+func NewViewExecutor(ct *catalog.Catalog, tx *storage.Transaction) *ViewExecutor {
+	return &ViewExecutor{
+		catalog:     ct,
+		transaction: tx,
+	}
+}
+
+func (e *ViewExecutor) Create(pl planner.CreateViewPlan) (*ResultSet, error) {
+	if pl.Replace {
+		if err := e.catalog.UpdateView(pl.View, e.transaction); err != nil {
+			return nil, err
+		}
+	} else if err := e.catalog.AddView(pl.View, e.transaction); err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Message: "successfully created view!",
+	}, nil
+}
+
+func (e *ViewExecutor) Drop(pl planner.DropViewPlan) (*ResultSet, error) {
+	if pl.Skip {
+		return &ResultSet{
+			Message: fmt.Sprintf("view %s does not exist, skipping", pl.ViewName),
+		}, nil
+	}
+
+	if err := e.catalog.DeleteView(pl.ViewName, e.transaction); err != nil {
+		return nil, err
+	}
+	return &ResultSet{
+		Message: "successfully dropped view!",
+	}, nil
+}
@@ -0,0 +1,145 @@
+package expression
+
+import (
+	"fmt"
+	"garakutadb/types"
+	"slices"
+)
+
+// Aggregation computes expressions which have aggregate functions over a group of rows, e.g. SELECT sum(a) + 1 FROM t
+type Aggregation struct {
+	// the copies of the expressions, whose aggregate calls are the keys of calls
+	exprs     []Expression
+	calls     map[*FunctionExpression]*aggregateCall
+	functions *Functions
+}
+
+type aggregateCall struct {
+	function   *Function
+	aggregator Aggregator
+}
+
+// NewAggregation makes an aggregation of a group for the expressions
+func NewAggregation(exprs []Expression, functions *Functions) (*Aggregation, error) {
+	aggregation := &Aggregation{
+		exprs:     make([]Expression, 0, len(exprs)),
+		calls:     make(map[*FunctionExpression]*aggregateCall),
+		functions: functions,
+	}
+	for _, expr := range exprs {
+		if err := CheckAggregate(expr, functions); err != nil {
+			return nil, err
+		}
+		copied, err := transform(expr, func(node Expression) (Expression, error) {
+			if f, call, ok := aggregateFunction(node, functions); ok {
+				aggregation.calls[call] = &aggregateCall{function: f, aggregator: f.NewAggregator()}
+			}
+			return node, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		aggregation.exprs = append(aggregation.exprs, copied)
+	}
+	return aggregation, nil
+}
+
+// Step adds a row to the aggregate functions
+func (a *Aggregation) Step(row Row) error {
+	for e, call := range a.calls {
+		args, _, hasNull, err := evalArgs(call.function, e, row, a.functions)
+		if err != nil {
+			return err
+		}
+		if hasNull && !call.function.CalledOnNull {
+			continue
+		}
+		if err := call.aggregator.Step(args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Results returns the values of the expressions after all the rows are added
+func (a *Aggregation) Results() ([]types.Value, error) {
+	values := make([]types.Value, 0, len(a.exprs))
+	for _, expr := range a.exprs {
+		value, err := Eval(expr, a, a.functions)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Column is called for a column outside of aggregate functions, which doesn't have a value for a group
+func (a *Aggregation) Column(name string) (types.Value, error) {
+	return types.Value{}, ungroupedColumnError(name)
+}
+
+func (a *Aggregation) result(e *FunctionExpression) (types.Value, error) {
+	call, ok := a.calls[e]
+	if !ok {
+		return types.Value{}, fmt.Errorf("aggregate functions are not allowed here")
+	}
+	value, err := call.aggregator.Result()
+	if err != nil || call.function.Returns == types.Unknown {
+		return value, err
+	}
+	return types.Cast(value, call.function.Returns)
+}
+
+// HasAggregate reports whether an expression calls an aggregate function
+func HasAggregate(expr Expression, functions *Functions) bool {
+	found := false
+	_, _ = transform(expr, func(node Expression) (Expression, error) {
+		if _, _, ok := aggregateFunction(node, functions); ok {
+			found = true
+		}
+		return node, nil
+	})
+	return found
+}
+
+// CheckAggregate returns an error if an expression of an aggregation refers to a column outside of aggregate functions
+// or nests aggregate functions
+func CheckAggregate(expr Expression, functions *Functions) error {
+	hasAggregate := func(arg Expression) bool {
+		return HasAggregate(arg, functions)
+	}
+	_, err := transform(expr, func(node Expression) (Expression, error) {
+		if _, call, ok := aggregateFunction(node, functions); ok && slices.ContainsFunc(call.Args, hasAggregate) {
+			return nil, fmt.Errorf("aggregate function calls cannot be nested")
+		}
+		return node, nil
+	})
+	if err != nil {
+		return err
+	}
+	// the columns in aggregate functions are removed
+	outside, _ := transform(expr, func(node Expression) (Expression, error) {
+		if _, _, ok := aggregateFunction(node, functions); ok {
+			return &NullExpression{}, nil
+		}
+		return node, nil
+	})
+	if columns := Columns(outside); len(columns) > 0 {
+		return ungroupedColumnError(columns[0])
+	}
+	return nil
+}
+
+func aggregateFunction(node Expression, functions *Functions) (*Function, *FunctionExpression, bool) {
+	call, ok := node.(*FunctionExpression)
+	if !ok {
+		return nil, nil, false
+	}
+	f, ok := functions.Lookup(call.Name)
+	return f, call, ok && f.IsAggregate()
+}
+
+func ungroupedColumnError(name string) error {
+	return fmt.Errorf("column \"%s\" must appear in the GROUP BY clause or be used in an aggregate function", name)
+}
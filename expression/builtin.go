@@ -0,0 +1,199 @@
+package expression
+
+import (
+	"fmt"
+	"garakutadb/types"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// the built-in functions
+func init() {
+	text := []types.Type{types.Text}
+	for _, f := range []*Function{
+		{Name: "lower", Params: text, Returns: types.Text, Call: textFunction(strings.ToLower)},
+		{Name: "upper", Params: text, Returns: types.Text, Call: textFunction(strings.ToUpper)},
+		{Name: "length", Params: text, Returns: types.Int, Call: length},
+		{Name: "substr", Params: []types.Type{types.Text, types.BigInt, types.BigInt}, Optional: 1, Returns: types.Text, Call: substr},
+		{Name: "trim", Params: []types.Type{types.Text, types.Text}, Optional: 1, Returns: types.Text, Call: trim},
+		{Name: "replace", Params: []types.Type{types.Text, types.Text, types.Text}, Returns: types.Text, Call: replace},
+		{Name: "coalesce", Params: []types.Type{types.Unknown}, Variadic: true, Call: coalesce, CalledOnNull: true},
+		{Name: "nullif", Params: []types.Type{types.Unknown, types.Unknown}, Call: nullIf, CalledOnNull: true},
+		{Name: "abs", Params: []types.Type{types.Unknown}, Accepts: isNumericArg, Call: abs},
+		{Name: "round", Params: []types.Type{types.Unknown, types.Int}, Optional: 1, Accepts: isNumericArg, Call: round},
+		{Name: "now", Returns: types.Timestamp, Call: now},
+		{Name: "date_trunc", Params: []types.Type{types.Text, types.Timestamp}, Returns: types.Timestamp, Call: dateTrunc},
+		{Name: "extract", Params: []types.Type{types.Text, types.Timestamp}, Returns: types.Decimal, Call: extract},
+	} {
+		// now() returns the current time
+		f.Deterministic = f.Name != "now"
+		if err := f.validate(); err != nil {
+			panic(err)
+		}
+		builtins[f.Name] = f
+	}
+}
+
+func textFunction(f func(s string) string) func(args []types.Value) (types.Value, error) {
+	return func(args []types.Value) (types.Value, error) {
+		return types.NewText(f(args[0].String())), nil
+	}
+}
+
+func length(args []types.Value) (types.Value, error) {
+	return types.NewInt(int64(utf8.RuneCountInString(args[0].String()))), nil
+}
+
+// substr returns the characters from the position (1-origin) of the second argument, as many as the third argument
+func substr(args []types.Value) (types.Value, error) {
+	runes := []rune(args[0].String())
+	from := args[1].Int()
+	to := int64(len(runes)) + 1
+	if len(args) > 2 {
+		count := args[2].Int()
+		if count < 0 {
+			return types.Value{}, fmt.Errorf("negative substring length not allowed")
+		}
+		if from <= math.MaxInt64-count {
+			to = min(to, from+count)
+		}
+	}
+	from = max(from, 1)
+	if from >= to {
+		return types.NewText(""), nil
+	}
+	return types.NewText(string(runes[from-1 : to-1])), nil
+}
+
+// trim removes the characters of the second argument, or spaces, from both ends
+func trim(args []types.Value) (types.Value, error) {
+	characters := " "
+	if len(args) > 1 {
+		characters = args[1].String()
+	}
+	return types.NewText(strings.Trim(args[0].String(), characters)), nil
+}
+
+func replace(args []types.Value) (types.Value, error) {
+	from := args[1].String()
+	if from == "" {
+		return args[0], nil
+	}
+	return types.NewText(strings.ReplaceAll(args[0].String(), from, args[2].String())), nil
+}
+
+// coalesce returns the first argument which is not NULL
+func coalesce(args []types.Value) (types.Value, error) {
+	for _, arg := range args {
+		if !arg.IsNull() {
+			return arg, nil
+		}
+	}
+	return args[0], nil
+}
+
+// nullIf returns NULL if the arguments are equal, otherwise the first argument
+func nullIf(args []types.Value) (types.Value, error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return args[0], nil
+	}
+	cmp, err := types.Compare(args[0], args[1])
+	if err != nil {
+		return types.Value{}, err
+	}
+	if cmp == 0 {
+		return types.NewNull(args[0].Type()), nil
+	}
+	return args[0], nil
+}
+
+func isNumericArg(argTypes []types.Type) bool {
+	return argTypes[0] == types.Unknown || argTypes[0].IsNumeric()
+}
+
+func abs(args []types.Value) (types.Value, error) {
+	cmp, err := types.Compare(args[0], types.NewInt(0))
+	if err != nil || cmp >= 0 {
+		return args[0], err
+	}
+	return types.Negate(args[0])
+}
+
+// round rounds the first argument to the digits after the decimal point of the second argument, or to an integer
+func round(args []types.Value) (types.Value, error) {
+	scale := int64(0)
+	if len(args) > 1 {
+		scale = args[1].Int()
+	}
+	return types.Round(args[0], int32(scale))
+}
+
+func now(args []types.Value) (types.Value, error) {
+	return types.NewTimestamp(time.Now()), nil
+}
+
+// dateTrunc truncates a timestamp to the unit of the first argument, e.g. date_trunc('month', t) is the first day of the month
+func dateTrunc(args []types.Value) (types.Value, error) {
+	t := args[1].Time()
+	year, month, day := t.Date()
+	var truncated time.Time
+	switch unit := strings.ToLower(args[0].String()); unit {
+	case "year":
+		truncated = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	case "quarter":
+		truncated = time.Date(year, (month-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	case "month":
+		truncated = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	case "week":
+		// weeks start on Monday
+		truncated = time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case "day":
+		truncated = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	case "hour":
+		truncated = t.Truncate(time.Hour)
+	case "minute":
+		truncated = t.Truncate(time.Minute)
+	case "second":
+		truncated = t.Truncate(time.Second)
+	default:
+		return types.Value{}, fmt.Errorf("unit \"%s\" not recognized for type timestamp", unit)
+	}
+	return types.NewTimestamp(truncated), nil
+}
+
+// extract returns the field of the first argument of a timestamp, e.g. extract('year', t) which is EXTRACT(year FROM t)
+func extract(args []types.Value) (types.Value, error) {
+	t := args[1].Time()
+	var field int64
+	switch unit := strings.ToLower(args[0].String()); unit {
+	case "year":
+		field = int64(t.Year())
+	case "quarter":
+		field = int64(t.Month()-1)/3 + 1
+	case "month":
+		field = int64(t.Month())
+	case "week":
+		_, week := t.ISOWeek()
+		field = int64(week)
+	case "day":
+		field = int64(t.Day())
+	case "hour":
+		field = int64(t.Hour())
+	case "minute":
+		field = int64(t.Minute())
+	case "dow":
+		// Sunday is 0
+		field = int64(t.Weekday())
+	case "doy":
+		field = int64(t.YearDay())
+	case "second":
+		return types.Parse(types.Decimal, fmt.Sprintf("%d.%06d", t.Second(), t.Nanosecond()/1000))
+	case "epoch":
+		return types.Parse(types.Decimal, fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000))
+	default:
+		return types.Value{}, fmt.Errorf("unit \"%s\" not recognized for type timestamp", unit)
+	}
+	return types.Parse(types.Decimal, fmt.Sprint(field))
+}
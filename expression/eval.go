@@ -0,0 +1,350 @@
+package expression
+
+import (
+	"fmt"
+	"garakutadb/types"
+)
+
+// Row gives the values of the columns which an expression refers to
+type Row interface {
+	Column(name string) (types.Value, error)
+}
+
+// Eval computes the value of an expression for row. row is nil where columns can't be referred, e.g. INSERT VALUES.
+// The functions called in it are found in functions.
+// A condition is a boolean, which is NULL when it is unknown.
+func Eval(expr Expression, row Row, functions *Functions) (types.Value, error) {
+	switch e := expr.(type) {
+	case *ColumnExpression:
+		if row == nil {
+			return types.Value{}, fmt.Errorf("column reference \"%s\" is not allowed here", e.Name)
+		}
+		return row.Column(e.Name)
+	case *ValueExpression:
+		return literalValue(e, types.Unknown)
+	case *NullExpression:
+		return types.NewNull(types.Unknown), nil
+	case *UnaryExpression:
+		operand, err := Eval(e.Expr, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		if e.Operator == OperatorMinus {
+			return types.Negate(operand)
+		}
+		if !operand.Type().IsNumeric() && operand.Type() != types.Unknown {
+			return types.Value{}, fmt.Errorf("operator does not exist: + %s", operand.Type())
+		}
+		return operand, nil
+	case *BinaryExpression:
+		return evalBinary(e, row, functions)
+	case *CastExpression:
+		value, err := evalCast(e, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return e.Typmod.Apply(value)
+	case *CaseExpression:
+		return evalCase(e, row, functions)
+	case *FunctionExpression:
+		return evalFunction(e, row, functions)
+	case *AndExpression:
+		left, err := evalBoolean(e.Left, row, functions, "AND")
+		if err != nil {
+			return types.Value{}, err
+		}
+		right, err := evalBoolean(e.Right, row, functions, "AND")
+		if err != nil {
+			return types.Value{}, err
+		}
+		return and(left, right), nil
+	case *OrExpression:
+		left, err := evalBoolean(e.Left, row, functions, "OR")
+		if err != nil {
+			return types.Value{}, err
+		}
+		right, err := evalBoolean(e.Right, row, functions, "OR")
+		if err != nil {
+			return types.Value{}, err
+		}
+		switch {
+		case left.Bool() || right.Bool():
+			return types.NewBoolean(true), nil
+		case left.IsNull() || right.IsNull():
+			return types.NewNull(types.Boolean), nil
+		default:
+			return types.NewBoolean(false), nil
+		}
+	case *NotExpression:
+		operand, err := evalBoolean(e.Expr, row, functions, "NOT")
+		if err != nil {
+			return types.Value{}, err
+		}
+		return negateIf(operand, true), nil
+	case *ComparisonExpression:
+		left, right, err := evalOperands(e.Left, e.Right, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return compare(left, e.Operator, right)
+	case *IsNullExpression:
+		operand, err := Eval(e.Expr, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return types.NewBoolean(operand.IsNull() != e.Not), nil
+	case *InExpression:
+		// true if any value is equal, otherwise unknown if any comparison is unknown
+		result := types.NewBoolean(false)
+		for _, value := range e.Values {
+			left, right, err := evalOperands(e.Expr, value, row, functions)
+			if err != nil {
+				return types.Value{}, err
+			}
+			equal, err := compare(left, OperatorEqual, right)
+			if err != nil {
+				return types.Value{}, err
+			}
+			if equal.Bool() {
+				result = equal
+				break
+			}
+			if equal.IsNull() {
+				result = equal
+			}
+		}
+		return negateIf(result, e.Not), nil
+	case *BetweenExpression:
+		operand, from, err := evalOperands(e.Expr, e.From, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		lower, err := compare(operand, OperatorGreaterEqual, from)
+		if err != nil {
+			return types.Value{}, err
+		}
+		operand, to, err := evalOperands(e.Expr, e.To, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		upper, err := compare(operand, OperatorLessEqual, to)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return negateIf(and(lower, upper), e.Not), nil
+	case *LikeExpression:
+		// the pattern is text even for a column of another type
+		operand, err := Eval(e.Expr, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		pattern, err := Eval(e.Pattern, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		if operand.IsNull() || pattern.IsNull() {
+			return types.NewNull(types.Boolean), nil
+		}
+		if operand.Type() != types.Text || pattern.Type() != types.Text {
+			return types.Value{}, fmt.Errorf("operator does not exist: %s LIKE %s", operand.Type(), pattern.Type())
+		}
+		matched, err := matchLike(operand.String(), pattern.String(), e.Escape, e.CaseInsensitive)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return types.NewBoolean(matched != e.Not), nil
+	default:
+		return types.Value{}, fmt.Errorf("not supported expression type: %T", expr)
+	}
+}
+
+// literalValue converts a literal to a value. A string literal is converted to typ, and it is text if typ is Unknown.
+func literalValue(literal *ValueExpression, typ types.Type) (types.Value, error) {
+	if literal.Type != types.Unknown {
+		return types.Parse(literal.Type, literal.Value)
+	}
+	if typ == types.Unknown {
+		typ = types.Text
+	}
+	return types.Parse(typ, literal.Value)
+}
+
+// evalOperands evaluates the operands of an operator. A literal with an operand which is not a literal is interpreted
+// as the type of the operand, e.g. '2024-01-01' is a date for a date column, unless both of them are numbers.
+// A string literal with another literal is interpreted as the type of the other one.
+func evalOperands(left Expression, right Expression, row Row, functions *Functions) (types.Value, types.Value, error) {
+	leftLiteral, leftIsLiteral := left.(*ValueExpression)
+	rightLiteral, rightIsLiteral := right.(*ValueExpression)
+	switch {
+	case leftIsLiteral && (!rightIsLiteral || leftLiteral.Type == types.Unknown && rightLiteral.Type != types.Unknown):
+		rightValue, leftValue, err := evalWithLiteral(right, leftLiteral, row, functions)
+		return leftValue, rightValue, err
+	case rightIsLiteral && (!leftIsLiteral || rightLiteral.Type == types.Unknown && leftLiteral.Type != types.Unknown):
+		return evalWithLiteral(left, rightLiteral, row, functions)
+	default:
+		leftValue, err := Eval(left, row, functions)
+		if err != nil {
+			return types.Value{}, types.Value{}, err
+		}
+		rightValue, err := Eval(right, row, functions)
+		return leftValue, rightValue, err
+	}
+}
+
+// evalWithLiteral evaluates an operand and a literal which is interpreted as the type of the operand
+func evalWithLiteral(operand Expression, literal *ValueExpression, row Row, functions *Functions) (types.Value, types.Value, error) {
+	value, err := Eval(operand, row, functions)
+	if err != nil {
+		return types.Value{}, types.Value{}, err
+	}
+	converted, err := ConvertLiteral(literal, value.Type())
+	return value, converted, err
+}
+
+// evalCast converts the operand to the type of CAST. A string literal is read as the type.
+func evalCast(e *CastExpression, row Row, functions *Functions) (types.Value, error) {
+	if literal, ok := e.Expr.(*ValueExpression); ok && literal.Type == types.Unknown {
+		return types.Parse(e.Type, literal.Value)
+	}
+	operand, err := Eval(e.Expr, row, functions)
+	if err != nil {
+		return types.Value{}, err
+	}
+	return types.Cast(operand, e.Type)
+}
+
+// ConvertLiteral converts a literal compared with a value of typ. A number keeps its own type if typ is numeric, and
+// the other literals are parsed as typ.
+func ConvertLiteral(literal *ValueExpression, typ types.Type) (types.Value, error) {
+	if typ == types.Unknown || literal.Type.IsNumeric() && typ.IsNumeric() {
+		return literalValue(literal, typ)
+	}
+	return types.Parse(typ, literal.Value)
+}
+
+// evalBinary computes an arithmetic operation or a concatenation. NULL gives NULL.
+func evalBinary(e *BinaryExpression, row Row, functions *Functions) (types.Value, error) {
+	if e.Operator == OperatorConcat {
+		left, err := Eval(e.Left, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		right, err := Eval(e.Right, row, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return types.Concat(left, right), nil
+	}
+
+	left, right, err := evalOperands(e.Left, e.Right, row, functions)
+	if err != nil {
+		return types.Value{}, err
+	}
+	switch e.Operator {
+	case OperatorPlus:
+		return types.Add(left, right)
+	case OperatorMinus:
+		return types.Subtract(left, right)
+	case OperatorMultiply:
+		return types.Multiply(left, right)
+	case OperatorDivide:
+		return types.Divide(left, right)
+	case OperatorModulo:
+		return types.Modulo(left, right)
+	default:
+		return types.Value{}, fmt.Errorf("not supported operator: %s", e.Operator)
+	}
+}
+
+// evalCase returns the result of the first WHEN which is true, or ELSE
+func evalCase(e *CaseExpression, row Row, functions *Functions) (types.Value, error) {
+	for _, when := range e.Whens {
+		var matched types.Value
+		var err error
+		if e.Operand == nil {
+			matched, err = evalBoolean(when.Condition, row, functions, "CASE/WHEN")
+		} else {
+			var operand, value types.Value
+			operand, value, err = evalOperands(e.Operand, when.Condition, row, functions)
+			if err == nil {
+				matched, err = compare(operand, OperatorEqual, value)
+			}
+		}
+		if err != nil {
+			return types.Value{}, err
+		}
+		if matched.Bool() {
+			return Eval(when.Result, row, functions)
+		}
+	}
+	if e.Else == nil {
+		return types.NewNull(types.Unknown), nil
+	}
+	return Eval(e.Else, row, functions)
+}
+
+// evalBoolean evaluates the argument of an operator of conditions, which must be a boolean or NULL
+func evalBoolean(expr Expression, row Row, functions *Functions, operator string) (types.Value, error) {
+	value, err := Eval(expr, row, functions)
+	if err != nil {
+		return types.Value{}, err
+	}
+	if value.IsNull() {
+		return types.NewNull(types.Boolean), nil
+	}
+	if value.Type() != types.Boolean {
+		return types.Value{}, fmt.Errorf("argument of %s must be type boolean, not type %s", operator, value.Type())
+	}
+	return value, nil
+}
+
+// compare compares two values by operator. A comparison with NULL is unknown.
+func compare(left types.Value, operator string, right types.Value) (types.Value, error) {
+	if left.IsNull() || right.IsNull() {
+		return types.NewNull(types.Boolean), nil
+	}
+	cmp, err := types.Compare(left, right)
+	if err != nil {
+		return types.Value{}, err
+	}
+	switch operator {
+	case OperatorEqual:
+		return types.NewBoolean(cmp == 0), nil
+	case OperatorNotEqual:
+		return types.NewBoolean(cmp != 0), nil
+	case OperatorLessThan:
+		return types.NewBoolean(cmp < 0), nil
+	case OperatorLessEqual:
+		return types.NewBoolean(cmp <= 0), nil
+	case OperatorGreaterThan:
+		return types.NewBoolean(cmp > 0), nil
+	case OperatorGreaterEqual:
+		return types.NewBoolean(cmp >= 0), nil
+	default:
+		return types.Value{}, fmt.Errorf("not supported operator: %s", operator)
+	}
+}
+
+// and is AND of three-valued logic, where NULL is unknown
+func and(left types.Value, right types.Value) types.Value {
+	switch {
+	case isFalse(left) || isFalse(right):
+		return types.NewBoolean(false)
+	case left.IsNull() || right.IsNull():
+		return types.NewNull(types.Boolean)
+	default:
+		return types.NewBoolean(true)
+	}
+}
+
+func isFalse(v types.Value) bool {
+	return !v.IsNull() && !v.Bool()
+}
+
+// negateIf returns NOT v if not is true. NOT unknown is unknown.
+func negateIf(v types.Value, not bool) types.Value {
+	if !not || v.IsNull() {
+		return v
+	}
+	return types.NewBoolean(!v.Bool())
+}
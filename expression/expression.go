@@ -2,7 +2,11 @@ package expression
 
 import (
 	"fmt"
+	"garakutadb/types"
 	"github.com/xwb1989/sqlparser"
+	"math"
+	"strconv"
+	"strings"
 )
 
 type Expression interface {
@@ -14,6 +18,16 @@ type AndExpression struct {
 	Right Expression
 }
 
+type OrExpression struct {
+	Left  Expression
+	Right Expression
+}
+
+type NotExpression struct {
+	Expr Expression
+}
+
+// ComparisonExpression is <expression> <operator> <expression>. A column compared with a literal is kept on the left.
 type ComparisonExpression struct {
 	Operator string
 	Left     Expression
@@ -21,23 +35,159 @@ type ComparisonExpression struct {
 }
 
 const (
-	OperatorEqual = "="
+	OperatorEqual        = "="
+	OperatorNotEqual     = "!="
+	OperatorLessThan     = "<"
+	OperatorLessEqual    = "<="
+	OperatorGreaterThan  = ">"
+	OperatorGreaterEqual = ">="
 )
 
+// flippedOperators are the operators with the operands swapped, e.g. 1 < a is a > 1
+var flippedOperators = map[string]string{
+	OperatorEqual:        OperatorEqual,
+	OperatorNotEqual:     OperatorNotEqual,
+	OperatorLessThan:     OperatorGreaterThan,
+	OperatorLessEqual:    OperatorGreaterEqual,
+	OperatorGreaterThan:  OperatorLessThan,
+	OperatorGreaterEqual: OperatorLessEqual,
+}
+
+// ColumnExpression is a reference to a column of the row
+type ColumnExpression struct {
+	Name string
+}
+
+// ValueExpression is a literal. The text of a string literal has no type until it is used with a value of a type,
+// e.g. '1' is an int in a = '1' for an int column a. Numbers, booleans and X'..' have their own types.
 type ValueExpression struct {
 	Value string
+	// Unknown for a string literal
+	Type types.Type
+}
+
+// NullExpression is the NULL literal
+type NullExpression struct {
+}
+
+// IsNullExpression is <expression> IS [NOT] NULL
+type IsNullExpression struct {
+	Expr Expression
+	Not  bool
+}
+
+// InExpression is <expression> [NOT] IN (<expression>, ...)
+type InExpression struct {
+	Expr   Expression
+	Values []Expression
+	Not    bool
+}
+
+// BetweenExpression is <expression> [NOT] BETWEEN <expression> AND <expression>
+type BetweenExpression struct {
+	Expr Expression
+	From Expression
+	To   Expression
+	Not  bool
+}
+
+// LikeExpression is <expression> [NOT] LIKE|ILIKE <pattern> [ESCAPE <character>].
+// % matches any string and _ matches any character in the pattern.
+type LikeExpression struct {
+	Expr    Expression
+	Pattern Expression
+	// the character which makes the next character match itself. Empty means no escape character.
+	Escape          string
+	CaseInsensitive bool
+	Not             bool
+}
+
+// DefaultLikeEscape is the escape character of LIKE without ESCAPE
+const DefaultLikeEscape = `\`
+
+// FunctionExpression is a call of a function, e.g. nextval('s')
+type FunctionExpression struct {
+	// lower case
+	Name string
+	Args []Expression
+}
+
+// UnaryExpression is -<expression> or +<expression>
+type UnaryExpression struct {
+	Operator string
+	Expr     Expression
+}
+
+// BinaryExpression is an arithmetic operation or a concatenation of strings
+type BinaryExpression struct {
+	Operator string
+	Left     Expression
+	Right    Expression
+}
+
+const (
+	OperatorPlus     = "+"
+	OperatorMinus    = "-"
+	OperatorMultiply = "*"
+	OperatorDivide   = "/"
+	OperatorModulo   = "%"
+	OperatorConcat   = "||"
+)
+
+var binaryOperators = map[string]string{
+	sqlparser.PlusStr:  OperatorPlus,
+	sqlparser.MinusStr: OperatorMinus,
+	sqlparser.MultStr:  OperatorMultiply,
+	sqlparser.DivStr:   OperatorDivide,
+	sqlparser.ModStr:   OperatorModulo,
+	// || is parsed as | (see Parse)
+	sqlparser.BitOrStr: OperatorConcat,
+}
+
+// CaseExpression is CASE [<operand>] WHEN ... THEN ... [ELSE ...] END.
+// Without the operand, the conditions of WHEN are evaluated. With it, the values of WHEN are compared with it.
+type CaseExpression struct {
+	Operand Expression
+	Whens   []*WhenClause
+	// nil means ELSE NULL
+	Else Expression
+}
+
+type WhenClause struct {
+	Condition Expression
+	Result    Expression
+}
+
+// CastExpression is CAST(<expression> AS <type>)
+type CastExpression struct {
+	Expr   Expression
+	Type   types.Type
+	Typmod *types.Typmod
 }
 
 func (e *AndExpression) implementExpr()        {}
+func (e *OrExpression) implementExpr()         {}
+func (e *NotExpression) implementExpr()        {}
+func (e *ColumnExpression) implementExpr()     {}
 func (e *ValueExpression) implementExpr()      {}
 func (e *ComparisonExpression) implementExpr() {}
+func (e *NullExpression) implementExpr()       {}
+func (e *IsNullExpression) implementExpr()     {}
+func (e *InExpression) implementExpr()         {}
+func (e *BetweenExpression) implementExpr()    {}
+func (e *LikeExpression) implementExpr()       {}
+func (e *FunctionExpression) implementExpr()   {}
+func (e *UnaryExpression) implementExpr()      {}
+func (e *BinaryExpression) implementExpr()     {}
+func (e *CaseExpression) implementExpr()       {}
+func (e *CastExpression) implementExpr()       {}
 
 func GetWhereFromWhereExpr(whereExpr *sqlparser.Where) (Expression, error) {
 	if whereExpr.Type != sqlparser.WhereStr {
 		return nil, fmt.Errorf("not supported where type: %s", whereExpr.Type)
 	}
 
-	expression, err := getExpressionFromExpr(whereExpr.Expr)
+	expression, err := FromExpr(whereExpr.Expr)
 	if err != nil {
 		return nil, err
 	}
@@ -45,34 +195,532 @@ func GetWhereFromWhereExpr(whereExpr *sqlparser.Where) (Expression, error) {
 	return expression, nil
 }
 
-func getExpressionFromExpr(expr sqlparser.Expr) (Expression, error) {
-	switch expr.(type) {
+// FromExpr converts an expression of a condition, a select list or a value to Expression
+func FromExpr(expr sqlparser.Expr) (Expression, error) {
+	switch e := expr.(type) {
+	case *sqlparser.ColName:
+		return &ColumnExpression{Name: e.Name.String()}, nil
+	case *sqlparser.SQLVal, *sqlparser.NullVal, sqlparser.BoolVal:
+		return LiteralFromExpr(expr)
+	case *sqlparser.UnaryExpr:
+		if literal, err := LiteralFromExpr(e); err == nil {
+			// a negative number
+			return literal, nil
+		}
+		if e.Operator != sqlparser.UMinusStr && e.Operator != sqlparser.UPlusStr {
+			return nil, fmt.Errorf("not supported operator: %s", e.Operator)
+		}
+		operand, err := FromExpr(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpression{Operator: e.Operator, Expr: operand}, nil
+	case *sqlparser.BinaryExpr:
+		operator, ok := binaryOperators[e.Operator]
+		if !ok {
+			return nil, fmt.Errorf("not supported operator: %s", e.Operator)
+		}
+		left, err := FromExpr(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := FromExpr(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpression{Operator: operator, Left: left, Right: right}, nil
+	case *sqlparser.CaseExpr:
+		return getCaseExpression(e)
+	case *sqlparser.ConvertExpr:
+		typ, typmod, err := castType(e.Type)
+		if err != nil {
+			return nil, err
+		}
+		operand, err := FromExpr(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &CastExpression{Expr: operand, Type: typ, Typmod: typmod}, nil
+	case *sqlparser.FuncExpr:
+		return getFunctionExpression(e, FromExpr)
 	case *sqlparser.ComparisonExpr:
-		comparisonExpr := expr.(*sqlparser.ComparisonExpr)
-		if comparisonExpr.Operator != OperatorEqual {
-			return nil, fmt.Errorf("not supported operator: %s", comparisonExpr.Operator)
-		}
-		return &ComparisonExpression{
-			Operator: comparisonExpr.Operator,
-			Left:     &ValueExpression{Value: comparisonExpr.Left.(*sqlparser.ColName).Name.String()},
-			Right:    &ValueExpression{Value: string(comparisonExpr.Right.(*sqlparser.SQLVal).Val)},
+		return getComparisonExpression(e)
+	case *sqlparser.RangeCond:
+		operand, err := FromExpr(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		from, err := FromExpr(e.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := FromExpr(e.To)
+		if err != nil {
+			return nil, err
+		}
+		return &BetweenExpression{
+			Expr: operand,
+			From: from,
+			To:   to,
+			Not:  e.Operator == sqlparser.NotBetweenStr,
 		}, nil
-
+	case *sqlparser.IsExpr:
+		operand, err := FromExpr(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Operator {
+		case sqlparser.IsNullStr, sqlparser.IsNotNullStr:
+			return &IsNullExpression{
+				Expr: operand,
+				Not:  e.Operator == sqlparser.IsNotNullStr,
+			}, nil
+		default:
+			return nil, fmt.Errorf("not supported operator: %s", e.Operator)
+		}
 	case *sqlparser.AndExpr:
-		andExpr := expr.(*sqlparser.AndExpr)
+		left, err := FromExpr(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := FromExpr(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &AndExpression{Left: left, Right: right}, nil
+	case *sqlparser.OrExpr:
+		left, err := FromExpr(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := FromExpr(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &OrExpression{Left: left, Right: right}, nil
+	case *sqlparser.NotExpr:
+		operand, err := FromExpr(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpression{Expr: operand}, nil
+	case *sqlparser.ParenExpr:
+		return FromExpr(e.Expr)
+	default:
+		return nil, fmt.Errorf("not supported expression type: %T", expr)
+	}
+}
 
-		left, err := getExpressionFromExpr(andExpr.Left)
+func getComparisonExpression(expr *sqlparser.ComparisonExpr) (Expression, error) {
+	switch expr.Operator {
+	case sqlparser.InStr, sqlparser.NotInStr:
+		operand, err := FromExpr(expr.Left)
 		if err != nil {
 			return nil, err
 		}
+		tuple, ok := expr.Right.(sqlparser.ValTuple)
+		if !ok {
+			return nil, fmt.Errorf("not supported expression: %s", String(expr.Right))
+		}
+		values := make([]Expression, 0, len(tuple))
+		for _, value := range tuple {
+			converted, err := FromExpr(value)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, converted)
+		}
+		return &InExpression{Expr: operand, Values: values, Not: expr.Operator == sqlparser.NotInStr}, nil
+	case sqlparser.LikeStr, sqlparser.NotLikeStr, sqlparser.RegexpStr, sqlparser.NotRegexpStr:
+		operand, err := FromExpr(expr.Left)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := FromExpr(expr.Right)
+		if err != nil {
+			return nil, err
+		}
+		like := &LikeExpression{
+			Expr:            operand,
+			Pattern:         pattern,
+			Escape:          DefaultLikeEscape,
+			CaseInsensitive: expr.Operator == sqlparser.RegexpStr || expr.Operator == sqlparser.NotRegexpStr,
+			Not:             expr.Operator == sqlparser.NotLikeStr || expr.Operator == sqlparser.NotRegexpStr,
+		}
+		if expr.Escape != nil {
+			escape, ok := expr.Escape.(*sqlparser.SQLVal)
+			if !ok || escape.Type != sqlparser.StrVal || len([]rune(string(escape.Val))) > 1 {
+				return nil, fmt.Errorf("invalid escape string: %s", String(expr.Escape))
+			}
+			like.Escape = string(escape.Val)
+		}
+		return like, nil
+	}
+
+	operator, ok := flippedOperators[expr.Operator]
+	if !ok {
+		return nil, fmt.Errorf("not supported operator: %s", expr.Operator)
+	}
+	left, right := expr.Left, expr.Right
+	_, leftIsColumn := left.(*sqlparser.ColName)
+	if _, ok := right.(*sqlparser.ColName); ok && !leftIsColumn {
+		// <expression> <operator> <column>
+		left, right = right, left
+	} else {
+		operator = expr.Operator
+	}
+	leftExpression, err := FromExpr(left)
+	if err != nil {
+		return nil, err
+	}
+	rightExpression, err := FromExpr(right)
+	if err != nil {
+		return nil, err
+	}
+	return &ComparisonExpression{
+		Operator: operator,
+		Left:     leftExpression,
+		Right:    rightExpression,
+	}, nil
+}
 
-		right, err := getExpressionFromExpr(andExpr.Right)
+func getCaseExpression(expr *sqlparser.CaseExpr) (Expression, error) {
+	caseExpression := &CaseExpression{}
+	var err error
+	if expr.Expr != nil {
+		if caseExpression.Operand, err = FromExpr(expr.Expr); err != nil {
+			return nil, err
+		}
+	}
+	for _, when := range expr.Whens {
+		condition, err := FromExpr(when.Cond)
 		if err != nil {
 			return nil, err
 		}
+		result, err := FromExpr(when.Val)
+		if err != nil {
+			return nil, err
+		}
+		caseExpression.Whens = append(caseExpression.Whens, &WhenClause{Condition: condition, Result: result})
+	}
+	if expr.Else != nil {
+		if caseExpression.Else, err = FromExpr(expr.Else); err != nil {
+			return nil, err
+		}
+	}
+	return caseExpression, nil
+}
 
-		return &AndExpression{Left: left, Right: right}, nil
+// castType maps the type of CAST to Type. sqlparser knows only the types of MySQL, so the other type names are given
+// as the character set of char (see Parse). The precision and the scale are given only by decimal(p, s).
+func castType(convertType *sqlparser.ConvertType) (types.Type, *types.Typmod, error) {
+	name := strings.ToLower(convertType.Type)
+	if name == "char" && convertType.Operator == "" && convertType.Charset != "" {
+		typ, err := types.ParseType(convertType.Charset)
+		return typ, nil, err
+	}
+	switch name {
+	case "char", "nchar":
+		return types.Text, nil, nil
+	case "signed", "unsigned":
+		return types.BigInt, nil, nil
+	case "json", "time":
+		return types.Unknown, nil, fmt.Errorf("unknown type: %s", name)
+	}
+
+	typ, err := types.ParseType(name)
+	if err != nil {
+		return types.Unknown, nil, err
+	}
+	var params []int64
+	for _, param := range []*sqlparser.SQLVal{convertType.Length, convertType.Scale} {
+		if param == nil {
+			break
+		}
+		n, err := strconv.ParseInt(string(param.Val), 10, 32)
+		if err != nil {
+			return types.Unknown, nil, fmt.Errorf("invalid type parameter %s", param.Val)
+		}
+		params = append(params, n)
+	}
+	typmod, err := types.NewTypmod(typ, params)
+	if err != nil {
+		return types.Unknown, nil, err
+	}
+	return typ, typmod, nil
+}
+
+// LiteralFromExpr converts a literal to ValueExpression or NullExpression
+func LiteralFromExpr(expr sqlparser.Expr) (Expression, error) {
+	switch e := expr.(type) {
+	case *sqlparser.SQLVal:
+		switch e.Type {
+		case sqlparser.HexVal:
+			// X'0a0b'
+			return &ValueExpression{Value: `\x` + string(e.Val), Type: types.Bytea}, nil
+		case sqlparser.StrVal, sqlparser.HexNum:
+			return &ValueExpression{Value: string(e.Val)}, nil
+		case sqlparser.IntVal, sqlparser.FloatVal:
+			return &ValueExpression{Value: string(e.Val), Type: numberType(string(e.Val))}, nil
+		default:
+			return nil, fmt.Errorf("not supported value: %s", sqlparser.String(e))
+		}
+	case *sqlparser.NullVal:
+		return &NullExpression{}, nil
+	case sqlparser.BoolVal:
+		return &ValueExpression{Value: strconv.FormatBool(bool(e)), Type: types.Boolean}, nil
+	case *sqlparser.UnaryExpr:
+		if val, ok := e.Expr.(*sqlparser.SQLVal); ok && e.Operator == sqlparser.UMinusStr && (val.Type == sqlparser.IntVal || val.Type == sqlparser.FloatVal) {
+			value := "-" + string(val.Val)
+			return &ValueExpression{Value: value, Type: numberType(value)}, nil
+		}
+		return nil, fmt.Errorf("not supported value: %s", sqlparser.String(e))
 	default:
-		return nil, fmt.Errorf("not supported expression type: %T", expr)
+		return nil, fmt.Errorf("not supported value: %s", sqlparser.String(expr))
+	}
+}
+
+// numberType returns the type of a numeric literal like PostgreSQL: int if it fits, then bigint, otherwise decimal.
+// A number with an exponent is a double.
+func numberType(literal string) types.Type {
+	if strings.ContainsAny(literal, "eE") {
+		return types.Double
+	}
+	if strings.Contains(literal, ".") {
+		return types.Decimal
+	}
+	i, err := strconv.ParseInt(literal, 10, 64)
+	switch {
+	case err != nil:
+		return types.Decimal
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return types.Int
+	default:
+		return types.BigInt
+	}
+}
+
+// MapColumns returns a copy of an expression whose column names are replaced by mapColumn
+func MapColumns(expr Expression, mapColumn func(name string) (string, error)) (Expression, error) {
+	return transform(expr, func(node Expression) (Expression, error) {
+		column, ok := node.(*ColumnExpression)
+		if !ok {
+			return node, nil
+		}
+		name, err := mapColumn(column.Name)
+		if err != nil {
+			return nil, err
+		}
+		return &ColumnExpression{Name: name}, nil
+	})
+}
+
+// Columns returns the names of the columns referred by an expression
+func Columns(expr Expression) []string {
+	columns := make([]string, 0)
+	_, _ = transform(expr, func(node Expression) (Expression, error) {
+		if column, ok := node.(*ColumnExpression); ok {
+			columns = append(columns, column.Name)
+		}
+		return node, nil
+	})
+	return columns
+}
+
+// FunctionNames returns the names of the functions called in an expression
+func FunctionNames(expr Expression) []string {
+	names := make([]string, 0)
+	_, _ = transform(expr, func(node Expression) (Expression, error) {
+		if function, ok := node.(*FunctionExpression); ok {
+			names = append(names, function.Name)
+		}
+		return node, nil
+	})
+	return names
+}
+
+// transform returns a copy of an expression whose nodes are replaced by f. The children of a node are replaced before it.
+func transform(expr Expression, f func(Expression) (Expression, error)) (Expression, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	var err error
+	each := func(exprs ...*Expression) {
+		for _, e := range exprs {
+			if err == nil {
+				*e, err = transform(*e, f)
+			}
+		}
+	}
+
+	switch e := expr.(type) {
+	case *AndExpression:
+		copied := *e
+		each(&copied.Left, &copied.Right)
+		expr = &copied
+	case *OrExpression:
+		copied := *e
+		each(&copied.Left, &copied.Right)
+		expr = &copied
+	case *NotExpression:
+		copied := *e
+		each(&copied.Expr)
+		expr = &copied
+	case *ComparisonExpression:
+		copied := *e
+		each(&copied.Left, &copied.Right)
+		expr = &copied
+	case *IsNullExpression:
+		copied := *e
+		each(&copied.Expr)
+		expr = &copied
+	case *InExpression:
+		copied := *e
+		copied.Values = append([]Expression(nil), e.Values...)
+		each(&copied.Expr)
+		for i := range copied.Values {
+			each(&copied.Values[i])
+		}
+		expr = &copied
+	case *BetweenExpression:
+		copied := *e
+		each(&copied.Expr, &copied.From, &copied.To)
+		expr = &copied
+	case *LikeExpression:
+		copied := *e
+		each(&copied.Expr, &copied.Pattern)
+		expr = &copied
+	case *FunctionExpression:
+		copied := *e
+		copied.Args = append([]Expression(nil), e.Args...)
+		for i := range copied.Args {
+			each(&copied.Args[i])
+		}
+		expr = &copied
+	case *UnaryExpression:
+		copied := *e
+		each(&copied.Expr)
+		expr = &copied
+	case *BinaryExpression:
+		copied := *e
+		each(&copied.Left, &copied.Right)
+		expr = &copied
+	case *CaseExpression:
+		copied := *e
+		copied.Whens = make([]*WhenClause, 0, len(e.Whens))
+		each(&copied.Operand, &copied.Else)
+		for _, when := range e.Whens {
+			w := *when
+			each(&w.Condition, &w.Result)
+			copied.Whens = append(copied.Whens, &w)
+		}
+		expr = &copied
+	case *CastExpression:
+		copied := *e
+		each(&copied.Expr)
+		expr = &copied
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f(expr)
+}
+
+// ScalarFromExpr converts a literal or a function call whose arguments are literals to an expression
+func ScalarFromExpr(expr sqlparser.Expr) (Expression, error) {
+	funcExpr, ok := expr.(*sqlparser.FuncExpr)
+	if !ok {
+		return LiteralFromExpr(expr)
+	}
+	return getFunctionExpression(funcExpr, LiteralFromExpr)
+}
+
+func getFunctionExpression(funcExpr *sqlparser.FuncExpr, convertArg func(sqlparser.Expr) (Expression, error)) (Expression, error) {
+	if !funcExpr.Qualifier.IsEmpty() || funcExpr.Distinct {
+		return nil, fmt.Errorf("not supported function call: %s", sqlparser.String(funcExpr))
+	}
+	args := make([]Expression, 0, len(funcExpr.Exprs))
+	for _, selectExpr := range funcExpr.Exprs {
+		aliasedExpr, ok := selectExpr.(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, fmt.Errorf("not supported function argument: %s", sqlparser.String(selectExpr))
+		}
+		arg, err := convertArg(aliasedExpr.Expr)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return &FunctionExpression{Name: funcExpr.Name.Lowered(), Args: args}, nil
+}
+
+// ParseCondition parses a condition written in SQL, e.g. a CHECK constraint
+func ParseCondition(sql string) (Expression, error) {
+	stmt, err := Parse("SELECT * FROM t WHERE " + sql)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %s", sql)
+	}
+	return GetWhereFromWhereExpr(stmt.(*sqlparser.Select).Where)
+}
+
+// ParseLiteral parses a literal or a function call written in SQL, e.g. a DEFAULT value
+func ParseLiteral(sql string) (Expression, error) {
+	stmt, err := Parse("SELECT " + sql)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %s", sql)
+	}
+	selectExprs := stmt.(*sqlparser.Select).SelectExprs
+	aliasedExpr, ok := selectExprs[0].(*sqlparser.AliasedExpr)
+	if len(selectExprs) != 1 || !ok {
+		return nil, fmt.Errorf("invalid expression: %s", sql)
+	}
+
+	expr := aliasedExpr.Expr
+	for {
+		parenExpr, ok := expr.(*sqlparser.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = parenExpr.Expr
+	}
+	return ScalarFromExpr(expr)
+}
+
+// ReferencedColumns returns the names of the columns used in a condition written in SQL
+func ReferencedColumns(sql string) ([]string, error) {
+	where, err := parseWhere(sql)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0)
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if col, ok := node.(*sqlparser.ColName); ok {
+			columns = append(columns, col.Name.String())
+		}
+		return true, nil
+	}, where)
+	return columns, nil
+}
+
+// RenameColumn replaces the column from with to in a condition written in SQL
+func RenameColumn(sql string, from string, to string) (string, error) {
+	where, err := parseWhere(sql)
+	if err != nil {
+		return "", err
+	}
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if col, ok := node.(*sqlparser.ColName); ok && col.Name.EqualString(from) {
+			col.Name = sqlparser.NewColIdent(to)
+		}
+		return true, nil
+	}, where)
+	return String(where), nil
+}
+
+func parseWhere(sql string) (sqlparser.Expr, error) {
+	stmt, err := Parse("SELECT * FROM t WHERE " + sql)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %s", sql)
 	}
+	return stmt.(*sqlparser.Select).Where.Expr, nil
 }
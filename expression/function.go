@@ -0,0 +1,240 @@
+package expression
+
+import (
+	"fmt"
+	"garakutadb/types"
+	"strings"
+	"sync"
+)
+
+// Function is a scalar or aggregate function which can be called in SQL
+type Function struct {
+	// case-insensitive
+	Name string
+	// the types of the parameters, to which the arguments are converted. Unknown accepts any type.
+	Params []types.Type
+	// the number of the last parameters which can be omitted
+	Optional int
+	// the last parameter can be repeated
+	Variadic bool
+	// the type of the result. Unknown means the common type of the arguments of the parameters of Unknown, e.g. coalesce,
+	// and those arguments are converted to it.
+	Returns types.Type
+	// Accepts checks the types of the arguments more than Params, e.g. abs takes only numbers. It can be nil.
+	Accepts func(argTypes []types.Type) bool
+	// Call computes the result. The arguments are converted to the types of the parameters.
+	Call func(args []types.Value) (types.Value, error)
+	// NewAggregator makes an aggregate function instead of Call. An aggregator is made for each group of rows.
+	NewAggregator func() Aggregator
+	// Call or Aggregator.Step is called with NULL arguments. Otherwise the result is NULL if any argument is NULL, and
+	// the rows whose arguments have NULL are not aggregated.
+	CalledOnNull bool
+	// the result is the same for the same arguments, so a call with constant arguments is computed in planning
+	Deterministic bool
+}
+
+// Aggregator computes the result of an aggregate function from the arguments of the rows in a group
+type Aggregator interface {
+	// Step adds the arguments of a row, which are converted to the types of the parameters
+	Step(args []types.Value) error
+	// Result returns the result after all the rows are added. It is called without Step for no rows.
+	Result() (types.Value, error)
+}
+
+// Functions are the functions which can be called in SQL. The built-in functions are found unless a function of the
+// same name is registered. A nil *Functions has only the built-in functions.
+type Functions struct {
+	mutex  sync.RWMutex
+	byName map[string]*Function
+	// incremented when a function is registered, so that the plans computed by the old functions are not used
+	version uint64
+}
+
+// the built-in functions, which are registered in init and never changed
+var builtins = make(map[string]*Function)
+
+func NewFunctions() *Functions {
+	return &Functions{
+		byName: make(map[string]*Function),
+	}
+}
+
+// Register adds a function. A function of the same name, including a built-in one, is replaced.
+func (fs *Functions) Register(f *Function) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	fs.byName[strings.ToLower(f.Name)] = f
+	fs.version++
+	return nil
+}
+
+// Version returns a number which is changed when a function is registered
+func (fs *Functions) Version() uint64 {
+	if fs == nil {
+		return 0
+	}
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return fs.version
+}
+
+// Lookup returns the function of the name
+func (fs *Functions) Lookup(name string) (*Function, bool) {
+	name = strings.ToLower(name)
+	if fs != nil {
+		fs.mutex.RLock()
+		f, ok := fs.byName[name]
+		fs.mutex.RUnlock()
+		if ok {
+			return f, true
+		}
+	}
+	f, ok := builtins[name]
+	return f, ok
+}
+
+func (f *Function) validate() error {
+	if f.Name == "" || (f.Call == nil) == (f.NewAggregator == nil) {
+		return fmt.Errorf("a function needs a name and either Call or NewAggregator")
+	}
+	if f.Optional > len(f.Params) || f.Variadic && len(f.Params) == 0 {
+		return fmt.Errorf("invalid parameters of function %s", f.Name)
+	}
+	return nil
+}
+
+// paramType returns the type of the parameter of the i-th argument
+func (f *Function) paramType(i int) types.Type {
+	if i >= len(f.Params) {
+		return f.Params[len(f.Params)-1]
+	}
+	return f.Params[i]
+}
+
+// resolve checks the types of the arguments and returns the type of the result.
+// Unknown is the type of string literals and NULL, which are converted to the type of the parameter.
+func (f *Function) resolve(argTypes []types.Type) (types.Type, error) {
+	if len(argTypes) < len(f.Params)-f.Optional || len(argTypes) > len(f.Params) && !f.Variadic {
+		return types.Unknown, f.notExistError(argTypes)
+	}
+	common := types.Unknown
+	for i, argType := range argTypes {
+		param := f.paramType(i)
+		if param == types.Unknown {
+			common = commonType(common, argType)
+		} else if !isConvertible(argType, param) {
+			return types.Unknown, f.notExistError(argTypes)
+		}
+	}
+	if f.Accepts != nil && !f.Accepts(argTypes) {
+		return types.Unknown, f.notExistError(argTypes)
+	}
+	if f.Returns != types.Unknown {
+		return f.Returns, nil
+	}
+	return common, nil
+}
+
+func (f *Function) notExistError(argTypes []types.Type) error {
+	names := make([]string, 0, len(argTypes))
+	for _, argType := range argTypes {
+		names = append(names, argType.String())
+	}
+	return fmt.Errorf("function %s(%s) does not exist", strings.ToLower(f.Name), strings.Join(names, ", "))
+}
+
+// isConvertible reports whether an argument is implicitly converted to the type of the parameter
+func isConvertible(from types.Type, to types.Type) bool {
+	return from == to || from == types.Unknown || from.IsNumeric() && to.IsNumeric() || from == types.Date && to == types.Timestamp
+}
+
+// commonType returns the type which values of a and b are converted to. A number is converted to the wider type.
+func commonType(a types.Type, b types.Type) types.Type {
+	switch {
+	case a == types.Unknown:
+		return b
+	case a.IsNumeric() && b.IsNumeric():
+		for _, typ := range []types.Type{types.Double, types.Decimal, types.BigInt} {
+			if a == typ || b == typ {
+				return typ
+			}
+		}
+		return types.Int
+	default:
+		return a
+	}
+}
+
+// IsAggregate reports whether the function is an aggregate function
+func (f *Function) IsAggregate() bool {
+	return f.NewAggregator != nil
+}
+
+// evalFunction calls a function with the arguments evaluated for row. An aggregate function gives the result of the
+// group when row is an aggregation.
+func evalFunction(e *FunctionExpression, row Row, functions *Functions) (types.Value, error) {
+	f, ok := functions.Lookup(e.Name)
+	if !ok {
+		return types.Value{}, fmt.Errorf("function %s does not exist", e.Name)
+	}
+	if f.IsAggregate() {
+		aggregation, ok := row.(*Aggregation)
+		if !ok {
+			return types.Value{}, fmt.Errorf("aggregate functions are not allowed here")
+		}
+		return aggregation.result(e)
+	}
+
+	args, returns, hasNull, err := evalArgs(f, e, row, functions)
+	if err != nil {
+		return types.Value{}, err
+	}
+	if hasNull && !f.CalledOnNull {
+		return types.NewNull(returns), nil
+	}
+	return f.Call(args)
+}
+
+// evalArgs evaluates the arguments and converts them to the types of the parameters. It returns the type of the result
+// and whether any argument is NULL.
+func evalArgs(f *Function, e *FunctionExpression, row Row, functions *Functions) ([]types.Value, types.Type, bool, error) {
+	// string literals are converted after the other arguments decide the common type
+	args := make([]types.Value, len(e.Args))
+	argTypes := make([]types.Type, len(e.Args))
+	for i, arg := range e.Args {
+		if literal, ok := arg.(*ValueExpression); ok && literal.Type == types.Unknown {
+			continue
+		}
+		value, err := Eval(arg, row, functions)
+		if err != nil {
+			return nil, types.Unknown, false, err
+		}
+		args[i] = value
+		argTypes[i] = value.Type()
+	}
+	returns, err := f.resolve(argTypes)
+	if err != nil {
+		return nil, types.Unknown, false, err
+	}
+
+	hasNull := false
+	for i, arg := range e.Args {
+		param := f.paramType(i)
+		if param == types.Unknown && f.Returns == types.Unknown {
+			param = returns
+		}
+		if literal, ok := arg.(*ValueExpression); ok && literal.Type == types.Unknown {
+			args[i], err = literalValue(literal, param)
+		} else if param != types.Unknown {
+			args[i], err = types.Cast(args[i], param)
+		}
+		if err != nil {
+			return nil, types.Unknown, false, err
+		}
+		hasNull = hasNull || args[i].IsNull()
+	}
+	return args, returns, hasNull, nil
+}
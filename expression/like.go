@@ -0,0 +1,64 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// matchLike reports whether s matches the pattern of LIKE. % matches any string and _ matches any character.
+// The escape character makes the next character match itself, and it is disabled when escape is empty.
+func matchLike(s string, pattern string, escape string, caseInsensitive bool) (bool, error) {
+	if caseInsensitive {
+		s = strings.ToLower(s)
+		pattern = strings.ToLower(pattern)
+	}
+	escapeRune, _ := utf8.DecodeRuneInString(escape)
+
+	// tokens of the pattern. A literal character is kept as it is, and wildcards are marked as special.
+	type token struct {
+		r        rune
+		wildcard bool
+	}
+	tokens := make([]token, 0, len(pattern))
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case escape != "" && r == escapeRune:
+			if i+1 == len(runes) {
+				return false, fmt.Errorf("LIKE pattern must not end with escape character")
+			}
+			i++
+			tokens = append(tokens, token{r: runes[i]})
+		case r == '%' || r == '_':
+			tokens = append(tokens, token{r: r, wildcard: true})
+		default:
+			tokens = append(tokens, token{r: r})
+		}
+	}
+
+	// backtrack to the last % when a match fails
+	text := []rune(s)
+	ti, pi := 0, 0
+	starPattern, starText := -1, 0
+	for ti < len(text) {
+		switch {
+		case pi < len(tokens) && tokens[pi].wildcard && tokens[pi].r == '%':
+			starPattern, starText = pi, ti
+			pi++
+		case pi < len(tokens) && (tokens[pi].wildcard || tokens[pi].r == text[ti]):
+			pi++
+			ti++
+		case starPattern >= 0:
+			starText++
+			ti = starText
+			pi = starPattern + 1
+		default:
+			return false, nil
+		}
+	}
+	for pi < len(tokens) && tokens[pi].wildcard && tokens[pi].r == '%' {
+		pi++
+	}
+	return pi == len(tokens), nil
+}
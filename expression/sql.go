@@ -0,0 +1,170 @@
+package expression
+
+import (
+	"github.com/xwb1989/sqlparser"
+	"strings"
+)
+
+// sqlparser doesn't know ILIKE, so it is replaced with REGEXP before parsing. REGEXP itself is not supported.
+const (
+	iLikeOperator    = "ilike"
+	notILikeOperator = "not ilike"
+)
+
+// castTypeNames are the type names which sqlparser doesn't accept in CAST. They are replaced with char of the type name
+// as the character set, e.g. CAST(a AS char `int`).
+var castTypeNames = map[string]bool{
+	"text": true, "varchar": true,
+	"int": true, "integer": true, "smallint": true, "tinyint": true, "mediumint": true, "bigint": true,
+	"double": true, "float": true, "real": true, "numeric": true,
+	"boolean": true, "bool": true, "timestamp": true,
+	"bytea": true, "blob": true, "varbinary": true,
+}
+
+// Parse parses a statement with sqlparser after replacing the operators which it doesn't know
+func Parse(sql string) (sqlparser.Statement, error) {
+	return sqlparser.Parse(rewrite(sql))
+}
+
+// String formats a node like sqlparser.String, writing back what Parse replaced
+func String(node sqlparser.SQLNode) string {
+	buf := sqlparser.NewTrackedBuffer(func(buf *sqlparser.TrackedBuffer, node sqlparser.SQLNode) {
+		switch n := node.(type) {
+		case sqlparser.ColIdent:
+			if n.Lowered() == iLikeOperator {
+				// a column named ilike
+				buf.Myprintf("`%s`", n.String())
+				return
+			}
+		case *sqlparser.ComparisonExpr:
+			if n.Operator == sqlparser.RegexpStr || n.Operator == sqlparser.NotRegexpStr {
+				iLike := *n
+				iLike.Operator = iLikeOperator
+				if n.Operator == sqlparser.NotRegexpStr {
+					iLike.Operator = notILikeOperator
+				}
+				iLike.Format(buf)
+				return
+			}
+		case *sqlparser.BinaryExpr:
+			if n.Operator == sqlparser.BitOrStr {
+				buf.Myprintf("%v || %v", n.Left, n.Right)
+				return
+			}
+		case *sqlparser.ConvertExpr:
+			// sqlparser writes CONVERT(a, type), whose type names are not replaced by Parse
+			buf.Myprintf("cast(%v as %v)", n.Expr, n.Type)
+			return
+		case *sqlparser.FuncExpr:
+			if field, ok := extractField(n); ok {
+				buf.Myprintf("extract(%s from %v)", field, n.Exprs[1])
+				return
+			}
+		case *sqlparser.ConvertType:
+			if castTypeNames[strings.ToLower(n.Charset)] && n.Operator == "" {
+				buf.Myprintf("%s", n.Charset)
+				return
+			}
+		}
+		node.Format(buf)
+	})
+	buf.Myprintf("%v", node)
+	return buf.String()
+}
+
+// extractField returns the field of extract('field', x), which is written as EXTRACT(field FROM x)
+func extractField(funcExpr *sqlparser.FuncExpr) (string, bool) {
+	if !funcExpr.Name.EqualString("extract") || len(funcExpr.Exprs) != 2 {
+		return "", false
+	}
+	aliasedExpr, ok := funcExpr.Exprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return "", false
+	}
+	field, ok := aliasedExpr.Expr.(*sqlparser.SQLVal)
+	if !ok || field.Type != sqlparser.StrVal || !isIdentifier(string(field.Val)) {
+		return "", false
+	}
+	return string(field.Val), true
+}
+
+func isIdentifier(s string) bool {
+	for i, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// rewrite replaces the keyword ILIKE with REGEXP, || with |, the type names of CAST and EXTRACT(field FROM x) with
+// extract('field', x). The keywords SUBSTR and SUBSTRING are replaced with a function name, because sqlparser accepts only
+// a column as their first argument. Strings and quoted identifiers are kept.
+func rewrite(sql string) string {
+	var b strings.Builder
+	copied := 0
+	replace := func(start int, end int, s string) {
+		b.WriteString(sql[copied:start])
+		b.WriteString(s)
+		copied = end
+	}
+
+	// the depths of the parentheses of CAST(, and the depth of the current token
+	var casts []int
+	depth := 0
+	afterCast, afterAs := false, false
+	// the tokens of EXTRACT(field FROM: 1 after EXTRACT, 2 after (, 3 after the field
+	extract, fieldStart, fieldEnd := 0, 0, 0
+	tokenizer := sqlparser.NewStringTokenizer(sql)
+	for {
+		typ, val := tokenizer.Scan()
+		if typ == 0 || typ == sqlparser.LEX_ERROR {
+			break
+		}
+		// the tokenizer has read one character after the token
+		end := min(tokenizer.Position-1, len(sql))
+		start := end - len(val)
+		castType := afterAs
+		afterAs = false
+		switch {
+		case typ == sqlparser.ID && strings.EqualFold(string(val), "extract"):
+			extract = 1
+		case extract == 1 && typ == '(':
+			extract = 2
+		case extract == 2 && start >= copied && isIdentifier(string(val)):
+			extract, fieldStart, fieldEnd = 3, start, end
+		case extract == 3 && typ == sqlparser.FROM:
+			replace(fieldStart, end, "'"+sql[fieldStart:fieldEnd]+"',")
+			fallthrough
+		default:
+			extract = 0
+		}
+
+		switch {
+		case typ == '(':
+			depth++
+			if afterCast {
+				casts = append(casts, depth)
+			}
+		case typ == ')':
+			if len(casts) > 0 && casts[len(casts)-1] == depth {
+				casts = casts[:len(casts)-1]
+			}
+			depth--
+		case typ == sqlparser.AS:
+			afterAs = len(casts) > 0 && casts[len(casts)-1] == depth
+		case typ == sqlparser.OR && val == nil && end >= 2 && sql[end-2:end] == "||":
+			replace(end-2, end, "|")
+		case start < copied || val == nil:
+		case typ == sqlparser.SUBSTR || typ == sqlparser.SUBSTRING:
+			replace(start, end, "`substr`")
+		case typ == sqlparser.ID && strings.EqualFold(sql[start:end], iLikeOperator):
+			replace(start, end, "regexp")
+		case castType && castTypeNames[strings.ToLower(sql[start:end])]:
+			replace(start, end, "char `"+sql[start:end]+"`")
+		}
+		afterCast = typ == sqlparser.CAST
+	}
+	b.WriteString(sql[copied:])
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package expression
+
+import (
+	"fmt"
+	"garakutadb/types"
+)
+
+// TypeOf returns the type of the value of an expression, checking the calls of functions in it. columnType gives the
+// types of the columns, and it is nil where columns can't be referred. Unknown is the type of string literals and NULL.
+func TypeOf(expr Expression, columnType func(name string) (types.Type, error), functions *Functions) (types.Type, error) {
+	typesOf := func(exprs ...Expression) ([]types.Type, error) {
+		result := make([]types.Type, 0, len(exprs))
+		for _, e := range exprs {
+			if e == nil {
+				continue
+			}
+			typ, err := TypeOf(e, columnType, functions)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, typ)
+		}
+		return result, nil
+	}
+
+	switch e := expr.(type) {
+	case *ColumnExpression:
+		if columnType == nil {
+			return types.Unknown, fmt.Errorf("column reference \"%s\" is not allowed here", e.Name)
+		}
+		return columnType(e.Name)
+	case *ValueExpression:
+		return e.Type, nil
+	case *NullExpression:
+		return types.Unknown, nil
+	case *UnaryExpression:
+		return TypeOf(e.Expr, columnType, functions)
+	case *BinaryExpression:
+		operands, err := typesOf(e.Left, e.Right)
+		if err != nil {
+			return types.Unknown, err
+		}
+		if e.Operator == OperatorConcat {
+			return types.Text, nil
+		}
+		return commonType(operands[0], operands[1]), nil
+	case *CastExpression:
+		if _, err := TypeOf(e.Expr, columnType, functions); err != nil {
+			return types.Unknown, err
+		}
+		return e.Type, nil
+	case *CaseExpression:
+		if _, err := typesOf(e.Operand); err != nil {
+			return types.Unknown, err
+		}
+		typ := types.Unknown
+		for _, when := range e.Whens {
+			whenTypes, err := typesOf(when.Condition, when.Result)
+			if err != nil {
+				return types.Unknown, err
+			}
+			typ = commonType(typ, whenTypes[1])
+		}
+		elseTypes, err := typesOf(e.Else)
+		if err != nil {
+			return types.Unknown, err
+		}
+		for _, elseType := range elseTypes {
+			typ = commonType(typ, elseType)
+		}
+		return typ, nil
+	case *FunctionExpression:
+		f, ok := functions.Lookup(e.Name)
+		if !ok {
+			return types.Unknown, fmt.Errorf("function %s does not exist", e.Name)
+		}
+		argTypes, err := typesOf(e.Args...)
+		if err != nil {
+			return types.Unknown, err
+		}
+		return f.resolve(argTypes)
+	case *AndExpression:
+		_, err := typesOf(e.Left, e.Right)
+		return types.Boolean, err
+	case *OrExpression:
+		_, err := typesOf(e.Left, e.Right)
+		return types.Boolean, err
+	case *NotExpression:
+		_, err := typesOf(e.Expr)
+		return types.Boolean, err
+	case *ComparisonExpression:
+		_, err := typesOf(e.Left, e.Right)
+		return types.Boolean, err
+	case *IsNullExpression:
+		_, err := typesOf(e.Expr)
+		return types.Boolean, err
+	case *InExpression:
+		_, err := typesOf(append([]Expression{e.Expr}, e.Values...)...)
+		return types.Boolean, err
+	case *BetweenExpression:
+		_, err := typesOf(e.Expr, e.From, e.To)
+		return types.Boolean, err
+	case *LikeExpression:
+		_, err := typesOf(e.Expr, e.Pattern)
+		return types.Boolean, err
+	default:
+		return types.Unknown, fmt.Errorf("not supported expression type: %T", expr)
+	}
+}
+
+// IsConstant reports whether an expression gives the same value every time, so that it can be computed in planning.
+// It doesn't refer to columns, and it calls only deterministic scalar functions.
+func IsConstant(expr Expression, functions *Functions) bool {
+	constant := true
+	_, _ = transform(expr, func(node Expression) (Expression, error) {
+		switch e := node.(type) {
+		case *ColumnExpression:
+			constant = false
+		case *FunctionExpression:
+			f, ok := functions.Lookup(e.Name)
+			constant = constant && ok && f.Deterministic && !f.IsAggregate()
+		}
+		return node, nil
+	})
+	return constant
+}
+
+// Fold returns a copy of an expression whose calls of deterministic functions with constant arguments are replaced
+// with their results, so that they are not computed for each row. A call which fails is left to be computed.
+func Fold(expr Expression, functions *Functions) Expression {
+	folded, _ := transform(expr, func(node Expression) (Expression, error) {
+		call, ok := node.(*FunctionExpression)
+		if !ok || !IsConstant(call, functions) {
+			return node, nil
+		}
+		value, err := Eval(call, nil, functions)
+		if err != nil {
+			return node, nil
+		}
+		if value.IsNull() {
+			return &NullExpression{}, nil
+		}
+		return &ValueExpression{Value: value.String(), Type: value.Type()}, nil
+	})
+	return folded
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"garakutadb/expression"
 	"garakutadb/parser/statements"
 	"garakutadb/parser/statements/ddl"
 	"github.com/xwb1989/sqlparser"
@@ -15,7 +16,66 @@ func NewSimpleParser() *SimpleParser {
 }
 
 func (sp *SimpleParser) Parse(SqlString string) (Stmt, error) {
-	stmt, err := sqlparser.Parse(SqlString)
+	// statements which sqlparser doesn't support
+	tokens, err := statements.Tokenize(SqlString)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tokens.HasPrefix("create", "table"):
+		return ddl.BuildCreateTableStmt(SqlString, tokens)
+	case tokens.HasPrefix("alter", "table"):
+		return ddl.BuildAlterTableStmt(SqlString, tokens)
+	case tokens.HasPrefix("drop", "table"):
+		return ddl.BuildDropTableStmt(SqlString, tokens)
+	case tokens.HasPrefix("create", "sequence"):
+		return ddl.BuildCreateSequenceStmt(SqlString, tokens)
+	case tokens.HasPrefix("drop", "sequence"):
+		return ddl.BuildDropSequenceStmt(SqlString, tokens)
+	case tokens.HasPrefix("create", "view"), tokens.HasPrefix("create", "or", "replace", "view"),
+		tokens.HasPrefix("create", "materialized", "view"):
+		return ddl.BuildCreateViewStmt(SqlString, tokens)
+	case tokens.HasPrefix("drop", "view"), tokens.HasPrefix("drop", "materialized", "view"):
+		return ddl.BuildDropViewStmt(SqlString, tokens)
+	case tokens.HasPrefix("refresh", "materialized", "view"):
+		return ddl.BuildRefreshMaterializedViewStmt(SqlString, tokens)
+	case tokens.HasPrefix("create", "schema"):
+		return ddl.BuildCreateSchemaStmt(SqlString, tokens)
+	case tokens.HasPrefix("drop", "schema"):
+		return ddl.BuildDropSchemaStmt(SqlString, tokens)
+	case tokens.HasPrefix("create", "database"):
+		return statements.BuildCreateDatabaseStmt(SqlString, tokens)
+	case tokens.HasPrefix("drop", "database"):
+		return statements.BuildDropDatabaseStmt(SqlString, tokens)
+	case tokens.HasPrefix("use"):
+		return statements.BuildUseStmt(SqlString, tokens)
+	case tokens.HasPrefix("show", "databases"):
+		return statements.BuildShowDatabasesStmt(SqlString, tokens)
+	case tokens.HasPrefix("set", "search_path"):
+		return statements.BuildSetSearchPathStmt(SqlString, tokens)
+	case tokens.HasPrefix("show", "search_path"):
+		return statements.BuildShowSearchPathStmt(SqlString, tokens)
+	case tokens.HasPrefix("truncate"):
+		return ddl.BuildTruncateTableStmt(SqlString, tokens)
+	case tokens.HasPrefix("show", "tables"):
+		return statements.BuildShowTablesStmt(SqlString, tokens)
+	case tokens.HasPrefix("show", "index"), tokens.HasPrefix("show", "indexes"), tokens.HasPrefix("show", "keys"):
+		return statements.BuildShowIndexesStmt(SqlString, tokens)
+	case tokens.HasPrefix("describe"), tokens.HasPrefix("desc"):
+		return statements.BuildDescribeStmt(SqlString, tokens)
+	case tokens.HasPrefix("kill"):
+		return statements.BuildKillStmt(tokens)
+	case tokens.HasPrefix("begin"), tokens.HasPrefix("start", "transaction"):
+		return statements.BuildBeginStmt(tokens)
+	case tokens.HasPrefix("export", "snapshot"):
+		return statements.BuildExportSnapshotStmt(tokens)
+	case tokens.HasPrefix("set", "transaction", "snapshot"):
+		return statements.BuildSetTransactionSnapshotStmt(tokens)
+	case tokens.HasPrefix("set", "transaction", "isolation"):
+		return statements.BuildSetTransactionStmt(tokens)
+	}
+
+	stmt, err := expression.Parse(SqlString)
 	if err != nil {
 		return nil, err
 	}
@@ -31,16 +91,16 @@ func (sp *SimpleParser) Parse(SqlString string) (Stmt, error) {
 		return statements.BuildDeleteStmt(s)
 	case *sqlparser.DDL:
 		return sp.parseDDLStatement(s)
+	case *sqlparser.Commit:
+		return &statements.CommitStmt{}, nil
+	case *sqlparser.Rollback:
+		return &statements.RollbackStmt{}, nil
 	default:
 		return nil, fmt.Errorf("not supported: %T", s)
 	}
 }
 
 func (sp *SimpleParser) parseDDLStatement(ddlStatement *sqlparser.DDL) (Stmt, error) {
-	switch ddlStatement.Action {
-	case "create":
-		return ddl.BuildCreateTableStmt(ddlStatement)
-	default:
-		return nil, fmt.Errorf("not supported DDL action: %s", ddlStatement.Action)
-	}
+	// CREATE TABLE, ALTER TABLE, DROP TABLE, TRUNCATE, sequences and views are parsed from tokens
+	return nil, fmt.Errorf("not supported DDL action: %s", ddlStatement.Action)
 }
@@ -0,0 +1,183 @@
+package statements
+
+import (
+	"fmt"
+	"github.com/xwb1989/sqlparser"
+)
+
+// The statements of databases and the search path change the state of the session, and they are run by the session.
+
+// CreateDatabaseStmt is CREATE DATABASE [IF NOT EXISTS] name
+type CreateDatabaseStmt struct {
+	DatabaseName string
+	IfNotExists  bool
+}
+
+// DropDatabaseStmt is DROP DATABASE [IF EXISTS] name
+type DropDatabaseStmt struct {
+	DatabaseName string
+	IfExists     bool
+}
+
+// UseStmt switches the database of the session: USE name
+type UseStmt struct {
+	DatabaseName string
+}
+
+// ShowDatabasesStmt is SHOW DATABASES
+type ShowDatabasesStmt struct {
+}
+
+// SetSearchPathStmt is SET search_path {TO | =} {schema [, ...] | DEFAULT}
+type SetSearchPathStmt struct {
+	// nil for DEFAULT
+	SearchPath []string
+}
+
+// ShowSearchPathStmt is SHOW search_path
+type ShowSearchPathStmt struct {
+}
+
+func BuildCreateDatabaseStmt(sql string, tokens Tokens) (*CreateDatabaseStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if err := r.Expect("create", "database"); err != nil {
+		return nil, err
+	}
+	ifNotExists := r.Accept("if", "not", "exists")
+	name, err := expectDatabaseName(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &CreateDatabaseStmt{
+		DatabaseName: name,
+		IfNotExists:  ifNotExists,
+	}, nil
+}
+
+func BuildDropDatabaseStmt(sql string, tokens Tokens) (*DropDatabaseStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if err := r.Expect("drop", "database"); err != nil {
+		return nil, err
+	}
+	ifExists := r.Accept("if", "exists")
+	name, err := expectDatabaseName(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &DropDatabaseStmt{
+		DatabaseName: name,
+		IfExists:     ifExists,
+	}, nil
+}
+
+func BuildUseStmt(sql string, tokens Tokens) (*UseStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if err := r.Expect("use"); err != nil {
+		return nil, err
+	}
+	name, err := expectDatabaseName(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &UseStmt{
+		DatabaseName: name,
+	}, nil
+}
+
+// CheckDatabaseName returns an error unless the name has only letters, digits and underscores, because a database is
+// stored in the directory of its name
+func CheckDatabaseName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("invalid database name \"%s\"", name)
+	}
+	for i := 0; i < len(name); i++ {
+		if !isIdentifierChar(name[i]) {
+			return fmt.Errorf("invalid database name \"%s\"", name)
+		}
+	}
+	return nil
+}
+
+func expectDatabaseName(r *TokenReader) (string, error) {
+	name, err := r.ExpectIdentifier()
+	if err != nil {
+		return "", err
+	}
+	if err := CheckDatabaseName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func BuildShowDatabasesStmt(sql string, tokens Tokens) (*ShowDatabasesStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if err := r.Expect("show", "databases"); err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+	return &ShowDatabasesStmt{}, nil
+}
+
+func BuildSetSearchPathStmt(sql string, tokens Tokens) (*SetSearchPathStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if err := r.Expect("set", "search_path"); err != nil {
+		return nil, err
+	}
+	if !r.Accept("to") && !r.Accept("=") {
+		return nil, fmt.Errorf("syntax error: expected TO or =")
+	}
+	if r.Accept("default") {
+		if err := r.ExpectEnd(); err != nil {
+			return nil, err
+		}
+		return &SetSearchPathStmt{}, nil
+	}
+
+	// the schemas may be quoted like PostgreSQL
+	searchPath := make([]string, 0)
+	for {
+		token, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if token.Type != sqlparser.STRING && (len(token.Value) == 1 && !isIdentifierChar(token.Value[0])) {
+			return nil, fmt.Errorf("syntax error: expected name but got %s", token.Value)
+		}
+		searchPath = append(searchPath, token.Value)
+		if r.Done() {
+			break
+		}
+		if err := r.Expect(","); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SetSearchPathStmt{
+		SearchPath: searchPath,
+	}, nil
+}
+
+func BuildShowSearchPathStmt(sql string, tokens Tokens) (*ShowSearchPathStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if err := r.Expect("show", "search_path"); err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+	return &ShowSearchPathStmt{}, nil
+}
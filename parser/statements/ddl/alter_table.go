@@ -0,0 +1,113 @@
+package ddl
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/parser/statements"
+	"garakutadb/types"
+)
+
+type AlterTableAction int
+
+const (
+	AddColumn AlterTableAction = iota
+	DropColumn
+	RenameColumn
+	RenameTable
+	AlterColumnType
+)
+
+// AlterTableStmt changes a table. Only one action is supported in a statement.
+//
+//	ALTER TABLE name ADD [COLUMN] column type [column options]
+//	ALTER TABLE name DROP [COLUMN] [IF EXISTS] column
+//	ALTER TABLE name RENAME [COLUMN] column TO new_name
+//	ALTER TABLE name RENAME TO new_name
+//	ALTER TABLE name ALTER [COLUMN] column [SET DATA] TYPE type
+type AlterTableStmt struct {
+	TableName  string
+	Action     AlterTableAction
+	ColumnName string
+	// new name of the column or the table
+	NewName   string
+	NewType   types.Type
+	NewTypmod *types.Typmod
+	IfExists  bool
+	// the added column and its constraints, in a table schema which has only the column
+	Definition *catalog.TableSchema
+}
+
+func BuildAlterTableStmt(sql string, tokens statements.Tokens) (*AlterTableStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("alter", "table"); err != nil {
+		return nil, err
+	}
+	tableName, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &AlterTableStmt{
+		TableName: tableName,
+	}
+
+	switch {
+	case r.Accept("add"):
+		r.Accept("column")
+		b := &tableSchemaBuilder{
+			schema: &catalog.TableSchema{
+				Name:    tableName,
+				Columns: make(catalog.ColumnSchemas, 0),
+			},
+		}
+		if err := b.parseColumnDefinition(r); err != nil {
+			return nil, err
+		}
+		stmt.Action = AddColumn
+		stmt.ColumnName = b.schema.Columns[0].Name
+		stmt.Definition = b.schema
+	case r.Accept("drop"):
+		r.Accept("column")
+		stmt.Action = DropColumn
+		stmt.IfExists = r.Accept("if", "exists")
+		if stmt.ColumnName, err = r.ExpectIdentifier(); err != nil {
+			return nil, err
+		}
+	case r.Accept("rename", "to"):
+		stmt.Action = RenameTable
+		if stmt.NewName, err = r.ExpectIdentifier(); err != nil {
+			return nil, err
+		}
+	case r.Accept("rename"):
+		r.Accept("column")
+		stmt.Action = RenameColumn
+		if stmt.ColumnName, err = r.ExpectIdentifier(); err != nil {
+			return nil, err
+		}
+		if err := r.Expect("to"); err != nil {
+			return nil, err
+		}
+		if stmt.NewName, err = r.ExpectIdentifier(); err != nil {
+			return nil, err
+		}
+	case r.Accept("alter"):
+		r.Accept("column")
+		stmt.Action = AlterColumnType
+		if stmt.ColumnName, err = r.ExpectIdentifier(); err != nil {
+			return nil, err
+		}
+		r.Accept("set", "data")
+		if err := r.Expect("type"); err != nil {
+			return nil, err
+		}
+		if stmt.NewType, stmt.NewTypmod, err = parseType(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("syntax error: expected ADD, DROP, RENAME or ALTER after ALTER TABLE %s", tableName)
+	}
+
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
@@ -3,75 +3,513 @@ package ddl
 import (
 	"fmt"
 	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/parser/statements"
+	"garakutadb/storage"
+	"garakutadb/types"
 	"github.com/xwb1989/sqlparser"
+	"strconv"
 )
 
 type CreateTableStmt struct {
 	Into        string
 	TableSchema *catalog.TableSchema
+	// the sequences owned by SERIAL, AUTO_INCREMENT and identity columns
+	Sequences []catalog.SequenceSchema
 }
 
-func BuildCreateTableStmt(statement *sqlparser.DDL) (*CreateTableStmt, error) {
-	var tableName string
-	if len(statement.NewName.Name.String()) == 0 {
-		return nil, fmt.Errorf("table name is empty")
+// BuildCreateTableStmt parses CREATE TABLE. sqlparser doesn't know some types (e.g. boolean) and CHECK, so tokens are parsed here.
+//
+//	CREATE TABLE name (
+//	  column type [PRIMARY KEY] [NOT NULL | NULL] [DEFAULT value] [UNIQUE] [CHECK (expr)] [REFERENCES ...] [AUTO_INCREMENT]
+//	    [GENERATED {ALWAYS | BY DEFAULT} AS IDENTITY [(sequence options)]] ...,
+//	  [CONSTRAINT name] PRIMARY KEY (column, ...) | UNIQUE (column) | CHECK (expr) | FOREIGN KEY (column) REFERENCES ..., ...
+//	)
+//
+// REFERENCES parent [(column)] [ON DELETE action] [ON UPDATE action]. The referenced column is resolved by the planner.
+// SERIAL and BIGSERIAL columns, AUTO_INCREMENT columns and identity columns own a sequence named <table>_<column>_seq,
+// and their default is nextval of it.
+func BuildCreateTableStmt(sql string, tokens statements.Tokens) (*CreateTableStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("create", "table"); err != nil {
+		return nil, err
 	}
-	tableName = statement.NewName.Name.String()
-	if len(statement.TableSpec.Columns) == 0 {
-		return nil, fmt.Errorf("columns is empty")
+	tableName, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Expect("("); err != nil {
+		return nil, err
 	}
 
-	columns := make([]catalog.ColumnSchema, 0)
-	for _, column := range statement.TableSpec.Columns {
-		columnType, err := mapType(&column.Type)
-		if err != nil {
+	b := &tableSchemaBuilder{
+		schema: &catalog.TableSchema{
+			Name:    tableName,
+			Columns: make(catalog.ColumnSchemas, 0),
+		},
+	}
+	for {
+		if err := b.parseTableElement(r); err != nil {
+			return nil, err
+		}
+		if r.Accept(")") {
+			break
+		}
+		if err := r.Expect(","); err != nil {
 			return nil, err
 		}
-		columns = append(columns, catalog.ColumnSchema{
-			Name: column.Name.String(),
-			Type: columnType,
-		})
 	}
-
-	pk, err := findPrimaryKey(statement.TableSpec.Columns)
-	if err != nil {
+	if err := r.ExpectEnd(); err != nil {
 		return nil, err
 	}
 
-	tableSchema := &catalog.TableSchema{
-		Name:    tableName,
-		Columns: columns,
-		PK:      pk.Name,
+	if err := b.validate(); err != nil {
+		return nil, err
 	}
 
 	return &CreateTableStmt{
 		Into:        tableName,
-		TableSchema: tableSchema,
+		TableSchema: b.schema,
+		Sequences:   b.sequences,
 	}, nil
 }
 
-func findPrimaryKey(columns []*sqlparser.ColumnDefinition) (*catalog.ColumnSchema, error) {
-	for _, column := range columns {
-		// WARNING: Do we have to use magic numbers?
-		if column.Type.KeyOpt == 1 {
-			columnType, err := mapType(&column.Type)
+type tableSchemaBuilder struct {
+	schema    *catalog.TableSchema
+	sequences []catalog.SequenceSchema
+}
+
+func (b *tableSchemaBuilder) parseTableElement(r *statements.TokenReader) error {
+	constraintName := ""
+	if r.Accept("constraint") {
+		name, err := r.ExpectIdentifier()
+		if err != nil {
+			return err
+		}
+		constraintName = name
+	}
+
+	switch {
+	case r.Accept("primary", "key"):
+		columns, err := parseColumnList(r)
+		if err != nil {
+			return err
+		}
+		return b.setPK(constraintName, columns)
+	case r.Accept("unique"):
+		column, err := parseSingleColumn(r, "unique constraint")
+		if err != nil {
+			return err
+		}
+		b.addUnique(constraintName, column)
+		return nil
+	case r.Accept("check"):
+		return b.parseCheck(r, constraintName, "")
+	case r.Accept("foreign", "key"):
+		column, err := parseSingleColumn(r, "foreign key")
+		if err != nil {
+			return err
+		}
+		if err := r.Expect("references"); err != nil {
+			return err
+		}
+		return b.parseReferences(r, constraintName, column)
+	case constraintName != "":
+		return fmt.Errorf("syntax error: expected PRIMARY KEY, UNIQUE, CHECK or FOREIGN KEY after CONSTRAINT %s", constraintName)
+	default:
+		return b.parseColumnDefinition(r)
+	}
+}
+
+func (b *tableSchemaBuilder) parseColumnDefinition(r *statements.TokenReader) error {
+	name, err := r.ExpectIdentifier()
+	if err != nil {
+		return err
+	}
+	if _, found := b.schema.Columns.Contains(name); found {
+		return fmt.Errorf("column specified more than once: %s", name)
+	}
+	columnType, serial := parseSerialType(r)
+	var typmod *types.Typmod
+	if !serial {
+		if columnType, typmod, err = parseType(r); err != nil {
+			return err
+		}
+	}
+	column := catalog.ColumnSchema{
+		Name:   name,
+		Type:   columnType,
+		Typmod: typmod,
+	}
+	// the options of the sequence owned by the column
+	var sequence *storage.SequenceOptions
+	if serial {
+		opts := defaultSequenceOptions(columnType)
+		sequence = &opts
+	}
+	hasDefault := false
+
+	for !r.Is(",") && !r.Is(")") && !r.Done() {
+		constraintName := ""
+		if r.Accept("constraint") {
+			if constraintName, err = r.ExpectIdentifier(); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case r.Accept("primary", "key"):
+			if err := b.setPK(constraintName, []string{name}); err != nil {
+				return err
+			}
+		case r.Accept("not", "null"):
+			column.NotNull = true
+		case r.Accept("null"):
+			column.NotNull = false
+		case r.Accept("default"):
+			value, err := parseDefault(r, &column)
 			if err != nil {
-				return nil, err
+				return err
+			}
+			column.Default = &value
+			hasDefault = true
+		case r.Accept("unique"):
+			b.addUnique(constraintName, name)
+		case r.Accept("check"):
+			if err := b.parseCheck(r, constraintName, name); err != nil {
+				return err
+			}
+		case r.Accept("references"):
+			if err := b.parseReferences(r, constraintName, name); err != nil {
+				return err
+			}
+		case r.Accept("auto_increment"):
+			opts := defaultSequenceOptions(columnType)
+			sequence = &opts
+		case r.Accept("generated"):
+			if column.Identity, err = parseIdentity(r); err != nil {
+				return err
+			}
+			opts := defaultSequenceOptions(columnType)
+			if r.Accept("(") {
+				if opts, err = parseSequenceOptions(r, columnType); err != nil {
+					return err
+				}
+				if err := r.Expect(")"); err != nil {
+					return err
+				}
+			}
+			sequence = &opts
+		default:
+			token, _ := r.Next()
+			return fmt.Errorf("not supported column option: %s", token.Value)
+		}
+	}
+
+	if sequence != nil {
+		if err := b.addSequence(&column, *sequence, hasDefault); err != nil {
+			return err
+		}
+	}
+
+	column.Slot = b.schema.NextSlot
+	b.schema.NextSlot++
+	b.schema.Columns = append(b.schema.Columns, column)
+	return nil
+}
+
+// serialTypes are the pseudo types of integer columns which own a sequence
+var serialTypes = map[string]types.Type{
+	"smallserial": types.Int,
+	"serial2":     types.Int,
+	"serial":      types.Int,
+	"serial4":     types.Int,
+	"bigserial":   types.BigInt,
+	"serial8":     types.BigInt,
+}
+
+func parseSerialType(r *statements.TokenReader) (types.Type, bool) {
+	for name, typ := range serialTypes {
+		if r.Accept(name) {
+			return typ, true
+		}
+	}
+	return types.Unknown, false
+}
+
+// parseIdentity parses {ALWAYS | BY DEFAULT} AS IDENTITY after GENERATED
+func parseIdentity(r *statements.TokenReader) (catalog.Identity, error) {
+	identity := catalog.IdentityAlways
+	if r.Accept("by", "default") {
+		identity = catalog.IdentityByDefault
+	} else if err := r.Expect("always"); err != nil {
+		return catalog.NotIdentity, err
+	}
+	if err := r.Expect("as", "identity"); err != nil {
+		return catalog.NotIdentity, err
+	}
+	return identity, nil
+}
+
+// addSequence makes the column own a new sequence, which gives the default value of the column
+func (b *tableSchemaBuilder) addSequence(column *catalog.ColumnSchema, opts storage.SequenceOptions, hasDefault bool) error {
+	if column.Type != types.Int && column.Type != types.BigInt {
+		return fmt.Errorf("identity column type must be integer or bigint: %s", column.Name)
+	}
+	if hasDefault || column.Sequence != "" {
+		return fmt.Errorf("multiple default values specified for column \"%s\" of table \"%s\"", column.Name, b.schema.Name)
+	}
+
+	name := b.schema.Name + "_" + column.Name + "_seq"
+	defaultValue := fmt.Sprintf("nextval('%s')", name)
+	column.Sequence = name
+	column.Default = &defaultValue
+	column.NotNull = true
+	b.sequences = append(b.sequences, catalog.SequenceSchema{
+		Name:            name,
+		SequenceOptions: opts,
+	})
+	return nil
+}
+
+func (b *tableSchemaBuilder) setPK(name string, columns []string) error {
+	if b.schema.HasPK() {
+		return fmt.Errorf("multiple primary keys for table %s are not allowed", b.schema.Name)
+	}
+	b.schema.PK = columns
+	// the name is kept when the table is renamed, because it is also the name of the index
+	b.schema.PKName = name
+	if name == "" {
+		b.schema.PKName = b.schema.PKConstraintName()
+	}
+	return nil
+}
+
+func (b *tableSchemaBuilder) addUnique(name string, column string) {
+	if name == "" {
+		name = b.constraintName(column, "key")
+	}
+	b.schema.Uniques = append(b.schema.Uniques, catalog.UniqueConstraint{
+		Name:   name,
+		Column: column,
+	})
+}
+
+func (b *tableSchemaBuilder) parseCheck(r *statements.TokenReader, name string, column string) error {
+	text, err := r.ExpectParenthesized()
+	if err != nil {
+		return err
+	}
+	if _, err := expression.ParseCondition(text); err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = b.constraintName(column, "check")
+	}
+	b.schema.Checks = append(b.schema.Checks, catalog.CheckConstraint{
+		Name:       name,
+		Expression: text,
+	})
+	return nil
+}
+
+func (b *tableSchemaBuilder) parseReferences(r *statements.TokenReader, name string, column string) error {
+	refTable, err := r.ExpectQualifiedName()
+	if err != nil {
+		return err
+	}
+	fk := catalog.ForeignKeyConstraint{
+		Name:     name,
+		Column:   column,
+		RefTable: refTable,
+	}
+	if r.Is("(") {
+		if fk.RefColumn, err = parseSingleColumn(r, "foreign key"); err != nil {
+			return err
+		}
+	}
+
+	for r.Accept("on") {
+		switch {
+		case r.Accept("delete"):
+			if fk.OnDelete, err = parseReferentialAction(r); err != nil {
+				return err
 			}
-			return &catalog.ColumnSchema{
-				Name: column.Name.String(),
-				Type: columnType,
-			}, nil
+		case r.Accept("update"):
+			if fk.OnUpdate, err = parseReferentialAction(r); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("syntax error: expected DELETE or UPDATE after ON")
 		}
 	}
-	return nil, fmt.Errorf("primary key not found")
+
+	if fk.Name == "" {
+		fk.Name = b.constraintName(column, "fkey")
+	}
+	b.schema.ForeignKeys = append(b.schema.ForeignKeys, fk)
+	return nil
 }
 
-func mapType(columnType *sqlparser.ColumnType) (catalog.ColumnType, error) {
-	switch columnType.Type {
-	case "text":
-		return catalog.String, nil
+func parseReferentialAction(r *statements.TokenReader) (catalog.ReferentialAction, error) {
+	switch {
+	case r.Accept("no", "action"):
+		return catalog.NoAction, nil
+	case r.Accept("restrict"):
+		return catalog.Restrict, nil
+	case r.Accept("cascade"):
+		return catalog.Cascade, nil
+	case r.Accept("set", "null"):
+		return catalog.SetNull, nil
 	default:
-		return catalog.Unknown, fmt.Errorf("unknown type: %s", columnType.Type)
+		return catalog.NoAction, fmt.Errorf("syntax error: expected NO ACTION, RESTRICT, CASCADE or SET NULL")
+	}
+}
+
+// constraintName generates a name of a constraint like PostgreSQL: <table>_<column>_<suffix>, with a number if it is used.
+// The schema of the table is not included.
+func (b *tableSchemaBuilder) constraintName(column string, suffix string) string {
+	_, base := catalog.SplitRelationName(b.schema.Name)
+	if column != "" {
+		base += "_" + column
+	}
+	name := base + "_" + suffix
+	for i := 1; b.hasConstraint(name); i++ {
+		name = fmt.Sprintf("%s_%s%d", base, suffix, i)
+	}
+	return name
+}
+
+func (b *tableSchemaBuilder) hasConstraint(name string) bool {
+	if b.schema.HasPK() && b.schema.PKConstraintName() == name {
+		return true
+	}
+	for _, unique := range b.schema.Uniques {
+		if unique.Name == name {
+			return true
+		}
+	}
+	for _, check := range b.schema.Checks {
+		if check.Name == name {
+			return true
+		}
+	}
+	for _, fk := range b.schema.ForeignKeys {
+		if fk.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *tableSchemaBuilder) validate() error {
+	return b.schema.Validate()
+}
+
+// parseDefault parses a literal, a negative number, a function call or an expression in parentheses and validates it for the column
+func parseDefault(r *statements.TokenReader, column *catalog.ColumnSchema) (string, error) {
+	from := r.Pos
+	if r.Is("(") {
+		if _, err := r.ExpectParenthesized(); err != nil {
+			return "", err
+		}
+	} else {
+		r.Accept("-")
+		if _, err := r.Next(); err != nil {
+			return "", err
+		}
+		// a function call, which may have no arguments, e.g. now()
+		if !r.Accept("(", ")") && r.Is("(") {
+			if _, err := r.ExpectParenthesized(); err != nil {
+				return "", err
+			}
+		}
+	}
+	text := r.Text(from, r.Pos)
+
+	literal, err := expression.ParseLiteral(text)
+	if err != nil {
+		return "", err
+	}
+	if value, ok := literal.(*expression.ValueExpression); ok {
+		if _, err := types.Parse(column.Type, value.Value); err != nil {
+			return "", fmt.Errorf("invalid default value for column %s: %w", column.Name, err)
+		}
+	}
+	return text, nil
+}
+
+// parseType parses a type name with optional parameters, e.g. decimal(10, 2). The parameters are kept only for decimal.
+func parseType(r *statements.TokenReader) (types.Type, *types.Typmod, error) {
+	name, err := r.ExpectIdentifier()
+	if err != nil {
+		return types.Unknown, nil, err
+	}
+	columnType, err := types.ParseType(name)
+	if err != nil {
+		return types.Unknown, nil, err
+	}
+
+	var params []int64
+	if r.Accept("(") {
+		for {
+			token, err := r.Next()
+			if err != nil {
+				return types.Unknown, nil, err
+			}
+			if token.Type != sqlparser.INTEGRAL {
+				return types.Unknown, nil, fmt.Errorf("syntax error: invalid type parameter %s", token.Value)
+			}
+			param, err := strconv.ParseInt(token.Value, 10, 32)
+			if err != nil {
+				return types.Unknown, nil, fmt.Errorf("invalid type parameter %s", token.Value)
+			}
+			params = append(params, param)
+			if r.Accept(")") {
+				break
+			}
+			if err := r.Expect(","); err != nil {
+				return types.Unknown, nil, err
+			}
+		}
+	}
+	typmod, err := types.NewTypmod(columnType, params)
+	if err != nil {
+		return types.Unknown, nil, err
+	}
+	return columnType, typmod, nil
+}
+
+// parseColumnList parses (column, ...)
+func parseColumnList(r *statements.TokenReader) ([]string, error) {
+	if err := r.Expect("("); err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0)
+	for {
+		name, err := r.ExpectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+		if r.Accept(")") {
+			return columns, nil
+		}
+		if err := r.Expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseSingleColumn parses (column) of a constraint which supports only one column
+func parseSingleColumn(r *statements.TokenReader, constraint string) (string, error) {
+	columns, err := parseColumnList(r)
+	if err != nil {
+		return "", err
+	}
+	if len(columns) != 1 {
+		return "", fmt.Errorf("%s on multiple columns is not supported", constraint)
 	}
+	return columns[0], nil
 }
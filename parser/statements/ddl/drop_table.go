@@ -0,0 +1,55 @@
+package ddl
+
+import (
+	"garakutadb/parser/statements"
+)
+
+// DropTableStmt is DROP TABLE [IF EXISTS] name
+type DropTableStmt struct {
+	TableName string
+	IfExists  bool
+}
+
+func BuildDropTableStmt(sql string, tokens statements.Tokens) (*DropTableStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("drop", "table"); err != nil {
+		return nil, err
+	}
+	ifExists := r.Accept("if", "exists")
+	tableName, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &DropTableStmt{
+		TableName: tableName,
+		IfExists:  ifExists,
+	}, nil
+}
+
+// TruncateTableStmt is TRUNCATE [TABLE] name
+type TruncateTableStmt struct {
+	TableName string
+}
+
+func BuildTruncateTableStmt(sql string, tokens statements.Tokens) (*TruncateTableStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("truncate"); err != nil {
+		return nil, err
+	}
+	r.Accept("table")
+	tableName, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &TruncateTableStmt{
+		TableName: tableName,
+	}, nil
+}
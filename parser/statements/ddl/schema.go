@@ -0,0 +1,58 @@
+package ddl
+
+import (
+	"garakutadb/parser/statements"
+)
+
+// CreateSchemaStmt is CREATE SCHEMA [IF NOT EXISTS] name
+type CreateSchemaStmt struct {
+	SchemaName  string
+	IfNotExists bool
+}
+
+func BuildCreateSchemaStmt(sql string, tokens statements.Tokens) (*CreateSchemaStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("create", "schema"); err != nil {
+		return nil, err
+	}
+	ifNotExists := r.Accept("if", "not", "exists")
+	name, err := r.ExpectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &CreateSchemaStmt{
+		SchemaName:  name,
+		IfNotExists: ifNotExists,
+	}, nil
+}
+
+// DropSchemaStmt is DROP SCHEMA [IF EXISTS] name [RESTRICT]. Only an empty schema can be dropped.
+type DropSchemaStmt struct {
+	SchemaName string
+	IfExists   bool
+}
+
+func BuildDropSchemaStmt(sql string, tokens statements.Tokens) (*DropSchemaStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("drop", "schema"); err != nil {
+		return nil, err
+	}
+	ifExists := r.Accept("if", "exists")
+	name, err := r.ExpectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	r.Accept("restrict")
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &DropSchemaStmt{
+		SchemaName: name,
+		IfExists:   ifExists,
+	}, nil
+}
@@ -0,0 +1,178 @@
+package ddl
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/parser/statements"
+	"garakutadb/storage"
+	"garakutadb/types"
+	"github.com/xwb1989/sqlparser"
+	"math"
+	"strconv"
+)
+
+// CreateSequenceStmt is CREATE SEQUENCE [IF NOT EXISTS] name [options]
+type CreateSequenceStmt struct {
+	Sequence    *catalog.SequenceSchema
+	IfNotExists bool
+}
+
+// BuildCreateSequenceStmt parses CREATE SEQUENCE. The options are
+//
+//	[INCREMENT [BY] n] [MINVALUE n | NO MINVALUE] [MAXVALUE n | NO MAXVALUE] [START [WITH] n] [CACHE n]
+func BuildCreateSequenceStmt(sql string, tokens statements.Tokens) (*CreateSequenceStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("create", "sequence"); err != nil {
+		return nil, err
+	}
+	ifNotExists := r.Accept("if", "not", "exists")
+	name, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := parseSequenceOptions(r, types.BigInt)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &CreateSequenceStmt{
+		Sequence: &catalog.SequenceSchema{
+			Name:            name,
+			SequenceOptions: opts,
+		},
+		IfNotExists: ifNotExists,
+	}, nil
+}
+
+// DropSequenceStmt is DROP SEQUENCE [IF EXISTS] name
+type DropSequenceStmt struct {
+	SequenceName string
+	IfExists     bool
+}
+
+func BuildDropSequenceStmt(sql string, tokens statements.Tokens) (*DropSequenceStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("drop", "sequence"); err != nil {
+		return nil, err
+	}
+	ifExists := r.Accept("if", "exists")
+	name, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &DropSequenceStmt{
+		SequenceName: name,
+		IfExists:     ifExists,
+	}, nil
+}
+
+// parseSequenceOptions parses the options of CREATE SEQUENCE or an identity column until an unknown token.
+// The omitted bounds and start value are decided by the direction like PostgreSQL, and the maximum value is limited by the type.
+func parseSequenceOptions(r *statements.TokenReader, typ types.Type) (storage.SequenceOptions, error) {
+	opts := storage.SequenceOptions{Increment: 1, Cache: storage.DefaultSequenceCache}
+	var minValue, maxValue, start *int64
+	for {
+		var err error
+		switch {
+		case r.Accept("increment"):
+			r.Accept("by")
+			opts.Increment, err = parseInteger(r)
+		case r.Accept("minvalue"):
+			minValue, err = parseIntegerPointer(r)
+		case r.Accept("no", "minvalue"):
+			minValue = nil
+		case r.Accept("maxvalue"):
+			maxValue, err = parseIntegerPointer(r)
+		case r.Accept("no", "maxvalue"):
+			maxValue = nil
+		case r.Accept("start"):
+			r.Accept("with")
+			start, err = parseIntegerPointer(r)
+		case r.Accept("cache"):
+			opts.Cache, err = parseInteger(r)
+		default:
+			return fillSequenceOptions(opts, minValue, maxValue, start, typ)
+		}
+		if err != nil {
+			return opts, err
+		}
+	}
+}
+
+// defaultSequenceOptions returns the options of a sequence of a SERIAL or AUTO_INCREMENT column
+func defaultSequenceOptions(typ types.Type) storage.SequenceOptions {
+	opts, _ := fillSequenceOptions(storage.SequenceOptions{Increment: 1, Cache: storage.DefaultSequenceCache}, nil, nil, nil, typ)
+	return opts
+}
+
+func fillSequenceOptions(opts storage.SequenceOptions, minValue *int64, maxValue *int64, start *int64, typ types.Type) (storage.SequenceOptions, error) {
+	typeMin, typeMax := int64(math.MinInt64), int64(math.MaxInt64)
+	if typ == types.Int {
+		typeMin, typeMax = math.MinInt32, math.MaxInt32
+	}
+
+	switch {
+	case minValue != nil:
+		opts.MinValue = *minValue
+	case opts.Increment > 0:
+		opts.MinValue = 1
+	default:
+		opts.MinValue = typeMin
+	}
+	switch {
+	case maxValue != nil:
+		opts.MaxValue = *maxValue
+	case opts.Increment > 0:
+		opts.MaxValue = typeMax
+	default:
+		opts.MaxValue = -1
+	}
+	switch {
+	case start != nil:
+		opts.Start = *start
+	case opts.Increment > 0:
+		opts.Start = opts.MinValue
+	default:
+		opts.Start = opts.MaxValue
+	}
+
+	if opts.MinValue < typeMin || opts.MaxValue > typeMax {
+		return opts, fmt.Errorf("MINVALUE (%d) and MAXVALUE (%d) are out of range for the type", opts.MinValue, opts.MaxValue)
+	}
+	return opts, opts.Validate()
+}
+
+// parseInteger parses an integer literal, which can be negative
+func parseInteger(r *statements.TokenReader) (int64, error) {
+	sign := ""
+	if r.Accept("-") {
+		sign = "-"
+	}
+	token, err := r.Next()
+	if err != nil {
+		return 0, err
+	}
+	if token.Type != sqlparser.INTEGRAL {
+		return 0, fmt.Errorf("syntax error: expected integer but got %s", token.Value)
+	}
+	n, err := strconv.ParseInt(sign+token.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value out of range for bigint: %s%s", sign, token.Value)
+	}
+	return n, nil
+}
+
+func parseIntegerPointer(r *statements.TokenReader) (*int64, error) {
+	n, err := parseInteger(r)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
@@ -0,0 +1,161 @@
+package ddl
+
+import (
+	"fmt"
+	"garakutadb/expression"
+	"garakutadb/parser/statements"
+	"github.com/xwb1989/sqlparser"
+)
+
+// CreateViewStmt is CREATE [OR REPLACE] VIEW name [(column, ...)] AS SELECT ...
+// or CREATE MATERIALIZED VIEW [IF NOT EXISTS] name [(column, ...)] AS SELECT ... [WITH [NO] DATA]
+type CreateViewStmt struct {
+	ViewName string
+	// the names of the columns of the view. The selected names are used if empty.
+	ColumnNames []string
+	OrReplace   bool
+
+	Materialized bool
+	IfNotExists  bool
+	// WITH NO DATA creates an empty materialized view, which can't be read until it is refreshed
+	WithNoData bool
+
+	// the SELECT statement in SQL
+	Query  string
+	Select *statements.SelectStmt
+}
+
+func BuildCreateViewStmt(sql string, tokens statements.Tokens) (*CreateViewStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("create"); err != nil {
+		return nil, err
+	}
+	orReplace := r.Accept("or", "replace")
+	materialized := !orReplace && r.Accept("materialized")
+	if err := r.Expect("view"); err != nil {
+		return nil, err
+	}
+	ifNotExists := materialized && r.Accept("if", "not", "exists")
+	name, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	var columnNames []string
+	if r.Is("(") {
+		if columnNames, err = parseColumnList(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Expect("as"); err != nil {
+		return nil, err
+	}
+	if r.Done() {
+		return nil, fmt.Errorf("syntax error: expected SELECT")
+	}
+
+	end, withNoData := len(r.Tokens), false
+	if materialized {
+		end, withNoData = trimWithData(r.Tokens)
+	}
+	query := r.Text(r.Pos, end)
+	stmt, err := expression.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := stmt.(*sqlparser.Select)
+	if !ok {
+		return nil, fmt.Errorf("the query of a view must be SELECT")
+	}
+	selectStmt, err := statements.BuildSelectStmt(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateViewStmt{
+		ViewName:     name,
+		ColumnNames:  columnNames,
+		OrReplace:    orReplace,
+		Materialized: materialized,
+		IfNotExists:  ifNotExists,
+		WithNoData:   withNoData,
+		Query:        query,
+		Select:       selectStmt,
+	}, nil
+}
+
+// trimWithData returns the end of the query without WITH [NO] DATA, and whether it is WITH NO DATA
+func trimWithData(tokens statements.Tokens) (int, bool) {
+	end := len(tokens)
+	switch {
+	case tokens.Is(end-3, "with") && tokens.Is(end-2, "no") && tokens.Is(end-1, "data"):
+		return end - 3, true
+	case tokens.Is(end-2, "with") && tokens.Is(end-1, "data"):
+		return end - 2, false
+	}
+	return end, false
+}
+
+// DropViewStmt is DROP [MATERIALIZED] VIEW [IF EXISTS] name
+type DropViewStmt struct {
+	ViewName     string
+	IfExists     bool
+	Materialized bool
+}
+
+func BuildDropViewStmt(sql string, tokens statements.Tokens) (*DropViewStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("drop"); err != nil {
+		return nil, err
+	}
+	materialized := r.Accept("materialized")
+	if err := r.Expect("view"); err != nil {
+		return nil, err
+	}
+	ifExists := r.Accept("if", "exists")
+	name, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &DropViewStmt{
+		ViewName:     name,
+		IfExists:     ifExists,
+		Materialized: materialized,
+	}, nil
+}
+
+// RefreshMaterializedViewStmt is REFRESH MATERIALIZED VIEW name [WITH [NO] DATA]
+type RefreshMaterializedViewStmt struct {
+	ViewName string
+	// WITH NO DATA empties the view
+	WithNoData bool
+}
+
+func BuildRefreshMaterializedViewStmt(sql string, tokens statements.Tokens) (*RefreshMaterializedViewStmt, error) {
+	r := statements.NewTokenReader(sql, tokens)
+	if err := r.Expect("refresh", "materialized", "view"); err != nil {
+		return nil, err
+	}
+	name, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	withNoData := false
+	if r.Accept("with") {
+		withNoData = r.Accept("no")
+		if err := r.Expect("data"); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &RefreshMaterializedViewStmt{
+		ViewName:   name,
+		WithNoData: withNoData,
+	}, nil
+}
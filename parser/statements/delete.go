@@ -16,7 +16,7 @@ func BuildDeleteStmt(statement *sqlparser.Delete) (*DeleteStmt, error) {
 		return nil, fmt.Errorf("only support one table. got: %d", len(statement.TableExprs))
 	}
 
-	target := statement.TableExprs[0].(*sqlparser.AliasedTableExpr).Expr.(sqlparser.TableName).Name.String()
+	target := qualifiedTableName(statement.TableExprs[0].(*sqlparser.AliasedTableExpr).Expr.(sqlparser.TableName))
 
 	var whereExpr expression.Expression
 	var err error
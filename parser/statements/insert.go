@@ -1,11 +1,15 @@
 package statements
 
-import "github.com/xwb1989/sqlparser"
+import (
+	"garakutadb/expression"
+	"github.com/xwb1989/sqlparser"
+)
 
 type InsertStmt struct {
 	Into        string
 	ColumnNames []string
-	Values      []string
+	// literals, calls of nextval or currval, or expressions of them
+	Values []expression.Expression
 }
 
 func BuildInsertStmt(statement *sqlparser.Insert) (*InsertStmt, error) {
@@ -14,15 +18,19 @@ func BuildInsertStmt(statement *sqlparser.Insert) (*InsertStmt, error) {
 		columnNames = append(columnNames, colName.String())
 	}
 
-	var values []string
+	var values []expression.Expression
 	for _, row := range statement.Rows.(sqlparser.Values) {
 		for _, expr := range row {
-			values = append(values, string(expr.(*sqlparser.SQLVal).Val))
+			value, err := expression.FromExpr(expr)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
 		}
 	}
 
 	return &InsertStmt{
-		Into:        statement.Table.Name.String(),
+		Into:        qualifiedTableName(statement.Table),
 		ColumnNames: columnNames,
 		Values:      values,
 	}, nil
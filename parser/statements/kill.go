@@ -0,0 +1,30 @@
+package statements
+
+import (
+	"fmt"
+	"github.com/xwb1989/sqlparser"
+	"strconv"
+)
+
+// KillStmt aborts a running transaction: KILL <transaction id>
+type KillStmt struct {
+	TransactionId uint64
+}
+
+func BuildKillStmt(tokens Tokens) (*KillStmt, error) {
+	if err := tokens.Expect(0, "kill"); err != nil {
+		return nil, err
+	}
+	if len(tokens) != 2 || tokens[1].Type != sqlparser.INTEGRAL {
+		return nil, fmt.Errorf("syntax error: expected KILL <transaction id>")
+	}
+
+	id, err := strconv.ParseUint(tokens[1].Value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction id: %s", tokens[1].Value)
+	}
+
+	return &KillStmt{
+		TransactionId: id,
+	}, nil
+}
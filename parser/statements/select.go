@@ -7,6 +7,7 @@ import (
 )
 
 type SelectStmt struct {
+	// empty for SELECT without FROM
 	From string
 
 	// Actual column name (not alias)
@@ -15,6 +16,10 @@ type SelectStmt struct {
 	IsAllColumns bool
 
 	Where *Where
+
+	// the expressions of a select list which isn't only columns, e.g. SELECT price * quantity, and of SELECT without FROM.
+	// ColumnNames are their names.
+	Values []expression.Expression
 }
 
 func BuildSelectStmt(statement *sqlparser.Select) (*SelectStmt, error) {
@@ -26,10 +31,9 @@ func BuildSelectStmt(statement *sqlparser.Select) (*SelectStmt, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	columnNames, err := getColumnNamesFromSelectExprs(statement.SelectExprs)
-	if err != nil {
-		return nil, err
+	// sqlparser gives dual to SELECT without FROM
+	if from == "dual" {
+		return buildSelectWithoutFrom(statement)
 	}
 
 	var whereExpression expression.Expression
@@ -40,6 +44,21 @@ func BuildSelectStmt(statement *sqlparser.Select) (*SelectStmt, error) {
 		}
 	}
 
+	if !isOnlyColumns(statement.SelectExprs) {
+		stmt, err := buildSelectValues(statement.SelectExprs)
+		if err != nil {
+			return nil, err
+		}
+		stmt.From = from
+		stmt.Where.Expression = whereExpression
+		return stmt, nil
+	}
+
+	columnNames, err := getColumnNamesFromSelectExprs(statement.SelectExprs)
+	if err != nil {
+		return nil, err
+	}
+
 	return &SelectStmt{
 		From:         from,
 		ColumnNames:  columnNames,
@@ -48,11 +67,50 @@ func BuildSelectStmt(statement *sqlparser.Select) (*SelectStmt, error) {
 	}, nil
 }
 
+func buildSelectWithoutFrom(statement *sqlparser.Select) (*SelectStmt, error) {
+	if statement.Where != nil {
+		return nil, fmt.Errorf("WHERE without FROM is not supported")
+	}
+	return buildSelectValues(statement.SelectExprs)
+}
+
+// buildSelectValues converts the expressions of a select list to Values
+func buildSelectValues(selectExprs sqlparser.SelectExprs) (*SelectStmt, error) {
+	stmt := &SelectStmt{
+		Where: &Where{},
+	}
+	for _, selectExpr := range selectExprs {
+		aliasedExpr, ok := selectExpr.(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, fmt.Errorf("not supported select expression type: %T", selectExpr)
+		}
+		value, err := expression.FromExpr(aliasedExpr.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		// the name of a column is the alias, the column name, the function name, or ?column? like PostgreSQL
+		name := aliasedExpr.As.String()
+		if name == "" {
+			name = "?column?"
+			switch e := value.(type) {
+			case *expression.ColumnExpression:
+				name = e.Name
+			case *expression.FunctionExpression:
+				name = e.Name
+			}
+		}
+		stmt.ColumnNames = append(stmt.ColumnNames, name)
+		stmt.Values = append(stmt.Values, value)
+	}
+	return stmt, nil
+}
+
 func getTableNameFromTableExpr(from sqlparser.TableExpr) (string, error) {
 	if _, ok := from.(*sqlparser.AliasedTableExpr); ok {
 		aliasedTableExpr := from.(*sqlparser.AliasedTableExpr).Expr
-		if _, ok2 := aliasedTableExpr.(sqlparser.TableName); ok2 {
-			return aliasedTableExpr.(sqlparser.TableName).Name.String(), nil
+		if tableName, ok2 := aliasedTableExpr.(sqlparser.TableName); ok2 {
+			return qualifiedTableName(tableName), nil
 		} else {
 			return "", fmt.Errorf("not supported table expression type: %T", aliasedTableExpr)
 		}
@@ -84,6 +142,20 @@ func getColumnNamesFromSelectExprs(selectExprs sqlparser.SelectExprs) ([]string,
 	return columnNames, nil
 }
 
+// isOnlyColumns reports whether the select list consists of columns or *
+func isOnlyColumns(selectExprs sqlparser.SelectExprs) bool {
+	for _, selectExpr := range selectExprs {
+		aliasedExpr, ok := selectExpr.(*sqlparser.AliasedExpr)
+		if !ok {
+			continue
+		}
+		if _, ok := aliasedExpr.Expr.(*sqlparser.ColName); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func isAllColumns(selectExprs sqlparser.SelectExprs) bool {
 	for _, selectExpr := range selectExprs {
 		switch selectExpr.(type) {
@@ -95,3 +167,11 @@ func isAllColumns(selectExprs sqlparser.SelectExprs) bool {
 	}
 	return false
 }
+
+// qualifiedTableName returns the name of the table with its schema if it is qualified, e.g. information_schema.tables
+func qualifiedTableName(tableName sqlparser.TableName) string {
+	if !tableName.Qualifier.IsEmpty() {
+		return tableName.Qualifier.String() + "." + tableName.Name.String()
+	}
+	return tableName.Name.String()
+}
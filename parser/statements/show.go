@@ -0,0 +1,70 @@
+package statements
+
+import "fmt"
+
+// ShowTablesStmt is SHOW TABLES, which lists the tables and the views
+type ShowTablesStmt struct {
+}
+
+// ShowIndexesStmt is SHOW {INDEX | INDEXES | KEYS} {FROM | IN} table
+type ShowIndexesStmt struct {
+	TableName string
+}
+
+// DescribeStmt is {DESCRIBE | DESC} relation, which lists the columns
+type DescribeStmt struct {
+	TableName string
+}
+
+func BuildShowTablesStmt(sql string, tokens Tokens) (*ShowTablesStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if err := r.Expect("show", "tables"); err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+	return &ShowTablesStmt{}, nil
+}
+
+func BuildShowIndexesStmt(sql string, tokens Tokens) (*ShowIndexesStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if err := r.Expect("show"); err != nil {
+		return nil, err
+	}
+	if !r.Accept("index") && !r.Accept("indexes") && !r.Accept("keys") {
+		return nil, fmt.Errorf("syntax error: expected INDEXES")
+	}
+	if !r.Accept("from") && !r.Accept("in") {
+		return nil, fmt.Errorf("syntax error: expected FROM")
+	}
+	name, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &ShowIndexesStmt{
+		TableName: name,
+	}, nil
+}
+
+func BuildDescribeStmt(sql string, tokens Tokens) (*DescribeStmt, error) {
+	r := NewTokenReader(sql, tokens)
+	if !r.Accept("describe") && !r.Accept("desc") {
+		return nil, fmt.Errorf("syntax error: expected DESCRIBE")
+	}
+	name, err := r.ExpectQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ExpectEnd(); err != nil {
+		return nil, err
+	}
+
+	return &DescribeStmt{
+		TableName: name,
+	}, nil
+}
@@ -0,0 +1,195 @@
+package statements
+
+import (
+	"fmt"
+	"github.com/xwb1989/sqlparser"
+	"strings"
+)
+
+// Token is used to parse statements which are not supported by sqlparser
+type Token struct {
+	Type  int
+	Value string
+	// offsets of the token in the sql string. Start includes the preceding spaces.
+	Start int
+	End   int
+}
+
+type Tokens []Token
+
+func Tokenize(sql string) (Tokens, error) {
+	tokenizer := sqlparser.NewStringTokenizer(sql)
+	tokens := make(Tokens, 0)
+	for {
+		typ, val := tokenizer.Scan()
+		switch typ {
+		case 0:
+			// trailing semicolon
+			if len(tokens) > 0 && tokens[len(tokens)-1].Value == ";" {
+				tokens = tokens[:len(tokens)-1]
+			}
+			return tokens, nil
+		case sqlparser.LEX_ERROR:
+			return nil, fmt.Errorf("syntax error at position %d", tokenizer.Position)
+		case sqlparser.COMMENT:
+			continue
+		}
+		if val == nil {
+			val = []byte{byte(typ)}
+		}
+		start := 0
+		if len(tokens) > 0 {
+			start = tokens[len(tokens)-1].End
+		}
+		tokens = append(tokens, Token{Type: typ, Value: string(val), Start: start, End: tokenizer.Position - 1})
+	}
+}
+
+// Is reports whether the i-th token is the keyword (or identifier) word, ignoring case
+func (t Tokens) Is(i int, word string) bool {
+	return i < len(t) && t[i].Type != sqlparser.STRING && strings.EqualFold(t[i].Value, word)
+}
+
+// HasPrefix reports whether tokens start with words
+func (t Tokens) HasPrefix(words ...string) bool {
+	for i, word := range words {
+		if !t.Is(i, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Expect returns an error unless the i-th token is word
+func (t Tokens) Expect(i int, word string) error {
+	if !t.Is(i, word) {
+		if i < len(t) {
+			return fmt.Errorf("syntax error: expected %s but got %s", word, t[i].Value)
+		}
+		return fmt.Errorf("syntax error: expected %s", word)
+	}
+	return nil
+}
+
+// TokenReader reads tokens from the head
+type TokenReader struct {
+	Sql    string
+	Tokens Tokens
+	Pos    int
+}
+
+func NewTokenReader(sql string, tokens Tokens) *TokenReader {
+	return &TokenReader{Sql: sql, Tokens: tokens}
+}
+
+func (r *TokenReader) Done() bool {
+	return r.Pos >= len(r.Tokens)
+}
+
+// Is reports whether the next token is word
+func (r *TokenReader) Is(word string) bool {
+	return r.Tokens.Is(r.Pos, word)
+}
+
+// Accept consumes words if the next tokens are words
+func (r *TokenReader) Accept(words ...string) bool {
+	for i, word := range words {
+		if !r.Tokens.Is(r.Pos+i, word) {
+			return false
+		}
+	}
+	r.Pos += len(words)
+	return true
+}
+
+// Expect consumes words or returns an error
+func (r *TokenReader) Expect(words ...string) error {
+	for _, word := range words {
+		if err := r.Tokens.Expect(r.Pos, word); err != nil {
+			return err
+		}
+		r.Pos++
+	}
+	return nil
+}
+
+// Next consumes any token
+func (r *TokenReader) Next() (Token, error) {
+	if r.Done() {
+		return Token{}, fmt.Errorf("syntax error: unexpected end of statement")
+	}
+	r.Pos++
+	return r.Tokens[r.Pos-1], nil
+}
+
+// ExpectIdentifier consumes a name of a table, column etc. Keywords are allowed as names.
+func (r *TokenReader) ExpectIdentifier() (string, error) {
+	token, err := r.Next()
+	if err != nil {
+		return "", err
+	}
+	if token.Type == sqlparser.STRING || len(token.Value) == 1 && !isIdentifierChar(token.Value[0]) {
+		return "", fmt.Errorf("syntax error: expected name but got %s", token.Value)
+	}
+	return token.Value, nil
+}
+
+// ExpectQualifiedName consumes a name of a relation or a sequence, which may be qualified by a schema: [schema.]name
+func (r *TokenReader) ExpectQualifiedName() (string, error) {
+	name, err := r.ExpectIdentifier()
+	if err != nil {
+		return "", err
+	}
+	if r.Accept(".") {
+		relation, err := r.ExpectIdentifier()
+		if err != nil {
+			return "", err
+		}
+		name += "." + relation
+	}
+	return name, nil
+}
+
+// ExpectEnd returns an error unless all tokens have been consumed
+func (r *TokenReader) ExpectEnd() error {
+	if !r.Done() {
+		return fmt.Errorf("syntax error at %s", r.Tokens[r.Pos].Value)
+	}
+	return nil
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// ExpectParenthesized consumes tokens in parentheses and returns the sql text between them
+func (r *TokenReader) ExpectParenthesized() (string, error) {
+	if err := r.Expect("("); err != nil {
+		return "", err
+	}
+	from := r.Pos
+	depth := 1
+	for {
+		token, err := r.Next()
+		if err != nil {
+			return "", err
+		}
+		if token.Type == '(' {
+			depth++
+		} else if token.Type == ')' {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	if r.Pos-1 == from {
+		return "", fmt.Errorf("syntax error: empty parentheses")
+	}
+	return r.Text(from, r.Pos-1), nil
+}
+
+// Text returns the sql text of tokens[from:to]
+func (r *TokenReader) Text(from int, to int) string {
+	return strings.TrimSpace(r.Sql[r.Tokens[from].Start:r.Tokens[to-1].End])
+}
@@ -0,0 +1,137 @@
+package statements
+
+import (
+	"fmt"
+	"github.com/xwb1989/sqlparser"
+	"strings"
+)
+
+// BeginStmt starts a transaction: BEGIN [TRANSACTION | WORK] [transaction_mode [, ...]] or START TRANSACTION [...]
+// where transaction_mode is ISOLATION LEVEL { SERIALIZABLE | REPEATABLE READ } or READ { ONLY | WRITE }
+type BeginStmt struct {
+	IsolationLevel IsolationLevel
+	ReadOnly       bool
+}
+
+type IsolationLevel int32
+
+const (
+	DefaultIsolationLevel IsolationLevel = iota
+	RepeatableRead
+	Serializable
+)
+
+type CommitStmt struct {
+}
+
+type RollbackStmt struct {
+}
+
+// ExportSnapshotStmt returns the identifier of the snapshot of the current transaction: EXPORT SNAPSHOT
+type ExportSnapshotStmt struct {
+}
+
+// SetTransactionSnapshotStmt imports an exported snapshot: SET TRANSACTION SNAPSHOT '<snapshot id>'
+type SetTransactionSnapshotStmt struct {
+	SnapshotId string
+}
+
+// SetTransactionStmt changes the isolation level of the current transaction before its first query:
+// SET TRANSACTION ISOLATION LEVEL { SERIALIZABLE | REPEATABLE READ }
+type SetTransactionStmt struct {
+	IsolationLevel IsolationLevel
+}
+
+func BuildBeginStmt(tokens Tokens) (*BeginStmt, error) {
+	i := 1
+	if tokens.HasPrefix("start") {
+		if err := tokens.Expect(1, "transaction"); err != nil {
+			return nil, err
+		}
+		i = 2
+	} else if tokens.Is(1, "transaction") || tokens.Is(1, "work") {
+		i = 2
+	}
+
+	stmt := &BeginStmt{}
+	for first := i; i < len(tokens); {
+		// the modes can be separated by commas
+		if i > first && tokens.Is(i, ",") {
+			i++
+		}
+		switch {
+		case tokens.Is(i, "isolation"):
+			level, next, err := parseIsolationLevel(tokens, i)
+			if err != nil {
+				return nil, err
+			}
+			stmt.IsolationLevel = level
+			i = next
+		case tokens.Is(i, "read") && tokens.Is(i+1, "only"):
+			stmt.ReadOnly = true
+			i += 2
+		case tokens.Is(i, "read") && tokens.Is(i+1, "write"):
+			stmt.ReadOnly = false
+			i += 2
+		default:
+			if i < len(tokens) {
+				return nil, fmt.Errorf("syntax error at %s", tokens[i].Value)
+			}
+			return nil, fmt.Errorf("syntax error: expected ISOLATION LEVEL, READ ONLY or READ WRITE")
+		}
+	}
+	return stmt, nil
+}
+
+// parseIsolationLevel parses ISOLATION LEVEL ... from the i-th token, and returns the index of the next token
+func parseIsolationLevel(tokens Tokens, i int) (IsolationLevel, int, error) {
+	if err := tokens.Expect(i, "isolation"); err != nil {
+		return DefaultIsolationLevel, i, err
+	}
+	if err := tokens.Expect(i+1, "level"); err != nil {
+		return DefaultIsolationLevel, i, err
+	}
+	i += 2
+	switch {
+	case tokens.Is(i, "serializable"):
+		return Serializable, i + 1, nil
+	case tokens.Is(i, "repeatable") && tokens.Is(i+1, "read"):
+		return RepeatableRead, i + 2, nil
+	case tokens.Is(i, "read") && (tokens.Is(i+1, "committed") || tokens.Is(i+1, "uncommitted")):
+		return DefaultIsolationLevel, i, fmt.Errorf("isolation level READ %s is not supported", strings.ToUpper(tokens[i+1].Value))
+	default:
+		return DefaultIsolationLevel, i, fmt.Errorf("syntax error: expected SERIALIZABLE or REPEATABLE READ")
+	}
+}
+
+func BuildExportSnapshotStmt(tokens Tokens) (*ExportSnapshotStmt, error) {
+	if !tokens.HasPrefix("export", "snapshot") || len(tokens) != 2 {
+		return nil, fmt.Errorf("syntax error: expected EXPORT SNAPSHOT")
+	}
+	return &ExportSnapshotStmt{}, nil
+}
+
+func BuildSetTransactionStmt(tokens Tokens) (*SetTransactionStmt, error) {
+	if !tokens.HasPrefix("set", "transaction") {
+		return nil, fmt.Errorf("syntax error: expected SET TRANSACTION")
+	}
+	level, i, err := parseIsolationLevel(tokens, 2)
+	if err != nil {
+		return nil, err
+	}
+	if i != len(tokens) {
+		return nil, fmt.Errorf("syntax error at %s", tokens[i].Value)
+	}
+	return &SetTransactionStmt{
+		IsolationLevel: level,
+	}, nil
+}
+
+func BuildSetTransactionSnapshotStmt(tokens Tokens) (*SetTransactionSnapshotStmt, error) {
+	if !tokens.HasPrefix("set", "transaction", "snapshot") || len(tokens) != 4 || tokens[3].Type != sqlparser.STRING {
+		return nil, fmt.Errorf("syntax error: expected SET TRANSACTION SNAPSHOT '<snapshot id>'")
+	}
+	return &SetTransactionSnapshotStmt{
+		SnapshotId: tokens[3].Value,
+	}, nil
+}
@@ -9,8 +9,9 @@ import (
 type UpdateStmt struct {
 	Target string
 
-	UpdatedColumnNames  []string
-	UpdatedColumnValues []string
+	UpdatedColumnNames []string
+	// the new values, which can refer to the current values of the columns
+	UpdatedColumnValues []expression.Expression
 
 	Where *Where
 }
@@ -26,10 +27,14 @@ func BuildUpdateStmt(statement *sqlparser.Update) (*UpdateStmt, error) {
 	}
 
 	updatedColumnNames := make([]string, 0)
-	updatedColumnValues := make([]string, 0)
+	updatedColumnValues := make([]expression.Expression, 0)
 	for _, expr := range statement.Exprs {
 		updatedColumnNames = append(updatedColumnNames, expr.Name.Name.String())
-		updatedColumnValues = append(updatedColumnValues, sqlparser.String(expr.Expr))
+		value, err := expression.FromExpr(expr.Expr)
+		if err != nil {
+			return nil, err
+		}
+		updatedColumnValues = append(updatedColumnValues, value)
 	}
 
 	var whereExpression expression.Expression
@@ -0,0 +1,331 @@
+package planner
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/parser/statements/ddl"
+	"garakutadb/types"
+	"slices"
+)
+
+type AlterTablePlan struct {
+	TableName  string
+	ColumnName string
+	// the schema after the change. Its name is the new name when the table is renamed.
+	NewSchema *catalog.TableSchema
+	// the schemas of the other tables whose foreign keys are changed by renaming
+	ReferencingSchemas []*catalog.TableSchema
+	// the indexes which are removed with the dropped column
+	DroppedIndexNames []string
+	// the sequence owned by the dropped column
+	DroppedSequenceName string
+	// Rewrite rebuilds the table with NewSchema. The values of the columns are converted to their new types, and all constraints are checked.
+	// Otherwise only the catalog is changed.
+	Rewrite bool
+	// DROP COLUMN IF EXISTS for a column which doesn't exist
+	Skip bool
+}
+
+func BuildAlterTablePlan(ct *catalog.Catalog, stmt *ddl.AlterTableStmt) (Plan, error) {
+	if catalog.IsSystemView(stmt.TableName) {
+		return nil, fmt.Errorf("cannot alter system view: %s", stmt.TableName)
+	}
+	tableSchema, err := ct.TableSchemas.Get(stmt.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
+	}
+	if tableSchema.IsMaterializedView() {
+		return nil, notTableError(stmt.TableName)
+	}
+
+	// the queries of views refer to the names of the columns and the table. Columns can be added, because SELECT * of a view is resolved when it is created.
+	if stmt.Action != ddl.AddColumn {
+		if err := checkNoDependentViews(ct, stmt.TableName, "alter table"); err != nil {
+			return nil, err
+		}
+	}
+
+	pl := &AlterTablePlan{
+		TableName:  stmt.TableName,
+		ColumnName: stmt.ColumnName,
+		NewSchema:  tableSchema.Clone(),
+	}
+	switch stmt.Action {
+	case ddl.AddColumn:
+		err = planAddColumn(ct, pl, stmt)
+	case ddl.DropColumn:
+		err = planDropColumn(ct, pl, stmt)
+	case ddl.RenameColumn:
+		err = planRenameColumn(ct, pl, stmt)
+	case ddl.RenameTable:
+		err = planRenameTable(ct, pl, stmt)
+	case ddl.AlterColumnType:
+		err = planAlterColumnType(ct, pl, stmt)
+	default:
+		err = fmt.Errorf("not supported ALTER TABLE action: %d", stmt.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pl.Skip {
+		return pl, nil
+	}
+
+	pl.NewSchema.Version++
+	if err := pl.NewSchema.Validate(); err != nil {
+		return nil, err
+	}
+	return pl, nil
+}
+
+func columnNotFoundError(tableName string, column string) error {
+	return fmt.Errorf("column \"%s\" of relation \"%s\" does not exist", column, tableName)
+}
+
+// planAddColumn adds the column to a new slot. The tuples written before have the default value as the missing value.
+// The table is rewritten only if the new constraints must be checked for the existing rows.
+func planAddColumn(ct *catalog.Catalog, pl *AlterTablePlan, stmt *ddl.AlterTableStmt) error {
+	schema := pl.NewSchema
+	definition := stmt.Definition
+	column := definition.Columns[0]
+	if _, found := schema.Columns.Contains(column.Name); found {
+		return fmt.Errorf("column \"%s\" of relation \"%s\" already exists", column.Name, schema.Name)
+	}
+
+	column.Slot = schema.NextSlot
+	schema.NextSlot++
+	if column.Default != nil {
+		literal, err := expression.ParseLiteral(*column.Default)
+		if err != nil {
+			return err
+		}
+		// the existing rows would need a value for each
+		if !expression.IsConstant(literal, ct.Functions()) {
+			return fmt.Errorf("adding column %s whose default is a function call is not supported", column.Name)
+		}
+		value, err := literalToValue(&column, literal, ct.Functions())
+		if err != nil {
+			return err
+		}
+		if value, err = column.Typmod.Apply(value); err != nil {
+			return err
+		}
+		if !value.IsNull() {
+			missing := value.String()
+			column.Missing = &missing
+		}
+	}
+	schema.Columns = append(schema.Columns, column)
+
+	if definition.HasPK() {
+		if schema.HasPK() {
+			return fmt.Errorf("multiple primary keys for table %s are not allowed", schema.Name)
+		}
+		schema.PK = definition.PK
+		schema.PKName = definition.PKName
+	}
+	schema.Uniques = append(schema.Uniques, definition.Uniques...)
+	schema.Checks = append(schema.Checks, definition.Checks...)
+	schema.ForeignKeys = append(schema.ForeignKeys, definition.ForeignKeys...)
+	if err := resolveForeignKeys(ct, schema); err != nil {
+		return err
+	}
+
+	pl.Rewrite = definition.HasPK() || len(definition.Uniques) > 0 || len(definition.Checks) > 0 || len(definition.ForeignKeys) > 0 ||
+		column.NotNull && column.Missing == nil
+	return nil
+}
+
+// planDropColumn removes the column and its constraints. The values remain in the tuples, but are not read any more.
+func planDropColumn(ct *catalog.Catalog, pl *AlterTablePlan, stmt *ddl.AlterTableStmt) error {
+	schema := pl.NewSchema
+	order, found := schema.Columns.Contains(stmt.ColumnName)
+	if !found {
+		if stmt.IfExists {
+			pl.Skip = true
+			return nil
+		}
+		return columnNotFoundError(schema.Name, stmt.ColumnName)
+	}
+	if len(schema.Columns) == 1 {
+		return fmt.Errorf("cannot drop the only column of table %s", schema.Name)
+	}
+	if slices.Contains(schema.PK, stmt.ColumnName) {
+		return fmt.Errorf("cannot drop column %s because it is a part of the primary key of table %s", stmt.ColumnName, schema.Name)
+	}
+	for _, reference := range ct.TableSchemas.ReferencedBy(schema.Name) {
+		if reference.ForeignKey.RefColumn == stmt.ColumnName {
+			return fmt.Errorf("cannot drop column %s of table %s because constraint %s on table %s depends on it", stmt.ColumnName, schema.Name, reference.ForeignKey.Name, reference.Table.Name)
+		}
+	}
+
+	pl.DroppedSequenceName = schema.Columns[order].Sequence
+	schema.Columns = slices.Delete(schema.Columns, int(order), int(order)+1)
+	schema.Uniques = slices.DeleteFunc(schema.Uniques, func(unique catalog.UniqueConstraint) bool {
+		if unique.Column == stmt.ColumnName {
+			pl.DroppedIndexNames = append(pl.DroppedIndexNames, unique.Name)
+			return true
+		}
+		return false
+	})
+	checks := make([]catalog.CheckConstraint, 0, len(schema.Checks))
+	for _, check := range schema.Checks {
+		columns, err := expression.ReferencedColumns(check.Expression)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(columns, stmt.ColumnName) {
+			checks = append(checks, check)
+		}
+	}
+	schema.Checks = checks
+	schema.ForeignKeys = slices.DeleteFunc(schema.ForeignKeys, func(fk catalog.ForeignKeyConstraint) bool {
+		return fk.Column == stmt.ColumnName
+	})
+	return nil
+}
+
+// planRenameColumn renames the column in the table and in the constraints referencing it
+func planRenameColumn(ct *catalog.Catalog, pl *AlterTablePlan, stmt *ddl.AlterTableStmt) error {
+	schema := pl.NewSchema
+	from, to := stmt.ColumnName, stmt.NewName
+	order, found := schema.Columns.Contains(from)
+	if !found {
+		return columnNotFoundError(schema.Name, from)
+	}
+	if _, found := schema.Columns.Contains(to); found {
+		return fmt.Errorf("column \"%s\" of relation \"%s\" already exists", to, schema.Name)
+	}
+
+	schema.Columns[order].Name = to
+	for i, column := range schema.PK {
+		if column == from {
+			schema.PK[i] = to
+		}
+	}
+	for i := range schema.Uniques {
+		if schema.Uniques[i].Column == from {
+			schema.Uniques[i].Column = to
+		}
+	}
+	for i := range schema.Checks {
+		renamed, err := expression.RenameColumn(schema.Checks[i].Expression, from, to)
+		if err != nil {
+			return err
+		}
+		schema.Checks[i].Expression = renamed
+	}
+	for i := range schema.ForeignKeys {
+		if schema.ForeignKeys[i].Column == from {
+			schema.ForeignKeys[i].Column = to
+		}
+	}
+
+	renameReferences(ct, pl, func(fk *catalog.ForeignKeyConstraint) {
+		if fk.RefColumn == from {
+			fk.RefColumn = to
+		}
+	})
+	return nil
+}
+
+// planRenameTable renames the table. The names of the constraints are not changed.
+func planRenameTable(ct *catalog.Catalog, pl *AlterTablePlan, stmt *ddl.AlterTableStmt) error {
+	schema := pl.NewSchema
+	if ct.RelationExists(stmt.NewName) {
+		return relationExistsError(stmt.NewName)
+	}
+
+	if schema.HasPK() {
+		// the index of the primary key keeps its name
+		schema.PKName = schema.PKConstraintName()
+	}
+	schema.Name = stmt.NewName
+
+	renameReferences(ct, pl, func(fk *catalog.ForeignKeyConstraint) {
+		fk.RefTable = stmt.NewName
+	})
+	return nil
+}
+
+// renameReferences applies rename to the foreign keys referencing the table, including the ones of the table itself
+func renameReferences(ct *catalog.Catalog, pl *AlterTablePlan, rename func(fk *catalog.ForeignKeyConstraint)) {
+	for i := range pl.NewSchema.ForeignKeys {
+		if pl.NewSchema.ForeignKeys[i].RefTable == pl.TableName {
+			rename(&pl.NewSchema.ForeignKeys[i])
+		}
+	}
+
+	for _, reference := range ct.TableSchemas.ReferencedBy(pl.TableName) {
+		if reference.Table.Name == pl.TableName ||
+			slices.ContainsFunc(pl.ReferencingSchemas, func(ts *catalog.TableSchema) bool { return ts.Name == reference.Table.Name }) {
+			continue
+		}
+		referencing := reference.Table.Clone()
+		for i := range referencing.ForeignKeys {
+			if referencing.ForeignKeys[i].RefTable == pl.TableName {
+				rename(&referencing.ForeignKeys[i])
+			}
+		}
+		pl.ReferencingSchemas = append(pl.ReferencingSchemas, referencing)
+	}
+}
+
+// planAlterColumnType changes the type of the column. The table is rewritten unless the stored values can be read as the new type.
+func planAlterColumnType(ct *catalog.Catalog, pl *AlterTablePlan, stmt *ddl.AlterTableStmt) error {
+	schema := pl.NewSchema
+	order, found := schema.Columns.Contains(stmt.ColumnName)
+	if !found {
+		return columnNotFoundError(schema.Name, stmt.ColumnName)
+	}
+	column := &schema.Columns[order]
+
+	usedByForeignKey := slices.ContainsFunc(schema.ForeignKeys, func(fk catalog.ForeignKeyConstraint) bool {
+		return fk.Column == column.Name
+	}) || slices.ContainsFunc(ct.TableSchemas.ReferencedBy(schema.Name), func(reference catalog.ForeignKeyReference) bool {
+		return reference.ForeignKey.RefColumn == column.Name
+	})
+	if usedByForeignKey {
+		return fmt.Errorf("cannot alter type of column %s because it is used in a foreign key constraint", column.Name)
+	}
+
+	oldType, oldTypmod := column.Type, column.Typmod
+	column.Type, column.Typmod = stmt.NewType, stmt.NewTypmod
+	if column.Default != nil {
+		literal, err := expression.ParseLiteral(*column.Default)
+		if err != nil {
+			return err
+		}
+		castable := true
+		if isSequenceCall(literal) {
+			// nextval gives an integer
+			castable = stmt.NewType == types.Int || stmt.NewType == types.BigInt
+		} else if _, err := literalToValue(column, literal, ct.Functions()); err != nil {
+			castable = false
+		}
+		if !castable {
+			return fmt.Errorf("default for column \"%s\" cannot be cast automatically to type %s", column.Name, stmt.NewType)
+		}
+	}
+	if column.Missing != nil {
+		missing, err := types.Parse(oldType, *column.Missing)
+		if err != nil {
+			return err
+		}
+		converted, err := types.Cast(missing, stmt.NewType)
+		if err != nil {
+			return fmt.Errorf("column \"%s\" cannot be cast automatically to type %s", column.Name, stmt.NewType)
+		}
+		if converted, err = column.Typmod.Apply(converted); err != nil {
+			return err
+		}
+		s := converted.String()
+		column.Missing = &s
+	}
+
+	// the stored values are rounded to a new precision and scale
+	typmodChanged := column.Typmod != nil && (oldTypmod == nil || *oldTypmod != *column.Typmod)
+	pl.Rewrite = !types.IsBinaryCoercible(oldType, stmt.NewType) || typmodChanged
+	return nil
+}
@@ -6,4 +6,6 @@ import (
 
 type CreateTablePlan struct {
 	TableSchema *catalog.TableSchema
+	// the sequences owned by the columns, which are created with the table
+	Sequences []catalog.SequenceSchema
 }
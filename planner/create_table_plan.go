@@ -12,8 +12,62 @@ func BuildCreateTablePlan(ct *catalog.Catalog, stmt *ddl.CreateTableStmt) (Plan,
 			return nil, fmt.Errorf("table already exists: %s", stmt.Into)
 		}
 	}
+	if catalog.IsSystemView(stmt.Into) {
+		return nil, fmt.Errorf("table name is reserved for system view: %s", stmt.Into)
+	}
+	if ct.RelationExists(stmt.Into) {
+		return nil, relationExistsError(stmt.Into)
+	}
+	for _, sequence := range stmt.Sequences {
+		if ct.RelationExists(sequence.Name) {
+			return nil, relationExistsError(sequence.Name)
+		}
+	}
+
+	if err := resolveForeignKeys(ct, stmt.TableSchema); err != nil {
+		return nil, err
+	}
 
 	return &CreateTablePlan{
 		TableSchema: stmt.TableSchema,
+		Sequences:   stmt.Sequences,
 	}, nil
 }
+
+// resolveForeignKeys fills the referenced columns omitted in the statement and validates them.
+// A table can reference itself.
+func resolveForeignKeys(ct *catalog.Catalog, tableSchema *catalog.TableSchema) error {
+	for i := range tableSchema.ForeignKeys {
+		fk := &tableSchema.ForeignKeys[i]
+		parent := tableSchema
+		if fk.RefTable != tableSchema.Name {
+			p, err := ct.TableSchemas.Get(fk.RefTable)
+			if err != nil {
+				return fmt.Errorf("referenced table not found: %s", fk.RefTable)
+			}
+			if p.IsMaterializedView() {
+				return fmt.Errorf("referenced relation \"%s\" is not a table", fk.RefTable)
+			}
+			parent = p
+		}
+		if fk.RefColumn == "" {
+			if len(parent.PK) != 1 {
+				return fmt.Errorf("there is no unique constraint matching given keys for referenced table \"%s\"", parent.Name)
+			}
+			fk.RefColumn = parent.PK[0]
+		}
+
+		refOrder, found := parent.Columns.Contains(fk.RefColumn)
+		if !found {
+			return fmt.Errorf("column not found: %s.%s", parent.Name, fk.RefColumn)
+		}
+		if _, found := parent.IndexNameOf(fk.RefColumn); !found {
+			return fmt.Errorf("there is no unique constraint matching given keys for referenced table \"%s\"", parent.Name)
+		}
+		order, _ := tableSchema.Columns.Contains(fk.Column)
+		if tableSchema.Columns[order].Type != parent.Columns[refOrder].Type {
+			return fmt.Errorf("foreign key constraint \"%s\" cannot be implemented: key columns \"%s\" and \"%s\" are of incompatible types", fk.Name, fk.Column, fk.RefColumn)
+		}
+	}
+	return nil
+}
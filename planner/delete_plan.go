@@ -13,13 +13,23 @@ type DeletePlan struct {
 }
 
 func BuildDeletePlan(ct *catalog.Catalog, deleteStmt *statements.DeleteStmt) (*DeletePlan, error) {
-	_, err := ct.TableSchemas.Get(deleteStmt.Target)
+	if err := checkNotView(ct, deleteStmt.Target, "delete from"); err != nil {
+		return nil, err
+	}
+	tableSchema, err := ct.TableSchemas.Get(deleteStmt.Target)
 	if err == catalog.TableSchemaNotFoundError {
 		return nil, fmt.Errorf("table not found: %s", deleteStmt.Target)
 	}
+	if err != nil {
+		return nil, err
+	}
+	where, err := resolveWhere(tableSchema, deleteStmt.Where.Expression, ct.Functions())
+	if err != nil {
+		return nil, err
+	}
 
 	return &DeletePlan{
 		TableName:       deleteStmt.Target,
-		WhereExpression: deleteStmt.Where.Expression,
+		WhereExpression: where,
 	}, nil
 }
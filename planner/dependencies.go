@@ -0,0 +1,58 @@
+package planner
+
+import (
+	"garakutadb/catalog"
+	"strings"
+)
+
+// Dependencies are the versions of the relations and the sequences which a plan was made from.
+// The plan is invalid when one of them has been changed (see catalog.Catalog.RelationVersion).
+type Dependencies map[string]uint64
+
+// IsValid reports whether none of the dependencies has been changed in the catalog
+func (d Dependencies) IsValid(ct *catalog.Catalog) bool {
+	for name, version := range d {
+		if ct.RelationVersion(name) != version {
+			return false
+		}
+	}
+	return true
+}
+
+// addName adds a name in the statement resolved with the search path. The names in the schemas before the resolved
+// one are added too, because a relation created there would be resolved instead.
+func (d Dependencies) addName(ct *catalog.Catalog, path catalog.SearchPath, name string, resolved string) {
+	d.addRelation(ct, resolved)
+	if !strings.Contains(name, ".") {
+		for _, schema := range path {
+			qualified := catalog.QualifyRelationName(schema, name)
+			d[qualified] = ct.RelationVersion(qualified)
+		}
+	}
+}
+
+// addRelation adds the relation and the relations which are read or checked with it:
+// the relations read by a view and the tables related by foreign keys
+func (d Dependencies) addRelation(ct *catalog.Catalog, name string) {
+	if _, ok := d[name]; ok {
+		return
+	}
+	d[name] = ct.RelationVersion(name)
+
+	if view, err := ct.Views.Get(name); err == nil {
+		d.addRelation(ct, view.From)
+	}
+	tableSchema, err := ct.TableSchemas.Get(name)
+	if err != nil {
+		return
+	}
+	if tableSchema.IsMaterializedView() {
+		d.addRelation(ct, tableSchema.Materialized.From)
+	}
+	for _, fk := range tableSchema.ForeignKeys {
+		d.addRelation(ct, fk.RefTable)
+	}
+	for _, reference := range ct.TableSchemas.ReferencedBy(name) {
+		d.addRelation(ct, reference.Table.Name)
+	}
+}
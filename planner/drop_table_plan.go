@@ -0,0 +1,77 @@
+package planner
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/parser/statements/ddl"
+)
+
+type DropTablePlan struct {
+	TableName string
+	// the sequences owned by the columns, which are dropped with the table
+	SequenceNames []string
+	// the table doesn't exist and IF EXISTS is specified
+	Skip bool
+}
+
+func BuildDropTablePlan(ct *catalog.Catalog, stmt *ddl.DropTableStmt) (Plan, error) {
+	if catalog.IsSystemView(stmt.TableName) {
+		return nil, fmt.Errorf("cannot drop system view: %s", stmt.TableName)
+	}
+	tableSchema, err := ct.TableSchemas.Get(stmt.TableName)
+	if err != nil {
+		if err == catalog.TableSchemaNotFoundError && stmt.IfExists {
+			return &DropTablePlan{TableName: stmt.TableName, Skip: true}, nil
+		}
+		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
+	}
+	if tableSchema.IsMaterializedView() {
+		return nil, notTableError(stmt.TableName)
+	}
+
+	for _, reference := range ct.TableSchemas.ReferencedBy(stmt.TableName) {
+		if reference.Table.Name != stmt.TableName {
+			return nil, fmt.Errorf("cannot drop table %s because constraint %s on table %s depends on it", stmt.TableName, reference.ForeignKey.Name, reference.Table.Name)
+		}
+	}
+	if err := checkNoDependentViews(ct, stmt.TableName, "drop table"); err != nil {
+		return nil, err
+	}
+
+	sequenceNames := make([]string, 0)
+	for _, column := range tableSchema.Columns {
+		if column.Sequence != "" {
+			sequenceNames = append(sequenceNames, column.Sequence)
+		}
+	}
+
+	return &DropTablePlan{
+		TableName:     stmt.TableName,
+		SequenceNames: sequenceNames,
+	}, nil
+}
+
+type TruncateTablePlan struct {
+	TableName string
+}
+
+func BuildTruncateTablePlan(ct *catalog.Catalog, stmt *ddl.TruncateTableStmt) (Plan, error) {
+	tableSchema, err := ct.TableSchemas.Get(stmt.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
+	}
+	if tableSchema.IsMaterializedView() {
+		return nil, notTableError(stmt.TableName)
+	}
+
+	// the referencing rows would be orphaned
+	for _, reference := range ct.TableSchemas.ReferencedBy(stmt.TableName) {
+		if reference.Table.Name != stmt.TableName {
+			return nil, fmt.Errorf("cannot truncate a table referenced in a foreign key constraint: %s references %s", reference.Table.Name, stmt.TableName)
+		}
+	}
+
+	return &TruncateTablePlan{
+		TableName: stmt.TableName,
+	}, nil
+}
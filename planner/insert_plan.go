@@ -3,19 +3,29 @@ package planner
 import (
 	"fmt"
 	"garakutadb/catalog"
+	"garakutadb/expression"
 	"garakutadb/parser/statements"
+	"garakutadb/types"
+	"slices"
 )
 
 type InsertPlan struct {
 	Into         string
 	ColumnNames  []string
 	ColumnOrders []uint64
-	Values       []string
-	ColumnNum    uint64
-	PKValue      string
+	// values of the columns in ColumnOrders, converted to the types of the columns
+	Values []types.Value
+	// nextval or currval, which replaces the value of Values at the same index
+	SequenceCalls map[int]*SequenceCall
+	// the expressions computed at every execution, e.g. now(), which replace the values of Values at the same index
+	Expressions map[int]expression.Expression
+	ColumnNum   uint64
 }
 
 func BuildInsertPlan(ct *catalog.Catalog, insertStmt *statements.InsertStmt) (Plan, error) {
+	if err := checkNotView(ct, insertStmt.Into, "insert into"); err != nil {
+		return nil, err
+	}
 	tableSchema, err := ct.TableSchemas.Get(insertStmt.Into)
 	if err == catalog.TableSchemaNotFoundError {
 		return nil, fmt.Errorf("table not found: %s", insertStmt.Into)
@@ -24,44 +34,76 @@ func BuildInsertPlan(ct *catalog.Catalog, insertStmt *statements.InsertStmt) (Pl
 		return nil, err
 	}
 
-	columnNames := make([]string, 0)
-	columnOrders := make([]uint64, 0)
-	columnValues := make([]string, 0)
-	pkValue := ""
-	if len(insertStmt.ColumnNames) == 0 {
-		pkValue = insertStmt.Values[0]
-		for order, col := range tableSchema.Columns {
+	columnNames := insertStmt.ColumnNames
+	if len(columnNames) == 0 {
+		for _, col := range tableSchema.Columns {
 			columnNames = append(columnNames, col.Name)
-			columnOrders = append(columnOrders, uint64(order))
-			columnValues = append(columnValues, insertStmt.Values[order])
 		}
-	} else {
-		if len(insertStmt.ColumnNames) != len(insertStmt.Values) {
-			return nil, fmt.Errorf("column length and value length are not matched. column length: %d, value length: %d", len(insertStmt.ColumnNames), len(insertStmt.Values))
+	}
+	if len(columnNames) != len(insertStmt.Values) {
+		return nil, fmt.Errorf("column length and value length are not matched. column length: %d, value length: %d", len(columnNames), len(insertStmt.Values))
+	}
+
+	columnOrders := make([]uint64, 0)
+	columnValues := make([]types.Value, 0)
+	sequenceCalls := make(map[int]*SequenceCall)
+	expressions := make(map[int]expression.Expression)
+	for i, col := range columnNames {
+		order, found := tableSchema.Columns.Contains(col)
+		if !found {
+			return nil, fmt.Errorf("column not found: %s", col)
+		}
+		if slices.Contains(columnOrders, order) {
+			return nil, fmt.Errorf("column specified more than once: %s", col)
+		}
+		if tableSchema.Columns[order].Identity == catalog.IdentityAlways {
+			return nil, fmt.Errorf("cannot insert a non-DEFAULT value into column \"%s\"", col)
 		}
 
-		for _, col := range insertStmt.ColumnNames {
-			order, found := tableSchema.Columns.Contains(col)
-			if found {
-				columnNames = append(columnNames, col)
-				columnOrders = append(columnOrders, order)
-				columnValues = append(columnValues, insertStmt.Values[order])
+		value, call, runtimeExpr, err := valueOrSequenceCall(ct, &tableSchema.Columns[order], insertStmt.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		if call != nil {
+			sequenceCalls[len(columnValues)] = call
+		}
+		if runtimeExpr != nil {
+			expressions[len(columnValues)] = runtimeExpr
+		}
+		columnOrders = append(columnOrders, order)
+		columnValues = append(columnValues, value)
+	}
 
-				if col == tableSchema.PK {
-					pkValue = insertStmt.Values[order]
-				}
-			} else {
-				return nil, fmt.Errorf("column not found: %s", col)
-			}
+	// fill omitted columns with their defaults
+	for order, column := range tableSchema.Columns {
+		if column.Default == nil || slices.Contains(columnOrders, uint64(order)) {
+			continue
+		}
+		expr, err := expression.ParseLiteral(*column.Default)
+		if err != nil {
+			return nil, err
+		}
+		value, call, runtimeExpr, err := valueOrSequenceCall(ct, &column, expr)
+		if err != nil {
+			return nil, err
+		}
+		if call != nil {
+			sequenceCalls[len(columnValues)] = call
+		}
+		if runtimeExpr != nil {
+			expressions[len(columnValues)] = runtimeExpr
 		}
+		columnOrders = append(columnOrders, uint64(order))
+		columnValues = append(columnValues, value)
 	}
 
 	return &InsertPlan{
-		Into:         tableSchema.Name,
-		ColumnNames:  columnNames,
-		ColumnOrders: columnOrders,
-		Values:       columnValues,
-		ColumnNum:    uint64(len(tableSchema.Columns)),
-		PKValue:      pkValue,
+		Into:          tableSchema.Name,
+		ColumnNames:   columnNames,
+		ColumnOrders:  columnOrders,
+		Values:        columnValues,
+		SequenceCalls: sequenceCalls,
+		Expressions:   expressions,
+		ColumnNum:     uint64(len(tableSchema.Columns)),
 	}, nil
 }
@@ -0,0 +1,16 @@
+package planner
+
+import (
+	"garakutadb/parser/statements"
+	"garakutadb/storage"
+)
+
+type KillPlan struct {
+	TransactionId storage.TransactionId
+}
+
+func BuildKillPlan(killStmt *statements.KillStmt) (*KillPlan, error) {
+	return &KillPlan{
+		TransactionId: storage.TransactionId(killStmt.TransactionId),
+	}, nil
+}
@@ -0,0 +1,23 @@
+package planner
+
+import (
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/types"
+)
+
+// literalToValue converts a literal in a statement to a value of the column. An expression without columns is computed.
+func literalToValue(column *catalog.ColumnSchema, expr expression.Expression, functions *expression.Functions) (types.Value, error) {
+	switch e := expr.(type) {
+	case *expression.NullExpression:
+		return types.NewNull(column.Type), nil
+	case *expression.ValueExpression:
+		return types.Parse(column.Type, e.Value)
+	default:
+		value, err := expression.Eval(expr, nil, functions)
+		if err != nil {
+			return types.Value{}, err
+		}
+		return types.Cast(value, column.Type)
+	}
+}
@@ -0,0 +1,112 @@
+package planner
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/parser/statements/ddl"
+)
+
+type CreateMaterializedViewPlan struct {
+	// the heap table which stores the rows of the query
+	TableSchema *catalog.TableSchema
+	// the plan of the query. nil for WITH NO DATA.
+	Query Plan
+	// the materialized view exists and IF NOT EXISTS is specified
+	Skip bool
+}
+
+func buildCreateMaterializedViewPlan(ct *catalog.Catalog, stmt *ddl.CreateViewStmt) (Plan, error) {
+	if ct.RelationExists(stmt.ViewName) {
+		if ts, err := ct.TableSchemas.Get(stmt.ViewName); err == nil && ts.IsMaterializedView() && stmt.IfNotExists {
+			return &CreateMaterializedViewPlan{TableSchema: ts, Skip: true}, nil
+		}
+		return nil, relationExistsError(stmt.ViewName)
+	}
+
+	columns, query, err := planViewQuery(ct, stmt)
+	if err != nil {
+		return nil, err
+	}
+	for i := range columns {
+		columns[i].Slot = uint32(i)
+	}
+	if stmt.WithNoData {
+		query = nil
+	}
+
+	return &CreateMaterializedViewPlan{
+		TableSchema: &catalog.TableSchema{
+			Name:     stmt.ViewName,
+			Columns:  columns,
+			NextSlot: uint32(len(columns)),
+			Materialized: &catalog.MaterializedView{
+				Query:       stmt.Query,
+				From:        stmt.Select.From,
+				Unpopulated: stmt.WithNoData,
+			},
+		},
+		Query: query,
+	}, nil
+}
+
+type RefreshMaterializedViewPlan struct {
+	ViewName string
+	// the plan of the query. nil for WITH NO DATA.
+	Query Plan
+}
+
+func BuildRefreshMaterializedViewPlan(ct *catalog.Catalog, stmt *ddl.RefreshMaterializedViewStmt) (Plan, error) {
+	tableSchema, err := getMaterializedView(ct, stmt.ViewName)
+	if err != nil {
+		return nil, err
+	}
+
+	pl := &RefreshMaterializedViewPlan{
+		ViewName: stmt.ViewName,
+	}
+	if !stmt.WithNoData {
+		selectStmt, err := parseViewQuery(stmt.ViewName, tableSchema.Materialized.Query)
+		if err != nil {
+			return nil, err
+		}
+		selectStmt.From = tableSchema.Materialized.From
+		// columns added to the relation since the view was created are selected by SELECT *, and they are ignored by the executor
+		if pl.Query, err = BuildSelectPlan(ct, selectStmt); err != nil {
+			return nil, err
+		}
+	}
+	return pl, nil
+}
+
+type DropMaterializedViewPlan struct {
+	ViewName string
+	// the materialized view doesn't exist and IF EXISTS is specified
+	Skip bool
+}
+
+func buildDropMaterializedViewPlan(ct *catalog.Catalog, stmt *ddl.DropViewStmt) (Plan, error) {
+	if _, err := getMaterializedView(ct, stmt.ViewName); err != nil {
+		if !ct.RelationExists(stmt.ViewName) && stmt.IfExists {
+			return &DropMaterializedViewPlan{ViewName: stmt.ViewName, Skip: true}, nil
+		}
+		return nil, err
+	}
+	if err := checkNoDependentViews(ct, stmt.ViewName, "drop materialized view"); err != nil {
+		return nil, err
+	}
+
+	return &DropMaterializedViewPlan{
+		ViewName: stmt.ViewName,
+	}, nil
+}
+
+func getMaterializedView(ct *catalog.Catalog, name string) (*catalog.TableSchema, error) {
+	if !ct.RelationExists(name) {
+		return nil, fmt.Errorf("materialized view \"%s\" does not exist", name)
+	}
+	tableSchema, err := ct.TableSchemas.Get(name)
+	if err != nil || !tableSchema.IsMaterializedView() {
+		return nil, fmt.Errorf("\"%s\" is not a materialized view", name)
+	}
+	return tableSchema, nil
+}
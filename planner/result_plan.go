@@ -0,0 +1,69 @@
+package planner
+
+import (
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/parser/statements"
+	"garakutadb/types"
+)
+
+// ResultPlan returns one row which is computed without tables, e.g. SELECT nextval('s')
+type ResultPlan struct {
+	ColumnNames []string
+	Values      []types.Value
+	// nextval or currval, which replaces the value of Values at the same index
+	SequenceCalls map[int]*SequenceCall
+	// the expressions computed at every execution, e.g. now(), which replace the values of Values at the same index
+	Expressions map[int]expression.Expression
+}
+
+func buildResultPlan(ct *catalog.Catalog, selectStmt *statements.SelectStmt) (Plan, error) {
+	// the values without columns are shown as text
+	column := &catalog.ColumnSchema{Type: types.Text}
+	values := make([]types.Value, 0, len(selectStmt.Values))
+	sequenceCalls := make(map[int]*SequenceCall)
+	expressions := make(map[int]expression.Expression)
+	for i, expr := range selectStmt.Values {
+		value, call, runtimeExpr, err := valueOrSequenceCall(ct, column, expr)
+		if err != nil {
+			return nil, err
+		}
+		if call != nil {
+			sequenceCalls[i] = call
+		}
+		if runtimeExpr != nil {
+			expressions[i] = runtimeExpr
+		}
+		values = append(values, value)
+	}
+
+	return &ResultPlan{
+		ColumnNames:   selectStmt.ColumnNames,
+		Values:        values,
+		SequenceCalls: sequenceCalls,
+		Expressions:   expressions,
+	}, nil
+}
+
+// valueOrSequenceCall converts a constant expression to a value of the column, or validates a call of nextval or currval
+// or an expression which is computed at every execution, e.g. now(), which gives the value. The value is NULL for them.
+func valueOrSequenceCall(ct *catalog.Catalog, column *catalog.ColumnSchema, expr expression.Expression) (types.Value, *SequenceCall, expression.Expression, error) {
+	if isSequenceCall(expr) {
+		call, err := sequenceCallOf(ct, expr.(*expression.FunctionExpression))
+		if err != nil {
+			return types.Value{}, nil, nil, err
+		}
+		return types.NewNull(column.Type), call, nil, nil
+	}
+	if err := checkNoSequenceCall(expr, "an expression"); err != nil {
+		return types.Value{}, nil, nil, err
+	}
+	if err := checkFunctions(nil, expr, ct.Functions()); err != nil {
+		return types.Value{}, nil, nil, err
+	}
+	if !expression.IsConstant(expr, ct.Functions()) {
+		return types.NewNull(column.Type), nil, expr, nil
+	}
+	value, err := literalToValue(column, expr, ct.Functions())
+	return value, nil, nil, err
+}
@@ -0,0 +1,52 @@
+package planner
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/parser/statements/ddl"
+)
+
+type CreateSchemaPlan struct {
+	SchemaName string
+	// the schema exists and IF NOT EXISTS is specified
+	Skip bool
+}
+
+func BuildCreateSchemaPlan(ct *catalog.Catalog, stmt *ddl.CreateSchemaStmt) (Plan, error) {
+	if ct.SchemaExists(stmt.SchemaName) {
+		if stmt.IfNotExists {
+			return &CreateSchemaPlan{SchemaName: stmt.SchemaName, Skip: true}, nil
+		}
+		return nil, fmt.Errorf("schema \"%s\" already exists", stmt.SchemaName)
+	}
+
+	return &CreateSchemaPlan{
+		SchemaName: stmt.SchemaName,
+	}, nil
+}
+
+type DropSchemaPlan struct {
+	SchemaName string
+	// the schema doesn't exist and IF EXISTS is specified
+	Skip bool
+}
+
+// BuildDropSchemaPlan drops an empty schema. public and the system schemas can't be dropped.
+func BuildDropSchemaPlan(ct *catalog.Catalog, stmt *ddl.DropSchemaStmt) (Plan, error) {
+	if stmt.SchemaName == catalog.PublicSchemaName || catalog.IsSystemSchema(stmt.SchemaName) {
+		return nil, fmt.Errorf("cannot drop schema %s because it is required by the database system", stmt.SchemaName)
+	}
+	if !ct.SchemaExists(stmt.SchemaName) {
+		if stmt.IfExists {
+			return &DropSchemaPlan{SchemaName: stmt.SchemaName, Skip: true}, nil
+		}
+		return nil, fmt.Errorf("schema \"%s\" does not exist", stmt.SchemaName)
+	}
+	if objects := ct.ObjectsInSchema(stmt.SchemaName); len(objects) > 0 {
+		return nil, fmt.Errorf("cannot drop schema %s because other objects depend on it: %s", stmt.SchemaName, objects[0])
+	}
+
+	return &DropSchemaPlan{
+		SchemaName: stmt.SchemaName,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package planner
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/parser"
+	"garakutadb/parser/statements"
+	"garakutadb/parser/statements/ddl"
+)
+
+// resolveNames replaces the names of relations and sequences in the statement with their names in the catalog.
+// Unqualified names are searched in the schemas of the search path, and new ones are created in the first schema.
+// The resolved relations are added to deps.
+func resolveNames(ct *catalog.Catalog, path catalog.SearchPath, stmt parser.Stmt, deps Dependencies) error {
+	relation := func(name *string) {
+		resolved := ct.ResolveRelation(*name, path)
+		deps.addName(ct, path, *name, resolved)
+		*name = resolved
+	}
+
+	switch s := stmt.(type) {
+	case *statements.SelectStmt:
+		if s.From != "" {
+			relation(&s.From)
+		}
+		resolveSequenceCalls(ct, path, s.Values, deps)
+	case *statements.InsertStmt:
+		relation(&s.Into)
+		resolveSequenceCalls(ct, path, s.Values, deps)
+	case *statements.UpdateStmt:
+		relation(&s.Target)
+	case *statements.DeleteStmt:
+		relation(&s.Target)
+	case *ddl.CreateTableStmt:
+		return resolveCreateTableNames(ct, path, s)
+	case *ddl.AlterTableStmt:
+		name := s.TableName
+		relation(&s.TableName)
+		if s.Action == ddl.RenameTable {
+			schema, _ := catalog.SplitRelationName(s.TableName)
+			s.NewName = catalog.QualifyRelationName(schema, s.NewName)
+		}
+		if s.Definition != nil {
+			s.Definition.Name = s.TableName
+			resolveForeignKeyNames(ct, path, s.Definition, name)
+		}
+	case *ddl.DropTableStmt:
+		relation(&s.TableName)
+	case *ddl.TruncateTableStmt:
+		relation(&s.TableName)
+	case *ddl.CreateSequenceStmt:
+		name, err := ct.CreationName(s.Sequence.Name, path)
+		if err != nil {
+			return err
+		}
+		s.Sequence.Name = name
+	case *ddl.DropSequenceStmt:
+		name := s.SequenceName
+		s.SequenceName = ct.ResolveSequence(s.SequenceName, path)
+		deps.addName(ct, path, name, s.SequenceName)
+	case *ddl.CreateViewStmt:
+		name, err := ct.CreationName(s.ViewName, path)
+		if err != nil {
+			return err
+		}
+		s.ViewName = name
+		relation(&s.Select.From)
+	case *ddl.DropViewStmt:
+		relation(&s.ViewName)
+	case *ddl.RefreshMaterializedViewStmt:
+		relation(&s.ViewName)
+	case *statements.ShowIndexesStmt:
+		relation(&s.TableName)
+	case *statements.DescribeStmt:
+		relation(&s.TableName)
+	}
+	return nil
+}
+
+// resolveCreateTableNames qualifies the table and the sequences owned by its columns with the schema which the table is created in
+func resolveCreateTableNames(ct *catalog.Catalog, path catalog.SearchPath, stmt *ddl.CreateTableStmt) error {
+	name := stmt.Into
+	qualified, err := ct.CreationName(name, path)
+	if err != nil {
+		return err
+	}
+	stmt.Into = qualified
+	stmt.TableSchema.Name = qualified
+
+	schema, _ := catalog.SplitRelationName(qualified)
+	for i := range stmt.Sequences {
+		sequence := &stmt.Sequences[i]
+		_, unqualified := catalog.SplitRelationName(sequence.Name)
+		newName := catalog.QualifyRelationName(schema, unqualified)
+		for j := range stmt.TableSchema.Columns {
+			column := &stmt.TableSchema.Columns[j]
+			if column.Sequence == sequence.Name {
+				defaultValue := fmt.Sprintf("nextval('%s')", newName)
+				column.Sequence = newName
+				column.Default = &defaultValue
+			}
+		}
+		sequence.Name = newName
+	}
+
+	resolveForeignKeyNames(ct, path, stmt.TableSchema, name)
+	return nil
+}
+
+// resolveForeignKeyNames resolves the referenced tables. A reference to the table itself by the name in the statement is kept.
+func resolveForeignKeyNames(ct *catalog.Catalog, path catalog.SearchPath, tableSchema *catalog.TableSchema, name string) {
+	for i := range tableSchema.ForeignKeys {
+		fk := &tableSchema.ForeignKeys[i]
+		if fk.RefTable == name {
+			fk.RefTable = tableSchema.Name
+		} else {
+			fk.RefTable = ct.ResolveRelation(fk.RefTable, path)
+		}
+	}
+}
+
+// resolveSequenceCalls resolves the sequences of nextval and currval
+func resolveSequenceCalls(ct *catalog.Catalog, path catalog.SearchPath, values []expression.Expression, deps Dependencies) {
+	for _, value := range values {
+		function, ok := value.(*expression.FunctionExpression)
+		if !ok || function.Name != FunctionNextval && function.Name != FunctionCurrval || len(function.Args) != 1 {
+			continue
+		}
+		if name, ok := function.Args[0].(*expression.ValueExpression); ok {
+			resolved := ct.ResolveSequence(name.Value, path)
+			deps.addName(ct, path, name.Value, resolved)
+			function.Args[0] = &expression.ValueExpression{Value: resolved}
+		}
+	}
+}
+
+// currentSchema returns the first schema of the search path which exists, where SHOW TABLES lists the tables
+func currentSchema(ct *catalog.Catalog, path catalog.SearchPath) string {
+	for _, schema := range path {
+		if ct.SchemaExists(schema) {
+			return schema
+		}
+	}
+	return catalog.PublicSchemaName
+}
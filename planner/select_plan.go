@@ -5,10 +5,21 @@ import (
 	"garakutadb/catalog"
 	"garakutadb/expression"
 	"garakutadb/parser/statements"
+	"garakutadb/types"
 	"slices"
 )
 
 func BuildSelectPlan(ct *catalog.Catalog, selectStmt *statements.SelectStmt) (Plan, error) {
+	if selectStmt.From == "" {
+		return buildResultPlan(ct, selectStmt)
+	}
+	if viewSchema, err := catalog.SystemViews.Get(selectStmt.From); err == nil {
+		return buildSystemViewScanPlan(viewSchema, selectStmt, ct.Functions())
+	}
+	if view, err := ct.Views.Get(selectStmt.From); err == nil {
+		return buildViewScanPlan(ct, view, selectStmt)
+	}
+
 	tableSchema, err := ct.TableSchemas.Get(selectStmt.From)
 	if err == catalog.TableSchemaNotFoundError {
 		return nil, fmt.Errorf("table not found: %s", selectStmt.From)
@@ -16,7 +27,132 @@ func BuildSelectPlan(ct *catalog.Catalog, selectStmt *statements.SelectStmt) (Pl
 	if err != nil {
 		return nil, err
 	}
+	if tableSchema.IsMaterializedView() && tableSchema.Materialized.Unpopulated {
+		return nil, fmt.Errorf("materialized view \"%s\" has not been populated", tableSchema.Name)
+	}
+
+	columnNames, columnOrders, projections, err := resolveSelectList(tableSchema, selectStmt, ct.Functions())
+	if err != nil {
+		return nil, err
+	}
+	aggregate, err := isAggregate(projections, ct.Functions())
+	if err != nil {
+		return nil, err
+	}
 
+	var whereExpression expression.Expression
+	if selectStmt.Where != nil {
+		if whereExpression, err = resolveWhere(tableSchema, selectStmt.Where.Expression, ct.Functions()); err != nil {
+			return nil, err
+		}
+		searchKeys, ok, err := primaryKeyLookup(tableSchema, whereExpression)
+		if err != nil {
+			return nil, err
+		}
+		// an aggregation reads all the rows
+		if ok && !aggregate {
+			return &IndexScanPlan{
+				TableName:    tableSchema.Name,
+				ColumnNames:  columnNames,
+				ColumnOrders: columnOrders,
+				Projections:  projections,
+				SearchKeys:   searchKeys,
+				IndexName:    tableSchema.PKConstraintName(),
+			}, nil
+		}
+	}
+
+	return &SeqScanPlan{
+		TableName:       tableSchema.Name,
+		ColumnNames:     columnNames,
+		ColumnOrders:    columnOrders,
+		Projections:     projections,
+		Aggregate:       aggregate,
+		WhereExpression: whereExpression,
+	}, nil
+}
+
+func buildSystemViewScanPlan(viewSchema *catalog.TableSchema, selectStmt *statements.SelectStmt, functions *expression.Functions) (Plan, error) {
+	columnNames, columnOrders, projections, err := resolveSelectList(viewSchema, selectStmt, functions)
+	if err != nil {
+		return nil, err
+	}
+	aggregate, err := isAggregate(projections, functions)
+	if err != nil {
+		return nil, err
+	}
+	if aggregate {
+		return nil, fmt.Errorf("aggregate functions are not supported for %s", viewSchema.Name)
+	}
+	var whereExpression expression.Expression
+	if selectStmt.Where != nil {
+		if whereExpression, err = resolveWhere(viewSchema, selectStmt.Where.Expression, functions); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SystemViewScanPlan{
+		ViewName:        viewSchema.Name,
+		ColumnNames:     columnNames,
+		ColumnOrders:    columnOrders,
+		Projections:     projections,
+		WhereExpression: whereExpression,
+	}, nil
+}
+
+// resolveSelectList resolves the select list to the columns, or validates the expressions computed from the columns
+func resolveSelectList(tableSchema *catalog.TableSchema, selectStmt *statements.SelectStmt, functions *expression.Functions) ([]string, []uint64, []expression.Expression, error) {
+	if selectStmt.Values == nil {
+		columnNames, columnOrders, err := resolveSelectColumns(tableSchema, selectStmt)
+		return columnNames, columnOrders, nil, err
+	}
+	projections := make([]expression.Expression, 0, len(selectStmt.Values))
+	for _, expr := range selectStmt.Values {
+		if err := checkNoSequenceCall(expr, "SELECT with FROM"); err != nil {
+			return nil, nil, nil, err
+		}
+		if err := checkColumns(tableSchema, expr, functions); err != nil {
+			return nil, nil, nil, err
+		}
+		projections = append(projections, expression.Fold(expr, functions))
+	}
+	return selectStmt.ColumnNames, nil, projections, nil
+}
+
+// isAggregate reports whether the select list has aggregate functions, which aggregate all the rows into one row
+func isAggregate(projections []expression.Expression, functions *expression.Functions) (bool, error) {
+	hasAggregate := func(expr expression.Expression) bool {
+		return expression.HasAggregate(expr, functions)
+	}
+	if !slices.ContainsFunc(projections, hasAggregate) {
+		return false, nil
+	}
+	for _, projection := range projections {
+		if err := expression.CheckAggregate(projection, functions); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// resolveWhere validates a condition and computes the function calls which give the same value for all the rows
+func resolveWhere(tableSchema *catalog.TableSchema, where expression.Expression, functions *expression.Functions) (expression.Expression, error) {
+	if where == nil {
+		return nil, nil
+	}
+	if err := checkNoSequenceCall(where, "WHERE"); err != nil {
+		return nil, err
+	}
+	if err := checkColumns(tableSchema, where, functions); err != nil {
+		return nil, err
+	}
+	if expression.HasAggregate(where, functions) {
+		return nil, fmt.Errorf("aggregate functions are not allowed in WHERE")
+	}
+	return expression.Fold(where, functions), nil
+}
+
+func resolveSelectColumns(tableSchema *catalog.TableSchema, selectStmt *statements.SelectStmt) ([]string, []uint64, error) {
 	columnNames := make([]string, 0)
 	columnOrders := make([]uint64, 0)
 	for _, col := range selectStmt.ColumnNames {
@@ -25,7 +161,7 @@ func BuildSelectPlan(ct *catalog.Catalog, selectStmt *statements.SelectStmt) (Pl
 			columnNames = append(columnNames, col)
 			columnOrders = append(columnOrders, order)
 		} else {
-			return nil, fmt.Errorf("column not found: %s", col)
+			return nil, nil, fmt.Errorf("column not found: %s", col)
 		}
 	}
 
@@ -38,28 +174,91 @@ func BuildSelectPlan(ct *catalog.Catalog, selectStmt *statements.SelectStmt) (Pl
 		}
 	}
 
-	var whereExpression expression.Expression
-	if selectStmt.Where != nil {
-		whereExpression = selectStmt.Where.Expression
-		compareExpr, ok := whereExpression.(*expression.ComparisonExpression)
-		// use index?
-		if ok &&
-			compareExpr.Operator == expression.OperatorEqual &&
-			compareExpr.Left.(*expression.ValueExpression).Value == tableSchema.PK {
-			return &IndexScanPlan{
-				TableName:    tableSchema.Name,
-				ColumnNames:  columnNames,
-				ColumnOrders: columnOrders,
-				SearchKey:    compareExpr.Right.(*expression.ValueExpression).Value,
-				IndexName:    tableSchema.PK,
-			}, nil
+	return columnNames, columnOrders, nil
+}
+
+// primaryKeyLookup returns the values of the primary key columns if where consists of equalities on all of them,
+// e.g. a = 1 AND b = 'x'. (= NULL is never true, so it is left to seq scan)
+func primaryKeyLookup(tableSchema *catalog.TableSchema, where expression.Expression) ([]types.Value, bool, error) {
+	if !tableSchema.HasPK() {
+		return nil, false, nil
+	}
+
+	equalities := make(map[string]*expression.ValueExpression)
+	var collect func(expr expression.Expression) bool
+	collect = func(expr expression.Expression) bool {
+		switch e := expr.(type) {
+		case *expression.AndExpression:
+			return collect(e.Left) && collect(e.Right)
+		case *expression.ComparisonExpression:
+			left, ok := e.Left.(*expression.ColumnExpression)
+			if !ok || e.Operator != expression.OperatorEqual {
+				return false
+			}
+			right, ok := e.Right.(*expression.ValueExpression)
+			if !ok || !slices.Contains(tableSchema.PK, left.Name) {
+				return false
+			}
+			if _, found := equalities[left.Name]; found {
+				return false
+			}
+			equalities[left.Name] = right
+			return true
+		default:
+			return false
 		}
 	}
+	if !collect(where) || len(equalities) != len(tableSchema.PK) {
+		return nil, false, nil
+	}
 
-	return &SeqScanPlan{
-		TableName:       tableSchema.Name,
-		ColumnNames:     columnNames,
-		ColumnOrders:    columnOrders,
-		WhereExpression: whereExpression,
-	}, nil
+	searchKeys := make([]types.Value, len(tableSchema.PK))
+	for i, order := range tableSchema.PKOrders() {
+		column := tableSchema.Columns[order]
+		value, err := expression.ConvertLiteral(equalities[column.Name], column.Type)
+		if err != nil {
+			return nil, false, err
+		}
+		// e.g. 20.5 never equals an integer. Such a condition is left to seq scan, which compares the values.
+		key, err := types.Cast(value, column.Type)
+		if err != nil {
+			return nil, false, nil
+		}
+		if c, err := types.Compare(key, value); err != nil || c != 0 {
+			return nil, false, nil
+		}
+		searchKeys[i] = key
+	}
+	return searchKeys, true, nil
+}
+
+// checkColumns returns an error if an expression refers to a column which the relation doesn't have, or calls a
+// function with wrong arguments
+func checkColumns(tableSchema *catalog.TableSchema, expr expression.Expression, functions *expression.Functions) error {
+	if expr == nil {
+		return nil
+	}
+	for _, name := range expression.Columns(expr) {
+		if _, found := tableSchema.Columns.Contains(name); !found {
+			return fmt.Errorf("column not found: %s", name)
+		}
+	}
+	return checkFunctions(tableSchema, expr, functions)
+}
+
+// checkFunctions checks the types of the arguments of the function calls in an expression. tableSchema is nil where
+// columns can't be referred.
+func checkFunctions(tableSchema *catalog.TableSchema, expr expression.Expression, functions *expression.Functions) error {
+	var columnType func(name string) (types.Type, error)
+	if tableSchema != nil {
+		columnType = func(name string) (types.Type, error) {
+			order, found := tableSchema.Columns.Contains(name)
+			if !found {
+				return types.Unknown, fmt.Errorf("column not found: %s", name)
+			}
+			return tableSchema.Columns[order].Type, nil
+		}
+	}
+	_, err := expression.TypeOf(expr, columnType, functions)
+	return err
 }
@@ -1,11 +1,27 @@
 package planner
 
-import "garakutadb/expression"
+import (
+	"garakutadb/expression"
+	"garakutadb/types"
+)
 
 type SeqScanPlan struct {
-	TableName       string
-	ColumnNames     []string
-	ColumnOrders    []uint64
+	TableName    string
+	ColumnNames  []string
+	ColumnOrders []uint64
+	// the select list computed from the columns, which replaces ColumnOrders if not nil
+	Projections []expression.Expression
+	// Projections have aggregate functions, and the rows are aggregated into one row
+	Aggregate       bool
+	WhereExpression expression.Expression
+}
+
+type SystemViewScanPlan struct {
+	ViewName     string
+	ColumnNames  []string
+	ColumnOrders []uint64
+	// the select list computed from the columns, which replaces ColumnOrders if not nil
+	Projections     []expression.Expression
 	WhereExpression expression.Expression
 }
 
@@ -13,6 +29,9 @@ type IndexScanPlan struct {
 	TableName    string
 	ColumnNames  []string
 	ColumnOrders []uint64
-	SearchKey    string
-	IndexName    string
+	// the select list computed from the columns, which replaces ColumnOrders if not nil
+	Projections []expression.Expression
+	// values of the columns of the index, in the order of the columns
+	SearchKeys []types.Value
+	IndexName  string
 }
@@ -0,0 +1,107 @@
+package planner
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/parser/statements/ddl"
+)
+
+type CreateSequencePlan struct {
+	Sequence *catalog.SequenceSchema
+	// the sequence exists and IF NOT EXISTS is specified
+	Skip bool
+}
+
+func BuildCreateSequencePlan(ct *catalog.Catalog, stmt *ddl.CreateSequenceStmt) (Plan, error) {
+	if ct.RelationExists(stmt.Sequence.Name) {
+		if _, err := ct.Sequences.Get(stmt.Sequence.Name); err == nil && stmt.IfNotExists {
+			return &CreateSequencePlan{Sequence: stmt.Sequence, Skip: true}, nil
+		}
+		return nil, relationExistsError(stmt.Sequence.Name)
+	}
+
+	return &CreateSequencePlan{
+		Sequence: stmt.Sequence,
+	}, nil
+}
+
+type DropSequencePlan struct {
+	SequenceName string
+	// the sequence doesn't exist and IF EXISTS is specified
+	Skip bool
+}
+
+func BuildDropSequencePlan(ct *catalog.Catalog, stmt *ddl.DropSequenceStmt) (Plan, error) {
+	if _, err := ct.Sequences.Get(stmt.SequenceName); err != nil {
+		if stmt.IfExists {
+			return &DropSequencePlan{SequenceName: stmt.SequenceName, Skip: true}, nil
+		}
+		return nil, sequenceNotFoundError(stmt.SequenceName)
+	}
+	if table, column, owned := ct.TableSchemas.SequenceOwner(stmt.SequenceName); owned {
+		return nil, fmt.Errorf("cannot drop sequence %s because column %s of table %s requires it", stmt.SequenceName, column.Name, table.Name)
+	}
+
+	return &DropSequencePlan{
+		SequenceName: stmt.SequenceName,
+	}, nil
+}
+
+func relationExistsError(name string) error {
+	return fmt.Errorf("relation \"%s\" already exists", name)
+}
+
+func sequenceNotFoundError(name string) error {
+	return fmt.Errorf("relation \"%s\" does not exist", name)
+}
+
+const (
+	FunctionNextval = "nextval"
+	FunctionCurrval = "currval"
+)
+
+// SequenceCall is nextval or currval of a sequence. It is evaluated by the executor every time the plan is executed.
+type SequenceCall struct {
+	Function     string
+	SequenceName string
+}
+
+// isSequenceCall reports whether an expression is a call of nextval or currval
+func isSequenceCall(expr expression.Expression) bool {
+	function, ok := expr.(*expression.FunctionExpression)
+	return ok && (function.Name == FunctionNextval || function.Name == FunctionCurrval)
+}
+
+// checkNoSequenceCall rejects nextval and currval in clause, which can have them only as a whole value of INSERT or of
+// SELECT without FROM
+func checkNoSequenceCall(expr expression.Expression, clause string) error {
+	for _, name := range expression.FunctionNames(expr) {
+		if name == FunctionNextval || name == FunctionCurrval {
+			return fmt.Errorf("%s is not allowed in %s", name, clause)
+		}
+	}
+	return nil
+}
+
+// sequenceCallOf validates a call of nextval or currval
+func sequenceCallOf(ct *catalog.Catalog, function *expression.FunctionExpression) (*SequenceCall, error) {
+	if !isSequenceCall(function) {
+		return nil, fmt.Errorf("function %s does not exist", function.Name)
+	}
+	if len(function.Args) != 1 {
+		return nil, fmt.Errorf("function %s takes one argument, but got %d", function.Name, len(function.Args))
+	}
+	name, ok := function.Args[0].(*expression.ValueExpression)
+	if !ok {
+		return nil, fmt.Errorf("the argument of %s must be the name of a sequence", function.Name)
+	}
+	if _, err := ct.Sequences.Get(name.Value); err != nil {
+		return nil, sequenceNotFoundError(name.Value)
+	}
+
+	return &SequenceCall{
+		Function:     function.Name,
+		SequenceName: name.Value,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package planner
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/parser/statements"
+)
+
+// SHOW and DESCRIBE are queries of information_schema
+
+// BuildShowTablesPlan lists the relations in the schema
+func BuildShowTablesPlan(ct *catalog.Catalog, schema string) (Plan, error) {
+	return BuildSelectPlan(ct, &statements.SelectStmt{
+		From:        catalog.InformationSchemaTablesViewName,
+		ColumnNames: []string{"table_name", "table_type"},
+		Where:       &statements.Where{Expression: equal("table_schema", schema)},
+	})
+}
+
+func BuildShowIndexesPlan(ct *catalog.Catalog, stmt *statements.ShowIndexesStmt) (Plan, error) {
+	if _, err := ct.TableSchemas.Get(stmt.TableName); err != nil {
+		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
+	}
+
+	return BuildSelectPlan(ct, &statements.SelectStmt{
+		From:        catalog.InformationSchemaStatisticsViewName,
+		ColumnNames: []string{"table_name", "index_name", "non_unique", "seq_in_index", "column_name"},
+		Where:       &statements.Where{Expression: relationCondition(stmt.TableName)},
+	})
+}
+
+func BuildDescribePlan(ct *catalog.Catalog, stmt *statements.DescribeStmt) (Plan, error) {
+	if _, err := ct.GetRelation(stmt.TableName); err != nil {
+		return nil, fmt.Errorf("table not found: %s", stmt.TableName)
+	}
+
+	return BuildSelectPlan(ct, &statements.SelectStmt{
+		From:        catalog.InformationSchemaColumnsViewName,
+		ColumnNames: []string{"column_name", "data_type", "is_nullable", "column_default"},
+		Where:       &statements.Where{Expression: relationCondition(stmt.TableName)},
+	})
+}
+
+// relationCondition selects the rows of the relation from a view of information_schema
+func relationCondition(name string) expression.Expression {
+	schema, table := catalog.SplitRelationName(name)
+	return &expression.AndExpression{
+		Left:  equal("table_schema", schema),
+		Right: equal("table_name", table),
+	}
+}
+
+func equal(column string, value string) expression.Expression {
+	return &expression.ComparisonExpression{
+		Operator: expression.OperatorEqual,
+		Left:     &expression.ColumnExpression{Name: column},
+		Right:    &expression.ValueExpression{Value: value},
+	}
+}
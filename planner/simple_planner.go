@@ -9,16 +9,42 @@ import (
 )
 
 type SimplePlanner struct {
-	catalog *catalog.Catalog
+	catalog    *catalog.Catalog
+	searchPath catalog.SearchPath
 }
 
-func NewSimplePlanner(catalog *catalog.Catalog) *SimplePlanner {
+func NewSimplePlanner(ct *catalog.Catalog) *SimplePlanner {
+	return NewSimplePlannerWithSearchPath(ct, catalog.DefaultSearchPath)
+}
+
+// NewSimplePlannerWithSearchPath returns a planner which resolves unqualified names with the search path of the session
+func NewSimplePlannerWithSearchPath(ct *catalog.Catalog, searchPath catalog.SearchPath) *SimplePlanner {
 	return &SimplePlanner{
-		catalog: catalog,
+		catalog:    ct,
+		searchPath: searchPath,
 	}
 }
 
 func (p *SimplePlanner) MakePlan(stmt parser.Stmt) (Plan, error) {
+	pl, _, err := p.MakePlanWithDependencies(stmt)
+	return pl, err
+}
+
+// MakePlanWithDependencies returns the plan and the relations which it depends on.
+// The plan can be executed again while the dependencies are valid.
+func (p *SimplePlanner) MakePlanWithDependencies(stmt parser.Stmt) (Plan, Dependencies, error) {
+	deps := make(Dependencies)
+	if err := resolveNames(p.catalog, p.searchPath, stmt, deps); err != nil {
+		return nil, nil, err
+	}
+	pl, err := p.makePlan(stmt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pl, deps, nil
+}
+
+func (p *SimplePlanner) makePlan(stmt parser.Stmt) (Plan, error) {
 	switch s := stmt.(type) {
 	case *statements.SelectStmt:
 		return BuildSelectPlan(p.catalog, s)
@@ -26,10 +52,42 @@ func (p *SimplePlanner) MakePlan(stmt parser.Stmt) (Plan, error) {
 		return BuildInsertPlan(p.catalog, s)
 	case *ddl.CreateTableStmt:
 		return BuildCreateTablePlan(p.catalog, s)
+	case *ddl.AlterTableStmt:
+		return BuildAlterTablePlan(p.catalog, s)
+	case *ddl.DropTableStmt:
+		return BuildDropTablePlan(p.catalog, s)
+	case *ddl.TruncateTableStmt:
+		return BuildTruncateTablePlan(p.catalog, s)
+	case *ddl.CreateSequenceStmt:
+		return BuildCreateSequencePlan(p.catalog, s)
+	case *ddl.DropSequenceStmt:
+		return BuildDropSequencePlan(p.catalog, s)
+	case *ddl.CreateViewStmt:
+		return BuildCreateViewPlan(p.catalog, s)
+	case *ddl.DropViewStmt:
+		return BuildDropViewPlan(p.catalog, s)
+	case *ddl.RefreshMaterializedViewStmt:
+		return BuildRefreshMaterializedViewPlan(p.catalog, s)
 	case *statements.DeleteStmt:
 		return BuildDeletePlan(p.catalog, s)
 	case *statements.UpdateStmt:
 		return BuildUpdatePlan(p.catalog, s)
+	case *statements.ShowTablesStmt:
+		return BuildShowTablesPlan(p.catalog, currentSchema(p.catalog, p.searchPath))
+	case *statements.ShowIndexesStmt:
+		return BuildShowIndexesPlan(p.catalog, s)
+	case *statements.DescribeStmt:
+		return BuildDescribePlan(p.catalog, s)
+	case *ddl.CreateSchemaStmt:
+		return BuildCreateSchemaPlan(p.catalog, s)
+	case *ddl.DropSchemaStmt:
+		return BuildDropSchemaPlan(p.catalog, s)
+	case *statements.KillStmt:
+		return BuildKillPlan(s)
+	case *statements.ExportSnapshotStmt:
+		return &ExportSnapshotPlan{}, nil
+	case *statements.SetTransactionSnapshotStmt:
+		return &ImportSnapshotPlan{SnapshotId: s.SnapshotId}, nil
 	default:
 		return nil, fmt.Errorf("not supported statement type: %T", s)
 	}
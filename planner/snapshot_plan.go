@@ -0,0 +1,8 @@
+package planner
+
+type ExportSnapshotPlan struct {
+}
+
+type ImportSnapshotPlan struct {
+	SnapshotId string
+}
@@ -5,17 +5,24 @@ import (
 	"garakutadb/catalog"
 	"garakutadb/expression"
 	"garakutadb/parser/statements"
+	"garakutadb/types"
 )
 
 type UpdatePlan struct {
-	TableName       string
-	ColumnNames     []string
-	ColumnOrders    []uint64
-	ColumnValues    []string
-	WhereExpression expression.Expression
+	TableName    string
+	ColumnNames  []string
+	ColumnOrders []uint64
+	ColumnValues []types.Value
+	// the new values which refer to the columns of the row. They are computed for each row and converted to the types of
+	// ColumnValues, which are NULL for them. nil for the constant values in ColumnValues.
+	ColumnExpressions []expression.Expression
+	WhereExpression   expression.Expression
 }
 
 func BuildUpdatePlan(ct *catalog.Catalog, updateStmt *statements.UpdateStmt) (Plan, error) {
+	if err := checkNotView(ct, updateStmt.Target, "update"); err != nil {
+		return nil, err
+	}
 	tableSchema, err := ct.TableSchemas.Get(updateStmt.Target)
 	if err == catalog.TableSchemaNotFoundError {
 		return nil, fmt.Errorf("table not found: %s", updateStmt.Target)
@@ -23,23 +30,50 @@ func BuildUpdatePlan(ct *catalog.Catalog, updateStmt *statements.UpdateStmt) (Pl
 
 	columnNames := make([]string, 0)
 	columnOrders := make([]uint64, 0)
-	columnValues := make([]string, 0)
+	columnValues := make([]types.Value, 0)
+	columnExpressions := make([]expression.Expression, 0)
 	for i, colName := range updateStmt.UpdatedColumnNames {
 		order, found := tableSchema.Columns.Contains(colName)
 		if found {
+			if tableSchema.Columns[order].Identity == catalog.IdentityAlways {
+				return nil, fmt.Errorf("column \"%s\" can only be updated to DEFAULT", colName)
+			}
+			expr := updateStmt.UpdatedColumnValues[i]
+			if err := checkNoSequenceCall(expr, "UPDATE SET"); err != nil {
+				return nil, err
+			}
+			if err := checkColumns(tableSchema, expr, ct.Functions()); err != nil {
+				return nil, err
+			}
+			value := types.NewNull(tableSchema.Columns[order].Type)
+			if expression.IsConstant(expr, ct.Functions()) {
+				if value, err = literalToValue(&tableSchema.Columns[order], expr, ct.Functions()); err != nil {
+					return nil, err
+				}
+				expr = nil
+			} else {
+				expr = expression.Fold(expr, ct.Functions())
+			}
 			columnNames = append(columnNames, colName)
 			columnOrders = append(columnOrders, order)
-			columnValues = append(columnValues, updateStmt.UpdatedColumnValues[i])
+			columnValues = append(columnValues, value)
+			columnExpressions = append(columnExpressions, expr)
 		} else {
 			return nil, fmt.Errorf("column not found: %s", colName)
 		}
 	}
 
+	where, err := resolveWhere(tableSchema, updateStmt.Where.Expression, ct.Functions())
+	if err != nil {
+		return nil, err
+	}
+
 	return &UpdatePlan{
-		TableName:       updateStmt.Target,
-		ColumnNames:     columnNames,
-		ColumnOrders:    columnOrders,
-		ColumnValues:    columnValues,
-		WhereExpression: updateStmt.Where.Expression,
+		TableName:         updateStmt.Target,
+		ColumnNames:       columnNames,
+		ColumnOrders:      columnOrders,
+		ColumnValues:      columnValues,
+		ColumnExpressions: columnExpressions,
+		WhereExpression:   where,
 	}, nil
 }
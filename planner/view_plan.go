@@ -0,0 +1,279 @@
+package planner
+
+import (
+	"fmt"
+	"garakutadb/catalog"
+	"garakutadb/expression"
+	"garakutadb/parser"
+	"garakutadb/parser/statements"
+	"garakutadb/parser/statements/ddl"
+)
+
+type CreateViewPlan struct {
+	View *catalog.ViewSchema
+	// CREATE OR REPLACE VIEW for an existing view
+	Replace bool
+}
+
+func BuildCreateViewPlan(ct *catalog.Catalog, stmt *ddl.CreateViewStmt) (Plan, error) {
+	if stmt.Materialized {
+		return buildCreateMaterializedViewPlan(ct, stmt)
+	}
+	existing, err := ct.Views.Get(stmt.ViewName)
+	replace := err == nil && stmt.OrReplace
+	if !replace && ct.RelationExists(stmt.ViewName) {
+		return nil, relationExistsError(stmt.ViewName)
+	}
+
+	selectStmt := stmt.Select
+	columns, _, err := planViewQuery(ct, stmt)
+	if err != nil {
+		return nil, err
+	}
+	if replace {
+		if err := checkReplacedViewColumns(existing.Columns, columns); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CreateViewPlan{
+		View: &catalog.ViewSchema{
+			Name:    stmt.ViewName,
+			Query:   stmt.Query,
+			From:    selectStmt.From,
+			Columns: columns,
+		},
+		Replace: replace,
+	}, nil
+}
+
+// planViewQuery validates the query of a view, and returns the columns of the view and the plan of the query
+func planViewQuery(ct *catalog.Catalog, stmt *ddl.CreateViewStmt) (catalog.ColumnSchemas, Plan, error) {
+	selectStmt := stmt.Select
+	if selectStmt.From == "" {
+		return nil, nil, fmt.Errorf("a view without FROM is not supported")
+	}
+	if selectStmt.Values != nil {
+		return nil, nil, fmt.Errorf("a view of computed columns is not supported")
+	}
+	// a view can't read itself through other views
+	for from := selectStmt.From; ; {
+		if from == stmt.ViewName {
+			return nil, nil, fmt.Errorf("infinite recursion detected in view %s", stmt.ViewName)
+		}
+		view, err := ct.Views.Get(from)
+		if err != nil {
+			break
+		}
+		from = view.From
+	}
+	source, err := ct.GetRelation(selectStmt.From)
+	if err != nil {
+		return nil, nil, fmt.Errorf("table not found: %s", selectStmt.From)
+	}
+	// the columns and the condition are validated by planning the query
+	query, err := BuildSelectPlan(ct, selectStmt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns, err := viewColumns(source, selectStmt, stmt.ColumnNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	return columns, query, nil
+}
+
+// viewColumns resolves the columns selected by the query. They are renamed by names.
+func viewColumns(source *catalog.TableSchema, selectStmt *statements.SelectStmt, names []string) (catalog.ColumnSchemas, error) {
+	_, columnOrders, err := resolveSelectColumns(source, selectStmt)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > len(columnOrders) {
+		return nil, fmt.Errorf("CREATE VIEW specifies more column names than columns")
+	}
+
+	columns := make(catalog.ColumnSchemas, 0, len(columnOrders))
+	for i, order := range columnOrders {
+		column := catalog.ColumnSchema{
+			Name: source.Columns[order].Name,
+			Type: source.Columns[order].Type,
+		}
+		if i < len(names) {
+			column.Name = names[i]
+		}
+		if _, found := columns.Contains(column.Name); found {
+			return nil, fmt.Errorf("column \"%s\" specified more than once", column.Name)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// checkReplacedViewColumns allows only adding columns to the end like PostgreSQL, because other views may read the columns
+func checkReplacedViewColumns(old catalog.ColumnSchemas, new catalog.ColumnSchemas) error {
+	if len(new) < len(old) {
+		return fmt.Errorf("cannot drop columns from view")
+	}
+	for i, column := range old {
+		if new[i].Name != column.Name {
+			return fmt.Errorf("cannot change name of view column \"%s\" to \"%s\"", column.Name, new[i].Name)
+		}
+		if new[i].Type != column.Type {
+			return fmt.Errorf("cannot change data type of view column \"%s\" from %s to %s", column.Name, column.Type, new[i].Type)
+		}
+	}
+	return nil
+}
+
+type DropViewPlan struct {
+	ViewName string
+	// the view doesn't exist and IF EXISTS is specified
+	Skip bool
+}
+
+func BuildDropViewPlan(ct *catalog.Catalog, stmt *ddl.DropViewStmt) (Plan, error) {
+	if stmt.Materialized {
+		return buildDropMaterializedViewPlan(ct, stmt)
+	}
+	if _, err := ct.Views.Get(stmt.ViewName); err != nil {
+		if ct.RelationExists(stmt.ViewName) {
+			return nil, fmt.Errorf("\"%s\" is not a view", stmt.ViewName)
+		}
+		if stmt.IfExists {
+			return &DropViewPlan{ViewName: stmt.ViewName, Skip: true}, nil
+		}
+		return nil, fmt.Errorf("view \"%s\" does not exist", stmt.ViewName)
+	}
+	if err := checkNoDependentViews(ct, stmt.ViewName, "drop view"); err != nil {
+		return nil, err
+	}
+
+	return &DropViewPlan{
+		ViewName: stmt.ViewName,
+	}, nil
+}
+
+// checkNoDependentViews returns an error if a view or a materialized view reads the relation, because its query would be broken
+func checkNoDependentViews(ct *catalog.Catalog, name string, action string) error {
+	if views := ct.DependentViews(name); len(views) > 0 {
+		return fmt.Errorf("cannot %s %s because view %s depends on it", action, name, views[0])
+	}
+	return nil
+}
+
+// checkNotView returns an error for INSERT, UPDATE and DELETE on a view, because views are read-only.
+// Materialized views are changed only by REFRESH MATERIALIZED VIEW.
+func checkNotView(ct *catalog.Catalog, name string, action string) error {
+	if _, err := ct.Views.Get(name); err == nil {
+		return fmt.Errorf("cannot %s view \"%s\"", action, name)
+	}
+	if tableSchema, err := ct.TableSchemas.Get(name); err == nil && tableSchema.IsMaterializedView() {
+		return fmt.Errorf("cannot change materialized view \"%s\"", name)
+	}
+	return nil
+}
+
+// notTableError is returned for DDL of tables on a materialized view
+func notTableError(name string) error {
+	return fmt.Errorf("\"%s\" is not a table", name)
+}
+
+// parseViewQuery parses the query of a view kept in the catalog
+func parseViewQuery(name string, query string) (*statements.SelectStmt, error) {
+	stmt, err := parser.NewSimpleParser().Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	selectStmt, ok := stmt.(*statements.SelectStmt)
+	if !ok {
+		return nil, fmt.Errorf("invalid query of view %s", name)
+	}
+	return selectStmt, nil
+}
+
+// buildViewScanPlan expands the view into its query. The selected columns and the condition are translated to the ones of
+// the relation which the view reads, and the condition of the view is added.
+func buildViewScanPlan(ct *catalog.Catalog, view *catalog.ViewSchema, selectStmt *statements.SelectStmt) (Plan, error) {
+	viewSelect, err := parseViewQuery(view.Name, view.Query)
+	if err != nil {
+		return nil, err
+	}
+	// the relation was resolved with the search path when the view was created
+	viewSelect.From = view.From
+	source, err := ct.GetRelation(viewSelect.From)
+	if err != nil {
+		return nil, fmt.Errorf("table not found: %s", viewSelect.From)
+	}
+	// SELECT * of the view is the first columns, because columns are only added to the end
+	sourceNames, _, err := resolveSelectColumns(source, viewSelect)
+	if err != nil {
+		return nil, err
+	}
+	if len(sourceNames) < len(view.Columns) {
+		return nil, fmt.Errorf("columns of view %s are missing in %s", view.Name, viewSelect.From)
+	}
+	sourceNameOf := make(map[string]string)
+	for i, column := range view.Columns {
+		sourceNameOf[column.Name] = sourceNames[i]
+	}
+
+	mapColumn := func(name string) (string, error) {
+		sourceName, ok := sourceNameOf[name]
+		if !ok {
+			return "", fmt.Errorf("column not found: %s", name)
+		}
+		return sourceName, nil
+	}
+	expanded := &statements.SelectStmt{
+		From:  viewSelect.From,
+		Where: &statements.Where{Expression: viewSelect.Where.Expression},
+	}
+	columnNames := selectStmt.ColumnNames
+	if selectStmt.Values == nil {
+		if columnNames, _, err = resolveSelectColumns(view.TableSchema(), selectStmt); err != nil {
+			return nil, err
+		}
+		expanded.ColumnNames = make([]string, 0, len(columnNames))
+		for _, name := range columnNames {
+			expanded.ColumnNames = append(expanded.ColumnNames, sourceNameOf[name])
+		}
+	} else {
+		expanded.ColumnNames = columnNames
+		for _, value := range selectStmt.Values {
+			mapped, err := expression.MapColumns(value, mapColumn)
+			if err != nil {
+				return nil, err
+			}
+			expanded.Values = append(expanded.Values, mapped)
+		}
+	}
+	if selectStmt.Where != nil && selectStmt.Where.Expression != nil {
+		where, err := expression.MapColumns(selectStmt.Where.Expression, mapColumn)
+		if err != nil {
+			return nil, err
+		}
+		if expanded.Where.Expression != nil {
+			where = &expression.AndExpression{Left: expanded.Where.Expression, Right: where}
+		}
+		expanded.Where.Expression = where
+	}
+
+	pl, err := BuildSelectPlan(ct, expanded)
+	if err != nil {
+		return nil, err
+	}
+	// the columns are shown with the names of the view
+	switch p := pl.(type) {
+	case *SeqScanPlan:
+		p.ColumnNames = columnNames
+	case *IndexScanPlan:
+		p.ColumnNames = columnNames
+	case *SystemViewScanPlan:
+		p.ColumnNames = columnNames
+	default:
+		return nil, fmt.Errorf("not supported plan of view %s: %T", view.Name, pl)
+	}
+	return pl, nil
+}
@@ -197,6 +197,37 @@ func (b *BTree) Search(item *StringItem) (*StringItem, bool) {
 	return b.Top.search(item)
 }
 
+// SearchNext returns the smallest item which is greater than item
+func (b *BTree) SearchNext(item *StringItem) (*StringItem, bool) {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+
+	if b.Top == nil {
+		return nil, false
+	}
+
+	return b.Top.searchNext(item)
+}
+
+func (n *Node) searchNext(item *StringItem) (*StringItem, bool) {
+	for i, itm := range n.Items {
+		if item.Less(itm) {
+			if len(n.Children) > 0 {
+				if next, found := n.Children[i].searchNext(item); found {
+					return next, true
+				}
+			}
+			return &itm, true
+		}
+	}
+
+	if len(n.Children) > 0 {
+		return n.Children[len(n.Children)-1].searchNext(item)
+	}
+
+	return nil, false
+}
+
 func (b *BTree) SearchAndUpdatePageId(item *StringItem) (*StringItem, bool) {
 	b.Mutex.Lock()
 	defer b.Mutex.Unlock()
@@ -160,3 +160,20 @@ func isBalanced(node *storage.Node) bool {
 
 	return true
 }
+
+func TestSearchNext(t *testing.T) {
+	btree := storage.NewBTree("test_table", "id")
+	for _, v := range []string{"c", "a", "k", "e", "i", "g", "m"} {
+		assert.Nil(t, btree.Insert(&storage.StringItem{Value: v}))
+	}
+
+	cases := map[string]string{"0": "a", "a": "c", "b": "c", "c": "e", "f": "g", "h": "i", "k": "m", "l": "m"}
+	for key, expected := range cases {
+		next, found := btree.SearchNext(&storage.StringItem{Value: key})
+		assert.True(t, found)
+		assert.Equal(t, expected, next.Value)
+	}
+
+	_, found := btree.SearchNext(&storage.StringItem{Value: "m"})
+	assert.False(t, found)
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// CommitLog records the status of every transaction, one byte per transaction id.
+// Transaction ids are reserved in chunks and the end of the reserved range is persisted,
+// so that ids are never reused after restart.
+// Every id below the persisted recovery watermark has finished, so the recovery at the next start begins there.
+
+const (
+	commitLogPath          = "commit_log"
+	transactionIdPath      = "transaction_id.json"
+	transactionIdChunkSize = 1024
+	// the number of the statuses of finished transactions kept in memory. Older statuses are read from the file again.
+	commitLogCacheSize = 4096
+
+	InvalidTransactionId     = TransactionId(0)
+	FirstNormalTransactionId = TransactionId(1)
+)
+
+type TransactionStatus uint8
+
+const (
+	TransactionStatusInProgress TransactionStatus = iota
+	TransactionStatusCommitted
+	TransactionStatusAborted
+)
+
+func (s TransactionStatus) String() string {
+	switch s {
+	case TransactionStatusInProgress:
+		return "in progress"
+	case TransactionStatusCommitted:
+		return "committed"
+	case TransactionStatusAborted:
+		return "aborted"
+	default:
+		return "unknown"
+	}
+}
+
+type transactionIdState struct {
+	ReservedUntil  TransactionId `json:"reservedUntil"`
+	RecoveredUntil TransactionId `json:"recoveredUntil"`
+}
+
+type cachedStatus struct {
+	id     TransactionId
+	status TransactionStatus
+}
+
+type CommitLog struct {
+	mutex *sync.Mutex
+
+	storage *Storage
+	file    *os.File
+
+	nextTransactionId TransactionId
+	reservedUntil     TransactionId
+	recoveredUntil    TransactionId
+
+	// the statuses of finished transactions, which never change, at id % commitLogCacheSize
+	finished []cachedStatus
+}
+
+// OpenCommitLog opens the commit log and marks transactions which were in progress at the last shutdown as aborted.
+func OpenCommitLog(st *Storage) (*CommitLog, error) {
+	file, err := os.OpenFile(st.diskManager.makeGeneralFilePath(commitLogPath), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	state := transactionIdState{ReservedUntil: FirstNormalTransactionId, RecoveredUntil: FirstNormalTransactionId}
+	if err := st.ReadJson(transactionIdPath, &state); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	cl := &CommitLog{
+		mutex:             new(sync.Mutex),
+		storage:           st,
+		file:              file,
+		nextTransactionId: state.ReservedUntil,
+		reservedUntil:     state.ReservedUntil,
+		recoveredUntil:    max(state.RecoveredUntil, FirstNormalTransactionId),
+		finished:          make([]cachedStatus, commitLogCacheSize),
+	}
+
+	if err := cl.recover(); err != nil {
+		return nil, err
+	}
+	return cl, nil
+}
+
+// recover marks every transaction id used by the previous processes which has not finished as aborted.
+// The ids below the recovery watermark have been recovered by a previous start.
+func (cl *CommitLog) recover() error {
+	if cl.recoveredUntil >= cl.nextTransactionId {
+		return nil
+	}
+
+	statuses := make([]byte, cl.nextTransactionId-cl.recoveredUntil)
+	n, err := cl.file.ReadAt(statuses, int64(cl.recoveredUntil))
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	for i := range statuses {
+		if i < n && TransactionStatus(statuses[i]) != TransactionStatusInProgress {
+			continue
+		}
+		if err := cl.writeStatus(cl.recoveredUntil+TransactionId(i), TransactionStatusAborted); err != nil {
+			return err
+		}
+	}
+	if err := cl.file.Sync(); err != nil {
+		return err
+	}
+
+	state := transactionIdState{ReservedUntil: cl.reservedUntil, RecoveredUntil: cl.nextTransactionId}
+	if err := cl.storage.WriteJson(transactionIdPath, &state); err != nil {
+		return err
+	}
+	cl.recoveredUntil = cl.nextTransactionId
+	return nil
+}
+
+// NextTransactionId allocates a new transaction id
+func (cl *CommitLog) NextTransactionId() (TransactionId, error) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	if cl.nextTransactionId >= cl.reservedUntil {
+		reservedUntil := cl.nextTransactionId + transactionIdChunkSize
+		state := transactionIdState{ReservedUntil: reservedUntil, RecoveredUntil: cl.recoveredUntil}
+		if err := cl.storage.WriteJson(transactionIdPath, &state); err != nil {
+			return InvalidTransactionId, err
+		}
+		cl.reservedUntil = reservedUntil
+	}
+
+	id := cl.nextTransactionId
+	cl.nextTransactionId++
+	return id, nil
+}
+
+// peekNextTransactionId returns the id which will be allocated next
+func (cl *CommitLog) peekNextTransactionId() TransactionId {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	return cl.nextTransactionId
+}
+
+// GetStatus returns the status of the transaction. The statuses of recently finished transactions are cached.
+func (cl *CommitLog) GetStatus(id TransactionId) (TransactionStatus, error) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	if cached := cl.finished[id%commitLogCacheSize]; cached.id == id && cached.status != TransactionStatusInProgress {
+		return cached.status, nil
+	}
+	status, err := cl.readStatus(id)
+	if err != nil {
+		return status, err
+	}
+	cl.cacheStatus(id, status)
+	return status, nil
+}
+
+// SetStatus persists the status of the transaction. The commit log is synced to disk before returning.
+func (cl *CommitLog) SetStatus(id TransactionId, status TransactionStatus) error {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	if err := cl.writeStatus(id, status); err != nil {
+		return err
+	}
+	if err := cl.file.Sync(); err != nil {
+		return err
+	}
+	cl.cacheStatus(id, status)
+	return nil
+}
+
+// cacheStatus keeps the status of a finished transaction in place of the one whose id has the same remainder
+func (cl *CommitLog) cacheStatus(id TransactionId, status TransactionStatus) {
+	if status != TransactionStatusInProgress {
+		cl.finished[id%commitLogCacheSize] = cachedStatus{id: id, status: status}
+	}
+}
+
+func (cl *CommitLog) Close() error {
+	return cl.file.Close()
+}
+
+func (cl *CommitLog) readStatus(id TransactionId) (TransactionStatus, error) {
+	b := make([]byte, 1)
+	if _, err := cl.file.ReadAt(b, int64(id)); err != nil {
+		if err == io.EOF {
+			return TransactionStatusInProgress, nil
+		}
+		return TransactionStatusInProgress, err
+	}
+	return TransactionStatus(b[0]), nil
+}
+
+func (cl *CommitLog) writeStatus(id TransactionId, status TransactionStatus) error {
+	_, err := cl.file.WriteAt([]byte{byte(status)}, int64(id))
+	return err
+}
@@ -0,0 +1,77 @@
+package storage_test
+
+import (
+	"garakutadb/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTransactionIdIsPersisted(t *testing.T) {
+	st := storage.NewStorage(storage.NewDiskManager(t.TempDir()))
+
+	txMgr, err := storage.NewTransactionManager(st)
+	assert.Nil(t, err)
+	committed, _ := txMgr.Begin()
+	aborted, _ := txMgr.Begin()
+	running, _ := txMgr.Begin()
+	assert.Nil(t, txMgr.Commit(committed))
+	assert.Nil(t, txMgr.Abort(aborted))
+
+	// restart
+	txMgr, err = storage.NewTransactionManager(st)
+	assert.Nil(t, err)
+	tx, _ := txMgr.Begin()
+	assert.Greater(t, tx.GetId(), running.GetId())
+
+	status, err := txMgr.GetTransactionStatus(committed.GetId())
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TransactionStatusCommitted, status)
+	status, err = txMgr.GetTransactionStatus(aborted.GetId())
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TransactionStatusAborted, status)
+	// the transaction running at the restart is aborted by the recovery
+	status, err = txMgr.GetTransactionStatus(running.GetId())
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TransactionStatusAborted, status)
+	status, err = txMgr.GetTransactionStatus(tx.GetId())
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TransactionStatusInProgress, status)
+
+	// the next recovery starts from where the previous one ended
+	txMgr, err = storage.NewTransactionManager(st)
+	assert.Nil(t, err)
+	status, err = txMgr.GetTransactionStatus(tx.GetId())
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TransactionStatusAborted, status)
+	status, err = txMgr.GetTransactionStatus(committed.GetId())
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TransactionStatusCommitted, status)
+}
+
+func TestStatusesOutsideOfCacheAreReadFromFile(t *testing.T) {
+	st := storage.NewStorage(storage.NewDiskManager(t.TempDir()))
+	txMgr, err := storage.NewTransactionManager(st)
+	assert.Nil(t, err)
+
+	// more transactions than the statuses kept in memory
+	ids := make([]storage.TransactionId, 0)
+	for i := 0; i < 5000; i++ {
+		tx, err := txMgr.Begin()
+		assert.Nil(t, err)
+		if i%2 == 0 {
+			assert.Nil(t, txMgr.Commit(tx))
+		} else {
+			assert.Nil(t, txMgr.Abort(tx))
+		}
+		ids = append(ids, tx.GetId())
+	}
+	for i, id := range ids {
+		expected := storage.TransactionStatusCommitted
+		if i%2 == 1 {
+			expected = storage.TransactionStatusAborted
+		}
+		status, err := txMgr.GetTransactionStatus(id)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, status)
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 type DiskManager struct {
@@ -27,6 +28,100 @@ func (d *DiskManager) makeGeneralFilePath(path string) string {
 	return fmt.Sprintf("%s/%s", d.BasePath, path)
 }
 
+// makeTableDirPath returns the directory of the table. The base directory is returned for the empty name.
+func (d *DiskManager) makeTableDirPath(tableName string) string {
+	return fmt.Sprintf("%s/%s", d.BasePath, tableName)
+}
+
+func (d *DiskManager) createTableDir(tableName string) error {
+	return os.MkdirAll(d.makeTableDirPath(tableName), 0755)
+}
+
+func (d *DiskManager) removeTableDir(tableName string) error {
+	return os.RemoveAll(d.makeTableDirPath(tableName))
+}
+
+func (d *DiskManager) renameTableDir(from string, to string) error {
+	return os.Rename(d.makeTableDirPath(from), d.makeTableDirPath(to))
+}
+
+// renameTable moves the directory of the table, and renames the page files and the indexes which have the name of the table
+func (d *DiskManager) renameTable(from string, to string) error {
+	if _, err := os.Stat(d.makeTableDirPath(to)); err == nil {
+		return fmt.Errorf("directory of table %s already exists", to)
+	}
+	if err := d.renameTableDir(from, to); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(d.makeTableDirPath(to))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if indexName, ok := strings.CutSuffix(name, ".json"); ok {
+			btree, err := d.ReadIndex(to, indexName)
+			if err != nil {
+				return err
+			}
+			btree.TableName = to
+			if err := d.WriteIndex(btree); err != nil {
+				return err
+			}
+		} else if pageId, ok := strings.CutPrefix(name, from+"_"); ok {
+			dir := d.makeTableDirPath(to)
+			if err := os.Rename(dir+"/"+name, dir+"/"+to+"_"+pageId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *DiskManager) renameFile(tableName string, from string, to string) error {
+	dir := d.makeTableDirPath(tableName)
+	return os.Rename(dir+"/"+from, dir+"/"+to)
+}
+
+// replaceFile renames the file over another one, and syncs the directory so that the rename survives a crash
+func (d *DiskManager) replaceFile(tableName string, from string, to string) error {
+	if err := d.renameFile(tableName, from, to); err != nil {
+		return err
+	}
+	return d.syncDir(tableName)
+}
+
+func (d *DiskManager) syncDir(tableName string) error {
+	dir, err := os.Open(d.makeTableDirPath(tableName))
+	if err != nil {
+		return err
+	}
+	if err := dir.Sync(); err != nil {
+		_ = dir.Close()
+		return err
+	}
+	return dir.Close()
+}
+
+// exists reports whether the file in the directory of the table exists. The directory is checked for the empty file name.
+func (d *DiskManager) exists(tableName string, fileName string) bool {
+	path := d.makeTableDirPath(tableName)
+	if fileName != "" {
+		path += "/" + fileName
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// removeFile removes a file in the directory of the table. It is not an error if the file has been removed with the table.
+func (d *DiskManager) removeFile(tableName string, name string) error {
+	if err := os.Remove(d.makeTableDirPath(tableName) + "/" + name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 func (d *DiskManager) readPage(tableName string, pageId PageId) (*Page, error) {
 	b, err := os.ReadFile(d.makePageFilePath(tableName, pageId))
 	if err != nil {
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var TransactionKilledError = errors.New("terminating transaction killed by another session")
+
+type TransactionInfo struct {
+	Id             TransactionId
+	State          TransactionState
+	IsolationLevel IsolationLevel
+	StartTime      time.Time
+	Statement      string
+	LocksHeld      int
+}
+
+type LockInfo struct {
+	Resource LockResource
+	Mode     LockMode
+	Holder   TransactionId
+	Waiters  []TransactionId
+}
+
+func (r LockResource) String() string {
+	switch r.Type {
+	case DatabaseResource:
+		return "database"
+	case TableResource:
+		return r.TableName
+	case PageResource:
+		return fmt.Sprintf("%s page %d", r.TableName, r.PageId)
+	case TupleResource:
+		return fmt.Sprintf("%s (%d,%d)", r.TableName, r.PageId, r.SlotId)
+	case KeyRangeResource:
+		if r.Supremum {
+			return fmt.Sprintf("%s.%s (..., supremum)", r.TableName, r.IndexName)
+		}
+		return fmt.Sprintf("%s.%s (..., %s]", r.TableName, r.IndexName, r.Key)
+	case KeyResource:
+		return fmt.Sprintf("%s.%s [%s]", r.TableName, r.IndexName, r.Key)
+	default:
+		return "unknown"
+	}
+}
+
+// SetStatement records the statement which tx is running, for introspection.
+func (tm *TransactionManager) SetStatement(tx *Transaction, statement string) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tx.statement = statement
+}
+
+// GetTransactionInfos returns the transactions which are running, ordered by id.
+func (tm *TransactionManager) GetTransactionInfos() []TransactionInfo {
+	lockCounts := tm.lockManager.getLockCounts()
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	infos := make([]TransactionInfo, 0)
+	for _, tx := range tm.transactions {
+		if tx.state != ACTIVE {
+			continue
+		}
+		infos = append(infos, TransactionInfo{
+			Id:             tx.id,
+			State:          tx.state,
+			IsolationLevel: tx.isolationLevel,
+			StartTime:      tx.startTime,
+			Statement:      tx.statement,
+			LocksHeld:      lockCounts[tx.id],
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Id < infos[j].Id
+	})
+	return infos
+}
+
+// GetLockInfos returns all locks granted, ordered by holder and resource.
+func (tm *TransactionManager) GetLockInfos() []LockInfo {
+	return tm.lockManager.getLockInfos()
+}
+
+// StartStatement is called by the session before it runs a statement of tx, so that KILL doesn't abort tx meanwhile
+func (tm *TransactionManager) StartStatement(tx *Transaction) {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+
+	tx.running = true
+}
+
+// EndStatement is called by the session after a statement of tx has finished
+func (tm *TransactionManager) EndStatement(tx *Transaction) {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+
+	tx.running = false
+}
+
+// Kill marks a transaction of another session as killed. An idle transaction is aborted here, which releases its locks.
+// A transaction running a statement is not, because its session may be changing the data; the session aborts it
+// at the next statement or lock (see AbortIfKilled), which releases its locks after its changes have been reverted.
+func (tm *TransactionManager) Kill(id TransactionId) error {
+	tm.mutex.Lock()
+	tx, ok := tm.transactions[id]
+	tm.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("transaction %d is not running", id)
+	}
+
+	// the session can't start a statement until the transaction is aborted
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+
+	tm.mutex.Lock()
+	if tx.state != ACTIVE || tx.IsKilled() {
+		tm.mutex.Unlock()
+		return fmt.Errorf("transaction %d is not running", id)
+	}
+	tx.killed.Store(true)
+	tm.mutex.Unlock()
+
+	if tx.running {
+		return nil
+	}
+	return tm.Abort(tx)
+}
+
+// AbortIfKilled aborts tx if it has been killed, and returns TransactionKilledError once.
+// It must be called by the session running tx.
+func (tm *TransactionManager) AbortIfKilled(tx *Transaction) error {
+	if !tx.IsKilled() || tx.killReported {
+		return nil
+	}
+	if tx.GetState() == ACTIVE {
+		if err := tm.Abort(tx); err != nil {
+			return err
+		}
+	}
+	tx.killReported = true
+	return TransactionKilledError
+}
+
+func (lm *LockManager) getLockCounts() map[TransactionId]int {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	counts := make(map[TransactionId]int, 0)
+	for id, locks := range lm.txLocks {
+		counts[id] = len(locks)
+	}
+	return counts
+}
+
+func (lm *LockManager) getLockInfos() []LockInfo {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	infos := make([]LockInfo, 0)
+	for resource, entry := range lm.locks {
+		waiters := make([]TransactionId, 0)
+		for id := range entry.waiters {
+			waiters = append(waiters, id)
+		}
+		sort.Slice(waiters, func(i, j int) bool {
+			return waiters[i] < waiters[j]
+		})
+
+		for holder, mode := range entry.holders {
+			infos = append(infos, LockInfo{
+				Resource: resource,
+				Mode:     mode,
+				Holder:   holder,
+				Waiters:  waiters,
+			})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Holder != infos[j].Holder {
+			return infos[i].Holder < infos[j].Holder
+		}
+		if infos[i].Resource.Type != infos[j].Resource.Type {
+			return infos[i].Resource.Type < infos[j].Resource.Type
+		}
+		return infos[i].Resource.String() < infos[j].Resource.String()
+	})
+	return infos
+}
@@ -0,0 +1,27 @@
+package storage_test
+
+import (
+	"garakutadb/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKillLeavesRunningTransactionToItsSession(t *testing.T) {
+	txMgr := newTransactionManager(t)
+
+	// a new transaction is running until its first statement ends
+	running, _ := txMgr.Begin()
+	assert.Nil(t, txMgr.Kill(running.GetId()))
+	assert.Equal(t, storage.ACTIVE, running.GetState())
+	assert.ErrorIs(t, txMgr.AbortIfKilled(running), storage.TransactionKilledError)
+	assert.Equal(t, storage.ABORTED, running.GetState())
+	assert.Nil(t, txMgr.AbortIfKilled(running))
+
+	idle, _ := txMgr.Begin()
+	txMgr.EndStatement(idle)
+	assert.Nil(t, txMgr.Kill(idle.GetId()))
+	assert.Equal(t, storage.ABORTED, idle.GetState())
+	txMgr.StartStatement(idle)
+	assert.ErrorIs(t, txMgr.AbortIfKilled(idle), storage.TransactionKilledError)
+	assert.NotNil(t, txMgr.Kill(idle.GetId()))
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The journal makes the changes of files by a transaction recoverable, e.g. creating, dropping and renaming tables.
+// A change is appended to the journal of the transaction and synced before it is made, and the journal is removed
+// when the transaction finishes. If the process stops before that, recoverJournals reverts the changes of the
+// transactions which have not been committed, and finishes the commit actions of the committed ones.
+// Rows don't need the journal, because the commit log decides their visibility. Index entries have no versions, so
+// their changes are journaled and reverted like the files.
+
+const journalFilePrefix = "journal_"
+
+type journalOp string
+
+const (
+	journalCreateTable journalOp = "createTable"
+	journalDropTable   journalOp = "dropTable"
+	journalRenameTable journalOp = "renameTable"
+	// a file in the directory of a table or in the base directory
+	journalCreateFile journalOp = "createFile"
+	journalDropFile   journalOp = "dropFile"
+	// a file replaced by a prepared file on commit
+	journalReplaceFile journalOp = "replaceFile"
+	// an entry of an index
+	journalInsertIndexItem journalOp = "insertIndexItem"
+	journalDeleteIndexItem journalOp = "deleteIndexItem"
+	journalUpdateIndexItem journalOp = "updateIndexItem"
+)
+
+type journalRecord struct {
+	Op journalOp `json:"op"`
+	// the table, or the directory of the file. Empty for the base directory.
+	TableName string `json:"tableName,omitempty"`
+	// the file, or the new name of a renamed table
+	FileName string `json:"fileName,omitempty"`
+	// where a dropped table or file is moved, or the prepared file which replaces FileName
+	TrashName string `json:"trashName,omitempty"`
+	// the index, the key of the entry and the page of a deleted entry or the old page of an updated entry
+	IndexName string `json:"indexName,omitempty"`
+	Key       string `json:"key,omitempty"`
+	PageId    PageId `json:"pageId,omitempty"`
+}
+
+func journalFileName(id TransactionId) string {
+	return fmt.Sprintf("%s%d", journalFilePrefix, id)
+}
+
+// journal appends record to the journal of tx and syncs it before the change is made
+func (st *Storage) journal(tx *Transaction, record journalRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(st.diskManager.makeGeneralFilePath(journalFileName(tx.id)), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	tx.journaled = true
+	return file.Close()
+}
+
+// removeJournal is called after tx is committed or aborted, and all of its changes are finished
+func (st *Storage) removeJournal(tx *Transaction) error {
+	if !tx.journaled {
+		return nil
+	}
+	tx.journaled = false
+	return st.diskManager.removeFile("", journalFileName(tx.id))
+}
+
+// recoverJournals finishes the transactions which were running when the process stopped. It is called after the
+// commit log is recovered, so the transactions which have not been committed are aborted.
+func (st *Storage) recoverJournals(cl *CommitLog) error {
+	entries, err := os.ReadDir(st.diskManager.BasePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), journalFilePrefix)
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseUint(suffix, 10, 64)
+		if err != nil {
+			continue
+		}
+		records, err := st.readJournal(entry.Name())
+		if err != nil {
+			return err
+		}
+		status, err := cl.GetStatus(TransactionId(id))
+		if err != nil {
+			return err
+		}
+
+		if status == TransactionStatusCommitted {
+			for _, record := range records {
+				if err := st.finishJournalRecord(record); err != nil {
+					return err
+				}
+			}
+		} else {
+			for i := len(records) - 1; i >= 0; i-- {
+				if err := st.revertJournalRecord(records[i]); err != nil {
+					return err
+				}
+			}
+		}
+		if err := st.diskManager.removeFile("", entry.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readJournal reads the records. A record which was being appended is ignored, because its change was not made.
+func (st *Storage) readJournal(fileName string) ([]journalRecord, error) {
+	file, err := os.Open(st.diskManager.makeGeneralFilePath(fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := make([]journalRecord, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			break
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// revertJournalRecord undoes a change of an aborted transaction. The change may have been undone or not made at all.
+func (st *Storage) revertJournalRecord(record journalRecord) error {
+	dm := st.diskManager
+	switch record.Op {
+	case journalCreateTable:
+		return dm.removeTableDir(record.TableName)
+	case journalDropTable:
+		if dm.exists(record.TrashName, "") {
+			return dm.renameTableDir(record.TrashName, record.TableName)
+		}
+	case journalRenameTable:
+		if dm.exists(record.FileName, "") && !dm.exists(record.TableName, "") {
+			return dm.renameTable(record.FileName, record.TableName)
+		}
+	case journalCreateFile:
+		return dm.removeFile(record.TableName, record.FileName)
+	case journalDropFile:
+		if dm.exists(record.TableName, record.TrashName) {
+			return dm.renameFile(record.TableName, record.TrashName, record.FileName)
+		}
+	case journalReplaceFile:
+		return dm.removeFile(record.TableName, record.TrashName)
+	case journalInsertIndexItem, journalDeleteIndexItem, journalUpdateIndexItem:
+		return st.revertIndexItem(record)
+	default:
+		return fmt.Errorf("unknown journal record: %s", record.Op)
+	}
+	return nil
+}
+
+// revertIndexItem puts an entry of an index back. The index may have been dropped with its table in the transaction.
+func (st *Storage) revertIndexItem(record journalRecord) error {
+	btree, err := st.diskManager.ReadIndex(record.TableName, record.IndexName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	item, found := btree.Search(&StringItem{Value: record.Key})
+	switch record.Op {
+	case journalInsertIndexItem:
+		if !found {
+			return nil
+		}
+		btree.Delete(&StringItem{Value: record.Key})
+	case journalDeleteIndexItem:
+		if found {
+			return nil
+		}
+		if err := btree.Insert(&StringItem{Value: record.Key, PageId: record.PageId}); err != nil {
+			return err
+		}
+	case journalUpdateIndexItem:
+		if !found || item.PageId == record.PageId {
+			return nil
+		}
+		btree.SearchAndUpdatePageId(&StringItem{Value: record.Key, PageId: record.PageId})
+	}
+	return st.diskManager.WriteIndex(btree)
+}
+
+// finishJournalRecord runs the commit action of a change of a committed transaction. The action may have been run.
+func (st *Storage) finishJournalRecord(record journalRecord) error {
+	dm := st.diskManager
+	switch record.Op {
+	case journalDropTable:
+		return dm.removeTableDir(record.TrashName)
+	case journalDropFile:
+		return dm.removeFile(record.TableName, record.TrashName)
+	case journalReplaceFile:
+		if dm.exists(record.TableName, record.TrashName) {
+			return dm.replaceFile(record.TableName, record.TrashName, record.FileName)
+		}
+	}
+	return nil
+}
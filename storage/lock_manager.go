@@ -0,0 +1,489 @@
+package storage
+
+import (
+	"sync"
+)
+
+// LockManager implements multi-granularity locking (database > table > page > tuple).
+// Key ranges of indexes are locked under their tables for next-key locking.
+// Before locking a resource in S (X) mode, a transaction must hold IS (IX) or stronger locks on all its ancestors.
+// Locks are never waited for; a request which conflicts with another transaction is rejected immediately.
+
+const DefaultLockEscalationThreshold = 1000
+
+type LockMode int32
+
+const (
+	IntentionShared LockMode = iota
+	IntentionExclusive
+	Shared
+	SharedIntentionExclusive
+	Exclusive
+)
+
+func (m LockMode) String() string {
+	switch m {
+	case IntentionShared:
+		return "IS"
+	case IntentionExclusive:
+		return "IX"
+	case Shared:
+		return "S"
+	case SharedIntentionExclusive:
+		return "SIX"
+	case Exclusive:
+		return "X"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// lockCompatibility[granted][requested]
+var lockCompatibility = [5][5]bool{
+	IntentionShared:          {true, true, true, true, false},
+	IntentionExclusive:       {true, true, false, false, false},
+	Shared:                   {true, false, true, false, false},
+	SharedIntentionExclusive: {true, false, false, false, false},
+	Exclusive:                {false, false, false, false, false},
+}
+
+func (m LockMode) isCompatibleWith(other LockMode) bool {
+	return lockCompatibility[m][other]
+}
+
+// combine returns the weakest mode which is at least as strong as both m and other
+func (m LockMode) combine(other LockMode) LockMode {
+	if m == other {
+		return m
+	}
+	if m == Exclusive || other == Exclusive {
+		return Exclusive
+	}
+	if m == SharedIntentionExclusive || other == SharedIntentionExclusive {
+		return SharedIntentionExclusive
+	}
+	if (m == Shared && other == IntentionExclusive) || (m == IntentionExclusive && other == Shared) {
+		return SharedIntentionExclusive
+	}
+	// one of them is IS
+	if m == IntentionShared {
+		return other
+	}
+	return m
+}
+
+// covers reports whether holding m makes a lock of other mode unnecessary
+func (m LockMode) covers(other LockMode) bool {
+	return m.combine(other) == m
+}
+
+// coversDescendants reports whether holding m on a resource implies other mode on all its descendants
+func (m LockMode) coversDescendants(other LockMode) bool {
+	switch m {
+	case Exclusive:
+		return true
+	case Shared, SharedIntentionExclusive:
+		return other == Shared || other == IntentionShared
+	default:
+		return false
+	}
+}
+
+func (m LockMode) intention() LockMode {
+	if m == Shared || m == IntentionShared {
+		return IntentionShared
+	}
+	return IntentionExclusive
+}
+
+type LockResourceType int32
+
+const (
+	DatabaseResource LockResourceType = iota
+	TableResource
+	PageResource
+	TupleResource
+	// KeyRangeResource is the gap in an index between Key and its previous key (next-key locking)
+	KeyRangeResource
+	// KeyResource is a key of an index, which is locked while it is deleted, so that no other transaction can
+	// insert the key until the deletion is committed or undone
+	KeyResource
+)
+
+func (t LockResourceType) String() string {
+	switch t {
+	case DatabaseResource:
+		return "database"
+	case TableResource:
+		return "table"
+	case PageResource:
+		return "page"
+	case TupleResource:
+		return "tuple"
+	case KeyRangeResource:
+		return "key range"
+	case KeyResource:
+		return "key"
+	default:
+		return "unknown"
+	}
+}
+
+type LockResource struct {
+	Type      LockResourceType
+	TableName string
+	PageId    PageId
+	SlotId    uint8
+
+	// key range
+	IndexName string
+	Key       string
+	// the gap after the largest key
+	Supremum bool
+}
+
+func databaseResource() LockResource {
+	return LockResource{Type: DatabaseResource}
+}
+
+func tableResource(tableName string) LockResource {
+	return LockResource{Type: TableResource, TableName: tableName}
+}
+
+func pageResource(tableName string, pageId PageId) LockResource {
+	return LockResource{Type: PageResource, TableName: tableName, PageId: pageId}
+}
+
+func tupleResource(tupleId *TupleId) LockResource {
+	return LockResource{Type: TupleResource, TableName: tupleId.tableName, PageId: tupleId.pageId, SlotId: tupleId.slotId}
+}
+
+func keyRangeResource(tableName string, indexName string, nextItem *StringItem) LockResource {
+	if nextItem == nil {
+		return LockResource{Type: KeyRangeResource, TableName: tableName, IndexName: indexName, Supremum: true}
+	}
+	return LockResource{Type: KeyRangeResource, TableName: tableName, IndexName: indexName, Key: nextItem.Value}
+}
+
+func keyResource(tableName string, indexName string, key string) LockResource {
+	return LockResource{Type: KeyResource, TableName: tableName, IndexName: indexName, Key: key}
+}
+
+// ancestors returns the resources containing r from the top
+func (r LockResource) ancestors() []LockResource {
+	switch r.Type {
+	case TableResource:
+		return []LockResource{databaseResource()}
+	case PageResource:
+		return []LockResource{databaseResource(), tableResource(r.TableName)}
+	case TupleResource:
+		return []LockResource{databaseResource(), tableResource(r.TableName), pageResource(r.TableName, r.PageId)}
+	case KeyRangeResource, KeyResource:
+		return []LockResource{databaseResource(), tableResource(r.TableName)}
+	default:
+		return nil
+	}
+}
+
+type lockEntry struct {
+	holders map[TransactionId]LockMode
+	// transactions whose last request was rejected
+	waiters map[TransactionId]LockMode
+}
+
+// tupleLockCount is the number of the tuple locks which a transaction holds on a table
+type tupleLockCount struct {
+	all       int
+	exclusive int
+}
+
+type LockManager struct {
+	mutex *sync.Mutex
+
+	locks map[LockResource]*lockEntry
+	// locks held by each transaction
+	txLocks map[TransactionId]map[LockResource]LockMode
+	// the tuple locks of txLocks counted by table, which decide the escalation
+	tupleLockCounts map[TransactionId]map[string]*tupleLockCount
+
+	EscalationThreshold int
+}
+
+func NewLockManager() *LockManager {
+	return &LockManager{
+		mutex:               new(sync.Mutex),
+		locks:               make(map[LockResource]*lockEntry, 0),
+		txLocks:             make(map[TransactionId]map[LockResource]LockMode, 0),
+		tupleLockCounts:     make(map[TransactionId]map[string]*tupleLockCount, 0),
+		EscalationThreshold: DefaultLockEscalationThreshold,
+	}
+}
+
+// Lock acquires mode on resource and intention locks on all its ancestors.
+// If it is rejected, the intention locks taken by this call are given back.
+func (lm *LockManager) Lock(txId TransactionId, resource LockResource, mode LockMode) bool {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	if lm.isCovered(txId, resource, mode) {
+		return true
+	}
+
+	// the modes held on the ancestors before this call
+	ancestors := resource.ancestors()
+	previous := make([]LockMode, 0, len(ancestors))
+	held := make([]bool, 0, len(ancestors))
+	rollback := func() {
+		for i := len(previous) - 1; i >= 0; i-- {
+			lm.restore(txId, ancestors[i], previous[i], held[i])
+		}
+	}
+	for _, ancestor := range ancestors {
+		m, ok := lm.txLocks[txId][ancestor]
+		if !lm.acquire(txId, ancestor, mode.intention()) {
+			rollback()
+			return false
+		}
+		previous = append(previous, m)
+		held = append(held, ok)
+	}
+	if !lm.acquire(txId, resource, mode) {
+		rollback()
+		return false
+	}
+
+	if resource.Type == TupleResource {
+		lm.escalateIfNeeded(txId, resource.TableName)
+	}
+	return true
+}
+
+// IsLocked reports whether txId holds mode (or stronger) on resource directly or through its ancestors.
+func (lm *LockManager) IsLocked(txId TransactionId, resource LockResource, mode LockMode) bool {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	return lm.isCovered(txId, resource, mode)
+}
+
+// Unlock releases the lock on resource. Intention locks on the ancestors are kept until UnlockAll.
+func (lm *LockManager) Unlock(txId TransactionId, resource LockResource) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	lm.release(txId, resource)
+}
+
+func (lm *LockManager) UnlockAll(txId TransactionId) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	for resource := range lm.txLocks[txId] {
+		lm.release(txId, resource)
+	}
+	delete(lm.txLocks, txId)
+	delete(lm.tupleLockCounts, txId)
+
+	for resource, entry := range lm.locks {
+		delete(entry.waiters, txId)
+		if len(entry.holders) == 0 && len(entry.waiters) == 0 {
+			delete(lm.locks, resource)
+		}
+	}
+}
+
+// IsLockedByOthers reports whether another transaction holds a lock on resource which conflicts with mode.
+func (lm *LockManager) IsLockedByOthers(txId TransactionId, resource LockResource, mode LockMode) (TransactionId, bool) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	if entry, ok := lm.locks[resource]; ok {
+		for holderId, held := range entry.holders {
+			if holderId != txId && !held.isCompatibleWith(mode) {
+				return holderId, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Inherit grants the locks on from to their holders also on to.
+func (lm *LockManager) Inherit(from LockResource, to LockResource) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	entry, ok := lm.locks[from]
+	if !ok {
+		return
+	}
+	for holderId, held := range entry.holders {
+		// only shared locks are taken on key ranges, so this never conflicts
+		lm.acquire(holderId, to, held)
+	}
+}
+
+// GetHolders returns the transactions holding a lock on resource and their modes.
+func (lm *LockManager) GetHolders(resource LockResource) map[TransactionId]LockMode {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	holders := make(map[TransactionId]LockMode, 0)
+	if entry, ok := lm.locks[resource]; ok {
+		for id, mode := range entry.holders {
+			holders[id] = mode
+		}
+	}
+	return holders
+}
+
+func (lm *LockManager) isCovered(txId TransactionId, resource LockResource, mode LockMode) bool {
+	held := lm.txLocks[txId]
+	if held == nil {
+		return false
+	}
+	if m, ok := held[resource]; ok && m.covers(mode) {
+		return true
+	}
+	for _, ancestor := range resource.ancestors() {
+		if m, ok := held[ancestor]; ok && m.coversDescendants(mode) {
+			return true
+		}
+	}
+	return false
+}
+
+// WARNING: caller must hold lm.mutex
+func (lm *LockManager) acquire(txId TransactionId, resource LockResource, mode LockMode) bool {
+	entry, ok := lm.locks[resource]
+	if !ok {
+		entry = &lockEntry{
+			holders: make(map[TransactionId]LockMode, 0),
+			waiters: make(map[TransactionId]LockMode, 0),
+		}
+		lm.locks[resource] = entry
+	}
+
+	newMode := mode
+	if held, ok := entry.holders[txId]; ok {
+		if held.covers(mode) {
+			return true
+		}
+		newMode = held.combine(mode)
+	}
+
+	for holderId, held := range entry.holders {
+		if holderId == txId {
+			continue
+		}
+		if !held.isCompatibleWith(newMode) {
+			entry.waiters[txId] = newMode
+			return false
+		}
+	}
+
+	delete(entry.waiters, txId)
+	entry.holders[txId] = newMode
+	lm.setTxLock(txId, resource, newMode)
+	return true
+}
+
+// WARNING: caller must hold lm.mutex
+func (lm *LockManager) release(txId TransactionId, resource LockResource) {
+	if entry, ok := lm.locks[resource]; ok {
+		delete(entry.holders, txId)
+		if len(entry.holders) == 0 && len(entry.waiters) == 0 {
+			delete(lm.locks, resource)
+		}
+	}
+	lm.deleteTxLock(txId, resource)
+}
+
+// restore sets the lock of the transaction on resource back to mode, or releases it if it was not held
+// WARNING: caller must hold lm.mutex
+func (lm *LockManager) restore(txId TransactionId, resource LockResource, mode LockMode, held bool) {
+	if !held {
+		lm.release(txId, resource)
+		return
+	}
+	lm.locks[resource].holders[txId] = mode
+	lm.setTxLock(txId, resource, mode)
+}
+
+// setTxLock records mode held by the transaction on resource
+// WARNING: caller must hold lm.mutex
+func (lm *LockManager) setTxLock(txId TransactionId, resource LockResource, mode LockMode) {
+	held, ok := lm.txLocks[txId]
+	if !ok {
+		held = make(map[LockResource]LockMode, 0)
+		lm.txLocks[txId] = held
+	}
+	previous, wasHeld := held[resource]
+	held[resource] = mode
+	if resource.Type != TupleResource {
+		return
+	}
+
+	counts, ok := lm.tupleLockCounts[txId]
+	if !ok {
+		counts = make(map[string]*tupleLockCount, 0)
+		lm.tupleLockCounts[txId] = counts
+	}
+	count, ok := counts[resource.TableName]
+	if !ok {
+		count = &tupleLockCount{}
+		counts[resource.TableName] = count
+	}
+	if !wasHeld {
+		count.all++
+	}
+	if (!wasHeld || previous != Exclusive) && mode == Exclusive {
+		count.exclusive++
+	} else if wasHeld && previous == Exclusive && mode != Exclusive {
+		count.exclusive--
+	}
+}
+
+// deleteTxLock removes the lock of the transaction on resource from its records
+// WARNING: caller must hold lm.mutex
+func (lm *LockManager) deleteTxLock(txId TransactionId, resource LockResource) {
+	mode, ok := lm.txLocks[txId][resource]
+	if !ok {
+		return
+	}
+	delete(lm.txLocks[txId], resource)
+	if resource.Type != TupleResource {
+		return
+	}
+
+	count := lm.tupleLockCounts[txId][resource.TableName]
+	count.all--
+	if mode == Exclusive {
+		count.exclusive--
+	}
+	if count.all == 0 {
+		delete(lm.tupleLockCounts[txId], resource.TableName)
+	}
+}
+
+// escalateIfNeeded replaces the tuple locks of a table with one table lock when the transaction holds too many of them.
+// If the table lock conflicts with other transactions, the tuple locks are kept.
+// WARNING: caller must hold lm.mutex
+func (lm *LockManager) escalateIfNeeded(txId TransactionId, tableName string) {
+	count, ok := lm.tupleLockCounts[txId][tableName]
+	if !ok || count.all <= lm.EscalationThreshold {
+		return
+	}
+	mode := Shared
+	if count.exclusive > 0 {
+		mode = Exclusive
+	}
+
+	if !lm.acquire(txId, tableResource(tableName), mode) {
+		return
+	}
+	for resource := range lm.txLocks[txId] {
+		if (resource.Type == TupleResource || resource.Type == PageResource) && resource.TableName == tableName {
+			lm.release(txId, resource)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package storage_test
+
+import (
+	"garakutadb/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func tuple(tableName string, pageId storage.PageId, slotId uint8) storage.LockResource {
+	return storage.LockResource{Type: storage.TupleResource, TableName: tableName, PageId: pageId, SlotId: slotId}
+}
+
+func table(tableName string) storage.LockResource {
+	return storage.LockResource{Type: storage.TableResource, TableName: tableName}
+}
+
+func TestIntentionLocks(t *testing.T) {
+	lm := storage.NewLockManager()
+
+	// row work on different tuples can run concurrently
+	assert.True(t, lm.Lock(1, tuple("users", 1, 0), storage.Exclusive))
+	assert.True(t, lm.Lock(2, tuple("users", 1, 1), storage.Exclusive))
+	assert.False(t, lm.Lock(3, tuple("users", 1, 0), storage.Shared))
+
+	// intention locks are taken on ancestors
+	assert.Equal(t, storage.IntentionExclusive, lm.GetHolders(table("users"))[1])
+
+	// DDL can not lock the table while rows are locked
+	assert.False(t, lm.Lock(3, table("users"), storage.Exclusive))
+	assert.False(t, lm.Lock(3, table("users"), storage.Shared))
+	assert.True(t, lm.Lock(3, table("items"), storage.Exclusive))
+
+	lm.UnlockAll(1)
+	lm.UnlockAll(2)
+	assert.True(t, lm.Lock(3, table("users"), storage.Exclusive))
+
+	// and row work is excluded while the table is locked
+	assert.False(t, lm.Lock(1, tuple("users", 1, 0), storage.Shared))
+	// the table lock covers all tuples in it
+	assert.True(t, lm.IsLocked(3, tuple("users", 1, 0), storage.Exclusive))
+}
+
+func TestRejectedLockReleasesIntentionLocks(t *testing.T) {
+	lm := storage.NewLockManager()
+
+	assert.True(t, lm.Lock(1, tuple("users", 1, 0), storage.Exclusive))
+	assert.True(t, lm.Lock(2, tuple("users", 2, 0), storage.Shared))
+	// the IS on the table held before is not upgraded, and the IS on page 1 is not left
+	assert.False(t, lm.Lock(2, tuple("users", 1, 0), storage.Exclusive))
+	assert.Equal(t, storage.IntentionShared, lm.GetHolders(table("users"))[2])
+	assert.NotContains(t, lm.GetHolders(storage.LockResource{Type: storage.PageResource, TableName: "users", PageId: 1}), storage.TransactionId(2))
+
+	// a rejected request of a transaction without other locks leaves nothing
+	assert.False(t, lm.Lock(3, tuple("users", 1, 0), storage.Shared))
+	lm.UnlockAll(1)
+	lm.UnlockAll(2)
+	assert.True(t, lm.Lock(4, table("users"), storage.Exclusive))
+}
+
+func TestLockUpgrade(t *testing.T) {
+	lm := storage.NewLockManager()
+
+	assert.True(t, lm.Lock(1, table("users"), storage.Shared))
+	assert.True(t, lm.Lock(1, tuple("users", 1, 0), storage.Exclusive))
+	assert.Equal(t, storage.SharedIntentionExclusive, lm.GetHolders(table("users"))[1])
+
+	// SIX is compatible only with IS
+	assert.True(t, lm.Lock(2, table("users"), storage.IntentionShared))
+	assert.False(t, lm.Lock(2, tuple("users", 1, 1), storage.Exclusive))
+}
+
+func TestLockEscalation(t *testing.T) {
+	lm := storage.NewLockManager()
+	lm.EscalationThreshold = 3
+
+	for i := uint8(0); i < 4; i++ {
+		assert.True(t, lm.Lock(1, tuple("users", 1, i), storage.Exclusive))
+	}
+
+	assert.Equal(t, storage.Exclusive, lm.GetHolders(table("users"))[1])
+	assert.Len(t, lm.GetHolders(tuple("users", 1, 0)), 0)
+	assert.True(t, lm.IsLocked(1, tuple("users", 1, 0), storage.Exclusive))
+	assert.False(t, lm.Lock(2, tuple("users", 2, 0), storage.Shared))
+}
+
+func TestLockEscalationConflict(t *testing.T) {
+	lm := storage.NewLockManager()
+	lm.EscalationThreshold = 1
+
+	assert.True(t, lm.Lock(2, tuple("users", 2, 0), storage.Shared))
+	assert.True(t, lm.Lock(1, tuple("users", 1, 0), storage.Exclusive))
+	assert.True(t, lm.Lock(1, tuple("users", 1, 1), storage.Exclusive))
+
+	// escalation is given up because of the lock held by another transaction
+	assert.Equal(t, storage.IntentionExclusive, lm.GetHolders(table("users"))[1])
+	assert.Equal(t, storage.Exclusive, lm.GetHolders(tuple("users", 1, 1))[1])
+}
+
+func TestLockEscalationCountsHeldTupleLocks(t *testing.T) {
+	lm := storage.NewLockManager()
+	lm.EscalationThreshold = 2
+
+	// released locks and the locks on other tables are not counted
+	assert.True(t, lm.Lock(1, tuple("users", 1, 0), storage.Shared))
+	assert.True(t, lm.Lock(1, tuple("users", 1, 1), storage.Shared))
+	lm.Unlock(1, tuple("users", 1, 0))
+	lm.Unlock(1, tuple("users", 1, 1))
+	assert.True(t, lm.Lock(1, tuple("users", 1, 2), storage.Shared))
+	assert.True(t, lm.Lock(1, tuple("users", 1, 3), storage.Shared))
+	assert.True(t, lm.Lock(1, tuple("items", 1, 0), storage.Shared))
+	assert.Equal(t, storage.IntentionShared, lm.GetHolders(table("users"))[1])
+
+	// an upgraded lock is not counted twice, and makes the table lock exclusive
+	assert.True(t, lm.Lock(1, tuple("users", 1, 2), storage.Exclusive))
+	assert.Equal(t, storage.IntentionExclusive, lm.GetHolders(table("users"))[1])
+	assert.True(t, lm.Lock(1, tuple("users", 1, 4), storage.Shared))
+	assert.Equal(t, storage.Exclusive, lm.GetHolders(table("users"))[1])
+	assert.Equal(t, storage.IntentionShared, lm.GetHolders(table("items"))[1])
+}
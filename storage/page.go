@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"encoding/binary"
+	"fmt"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -28,6 +30,15 @@ func (t *Tuples) Insert(tuple *Tuple) {
 	}
 }
 
+func (t *Tuples) emptySlot() uint8 {
+	for i, v := range t {
+		if v == nil || v.Data == nil {
+			return uint8(i)
+		}
+	}
+	return TupleNumPerPage
+}
+
 func (t *Tuples) IsFull() bool {
 	for _, tuple := range t {
 		if tuple == nil || tuple.Data == nil {
@@ -37,8 +48,9 @@ func (t *Tuples) IsFull() bool {
 	return true
 }
 
-func (t *Tuples) DeleteTuple(slot uint8) {
+func (t *Tuples) DeleteTuple(slot uint8, xmax TransactionId) {
 	t[slot].IsDeleted = true
+	t[slot].Xmax = uint64(xmax)
 }
 
 func NewPage(tableName string, id PageId, tuples [TupleNumPerPage]*Tuple) *Page {
@@ -49,19 +61,29 @@ func NewPage(tableName string, id PageId, tuples [TupleNumPerPage]*Tuple) *Page
 	}
 }
 
+// Each tuple is stored in a slot of TupleByteSize bytes: 2 bytes of length followed by the protobuf message.
+const TupleByteSize = PageByteSize / TupleNumPerPage
+
+var TupleTooLargeError = fmt.Errorf("tuple is too large: the maximum size is %d bytes", TupleByteSize-2)
+
 func (p *Page) Serialize() ([PageByteSize]byte, error) {
 	pageBytes := [PageByteSize]byte{}
 
 	for i, t := range p.Tuples {
 		if t == nil || t.Data == nil {
-			break
+			continue
 		}
 		b, err := proto.Marshal(t)
 		if err != nil {
 			return [PageByteSize]byte{}, err
 		}
+		if len(b) > TupleByteSize-2 {
+			return [PageByteSize]byte{}, TupleTooLargeError
+		}
 
-		copy(pageBytes[i*128:i*128+128], b)
+		slot := pageBytes[i*TupleByteSize : (i+1)*TupleByteSize]
+		binary.BigEndian.PutUint16(slot, uint16(len(b)))
+		copy(slot[2:], b)
 	}
 
 	return pageBytes, nil
@@ -71,21 +93,14 @@ func DeserializePage(tableName string, pageId PageId, pageBytes [PageByteSize]by
 	tuples := [TupleNumPerPage]*Tuple{}
 
 	for i := 0; i < TupleNumPerPage; i++ {
-		var in [128]byte
-		copy(in[:], pageBytes[i*128:i*128+128])
-
-		// TODO: thinking about remove Zero padding?
-		byteLen := len(in)
-		for j, b := range in {
-			if b == 0 {
-				byteLen = j
-				break
-			}
+		slot := pageBytes[i*TupleByteSize : (i+1)*TupleByteSize]
+		byteLen := int(binary.BigEndian.Uint16(slot))
+		if byteLen > TupleByteSize-2 {
+			return nil, fmt.Errorf("corrupted page: %s %d", tableName, pageId)
 		}
 
 		t := Tuple{}
-		err := proto.Unmarshal(pageBytes[i*128:i*128+byteLen], &t)
-		if err != nil {
+		if err := proto.Unmarshal(slot[2:2+byteLen], &t); err != nil {
 			return nil, err
 		}
 
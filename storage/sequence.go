@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+)
+
+// Sequences hand out values outside of transactions, so a value is never returned twice even if the transaction is aborted.
+// Values are reserved in chunks of Cache and the end of the reserved range is persisted, like transaction ids (see commit_log.go).
+// Concurrent callers only take the lock of the counter in memory, and the file is written once per chunk.
+// The values reserved but not returned are skipped after restart.
+
+const DefaultSequenceCache = 32
+
+type SequenceOptions struct {
+	Start     int64 `json:"start"`
+	Increment int64 `json:"increment"`
+	MinValue  int64 `json:"minValue"`
+	MaxValue  int64 `json:"maxValue"`
+	// the number of values reserved at once
+	Cache int64 `json:"cache"`
+}
+
+// Validate checks the options like PostgreSQL
+func (o SequenceOptions) Validate() error {
+	switch {
+	case o.Increment == 0:
+		return errors.New("INCREMENT must not be zero")
+	case o.MinValue >= o.MaxValue:
+		return fmt.Errorf("MINVALUE (%d) must be less than MAXVALUE (%d)", o.MinValue, o.MaxValue)
+	case o.Start < o.MinValue:
+		return fmt.Errorf("START value (%d) cannot be less than MINVALUE (%d)", o.Start, o.MinValue)
+	case o.Start > o.MaxValue:
+		return fmt.Errorf("START value (%d) cannot be greater than MAXVALUE (%d)", o.Start, o.MaxValue)
+	case o.Cache < 1:
+		return fmt.Errorf("CACHE (%d) must be greater than zero", o.Cache)
+	}
+	return nil
+}
+
+// sequenceState is persisted for each sequence
+type sequenceState struct {
+	// the first value which is not reserved
+	Next int64 `json:"next"`
+	// all values have been reserved
+	Exhausted bool `json:"exhausted,omitempty"`
+}
+
+type sequenceCounter struct {
+	mutex sync.Mutex
+
+	next      int64
+	exhausted bool
+	// the number of values from next which are reserved
+	reserved int64
+}
+
+type sequenceManager struct {
+	mutex    sync.Mutex
+	counters map[string]*sequenceCounter
+}
+
+func newSequenceManager() *sequenceManager {
+	return &sequenceManager{
+		counters: make(map[string]*sequenceCounter),
+	}
+}
+
+func sequenceFileName(name string) string {
+	return "sequence_" + name + ".json"
+}
+
+// sequenceCounter returns the counter of the sequence, which is read from the file or starts from start
+func (st *Storage) sequenceCounter(name string, start int64) (*sequenceCounter, error) {
+	sm := st.sequences
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if counter, ok := sm.counters[name]; ok {
+		return counter, nil
+	}
+	state := sequenceState{Next: start}
+	if err := st.ReadJson(sequenceFileName(name), &state); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	counter := &sequenceCounter{
+		next:      state.Next,
+		exhausted: state.Exhausted,
+	}
+	sm.counters[name] = counter
+	return counter, nil
+}
+
+// forgetSequence drops the counter in memory. The counter is read from the file next time.
+func (st *Storage) forgetSequence(name string) {
+	st.sequences.mutex.Lock()
+	defer st.sequences.mutex.Unlock()
+
+	delete(st.sequences.counters, name)
+}
+
+// NextSequenceValue advances the sequence and returns the new value. It is not reverted when the transaction is aborted.
+func (st *Storage) NextSequenceValue(name string, opts SequenceOptions) (int64, error) {
+	counter, err := st.sequenceCounter(name, opts.Start)
+	if err != nil {
+		return 0, err
+	}
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	if counter.exhausted || counter.next > opts.MaxValue {
+		return 0, fmt.Errorf("nextval: reached maximum value of sequence \"%s\" (%d)", name, opts.MaxValue)
+	}
+	if counter.next < opts.MinValue {
+		return 0, fmt.Errorf("nextval: reached minimum value of sequence \"%s\" (%d)", name, opts.MinValue)
+	}
+
+	if counter.reserved == 0 {
+		state := sequenceState{Next: counter.next}
+		for i := int64(0); i < opts.Cache && !state.Exhausted; i++ {
+			state.Next, state.Exhausted = addSequenceValue(state.Next, opts.Increment)
+		}
+		if err := st.WriteJson(sequenceFileName(name), &state); err != nil {
+			return 0, err
+		}
+		counter.reserved = opts.Cache
+	}
+
+	value := counter.next
+	counter.reserved--
+	counter.next, counter.exhausted = addSequenceValue(counter.next, opts.Increment)
+	return value, nil
+}
+
+// addSequenceValue returns value + increment, and whether it overflows
+func addSequenceValue(value int64, increment int64) (int64, bool) {
+	if increment > 0 && value > math.MaxInt64-increment || increment < 0 && value < math.MinInt64-increment {
+		return value, true
+	}
+	return value + increment, false
+}
+
+// CreateSequence starts the counter of a new sequence from its start value. The counter is removed when tx is aborted.
+func (st *Storage) CreateSequence(name string, tx *Transaction) error {
+	// a counter left by an aborted transaction or a crash
+	st.forgetSequence(name)
+	if err := st.diskManager.removeFile("", sequenceFileName(name)); err != nil {
+		return err
+	}
+	if err := st.journal(tx, journalRecord{Op: journalCreateFile, FileName: sequenceFileName(name)}); err != nil {
+		return err
+	}
+	tx.AddUndoRecord(&createSequenceUndoRecord{
+		name: name,
+	})
+	return nil
+}
+
+// DropSequence moves the counter aside. It is removed when tx is committed, and restored when tx is aborted.
+func (st *Storage) DropSequence(name string, tx *Transaction) error {
+	st.forgetSequence(name)
+	fileName := sequenceFileName(name)
+	trashName := fmt.Sprintf(".%s.%d.%d", fileName, tx.id, len(tx.undoRecords))
+	if err := st.journal(tx, journalRecord{Op: journalDropFile, FileName: fileName, TrashName: trashName}); err != nil {
+		return err
+	}
+	if err := st.diskManager.renameFile("", fileName, trashName); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		// no value has been returned yet
+		return nil
+	}
+	tx.AddUndoRecord(&dropSequenceUndoRecord{
+		name:      name,
+		trashName: trashName,
+	})
+	tx.AddCommitAction(&removeFileAction{
+		fileName: trashName,
+	})
+	return nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Read-only and serializable transactions read from a snapshot instead of taking locks. Serializable transactions
+// detect the changes which their snapshots don't see with SSI (see ssi.go).
+// A snapshot sees the changes of the transactions which had committed when it was taken, at the first statement.
+// It can be exported and imported by other read-only transactions while the exporting transaction is running,
+// so that parallel readers see exactly the same data.
+
+var ReadOnlyTransactionError = errors.New("cannot execute the statement in a read-only transaction")
+
+type Snapshot struct {
+	// transactions whose id is Xmax or larger had not started when the snapshot was taken
+	Xmax TransactionId
+	// transactions which were running when the snapshot was taken
+	Active map[TransactionId]struct{}
+}
+
+// includes reports whether transaction id had finished when the snapshot was taken
+func (s *Snapshot) includes(id TransactionId) bool {
+	if id >= s.Xmax {
+		return false
+	}
+	_, active := s.Active[id]
+	return !active
+}
+
+type exportedSnapshot struct {
+	owner    TransactionId
+	snapshot *Snapshot
+}
+
+// takeSnapshot must be called with tm.mutex held, so that no transaction id is allocated meanwhile.
+// WARNING: caller must hold tm.mutex
+func (tm *TransactionManager) takeSnapshot() *Snapshot {
+	active := make(map[TransactionId]struct{}, 0)
+	for id, t := range tm.transactions {
+		if t.state == ACTIVE {
+			active[id] = struct{}{}
+		}
+	}
+	return &Snapshot{
+		Xmax:   tm.commitLog.peekNextTransactionId(),
+		Active: active,
+	}
+}
+
+// TakeSnapshot is called when a statement of tx starts. The first statement of a read-only or serializable
+// transaction takes the snapshot which the transaction reads from until it finishes, unless it is SET TRANSACTION SNAPSHOT.
+func (tm *TransactionManager) TakeSnapshot(tx *Transaction) {
+	if !tx.readOnly && !tx.IsSerializable() {
+		return
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if tx.snapshot == nil {
+		tx.snapshot = tm.takeSnapshot()
+	}
+}
+
+// ExportSnapshot returns an identifier of the snapshot of tx which other read-only transactions can import.
+func (tm *TransactionManager) ExportSnapshot(tx *Transaction) (string, error) {
+	if !tx.readOnly {
+		return "", fmt.Errorf("snapshot can be exported only by read-only transactions")
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tm.snapshotSequence++
+	id := fmt.Sprintf("%d-%d", tx.id, tm.snapshotSequence)
+	tm.exportedSnapshots[id] = &exportedSnapshot{
+		owner:    tx.id,
+		snapshot: tx.snapshot,
+	}
+	return id, nil
+}
+
+// ImportSnapshot makes tx read from the snapshot exported as id. It must be done before tx reads anything.
+func (tm *TransactionManager) ImportSnapshot(tx *Transaction, id string) error {
+	if !tx.readOnly {
+		return fmt.Errorf("snapshot can be imported only by read-only transactions")
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if tx.snapshot != nil {
+		return fmt.Errorf("snapshot must be imported before any query")
+	}
+	exported, ok := tm.exportedSnapshots[id]
+	if !ok {
+		return fmt.Errorf("invalid snapshot identifier: %s", id)
+	}
+	tx.snapshot = exported.snapshot
+	return nil
+}
+
+// releaseExportedSnapshots drops the snapshots exported by tx, which has finished
+func (tm *TransactionManager) releaseExportedSnapshots(tx *Transaction) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	for id, exported := range tm.exportedSnapshots {
+		if exported.owner == tx.id {
+			delete(tm.exportedSnapshots, id)
+		}
+	}
+}
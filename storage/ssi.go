@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"errors"
+)
+
+// Serializable Snapshot Isolation (SSI)
+// Serializable transactions take SIREAD locks on what they read. SIREAD locks never block,
+// they are only used to detect rw-antidependencies (reader -rw-> writer) between concurrent transactions.
+// When a transaction has both an incoming and an outgoing rw-antidependency (a "dangerous structure"),
+// one transaction of the structure is aborted with SerializationFailureError.
+
+var SerializationFailureError = errors.New("could not serialize access due to read/write dependencies among transactions")
+
+type predicateLockKind int32
+
+const (
+	relationPredicateLock predicateLockKind = iota
+	tuplePredicateLock
+	indexRangePredicateLock
+)
+
+type predicateLock struct {
+	kind      predicateLockKind
+	tableName string
+
+	// tuple lock
+	tupleId TupleId
+
+	// index range lock (both inclusive)
+	indexName string
+	low       string
+	high      string
+}
+
+// PredicateLockTarget describes the data that a write touches.
+type PredicateLockTarget struct {
+	TableName string
+	TupleId   *TupleId
+	IndexName string
+	Key       string
+}
+
+func (l *predicateLock) covers(target *PredicateLockTarget) bool {
+	if l.tableName != target.TableName {
+		return false
+	}
+
+	switch l.kind {
+	case relationPredicateLock:
+		return true
+	case tuplePredicateLock:
+		return target.TupleId != nil && *target.TupleId == l.tupleId
+	case indexRangePredicateLock:
+		return target.IndexName == l.indexName && l.low <= target.Key && target.Key <= l.high
+	default:
+		return false
+	}
+}
+
+func (tm *TransactionManager) PredicateLockRelation(tx *Transaction, tableName string) {
+	tm.addPredicateLock(tx, &predicateLock{
+		kind:      relationPredicateLock,
+		tableName: tableName,
+	})
+}
+
+func (tm *TransactionManager) PredicateLockTuple(tx *Transaction, tableName string, tupleId *TupleId) {
+	tm.addPredicateLock(tx, &predicateLock{
+		kind:      tuplePredicateLock,
+		tableName: tableName,
+		tupleId:   *tupleId,
+	})
+}
+
+func (tm *TransactionManager) PredicateLockIndexRange(tx *Transaction, tableName string, indexName string, low string, high string) {
+	tm.addPredicateLock(tx, &predicateLock{
+		kind:      indexRangePredicateLock,
+		tableName: tableName,
+		indexName: indexName,
+		low:       low,
+		high:      high,
+	})
+}
+
+func (tm *TransactionManager) addPredicateLock(tx *Transaction, lock *predicateLock) {
+	if !tx.IsSerializable() {
+		return
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	for _, l := range tm.predicateLocks[tx.id] {
+		if *l == *lock {
+			return
+		}
+		// relation lock already covers everything in the table
+		if l.kind == relationPredicateLock && l.tableName == lock.tableName {
+			return
+		}
+	}
+	tm.predicateLocks[tx.id] = append(tm.predicateLocks[tx.id], lock)
+}
+
+func (tm *TransactionManager) holdsRelationPredicateLock(tx *Transaction, tableName string) bool {
+	if !tx.IsSerializable() {
+		return false
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	for _, l := range tm.predicateLocks[tx.id] {
+		if l.kind == relationPredicateLock && l.tableName == tableName {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckForSerializableConflictIn is called before tx writes target.
+// Every concurrent serializable transaction which has read target gets a rw-antidependency to tx.
+func (tm *TransactionManager) CheckForSerializableConflictIn(tx *Transaction, target *PredicateLockTarget) error {
+	if !tx.IsSerializable() {
+		return nil
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if tx.doomed {
+		return SerializationFailureError
+	}
+
+	for readerId, locks := range tm.predicateLocks {
+		if readerId == tx.id {
+			continue
+		}
+		reader := tm.transactions[readerId]
+		if reader == nil || reader.state == ABORTED || !tm.isConcurrent(reader, tx) {
+			continue
+		}
+		for _, l := range locks {
+			if l.covers(target) {
+				if err := tm.addRwConflict(reader, tx, tx); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// checkForSerializableConflictOut is called when tx could not read a tuple because writerId has modified it.
+func (tm *TransactionManager) checkForSerializableConflictOut(tx *Transaction, writerId TransactionId) error {
+	if !tx.IsSerializable() || writerId == tx.id {
+		return nil
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	writer := tm.transactions[writerId]
+	if writer == nil || !writer.IsSerializable() || writer.state == ABORTED {
+		return nil
+	}
+	return tm.addRwConflict(tx, writer, tx)
+}
+
+// checkForSerializableConflictOut is called when tx reads a tuple from its snapshot. A version which the snapshot
+// doesn't see, or a deletion which it doesn't see, has been made by a concurrent transaction, so tx -rw-> the writer.
+// Only the tuples of tables which tx has read as a whole are checked, like the tuples skipped by the locking readers.
+func (tm *TransactionManager) checkForSnapshotConflictOut(tx *Transaction, tableName string, tuple *Tuple) error {
+	if !tx.IsSerializable() || tx.snapshot == nil {
+		return nil
+	}
+	for _, writerId := range []TransactionId{TransactionId(tuple.Xmin), TransactionId(tuple.Xmax)} {
+		if writerId == InvalidTransactionId || writerId == tx.id || tx.snapshot.includes(writerId) {
+			continue
+		}
+		if !tm.holdsRelationPredicateLock(tx, tableName) {
+			return nil
+		}
+		if err := tm.checkForSerializableConflictOut(tx, writerId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRwConflict records reader -rw-> writer and checks whether a dangerous structure has been formed.
+// The new edge is always a part of the structure, so the transaction running the operation (current) is aborted.
+// WARNING: caller must hold tm.mutex
+func (tm *TransactionManager) addRwConflict(reader *Transaction, writer *Transaction, current *Transaction) error {
+	reader.outConflicts[writer.id] = struct{}{}
+	writer.inConflicts[reader.id] = struct{}{}
+
+	if tm.isPivot(reader) || tm.isPivot(writer) {
+		current.doomed = true
+		return SerializationFailureError
+	}
+	return nil
+}
+
+// isPivot reports whether tx has both an incoming and an outgoing rw-antidependency with live transactions.
+// Doomed transactions are not live, because they will never commit.
+// WARNING: caller must hold tm.mutex
+func (tm *TransactionManager) isPivot(tx *Transaction) bool {
+	return tm.hasLiveConflict(tx.inConflicts) && tm.hasLiveConflict(tx.outConflicts)
+}
+
+func (tm *TransactionManager) hasLiveConflict(conflicts map[TransactionId]struct{}) bool {
+	for id := range conflicts {
+		if t, ok := tm.transactions[id]; ok && t.state != ABORTED && !t.doomed {
+			return true
+		}
+	}
+	return false
+}
+
+// isConcurrent reports whether the lifetimes of two transactions overlap.
+func (tm *TransactionManager) isConcurrent(t1 *Transaction, t2 *Transaction) bool {
+	if t1.state == COMMITTED && t1.commitSeq < t2.startSeq {
+		return false
+	}
+	if t2.state == COMMITTED && t2.commitSeq < t1.startSeq {
+		return false
+	}
+	return true
+}
+
+// preCommitCheck fails when tx is part of a dangerous structure.
+func (tm *TransactionManager) preCommitCheck(tx *Transaction) error {
+	if !tx.IsSerializable() {
+		return nil
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if tx.doomed || tm.isPivot(tx) {
+		return SerializationFailureError
+	}
+	return nil
+}
+
+// releasePredicateLocks drops SIREAD locks and finished transactions which can no longer cause a conflict.
+// SIREAD locks of a committed transaction must be kept until all transactions concurrent with it have finished.
+func (tm *TransactionManager) releasePredicateLocks() {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	var oldestActiveSeq uint64
+	for _, t := range tm.transactions {
+		if t.state == ACTIVE && (oldestActiveSeq == 0 || t.startSeq < oldestActiveSeq) {
+			oldestActiveSeq = t.startSeq
+		}
+	}
+
+	for id := range tm.predicateLocks {
+		t, ok := tm.transactions[id]
+		if !ok || t.state == ABORTED {
+			delete(tm.predicateLocks, id)
+			continue
+		}
+		if t.state == COMMITTED && (oldestActiveSeq == 0 || t.commitSeq < oldestActiveSeq) {
+			delete(tm.predicateLocks, id)
+		}
+	}
+
+	for id, t := range tm.transactions {
+		if t.state == ABORTED || (t.state == COMMITTED && (oldestActiveSeq == 0 || t.commitSeq < oldestActiveSeq)) {
+			delete(tm.transactions, id)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package storage_test
+
+import (
+	"garakutadb/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTransactionManager(t *testing.T) *storage.TransactionManager {
+	txMgr, err := storage.NewTransactionManager(storage.NewStorage(storage.NewDiskManager(t.TempDir())))
+	assert.Nil(t, err)
+	return txMgr
+}
+
+func TestWriteSkewIsNotSerializable(t *testing.T) {
+	txMgr := newTransactionManager(t)
+	tx1, _ := txMgr.BeginWithOptions(storage.TransactionOptions{IsolationLevel: storage.Serializable})
+	tx2, _ := txMgr.BeginWithOptions(storage.TransactionOptions{IsolationLevel: storage.Serializable})
+
+	// both transactions read the whole table
+	txMgr.PredicateLockRelation(tx1, "accounts")
+	txMgr.PredicateLockRelation(tx2, "accounts")
+
+	// tx2 -rw-> tx1
+	assert.Nil(t, txMgr.CheckForSerializableConflictIn(tx1, &storage.PredicateLockTarget{TableName: "accounts"}))
+	// tx1 -rw-> tx2 makes a cycle
+	assert.ErrorIs(t, txMgr.CheckForSerializableConflictIn(tx2, &storage.PredicateLockTarget{TableName: "accounts"}), storage.SerializationFailureError)
+
+	assert.ErrorIs(t, txMgr.Commit(tx2), storage.SerializationFailureError)
+	assert.Equal(t, storage.ABORTED, tx2.GetState())
+	assert.Nil(t, txMgr.Commit(tx1))
+}
+
+func TestIndexRangeConflict(t *testing.T) {
+	txMgr := newTransactionManager(t)
+	tx1, _ := txMgr.BeginWithOptions(storage.TransactionOptions{IsolationLevel: storage.Serializable})
+	tx2, _ := txMgr.BeginWithOptions(storage.TransactionOptions{IsolationLevel: storage.Serializable})
+
+	txMgr.PredicateLockIndexRange(tx1, "accounts", "id", "b", "d")
+	txMgr.PredicateLockIndexRange(tx2, "accounts", "id", "x", "x")
+
+	// out of the range
+	assert.Nil(t, txMgr.CheckForSerializableConflictIn(tx2, &storage.PredicateLockTarget{TableName: "accounts", IndexName: "id", Key: "e"}))
+	// tx1 -rw-> tx2
+	assert.Nil(t, txMgr.CheckForSerializableConflictIn(tx2, &storage.PredicateLockTarget{TableName: "accounts", IndexName: "id", Key: "c"}))
+	// tx2 -rw-> tx1
+	assert.ErrorIs(t, txMgr.CheckForSerializableConflictIn(tx1, &storage.PredicateLockTarget{TableName: "accounts", IndexName: "id", Key: "x"}), storage.SerializationFailureError)
+}
+
+func TestNonSerializableTransactionIsNotTracked(t *testing.T) {
+	txMgr := newTransactionManager(t)
+	tx1, _ := txMgr.Begin()
+	tx2, _ := txMgr.Begin()
+
+	txMgr.PredicateLockRelation(tx1, "accounts")
+	txMgr.PredicateLockRelation(tx2, "accounts")
+
+	assert.Nil(t, txMgr.CheckForSerializableConflictIn(tx1, &storage.PredicateLockTarget{TableName: "accounts"}))
+	assert.Nil(t, txMgr.CheckForSerializableConflictIn(tx2, &storage.PredicateLockTarget{TableName: "accounts"}))
+}
@@ -9,11 +9,14 @@ import (
 
 type Storage struct {
 	diskManager *DiskManager
+	// counters of sequences (see sequence.go)
+	sequences *sequenceManager
 }
 
 func NewStorage(dm *DiskManager) *Storage {
 	return &Storage{
 		diskManager: dm,
+		sequences:   newSequenceManager(),
 	}
 }
 
@@ -24,6 +27,8 @@ type TupleIterator struct {
 
 	Page        *Page
 	transaction *Transaction
+	// the error which stopped the iteration
+	err error
 }
 
 type TupleIteratorCursor struct {
@@ -49,16 +54,28 @@ func (it *TupleIteratorCursor) Next() bool {
 }
 
 func (it *TupleIterator) canSee(txMgr *TransactionManager) bool {
-	return txMgr.IsLockShared(it.transaction, &TupleId{
-		pageId: it.pageIteratorCursor.pageId,
-		slotId: it.pageIteratorCursor.tupleOffset,
-	}) || txMgr.IsLockExclusive(it.transaction, &TupleId{
-		pageId: it.pageIteratorCursor.pageId,
-		slotId: it.pageIteratorCursor.tupleOffset,
-	}) || txMgr.LockShared(it.transaction, &TupleId{
-		pageId: it.pageIteratorCursor.pageId,
-		slotId: it.pageIteratorCursor.tupleOffset,
-	})
+	// visibility is decided by the snapshot
+	if it.transaction.snapshot != nil {
+		return true
+	}
+
+	tupleId := it.GetTupleId()
+	if txMgr.IsLockShared(it.transaction, tupleId) ||
+		txMgr.IsLockExclusive(it.transaction, tupleId) ||
+		txMgr.LockShared(it.transaction, tupleId) {
+		return true
+	}
+
+	// skipping a tuple modified by a concurrent transaction while reading the whole table is a rw-antidependency
+	if writerId, ok := txMgr.getExclusiveLockHolder(tupleId); ok && txMgr.holdsRelationPredicateLock(it.transaction, it.tableName) {
+		it.err = txMgr.checkForSerializableConflictOut(it.transaction, writerId)
+	}
+	return false
+}
+
+// Err returns the error which stopped the iteration. Next returns false for it as well as for the end of the table.
+func (it *TupleIterator) Err() error {
+	return it.err
 }
 
 func (st *Storage) NewTupleIterator(tableName string, tx *Transaction) *TupleIterator {
@@ -78,7 +95,16 @@ func (it *TupleIterator) Next(txMgr *TransactionManager) (*Tuple, bool) {
 		return nil, false
 	}
 
-	if tuple.IsDeleted {
+	visible, err := txMgr.isVisible(it.transaction, tuple)
+	if err != nil {
+		it.err = err
+		return nil, false
+	}
+	if err := txMgr.checkForSnapshotConflictOut(it.transaction, it.tableName, tuple); err != nil {
+		it.err = err
+		return nil, false
+	}
+	if !visible {
 		return it.Next(txMgr)
 	}
 
@@ -105,6 +131,8 @@ func (it *TupleIterator) next(txMgr *TransactionManager) (*Tuple, bool) {
 			return nil, false
 		} else if it.canSee(txMgr) {
 			return it.Page.Tuples[it.pageIteratorCursor.tupleOffset], true
+		} else if it.err != nil {
+			return nil, false
 		} else {
 			return it.next(txMgr)
 		}
@@ -122,6 +150,9 @@ func (it *TupleIterator) next(txMgr *TransactionManager) (*Tuple, bool) {
 	}
 
 	if !it.canSee(txMgr) {
+		if it.err != nil {
+			return nil, false
+		}
 		return it.next(txMgr)
 	}
 	return it.Page.Tuples[it.pageIteratorCursor.tupleOffset], true
@@ -129,39 +160,55 @@ func (it *TupleIterator) next(txMgr *TransactionManager) (*Tuple, bool) {
 
 func (it *TupleIterator) GetTupleId() *TupleId {
 	return &TupleId{
-		pageId: it.pageIteratorCursor.pageId,
-		slotId: it.pageIteratorCursor.tupleOffset,
+		tableName: it.tableName,
+		pageId:    it.pageIteratorCursor.pageId,
+		slotId:    it.pageIteratorCursor.tupleOffset,
 	}
 }
 
-func (st *Storage) GetTupleFromPage(tableName string, pageId PageId, pkValue string, transaction *Transaction, transactionMgr *TransactionManager) (*Tuple, error) {
+var TupleNotFoundError = errors.New("tuple not found")
+
+var ConcurrentUpdateError = errors.New("could not serialize access due to concurrent update")
+
+// GetTupleFromPage returns the tuple in the page for which match returns true
+func (st *Storage) GetTupleFromPage(tableName string, pageId PageId, match func(tuple *Tuple) bool, transaction *Transaction, transactionMgr *TransactionManager) (*Tuple, error) {
 	page, err := st.diskManager.readPage(tableName, pageId)
 	if err != nil {
 		return nil, err
 	}
 
 	for slotId, tuple := range page.Tuples {
-		if tuple == nil {
+		if tuple == nil || len(tuple.Data) == 0 {
 			continue
 		}
-		if tuple.Data[0].Value == pkValue {
-			if transactionMgr.IsLockShared(transaction, &TupleId{
-				pageId: pageId,
-				slotId: uint8(slotId),
-			}) || transactionMgr.IsLockExclusive(transaction, &TupleId{
-				pageId: pageId,
-				slotId: uint8(slotId),
-			}) || transactionMgr.LockShared(transaction, &TupleId{
-				pageId: pageId,
-				slotId: uint8(slotId),
-			}) {
+		visible, err := transactionMgr.isVisible(transaction, tuple)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			continue
+		}
+		if match(tuple) {
+			// a serializable transaction locks the tuple which it writes depending on, e.g. the parent of a foreign key
+			if transaction.readOnly {
+				return tuple, nil
+			}
+			tupleId := &TupleId{
+				tableName: tableName,
+				pageId:    pageId,
+				slotId:    uint8(slotId),
+			}
+			if transactionMgr.IsLockShared(transaction, tupleId) ||
+				transactionMgr.IsLockExclusive(transaction, tupleId) ||
+				transactionMgr.LockShared(transaction, tupleId) {
+				transactionMgr.PredicateLockTuple(transaction, tableName, tupleId)
 				return tuple, nil
 			} else {
 				return nil, fmt.Errorf("don't have lock for tuple %v", tuple)
 			}
 		}
 	}
-	return nil, fmt.Errorf("tuple not found")
+	return nil, TupleNotFoundError
 }
 
 func (st *Storage) readPage(tableName string, pageId PageId) (*Page, error) {
@@ -181,6 +228,10 @@ func (st *Storage) WriteIndex(btree *BTree) error {
 }
 
 func (st *Storage) InsertTuple(tableName string, tuple *Tuple, tx *Transaction, txMgr *TransactionManager) (*Page, error) {
+	tuple.Xmin = uint64(tx.id)
+	tuple.Xmax = uint64(InvalidTransactionId)
+	tuple.IsDeleted = false
+
 	it := st.NewTupleIterator(tableName, tx)
 
 	// TODO: improve performance (want to avoid full scan)
@@ -191,52 +242,61 @@ func (st *Storage) InsertTuple(tableName string, tuple *Tuple, tx *Transaction,
 		}
 		txMgr.UnlockSharedByTupleId(tx, it.GetTupleId())
 	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
 
 	if it.Page == nil {
 		newPage := NewPage(tableName, it.pageIteratorCursor.pageId, [TupleNumPerPage]*Tuple{tuple})
 		newTupleId := &TupleId{
-			pageId: newPage.Id,
-			slotId: 0,
+			tableName: tableName,
+			pageId:    newPage.Id,
+			slotId:    0,
+		}
+		if err := st.checkForSerializableConflictIn(tableName, newTupleId, tx, txMgr); err != nil {
+			return nil, err
 		}
 		success := txMgr.LockExclusive(tx, newTupleId)
 		if !success {
 			return nil, fmt.Errorf("failed to lock exclusive")
 		}
 
-		if tx.state == ACTIVE {
-			tx.AddWriteRecord(
-				tableName,
-				nil,
-				newTupleId,
-			)
-		}
+		tx.AddUndoRecord(&insertTupleUndoRecord{
+			tableName: tableName,
+			tupleId:   *newTupleId,
+		})
 		return newPage, st.diskManager.WritePage(newPage)
 	}
 
 	if it.Page.Tuples.IsFull() {
-		newPage := NewPage(tableName, it.pageIteratorCursor.pageId+1, [TupleNumPerPage]*Tuple{tuple})
+		newPage := NewPage(tableName, it.Page.Id+1, [TupleNumPerPage]*Tuple{tuple})
 		newTupleId := &TupleId{
-			pageId: newPage.Id,
-			slotId: 0,
+			tableName: tableName,
+			pageId:    newPage.Id,
+			slotId:    0,
+		}
+		if err := st.checkForSerializableConflictIn(tableName, newTupleId, tx, txMgr); err != nil {
+			return nil, err
 		}
 		success := txMgr.LockExclusive(tx, newTupleId)
 		if !success {
 			return nil, fmt.Errorf("failed to lock exclusive")
 		}
 
-		if tx.state == ACTIVE {
-			tx.AddWriteRecord(
-				tableName,
-				nil,
-				newTupleId,
-			)
-		}
+		tx.AddUndoRecord(&insertTupleUndoRecord{
+			tableName: tableName,
+			tupleId:   *newTupleId,
+		})
 		return newPage, st.diskManager.WritePage(newPage)
 	}
 
 	tupleId := &TupleId{
-		pageId: it.pageIteratorCursor.pageId,
-		slotId: it.pageIteratorCursor.tupleOffset + 1,
+		tableName: tableName,
+		pageId:    it.Page.Id,
+		slotId:    it.Page.Tuples.emptySlot(),
+	}
+	if err := st.checkForSerializableConflictIn(tableName, tupleId, tx, txMgr); err != nil {
+		return nil, err
 	}
 	success := txMgr.LockExclusive(tx, tupleId)
 	if !success {
@@ -244,13 +304,10 @@ func (st *Storage) InsertTuple(tableName string, tuple *Tuple, tx *Transaction,
 	}
 
 	it.Page.Tuples.Insert(tuple)
-	if tx.state == ACTIVE {
-		tx.AddWriteRecord(
-			tableName,
-			nil,
-			tupleId,
-		)
-	}
+	tx.AddUndoRecord(&insertTupleUndoRecord{
+		tableName: tableName,
+		tupleId:   *tupleId,
+	})
 
 	return it.Page, st.diskManager.WritePage(it.Page)
 }
@@ -261,19 +318,191 @@ func (st *Storage) DeleteTuple(tableName string, tupleId *TupleId, tx *Transacti
 		return err
 	}
 
+	if err := st.checkForSerializableConflictIn(tableName, tupleId, tx, txMgr); err != nil {
+		return err
+	}
 	success := txMgr.LockExclusive(tx, tupleId)
 	if !success {
 		return errors.New("failed to lock tuple")
 	}
+	// a transaction reading from a snapshot may see a tuple which a concurrent transaction has deleted since
+	if deleted, err := txMgr.isDeletedByOthers(tx, page.Tuples[tupleId.slotId]); err != nil {
+		return err
+	} else if deleted {
+		return ConcurrentUpdateError
+	}
 
-	page.Tuples.DeleteTuple(tupleId.slotId)
-	if tx.state == ACTIVE {
-		tx.AddWriteRecord(tableName, tupleId, nil)
+	page.Tuples.DeleteTuple(tupleId.slotId, tx.id)
+	tx.AddUndoRecord(&deleteTupleUndoRecord{
+		tableName: tableName,
+		tupleId:   *tupleId,
+	})
+
+	return st.diskManager.WritePage(page)
+}
+
+func (st *Storage) setTupleDeleted(tableName string, tupleId *TupleId, isDeleted bool) error {
+	page, err := st.diskManager.readPage(tableName, tupleId.pageId)
+	if err != nil {
+		return err
 	}
 
+	page.Tuples[tupleId.slotId].IsDeleted = isDeleted
+	if !isDeleted {
+		page.Tuples[tupleId.slotId].Xmax = uint64(InvalidTransactionId)
+	}
 	return st.diskManager.WritePage(page)
 }
 
+// CreateTable creates the directory of the table and its empty indexes
+func (st *Storage) CreateTable(tableName string, indexNames []string, tx *Transaction) error {
+	if err := st.journal(tx, journalRecord{Op: journalCreateTable, TableName: tableName}); err != nil {
+		return err
+	}
+	if err := st.diskManager.createTableDir(tableName); err != nil {
+		return err
+	}
+	tx.AddUndoRecord(&createTableUndoRecord{
+		tableName: tableName,
+	})
+
+	for _, indexName := range indexNames {
+		if err := st.diskManager.WriteIndex(NewBTree(tableName, indexName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropTable moves the directory of the table aside. It is removed when tx is committed, and restored when tx is aborted.
+func (st *Storage) DropTable(tableName string, tx *Transaction) error {
+	// unique in the transaction, even if the table is dropped, created and dropped again
+	trashName := fmt.Sprintf(".%s.%d.%d", tableName, tx.id, len(tx.undoRecords))
+	if err := st.journal(tx, journalRecord{Op: journalDropTable, TableName: tableName, TrashName: trashName}); err != nil {
+		return err
+	}
+	if err := st.diskManager.renameTableDir(tableName, trashName); err != nil {
+		return err
+	}
+	tx.AddUndoRecord(&dropTableUndoRecord{
+		tableName: tableName,
+		trashName: trashName,
+	})
+	tx.AddCommitAction(&removeTableDirAction{
+		tableName: trashName,
+	})
+	return nil
+}
+
+// TruncateTable replaces the table with an empty one, which has the empty indexes
+func (st *Storage) TruncateTable(tableName string, indexNames []string, tx *Transaction) error {
+	if err := st.DropTable(tableName, tx); err != nil {
+		return err
+	}
+	return st.CreateTable(tableName, indexNames, tx)
+}
+
+// DropIndex moves the index aside. It is removed when tx is committed, and restored when tx is aborted.
+func (st *Storage) DropIndex(tableName string, indexName string, tx *Transaction) error {
+	fileName := indexName + ".json"
+	trashName := fmt.Sprintf(".%s.%d.%d", indexName, tx.id, len(tx.undoRecords))
+	if err := st.journal(tx, journalRecord{Op: journalDropFile, TableName: tableName, FileName: fileName, TrashName: trashName}); err != nil {
+		return err
+	}
+	if err := st.diskManager.renameFile(tableName, fileName, trashName); err != nil {
+		return err
+	}
+	tx.AddUndoRecord(&dropIndexUndoRecord{
+		tableName: tableName,
+		fileName:  fileName,
+		trashName: trashName,
+	})
+	tx.AddCommitAction(&removeFileAction{
+		tableName: tableName,
+		fileName:  trashName,
+	})
+	return nil
+}
+
+// RenameTable moves the files of the table. The change is reverted when tx is aborted.
+func (st *Storage) RenameTable(oldName string, newName string, tx *Transaction) error {
+	if err := st.journal(tx, journalRecord{Op: journalRenameTable, TableName: oldName, FileName: newName}); err != nil {
+		return err
+	}
+	if err := st.diskManager.renameTable(oldName, newName); err != nil {
+		return err
+	}
+	tx.AddUndoRecord(&renameTableUndoRecord{
+		oldName: oldName,
+		newName: newName,
+	})
+	return nil
+}
+
+func (st *Storage) InsertIndexItem(btree *BTree, item *StringItem, tx *Transaction) error {
+	if err := btree.Insert(item); err != nil {
+		return err
+	}
+	// journaled after the key is known to be new, so that recovery never removes the entry of another row
+	if err := st.journal(tx, journalRecord{Op: journalInsertIndexItem, TableName: btree.TableName, IndexName: btree.IndexName, Key: item.Value}); err != nil {
+		return err
+	}
+	tx.AddUndoRecord(&insertIndexItemUndoRecord{
+		tableName: btree.TableName,
+		indexName: btree.IndexName,
+		key:       item.Value,
+	})
+	return st.diskManager.WriteIndex(btree)
+}
+
+func (st *Storage) DeleteIndexItem(btree *BTree, key string, tx *Transaction) error {
+	item, found := btree.Search(&StringItem{Value: key})
+	if !found {
+		return nil
+	}
+	deletedItem := *item
+	if err := st.journal(tx, journalRecord{Op: journalDeleteIndexItem, TableName: btree.TableName, IndexName: btree.IndexName, Key: key, PageId: item.PageId}); err != nil {
+		return err
+	}
+	btree.Delete(&deletedItem)
+	tx.AddUndoRecord(&deleteIndexItemUndoRecord{
+		tableName: btree.TableName,
+		indexName: btree.IndexName,
+		item:      deletedItem,
+	})
+	return st.diskManager.WriteIndex(btree)
+}
+
+func (st *Storage) UpdateIndexItemPageId(btree *BTree, key string, pageId PageId, tx *Transaction) error {
+	item, found := btree.Search(&StringItem{Value: key})
+	if !found {
+		return fmt.Errorf("index entry not found: %s", key)
+	}
+	oldPageId := item.PageId
+	if err := st.journal(tx, journalRecord{Op: journalUpdateIndexItem, TableName: btree.TableName, IndexName: btree.IndexName, Key: key, PageId: oldPageId}); err != nil {
+		return err
+	}
+	btree.SearchAndUpdatePageId(&StringItem{Value: key, PageId: pageId})
+	tx.AddUndoRecord(&updateIndexItemUndoRecord{
+		tableName: btree.TableName,
+		indexName: btree.IndexName,
+		key:       key,
+		oldPageId: oldPageId,
+	})
+	return st.diskManager.WriteIndex(btree)
+}
+
+func (st *Storage) checkForSerializableConflictIn(tableName string, tupleId *TupleId, tx *Transaction, txMgr *TransactionManager) error {
+	// commit and abort also write tuples, but they never make a new rw-antidependency
+	if tx.state != ACTIVE {
+		return nil
+	}
+	return txMgr.CheckForSerializableConflictIn(tx, &PredicateLockTarget{
+		TableName: tableName,
+		TupleId:   tupleId,
+	})
+}
+
 func (st *Storage) ReadJson(path string, out interface{}) error {
 	jsonStr, err := os.ReadFile(st.diskManager.makeGeneralFilePath(path))
 	if err != nil {
@@ -283,11 +512,52 @@ func (st *Storage) ReadJson(path string, out interface{}) error {
 	return json.Unmarshal(jsonStr, out)
 }
 
+// WriteJson replaces the file atomically and syncs it to disk before returning, so a crash leaves either the old or the new file
 func (st *Storage) WriteJson(path string, in interface{}) error {
-	jsonStr, err := json.Marshal(in)
+	if err := st.writeJsonFile(path+".tmp", in); err != nil {
+		return err
+	}
+	return st.diskManager.replaceFile("", path+".tmp", path)
+}
+
+// ReplaceJsonOnCommit prepares the new content of the file, which replaces the file when tx is committed.
+// If the process stops after tx is committed, the file is replaced on recovery.
+func (st *Storage) ReplaceJsonOnCommit(path string, in interface{}, tx *Transaction) error {
+	preparedName := fmt.Sprintf(".%s.%d", path, tx.id)
+	if err := st.journal(tx, journalRecord{Op: journalReplaceFile, FileName: path, TrashName: preparedName}); err != nil {
+		return err
+	}
+	if err := st.writeJsonFile(preparedName, in); err != nil {
+		return err
+	}
+	tx.AddUndoRecord(&removeFileUndoRecord{
+		fileName: preparedName,
+	})
+	tx.AddCommitAction(&replaceFileAction{
+		from: preparedName,
+		to:   path,
+	})
+	return nil
+}
+
+// writeJsonFile writes and syncs the file
+func (st *Storage) writeJsonFile(path string, in interface{}) error {
+	b, err := json.Marshal(in)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(st.diskManager.makeGeneralFilePath(path), jsonStr, 0644)
+	file, err := os.OpenFile(st.diskManager.makeGeneralFilePath(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(b); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
 }
@@ -1,24 +1,58 @@
 package storage
 
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 type Transaction struct {
-	state TransactionState
-	id    TransactionId
+	// changed under TransactionManager.mutex by the session running the transaction, or by KILL while the transaction
+	// is idle (see mutex). The session reads it without the lock, and the others read it under the lock.
+	state          TransactionState
+	id             TransactionId
+	isolationLevel IsolationLevel
+	startTime      time.Time
+	// the statement which is running or has run last
+	statement string
 
-	writeRecords []*WriteRecord
+	undoRecords []UndoRecord
+	// run before the transaction is committed, and may fail the commit
+	prepareActions []CommitAction
+	// run after the transaction is committed
+	commitActions []CommitAction
+	// the changes of files have been written to the journal (see journal.go)
+	journaled bool
 
-	sharedLocks    map[PageId][]TransactionId
-	exclusiveLocks map[PageId]TransactionId
-}
+	// the last values returned by nextval, which currval returns. Transactions of a session share them.
+	sequenceValues map[string]int64
+
+	// read-only and serializable transactions read from a snapshot without locks (see snapshot.go)
+	readOnly bool
+	snapshot *Snapshot
+
+	// used by serializable snapshot isolation (see ssi.go)
+	startSeq     uint64
+	commitSeq    uint64
+	inConflicts  map[TransactionId]struct{}
+	outConflicts map[TransactionId]struct{}
+	doomed       bool
 
-type WriteRecord struct {
-	tableName  string
-	oldTupleId *TupleId
-	newTupleId *TupleId
+	// set by KILL in another session. The session running the transaction aborts it at the next statement or lock.
+	killed atomic.Bool
+	// TransactionKilledError has been returned to the session
+	killReported bool
+
+	// held by the session while it starts or ends a statement, and by KILL while it aborts an idle transaction
+	mutex sync.Mutex
+	// a statement is running. A new transaction is running until the statement which began it ends.
+	running bool
 }
 
 type TupleId struct {
-	pageId PageId
-	slotId uint8
+	tableName string
+	pageId    PageId
+	slotId    uint8
 }
 
 type TransactionState int32
@@ -29,14 +63,59 @@ const (
 	ABORTED
 )
 
-type TransactionId int32
+func (s TransactionState) String() string {
+	switch s {
+	case ACTIVE:
+		return "active"
+	case COMMITTED:
+		return "committed"
+	case ABORTED:
+		return "aborted"
+	default:
+		return "unknown"
+	}
+}
+
+type TransactionId uint64
+
+type IsolationLevel int32
+
+const (
+	RepeatableRead IsolationLevel = iota
+	Serializable
+)
+
+func (l IsolationLevel) String() string {
+	switch l {
+	case RepeatableRead:
+		return "repeatable read"
+	case Serializable:
+		return "serializable"
+	default:
+		return "unknown"
+	}
+}
+
+type TransactionOptions struct {
+	IsolationLevel IsolationLevel
+	ReadOnly       bool
+}
 
 func NewTransaction(id TransactionId) *Transaction {
+	return NewTransactionWithOptions(id, TransactionOptions{})
+}
+
+func NewTransactionWithOptions(id TransactionId, opts TransactionOptions) *Transaction {
 	return &Transaction{
 		state:          ACTIVE,
 		id:             id,
-		sharedLocks:    make(map[PageId][]TransactionId, 0),
-		exclusiveLocks: make(map[PageId]TransactionId, 0),
+		isolationLevel: opts.IsolationLevel,
+		readOnly:       opts.ReadOnly,
+		startTime:      time.Now(),
+		sequenceValues: make(map[string]int64, 0),
+		inConflicts:    make(map[TransactionId]struct{}, 0),
+		outConflicts:   make(map[TransactionId]struct{}, 0),
+		running:        true,
 	}
 }
 
@@ -48,10 +127,53 @@ func (t *Transaction) GetId() TransactionId {
 	return t.id
 }
 
-func (t *Transaction) AddWriteRecord(tableName string, oldTupleId *TupleId, newTupleId *TupleId) {
-	t.writeRecords = append(t.writeRecords, &WriteRecord{
-		tableName:  tableName,
-		oldTupleId: oldTupleId,
-		newTupleId: newTupleId,
-	})
+func (t *Transaction) GetIsolationLevel() IsolationLevel {
+	return t.isolationLevel
+}
+
+func (t *Transaction) IsReadOnly() bool {
+	return t.readOnly
+}
+
+func (t *Transaction) IsSerializable() bool {
+	return t.isolationLevel == Serializable
+}
+
+// IsKilled reports whether the transaction has been killed by another session
+func (t *Transaction) IsKilled() bool {
+	return t.killed.Load()
+}
+
+// ShareSequenceValues makes the transaction use the values returned by nextval in the session
+func (t *Transaction) ShareSequenceValues(values map[string]int64) {
+	t.sequenceValues = values
+}
+
+func (t *Transaction) SetSequenceValue(name string, value int64) {
+	t.sequenceValues[name] = value
+}
+
+// SequenceValue returns the value last returned by nextval for the sequence
+func (t *Transaction) SequenceValue(name string) (int64, bool) {
+	value, ok := t.sequenceValues[name]
+	return value, ok
+}
+
+// AddUndoRecord records how to revert a change. Changes made while committing or aborting are not recorded.
+func (t *Transaction) AddUndoRecord(record UndoRecord) {
+	if t.state != ACTIVE {
+		return
+	}
+	t.undoRecords = append(t.undoRecords, record)
+}
+
+// AddPrepareAction records an action which must succeed before the transaction is committed, e.g. writing a file
+// which replaces another one on commit. If it fails, the transaction is aborted.
+func (t *Transaction) AddPrepareAction(action CommitAction) {
+	t.prepareActions = append(t.prepareActions, action)
+}
+
+// AddCommitAction records a change which is finished only when the transaction is committed
+func (t *Transaction) AddCommitAction(action CommitAction) {
+	t.commitActions = append(t.commitActions, action)
 }
@@ -1,180 +1,320 @@
 package storage
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 )
 
 type TransactionManager struct {
-	transactions        map[TransactionId]*Transaction
-	latestTransactionId TransactionId
+	transactions map[TransactionId]*Transaction
+	commitLog    *CommitLog
 
 	mutex *sync.Mutex
 
-	exclusiveLockTable map[TupleId]TransactionId
-	sharedLockTable    map[TupleId][]TransactionId
+	lockManager *LockManager
+
+	// SIREAD locks of serializable transactions (see ssi.go)
+	predicateLocks map[TransactionId][]*predicateLock
+	// incremented on every begin and commit to order transactions
+	sequence uint64
+
+	// snapshots exported by read-only transactions (see snapshot.go)
+	exportedSnapshots map[string]*exportedSnapshot
+	snapshotSequence  uint64
 
 	storage *Storage
 }
 
-func NewTransactionManager(st *Storage) *TransactionManager {
+func NewTransactionManager(st *Storage) (*TransactionManager, error) {
+	commitLog, err := OpenCommitLog(st)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.recoverJournals(commitLog); err != nil {
+		return nil, err
+	}
+
 	return &TransactionManager{
-		transactions:       make(map[TransactionId]*Transaction, 0),
-		mutex:              new(sync.Mutex),
-		exclusiveLockTable: make(map[TupleId]TransactionId, 0),
-		sharedLockTable:    make(map[TupleId][]TransactionId, 0),
-		storage:            st,
+		transactions:      make(map[TransactionId]*Transaction, 0),
+		commitLog:         commitLog,
+		mutex:             new(sync.Mutex),
+		lockManager:       NewLockManager(),
+		predicateLocks:    make(map[TransactionId][]*predicateLock, 0),
+		exportedSnapshots: make(map[string]*exportedSnapshot, 0),
+		storage:           st,
+	}, nil
+}
+
+// Close closes the commit log. The transaction manager can't be used after it is closed.
+func (tm *TransactionManager) Close() error {
+	return tm.commitLog.Close()
+}
+
+func (tm *TransactionManager) Begin() (*Transaction, error) {
+	return tm.BeginWithOptions(TransactionOptions{})
+}
+
+func (tm *TransactionManager) BeginWithOptions(opts TransactionOptions) (*Transaction, error) {
+	if opts.ReadOnly && opts.IsolationLevel == Serializable {
+		return nil, fmt.Errorf("serializable read-only transactions are not supported")
 	}
+
+	// the id is allocated under tm.mutex, so that snapshots never miss a transaction which has got its id
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	id, err := tm.commitLog.NextTransactionId()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := NewTransactionWithOptions(id, opts)
+	tm.sequence++
+	tx.startSeq = tm.sequence
+	tm.transactions[tx.id] = tx
+	return tx, nil
 }
 
-func (tm *TransactionManager) Begin() *Transaction {
+// SetIsolationLevel changes the isolation level of tx, which must not have run any statement yet.
+func (tm *TransactionManager) SetIsolationLevel(tx *Transaction, level IsolationLevel) error {
+	if tx.readOnly && level == Serializable {
+		return fmt.Errorf("serializable read-only transactions are not supported")
+	}
+
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
 
-	tm.latestTransactionId++
-	tm.transactions[tm.latestTransactionId] = NewTransaction(tm.latestTransactionId)
-	return tm.transactions[tm.latestTransactionId]
+	if tx.statement != "" {
+		return fmt.Errorf("SET TRANSACTION ISOLATION LEVEL must be called before any query")
+	}
+	tx.isolationLevel = level
+	return nil
+}
+
+// GetTransactionStatus returns the fate of any transaction, including the ones of previous processes.
+func (tm *TransactionManager) GetTransactionStatus(id TransactionId) (TransactionStatus, error) {
+	return tm.commitLog.GetStatus(id)
 }
 
+// Commit commits tx. If tx is serializable and can not be serialized, tx is aborted and SerializationFailureError is returned.
+// A killed transaction is aborted and TransactionKilledError is returned.
 func (tm *TransactionManager) Commit(tx *Transaction) error {
-	for _, writeRecord := range tx.writeRecords {
-		if writeRecord.oldTupleId != nil {
-			if err := tm.storage.DeleteTuple(writeRecord.tableName, writeRecord.oldTupleId, tx, tm); err != nil {
-				return err
-			}
+	if err := tm.AbortIfKilled(tx); err != nil {
+		return err
+	}
+	if err := tm.preCommitCheck(tx); err != nil {
+		if abortErr := tm.Abort(tx); abortErr != nil {
+			return abortErr
 		}
+		return err
 	}
-	tm.UnlockSharedAll(tx)
-	tm.UnlockExclusiveAll(tx)
-	return nil
-}
 
-func (tm *TransactionManager) Abort(tx *Transaction) error {
-	for _, writeRecord := range tx.writeRecords {
-		if writeRecord.newTupleId != nil {
-			if err := tm.storage.DeleteTuple(writeRecord.tableName, writeRecord.newTupleId, tx, tm); err != nil {
-				return err
+	for _, action := range tx.prepareActions {
+		if err := action.Run(tm); err != nil {
+			if abortErr := tm.Abort(tx); abortErr != nil {
+				return abortErr
 			}
+			return err
 		}
 	}
-	tm.UnlockSharedAll(tx)
-	tm.UnlockExclusiveAll(tx)
-	return nil
-}
-
-func (tm *TransactionManager) LockShared(tx *Transaction, tupleId *TupleId) bool {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
+	tx.prepareActions = nil
 
-	if _, ok := tm.exclusiveLockTable[*tupleId]; ok {
-		return false
+	// the transaction is durably committed here
+	if err := tm.commitLog.SetStatus(tx.id, TransactionStatusCommitted); err != nil {
+		if abortErr := tm.Abort(tx); abortErr != nil {
+			return abortErr
+		}
+		return err
 	}
 
-	if _, ok := tm.sharedLockTable[*tupleId]; !ok {
-		tm.sharedLockTable[*tupleId] = make([]TransactionId, 0)
+	tm.mutex.Lock()
+	tm.sequence++
+	tx.commitSeq = tm.sequence
+	tx.state = COMMITTED
+	tm.mutex.Unlock()
+	tx.undoRecords = nil
+
+	// the transaction is committed even if an action fails, so the error is returned after releasing the locks
+	var actionErr error
+	for _, action := range tx.commitActions {
+		if err := action.Run(tm); err != nil && actionErr == nil {
+			actionErr = fmt.Errorf("transaction is committed, but failed to clean up: %w", err)
+		}
+	}
+	tx.commitActions = nil
+	if err := tm.storage.removeJournal(tx); err != nil && actionErr == nil {
+		actionErr = fmt.Errorf("transaction is committed, but failed to clean up: %w", err)
 	}
-	tm.sharedLockTable[*tupleId] = append(tm.sharedLockTable[*tupleId], tx.id)
 
-	return true
+	tm.lockManager.UnlockAll(tx.id)
+	tm.releasePredicateLocks()
+	tm.releaseExportedSnapshots(tx)
+	return actionErr
 }
 
-func (tm *TransactionManager) LockExclusive(tx *Transaction, tupleId *TupleId) bool {
+// Abort reverts the changes of tx and releases its locks. Every step is run even if an earlier one fails, so that a
+// failed undo never leaves the transaction active or its locks held. The errors are returned together.
+func (tm *TransactionManager) Abort(tx *Transaction) error {
 	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
-
-	if _, ok := tm.exclusiveLockTable[*tupleId]; ok {
-		if tm.exclusiveLockTable[*tupleId] == tx.id {
-			return true
+	tx.state = ABORTED
+	tm.mutex.Unlock()
+
+	var errs []error
+	// revert all changes in reverse order
+	for i := len(tx.undoRecords) - 1; i >= 0; i-- {
+		if err := tx.undoRecords[i].Undo(tm); err != nil {
+			errs = append(errs, err)
 		}
-		return false
 	}
+	tx.undoRecords = nil
+	tx.prepareActions = nil
+	tx.commitActions = nil
 
-	if sharedLockedTxs, ok := tm.sharedLockTable[*tupleId]; ok {
-		for _, sharedLockedTx := range sharedLockedTxs {
-			if sharedLockedTx == tx.id {
-				continue
-			}
-			return false
-		}
+	if err := tm.commitLog.SetStatus(tx.id, TransactionStatusAborted); err != nil {
+		errs = append(errs, err)
+	}
+	if err := tm.storage.removeJournal(tx); err != nil {
+		errs = append(errs, err)
 	}
 
-	tm.exclusiveLockTable[*tupleId] = tx.id
+	tm.lockManager.UnlockAll(tx.id)
+	tm.releasePredicateLocks()
+	tm.releaseExportedSnapshots(tx)
+	return errors.Join(errs...)
+}
 
-	return true
+// isVisible hides the tuples written by aborted transactions, including the ones running when the process stopped.
+// Tuples written by running transactions are protected by locks, except for the transactions which read from their snapshots.
+func (tm *TransactionManager) isVisible(tx *Transaction, tuple *Tuple) (bool, error) {
+	inserted, err := tm.isEffective(tx, TransactionId(tuple.Xmin))
+	if err != nil || !inserted {
+		return false, err
+	}
+	if !tuple.IsDeleted {
+		return true, nil
+	}
+	// the deletion is not effective if the deleting transaction has been aborted
+	if TransactionId(tuple.Xmax) == InvalidTransactionId {
+		return false, nil
+	}
+	deleted, err := tm.isEffective(tx, TransactionId(tuple.Xmax))
+	return !deleted, err
 }
 
-func (tm *TransactionManager) IsLockShared(tx *Transaction, tupleId *TupleId) bool {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
+// isEffective reports whether a change made by transaction id is effective for tx
+func (tm *TransactionManager) isEffective(tx *Transaction, id TransactionId) (bool, error) {
+	if id == InvalidTransactionId || id == tx.id {
+		return true, nil
+	}
+	if tx.snapshot != nil && !tx.snapshot.includes(id) {
+		return false, nil
+	}
 
-	if _, ok := tm.sharedLockTable[*tupleId]; !ok {
-		return false
+	status, err := tm.commitLog.GetStatus(id)
+	if err != nil {
+		return false, err
+	}
+	if tx.snapshot != nil {
+		return status == TransactionStatusCommitted, nil
 	}
+	return status != TransactionStatusAborted, nil
+}
 
-	for _, id := range tm.sharedLockTable[*tupleId] {
-		if id == tx.id {
-			return true
-		}
+// isDeletedByOthers reports whether another transaction which has not been aborted has deleted the tuple
+func (tm *TransactionManager) isDeletedByOthers(tx *Transaction, tuple *Tuple) (bool, error) {
+	deleter := TransactionId(tuple.Xmax)
+	if !tuple.IsDeleted || deleter == InvalidTransactionId || deleter == tx.id {
+		return false, nil
+	}
+	status, err := tm.commitLog.GetStatus(deleter)
+	if err != nil {
+		return false, err
 	}
-	return false
+	return status != TransactionStatusAborted, nil
 }
 
-func (tm *TransactionManager) IsLockExclusive(tx *Transaction, tupleId *TupleId) bool {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
+// Killed transactions can't take any more locks, so that their statements fail and the sessions abort them.
 
-	if _, ok := tm.exclusiveLockTable[*tupleId]; !ok {
-		return false
-	}
+func (tm *TransactionManager) LockShared(tx *Transaction, tupleId *TupleId) bool {
+	return !tx.IsKilled() && tm.lockManager.Lock(tx.id, tupleResource(tupleId), Shared)
+}
 
-	return tm.exclusiveLockTable[*tupleId] == tx.id
+func (tm *TransactionManager) LockExclusive(tx *Transaction, tupleId *TupleId) bool {
+	return !tx.IsKilled() && tm.lockManager.Lock(tx.id, tupleResource(tupleId), Exclusive)
 }
 
-func (tm *TransactionManager) UnlockSharedAll(tx *Transaction) {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
+// LockTable locks the whole table. DDL takes an exclusive table lock to exclude concurrent row operations.
+func (tm *TransactionManager) LockTable(tx *Transaction, tableName string, mode LockMode) bool {
+	return !tx.IsKilled() && tm.lockManager.Lock(tx.id, tableResource(tableName), mode)
+}
 
-	for tupleId, ids := range tm.sharedLockTable {
-		for i, id := range ids {
-			if id == tx.id {
-				ids = append(ids[:i], ids[i+1:]...)
-				if len(ids) == 0 {
-					delete(tm.sharedLockTable, tupleId)
-				} else {
-					tm.sharedLockTable[tupleId] = ids
-				}
-				return
-			}
-		}
+// LockKeyRange locks the gap of the index which contains key (next-key locking),
+// so that no other transaction can insert key until tx finishes. Only serializable transactions lock key ranges.
+func (tm *TransactionManager) LockKeyRange(tx *Transaction, btree *BTree, key string) bool {
+	if !tx.IsSerializable() {
+		return true
 	}
+	next, _ := btree.SearchNext(&StringItem{Value: key})
+	return !tx.IsKilled() && tm.lockManager.Lock(tx.id, keyRangeResource(btree.TableName, btree.IndexName, next), Shared)
 }
 
-func (tm *TransactionManager) UnlockSharedByTupleId(tx *Transaction, tupleId *TupleId) {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
+// LockKey locks a key of the index which tx inserts, deletes or updates at any isolation level. A deleted key is
+// put back when tx is aborted, so no other transaction may insert it in the meantime.
+func (tm *TransactionManager) LockKey(tx *Transaction, btree *BTree, key string) bool {
+	return !tx.IsKilled() && tm.lockManager.Lock(tx.id, keyResource(btree.TableName, btree.IndexName, key), Exclusive)
+}
 
-	if _, ok := tm.sharedLockTable[*tupleId]; !ok {
-		return
+// CheckKeyRangeForInsert fails when another transaction has locked the gap into which key is inserted.
+func (tm *TransactionManager) CheckKeyRangeForInsert(tx *Transaction, btree *BTree, key string) error {
+	next, _ := btree.SearchNext(&StringItem{Value: key})
+	resource := keyRangeResource(btree.TableName, btree.IndexName, next)
+	if holderId, locked := tm.lockManager.IsLockedByOthers(tx.id, resource, Exclusive); locked {
+		return fmt.Errorf("could not insert into index %s of table %s: the key range is locked by transaction %d", btree.IndexName, btree.TableName, holderId)
 	}
+	return nil
+}
+
+// InheritKeyRangeLocks must be called before key is deleted from the index.
+// The gap before key is merged into the next gap, so the locks on it are inherited by the next gap.
+func (tm *TransactionManager) InheritKeyRangeLocks(btree *BTree, key string) {
+	next, _ := btree.SearchNext(&StringItem{Value: key})
+	tm.lockManager.Inherit(
+		keyRangeResource(btree.TableName, btree.IndexName, &StringItem{Value: key}),
+		keyRangeResource(btree.TableName, btree.IndexName, next),
+	)
+}
 
-	for i, id := range tm.sharedLockTable[*tupleId] {
-		if id == tx.id {
-			tm.sharedLockTable[*tupleId] = append(tm.sharedLockTable[*tupleId][:i], tm.sharedLockTable[*tupleId][i+1:]...)
-			break
+func (tm *TransactionManager) getExclusiveLockHolder(tupleId *TupleId) (TransactionId, bool) {
+	for _, resource := range []LockResource{tupleResource(tupleId), tableResource(tupleId.tableName)} {
+		for id, mode := range tm.lockManager.GetHolders(resource) {
+			if mode == Exclusive {
+				return id, true
+			}
 		}
 	}
-	if len(tm.sharedLockTable[*tupleId]) == 0 {
-		delete(tm.sharedLockTable, *tupleId)
-	}
+	return 0, false
 }
 
-func (tm *TransactionManager) UnlockExclusiveAll(tx *Transaction) {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
+func (tm *TransactionManager) IsLockShared(tx *Transaction, tupleId *TupleId) bool {
+	return tm.lockManager.IsLocked(tx.id, tupleResource(tupleId), Shared)
+}
 
-	for tupleId, id := range tm.exclusiveLockTable {
-		if id == tx.id {
-			delete(tm.exclusiveLockTable, tupleId)
-			return
-		}
+func (tm *TransactionManager) IsLockExclusive(tx *Transaction, tupleId *TupleId) bool {
+	return tm.lockManager.IsLocked(tx.id, tupleResource(tupleId), Exclusive)
+}
+
+// UnlockSharedByTupleId releases a shared tuple lock. Exclusive locks are kept until the end of the transaction.
+func (tm *TransactionManager) UnlockSharedByTupleId(tx *Transaction, tupleId *TupleId) {
+	resource := tupleResource(tupleId)
+	if mode, ok := tm.lockManager.GetHolders(resource)[tx.id]; ok && mode == Shared {
+		tm.lockManager.Unlock(tx.id, resource)
 	}
 }
+
+func (tm *TransactionManager) SetLockEscalationThreshold(threshold int) {
+	tm.lockManager.mutex.Lock()
+	defer tm.lockManager.mutex.Unlock()
+
+	tm.lockManager.EscalationThreshold = threshold
+}
@@ -25,7 +25,7 @@ type TupleValue struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Value string `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Value []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
 }
 
 func (x *TupleValue) Reset() {
@@ -60,11 +60,11 @@ func (*TupleValue) Descriptor() ([]byte, []int) {
 	return file_tuple_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *TupleValue) GetValue() string {
+func (x *TupleValue) GetValue() []byte {
 	if x != nil {
 		return x.Value
 	}
-	return ""
+	return nil
 }
 
 type Tuple struct {
@@ -74,6 +74,9 @@ type Tuple struct {
 
 	Data      []*TupleValue `protobuf:"bytes,2,rep,name=data,proto3" json:"data,omitempty"`
 	IsDeleted bool          `protobuf:"varint,3,opt,name=isDeleted,proto3" json:"isDeleted,omitempty"`
+	Xmin      uint64        `protobuf:"varint,4,opt,name=xmin,proto3" json:"xmin,omitempty"`
+	Xmax      uint64        `protobuf:"varint,5,opt,name=xmax,proto3" json:"xmax,omitempty"`
+	Nulls     []byte        `protobuf:"bytes,6,opt,name=nulls,proto3" json:"nulls,omitempty"`
 }
 
 func (x *Tuple) Reset() {
@@ -122,18 +125,43 @@ func (x *Tuple) GetIsDeleted() bool {
 	return false
 }
 
+func (x *Tuple) GetXmin() uint64 {
+	if x != nil {
+		return x.Xmin
+	}
+	return 0
+}
+
+func (x *Tuple) GetXmax() uint64 {
+	if x != nil {
+		return x.Xmax
+	}
+	return 0
+}
+
+func (x *Tuple) GetNulls() []byte {
+	if x != nil {
+		return x.Nulls
+	}
+	return nil
+}
+
 var File_tuple_proto protoreflect.FileDescriptor
 
 var file_tuple_proto_rawDesc = []byte{
 	0x0a, 0x0b, 0x74, 0x75, 0x70, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x22, 0x0a,
 	0x0a, 0x54, 0x75, 0x70, 0x6c, 0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
-	0x65, 0x22, 0x46, 0x0a, 0x05, 0x54, 0x75, 0x70, 0x6c, 0x65, 0x12, 0x1f, 0x0a, 0x04, 0x64, 0x61,
-	0x74, 0x61, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x54, 0x75, 0x70, 0x6c, 0x65,
-	0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x1c, 0x0a, 0x09, 0x69,
-	0x73, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09,
-	0x69, 0x73, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x42, 0x0c, 0x5a, 0x0a, 0x2e, 0x2e, 0x2f,
-	0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x22, 0x84, 0x01, 0x0a, 0x05, 0x54, 0x75, 0x70, 0x6c, 0x65, 0x12, 0x1f, 0x0a, 0x04, 0x64,
+	0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x54, 0x75, 0x70, 0x6c,
+	0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x1c, 0x0a, 0x09,
+	0x69, 0x73, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x09, 0x69, 0x73, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x78, 0x6d,
+	0x69, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x78, 0x6d, 0x69, 0x6e, 0x12, 0x12,
+	0x0a, 0x04, 0x78, 0x6d, 0x61, 0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x78, 0x6d,
+	0x61, 0x78, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x75, 0x6c, 0x6c, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x05, 0x6e, 0x75, 0x6c, 0x6c, 0x73, 0x42, 0x0c, 0x5a, 0x0a, 0x2e, 0x2e, 0x2f, 0x73,
+	0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"fmt"
+)
+
+// UndoRecord reverts one change made by a transaction.
+// Undo records are applied in reverse order when the transaction is aborted.
+type UndoRecord interface {
+	Undo(txMgr *TransactionManager) error
+}
+
+type insertTupleUndoRecord struct {
+	tableName string
+	tupleId   TupleId
+}
+
+func (r *insertTupleUndoRecord) Undo(txMgr *TransactionManager) error {
+	return txMgr.storage.setTupleDeleted(r.tableName, &r.tupleId, true)
+}
+
+type deleteTupleUndoRecord struct {
+	tableName string
+	tupleId   TupleId
+}
+
+func (r *deleteTupleUndoRecord) Undo(txMgr *TransactionManager) error {
+	return txMgr.storage.setTupleDeleted(r.tableName, &r.tupleId, false)
+}
+
+type insertIndexItemUndoRecord struct {
+	tableName string
+	indexName string
+	key       string
+}
+
+func (r *insertIndexItemUndoRecord) Undo(txMgr *TransactionManager) error {
+	btree, err := txMgr.storage.ReadIndex(r.tableName, r.indexName)
+	if err != nil {
+		return err
+	}
+	btree.Delete(&StringItem{Value: r.key})
+	return txMgr.storage.WriteIndex(btree)
+}
+
+type deleteIndexItemUndoRecord struct {
+	tableName string
+	indexName string
+	item      StringItem
+}
+
+func (r *deleteIndexItemUndoRecord) Undo(txMgr *TransactionManager) error {
+	btree, err := txMgr.storage.ReadIndex(r.tableName, r.indexName)
+	if err != nil {
+		return err
+	}
+	if err := btree.Insert(&r.item); err != nil {
+		return err
+	}
+	return txMgr.storage.WriteIndex(btree)
+}
+
+type updateIndexItemUndoRecord struct {
+	tableName string
+	indexName string
+	key       string
+	oldPageId PageId
+}
+
+func (r *updateIndexItemUndoRecord) Undo(txMgr *TransactionManager) error {
+	btree, err := txMgr.storage.ReadIndex(r.tableName, r.indexName)
+	if err != nil {
+		return err
+	}
+	if _, found := btree.SearchAndUpdatePageId(&StringItem{Value: r.key, PageId: r.oldPageId}); !found {
+		return fmt.Errorf("index entry not found: %s", r.key)
+	}
+	return txMgr.storage.WriteIndex(btree)
+}
+
+type createTableUndoRecord struct {
+	tableName string
+}
+
+func (r *createTableUndoRecord) Undo(txMgr *TransactionManager) error {
+	return txMgr.storage.diskManager.removeTableDir(r.tableName)
+}
+
+type dropTableUndoRecord struct {
+	tableName string
+	trashName string
+}
+
+func (r *dropTableUndoRecord) Undo(txMgr *TransactionManager) error {
+	return txMgr.storage.diskManager.renameTableDir(r.trashName, r.tableName)
+}
+
+type renameTableUndoRecord struct {
+	oldName string
+	newName string
+}
+
+func (r *renameTableUndoRecord) Undo(txMgr *TransactionManager) error {
+	return txMgr.storage.diskManager.renameTable(r.newName, r.oldName)
+}
+
+type dropIndexUndoRecord struct {
+	tableName string
+	fileName  string
+	trashName string
+}
+
+func (r *dropIndexUndoRecord) Undo(txMgr *TransactionManager) error {
+	return txMgr.storage.diskManager.renameFile(r.tableName, r.trashName, r.fileName)
+}
+
+type createSequenceUndoRecord struct {
+	name string
+}
+
+func (r *createSequenceUndoRecord) Undo(txMgr *TransactionManager) error {
+	txMgr.storage.forgetSequence(r.name)
+	return txMgr.storage.diskManager.removeFile("", sequenceFileName(r.name))
+}
+
+type dropSequenceUndoRecord struct {
+	name      string
+	trashName string
+}
+
+func (r *dropSequenceUndoRecord) Undo(txMgr *TransactionManager) error {
+	// a sequence created with the same name in the transaction has been removed already
+	txMgr.storage.forgetSequence(r.name)
+	return txMgr.storage.diskManager.renameFile("", r.trashName, sequenceFileName(r.name))
+}
+
+type removeFileUndoRecord struct {
+	fileName string
+}
+
+func (r *removeFileUndoRecord) Undo(txMgr *TransactionManager) error {
+	return txMgr.storage.diskManager.removeFile("", r.fileName)
+}
+
+// CommitAction finishes a change when the transaction is committed.
+// It is used for the changes which can't be undone, e.g. removing files.
+type CommitAction interface {
+	Run(txMgr *TransactionManager) error
+}
+
+type removeTableDirAction struct {
+	tableName string
+}
+
+func (a *removeTableDirAction) Run(txMgr *TransactionManager) error {
+	return txMgr.storage.diskManager.removeTableDir(a.tableName)
+}
+
+type removeFileAction struct {
+	tableName string
+	fileName  string
+}
+
+func (a *removeFileAction) Run(txMgr *TransactionManager) error {
+	return txMgr.storage.diskManager.removeFile(a.tableName, a.fileName)
+}
+
+type replaceFileAction struct {
+	from string
+	to   string
+}
+
+func (a *replaceFileAction) Run(txMgr *TransactionManager) error {
+	return txMgr.storage.diskManager.replaceFile("", a.from, a.to)
+}
@@ -0,0 +1,258 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// The arithmetic operators compute numeric values of the wider type of the operands: Int < BigInt < Decimal < Double.
+// An operation with NULL is NULL.
+
+func Add(a Value, b Value) (Value, error) {
+	return arithmetic("+", a, b)
+}
+
+func Subtract(a Value, b Value) (Value, error) {
+	return arithmetic("-", a, b)
+}
+
+func Multiply(a Value, b Value) (Value, error) {
+	return arithmetic("*", a, b)
+}
+
+// Divide truncates the quotient of integers toward zero
+func Divide(a Value, b Value) (Value, error) {
+	return arithmetic("/", a, b)
+}
+
+// Modulo returns the remainder, whose sign is the sign of a
+func Modulo(a Value, b Value) (Value, error) {
+	return arithmetic("%", a, b)
+}
+
+func Negate(v Value) (Value, error) {
+	if !v.typ.IsNumeric() && !(v.IsNull() && v.typ == Unknown) {
+		return Value{}, fmt.Errorf("operator does not exist: - %s", v.typ)
+	}
+	return arithmetic("-", NewInt(0), v)
+}
+
+// Round rounds a number half away from zero to scale digits after the decimal point. A negative scale rounds the digits
+// before it, e.g. Round(1250, -2) is 1300.
+func Round(v Value, scale int32) (Value, error) {
+	if !v.typ.IsNumeric() {
+		return Value{}, fmt.Errorf("cannot round %s", v.typ)
+	}
+	if v.IsNull() || scale >= 0 && (v.typ == Int || v.typ == BigInt) {
+		return v, nil
+	}
+	if f, ok := v.v.(float64); ok {
+		p := math.Pow10(int(scale))
+		return NewDouble(math.Round(f*p) / p), nil
+	}
+
+	if scale >= 0 {
+		return Value{typ: Decimal, v: decimal{unscaled: roundRat(v.rat(), scale), scale: scale}}, nil
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil)
+	rounded := roundRat(new(big.Rat).Quo(v.rat(), new(big.Rat).SetInt(unit)), 0)
+	rounded.Mul(rounded, unit)
+	if v.typ == Decimal {
+		return Value{typ: Decimal, v: decimal{unscaled: rounded, scale: 0}}, nil
+	}
+	return integerOf(v.typ, rounded)
+}
+
+// Concat concatenates the text representations of the values
+func Concat(a Value, b Value) Value {
+	if a.IsNull() || b.IsNull() {
+		return NewNull(Text)
+	}
+	return NewText(a.String() + b.String())
+}
+
+// Bool returns the value of a boolean. It is false for NULL.
+func (v Value) Bool() bool {
+	b, _ := v.v.(bool)
+	return b
+}
+
+// widerNumericType returns the type of the result of an arithmetic operation. NULL of Unknown type takes the other type.
+func widerNumericType(operator string, a Value, b Value) (Type, error) {
+	if a.typ == Unknown && a.IsNull() {
+		a.typ = b.typ
+	}
+	if b.typ == Unknown && b.IsNull() {
+		b.typ = a.typ
+	}
+	if a.typ == Unknown && b.typ == Unknown {
+		return Unknown, nil
+	}
+	if !a.typ.IsNumeric() || !b.typ.IsNumeric() {
+		return Unknown, fmt.Errorf("operator does not exist: %s %s %s", a.typ, operator, b.typ)
+	}
+	for _, typ := range []Type{Double, Decimal, BigInt} {
+		if a.typ == typ || b.typ == typ {
+			return typ, nil
+		}
+	}
+	return Int, nil
+}
+
+func arithmetic(operator string, a Value, b Value) (Value, error) {
+	typ, err := widerNumericType(operator, a, b)
+	if err != nil {
+		return Value{}, err
+	}
+	if a.IsNull() || b.IsNull() {
+		return NewNull(typ), nil
+	}
+
+	switch typ {
+	case Double:
+		return doubleArithmetic(operator, a.float(), b.float())
+	case Decimal:
+		return decimalArithmetic(operator, toDecimal(a), toDecimal(b))
+	default:
+		return integerArithmetic(operator, typ, a.v.(int64), b.v.(int64))
+	}
+}
+
+var divisionByZeroError = fmt.Errorf("division by zero")
+
+func integerArithmetic(operator string, typ Type, x int64, y int64) (Value, error) {
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(big.NewInt(x), big.NewInt(y))
+	case "-":
+		result.Sub(big.NewInt(x), big.NewInt(y))
+	case "*":
+		result.Mul(big.NewInt(x), big.NewInt(y))
+	case "/":
+		if y == 0 {
+			return Value{}, divisionByZeroError
+		}
+		result.Quo(big.NewInt(x), big.NewInt(y))
+	case "%":
+		if y == 0 {
+			return Value{}, divisionByZeroError
+		}
+		result.Rem(big.NewInt(x), big.NewInt(y))
+	}
+	return integerOf(typ, result)
+}
+
+// integerOf returns a value of Int or BigInt, or an error if i is out of the range of typ
+func integerOf(typ Type, i *big.Int) (Value, error) {
+	minimum, maximum := int64(math.MinInt64), int64(math.MaxInt64)
+	if typ == Int {
+		minimum, maximum = math.MinInt32, math.MaxInt32
+	}
+	if !i.IsInt64() || i.Int64() < minimum || i.Int64() > maximum {
+		return Value{}, fmt.Errorf("%s out of range", typ)
+	}
+	return Value{typ: typ, v: i.Int64()}, nil
+}
+
+func doubleArithmetic(operator string, x float64, y float64) (Value, error) {
+	switch operator {
+	case "+":
+		return NewDouble(x + y), nil
+	case "-":
+		return NewDouble(x - y), nil
+	case "*":
+		return NewDouble(x * y), nil
+	case "/":
+		if y == 0 {
+			return Value{}, divisionByZeroError
+		}
+		return NewDouble(x / y), nil
+	default:
+		if y == 0 {
+			return Value{}, divisionByZeroError
+		}
+		return NewDouble(math.Mod(x, y)), nil
+	}
+}
+
+// the minimum scale of the quotient of decimals
+const divisionScale = 16
+
+func decimalArithmetic(operator string, x decimal, y decimal) (Value, error) {
+	scale := max(x.scale, y.scale)
+	var result decimal
+	switch operator {
+	case "+":
+		result = decimal{unscaled: new(big.Int).Add(x.rescale(scale), y.rescale(scale)), scale: scale}
+	case "-":
+		result = decimal{unscaled: new(big.Int).Sub(x.rescale(scale), y.rescale(scale)), scale: scale}
+	case "*":
+		result = decimal{unscaled: new(big.Int).Mul(x.unscaled, y.unscaled), scale: x.scale + y.scale}
+	case "/":
+		if y.unscaled.Sign() == 0 {
+			return Value{}, divisionByZeroError
+		}
+		scale = max(scale, divisionScale)
+		result = decimal{unscaled: roundRat(new(big.Rat).Quo(x.rat(), y.rat()), scale), scale: scale}
+	default:
+		if y.unscaled.Sign() == 0 {
+			return Value{}, divisionByZeroError
+		}
+		quotient := new(big.Int).Quo(x.rescale(scale), y.rescale(scale))
+		remainder := new(big.Int).Sub(x.rescale(scale), new(big.Int).Mul(quotient, y.rescale(scale)))
+		result = decimal{unscaled: remainder, scale: scale}
+	}
+	return Value{typ: Decimal, v: result}, nil
+}
+
+// rescale returns the unscaled value of d with the larger scale
+func (d decimal) rescale(scale int32) *big.Int {
+	return new(big.Int).Mul(d.unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-d.scale)), nil))
+}
+
+// roundRat returns r * 10^scale rounded half away from zero
+func roundRat(r *big.Rat, scale int32) *big.Int {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	numerator := new(big.Int).Abs(scaled.Num())
+	quotient, remainder := new(big.Int).QuoRem(numerator, scaled.Denom(), new(big.Int))
+	if new(big.Int).Lsh(remainder, 1).Cmp(scaled.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if scaled.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	return quotient
+}
+
+// toDecimal converts an integer or a decimal
+func toDecimal(v Value) decimal {
+	if d, ok := v.v.(decimal); ok {
+		return d
+	}
+	return decimal{unscaled: big.NewInt(v.v.(int64)), scale: 0}
+}
+
+// castNumeric converts a number to another numeric type. Integers are rounded half away from zero.
+func castNumeric(v Value, typ Type) (Value, error) {
+	switch typ {
+	case Double:
+		return NewDouble(v.float()), nil
+	case Decimal:
+		if f, ok := v.v.(float64); ok {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return Value{}, fmt.Errorf("cannot convert %s to decimal", v)
+			}
+			d, _ := parseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+			return Value{typ: Decimal, v: d}, nil
+		}
+		return Value{typ: Decimal, v: toDecimal(v)}, nil
+	default:
+		if f, ok := v.v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return Value{}, fmt.Errorf("%s out of range", typ)
+		}
+		return integerOf(typ, roundRat(v.rat(), 0))
+	}
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// decimal is an exact number unscaled * 10^-scale. The scale written in the literal is kept (1.50 is not 1.5 when printed).
+type decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+func parseDecimal(s string) (decimal, bool) {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return decimal{}, false
+	}
+
+	unscaled, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return decimal{}, false
+	}
+	if sign == "-" {
+		unscaled.Neg(unscaled)
+	}
+	return decimal{unscaled: unscaled, scale: int32(len(fracPart))}, true
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (d decimal) rat() *big.Rat {
+	denominator := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil)
+	return new(big.Rat).SetFrac(d.unscaled, denominator)
+}
+
+func (d decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= int(d.scale) {
+			digits = strings.Repeat("0", int(d.scale)-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+	if d.unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// normalize returns the significant digits without trailing zeros and the exponent e, where |d| = 0.digits * 10^e
+func (d decimal) normalize() (string, int32) {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	exponent := int32(len(digits)) - d.scale
+	digits = strings.TrimRight(digits, "0")
+	return digits, exponent
+}
+
+const maxDecimalPrecision = 1000
+
+// Typmod is the precision and the scale of decimal(precision, scale). A decimal without them has no typmod (nil).
+type Typmod struct {
+	Precision int32 `json:"precision"`
+	Scale     int32 `json:"scale"`
+}
+
+// NewTypmod validates the parameters written after a type name, e.g. (10, 2) of decimal(10, 2). The parameters of the
+// other types, e.g. varchar(255), are not enforced and give nil.
+func NewTypmod(typ Type, params []int64) (*Typmod, error) {
+	if typ != Decimal || len(params) == 0 {
+		return nil, nil
+	}
+	if len(params) > 2 {
+		return nil, fmt.Errorf("invalid NUMERIC type modifier")
+	}
+	precision, scale := params[0], int64(0)
+	if len(params) == 2 {
+		scale = params[1]
+	}
+	if precision < 1 || precision > maxDecimalPrecision {
+		return nil, fmt.Errorf("NUMERIC precision %d must be between 1 and %d", precision, maxDecimalPrecision)
+	}
+	if scale < 0 || scale > precision {
+		return nil, fmt.Errorf("NUMERIC scale %d must be between 0 and precision %d", scale, precision)
+	}
+	return &Typmod{Precision: int32(precision), Scale: int32(scale)}, nil
+}
+
+// Apply rounds a decimal half away from zero to the scale and rejects it if the digits before the decimal point don't
+// fit in the precision. nil accepts any value.
+func (m *Typmod) Apply(v Value) (Value, error) {
+	if m == nil || v.IsNull() || v.typ != Decimal {
+		return v, nil
+	}
+	rounded := decimal{unscaled: roundRat(v.rat(), m.Scale), scale: m.Scale}
+	if len(new(big.Int).Abs(rounded.unscaled).String()) > int(m.Precision) {
+		return Value{}, fmt.Errorf("numeric field overflow: a field with precision %d, scale %d must round to an absolute value less than 10^%d",
+			m.Precision, m.Scale, m.Precision-m.Scale)
+	}
+	return Value{typ: Decimal, v: rounded}, nil
+}
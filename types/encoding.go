@@ -0,0 +1,194 @@
+package types
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Encode converts v to the binary format stored in tuples. NULL is recorded in the null bitmap of the tuple instead.
+func Encode(v Value) []byte {
+	switch x := v.v.(type) {
+	case string:
+		return []byte(x)
+	case int64:
+		if v.typ == Int {
+			return binary.BigEndian.AppendUint32(nil, uint32(int32(x)))
+		}
+		return binary.BigEndian.AppendUint64(nil, uint64(x))
+	case float64:
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(x))
+	case decimal:
+		// scale, sign and the absolute value of unscaled
+		b := binary.BigEndian.AppendUint32(nil, uint32(x.scale))
+		if x.unscaled.Sign() < 0 {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+		return append(b, new(big.Int).Abs(x.unscaled).Bytes()...)
+	case bool:
+		if x {
+			return []byte{1}
+		}
+		return []byte{0}
+	case time.Time:
+		if v.typ == Date {
+			return binary.BigEndian.AppendUint32(nil, uint32(int32(x.Unix()/secondsPerDay)))
+		}
+		return binary.BigEndian.AppendUint64(nil, uint64(x.UnixMicro()))
+	case []byte:
+		return x
+	default:
+		return nil
+	}
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// Decode converts the binary format stored in tuples to a value of typ
+func Decode(typ Type, b []byte) (Value, error) {
+	invalid := fmt.Errorf("invalid binary data for type %s", typ)
+
+	switch typ {
+	case Text:
+		return NewText(string(b)), nil
+	case Int:
+		if len(b) != 4 {
+			return Value{}, invalid
+		}
+		return NewInt(int64(int32(binary.BigEndian.Uint32(b)))), nil
+	case BigInt:
+		// written as int before the type of the column was changed
+		if len(b) == 4 {
+			return NewBigInt(int64(int32(binary.BigEndian.Uint32(b)))), nil
+		}
+		if len(b) != 8 {
+			return Value{}, invalid
+		}
+		return NewBigInt(int64(binary.BigEndian.Uint64(b))), nil
+	case Double:
+		if len(b) != 8 {
+			return Value{}, invalid
+		}
+		return NewDouble(math.Float64frombits(binary.BigEndian.Uint64(b))), nil
+	case Decimal:
+		if len(b) < 5 {
+			return Value{}, invalid
+		}
+		unscaled := new(big.Int).SetBytes(b[5:])
+		if b[4] == 1 {
+			unscaled.Neg(unscaled)
+		}
+		return Value{typ: Decimal, v: decimal{unscaled: unscaled, scale: int32(binary.BigEndian.Uint32(b))}}, nil
+	case Boolean:
+		if len(b) != 1 {
+			return Value{}, invalid
+		}
+		return NewBoolean(b[0] == 1), nil
+	case Date:
+		if len(b) != 4 {
+			return Value{}, invalid
+		}
+		return NewDate(time.Unix(int64(int32(binary.BigEndian.Uint32(b)))*secondsPerDay, 0).UTC()), nil
+	case Timestamp:
+		if len(b) != 8 {
+			return Value{}, invalid
+		}
+		return NewTimestamp(time.UnixMicro(int64(binary.BigEndian.Uint64(b)))), nil
+	case Bytea:
+		return NewBytea(b), nil
+	default:
+		return Value{}, fmt.Errorf("unknown type: %d", typ)
+	}
+}
+
+// NullKey is the index key of NULL. It is larger than the keys of any other values.
+const NullKey = "\U0010ffff"
+
+// EncodeKey converts v to an index key. Keys are compared as strings, so the encoding keeps the order of values.
+// Text is used as it is, and the other types are hex encoded memcomparable bytes.
+func EncodeKey(v Value) string {
+	switch x := v.v.(type) {
+	case nil:
+		return NullKey
+	case string:
+		return x
+	case int64:
+		return hex.EncodeToString(binary.BigEndian.AppendUint64(nil, uint64(x)^(1<<63)))
+	case float64:
+		// -0 is equal to 0, and NaNs are equal to each other and larger than any other number
+		switch {
+		case x == 0:
+			x = 0
+		case math.IsNaN(x):
+			x = math.NaN()
+		}
+		bits := math.Float64bits(x)
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		return hex.EncodeToString(binary.BigEndian.AppendUint64(nil, bits))
+	case decimal:
+		return hex.EncodeToString(encodeDecimalKey(x))
+	case bool:
+		if x {
+			return "01"
+		}
+		return "00"
+	case time.Time:
+		return hex.EncodeToString(binary.BigEndian.AppendUint64(nil, uint64(x.UnixMicro())^(1<<63)))
+	case []byte:
+		return hex.EncodeToString(x)
+	default:
+		return ""
+	}
+}
+
+// In a composite key, 0x00 in the key of a column is escaped as 0x00 0xff, and each key ends with keyTerminator.
+// keyTerminator is smaller than the rest of any longer key, so a composite key is ordered by the first column, then
+// the second column and so on, and different values never make the same key.
+const (
+	keyTerminator = "\x00\x01"
+	escapedZero   = "\x00\xff"
+)
+
+// EncodeKeys converts values of a composite key to an index key. A single value is encoded the same as EncodeKey.
+func EncodeKeys(values ...Value) string {
+	if len(values) == 1 {
+		return EncodeKey(values[0])
+	}
+	var b strings.Builder
+	for _, v := range values {
+		b.WriteString(strings.ReplaceAll(EncodeKey(v), "\x00", escapedZero))
+		b.WriteString(keyTerminator)
+	}
+	return b.String()
+}
+
+// encodeDecimalKey encodes the sign, the exponent and the significant digits, so that equal values have the same key
+// regardless of their scales (1.5 and 1.50).
+func encodeDecimalKey(d decimal) []byte {
+	if d.unscaled.Sign() == 0 {
+		return []byte{1}
+	}
+
+	digits, exponent := d.normalize()
+	if d.unscaled.Sign() > 0 {
+		b := binary.BigEndian.AppendUint32([]byte{2}, uint32(exponent)^(1<<31))
+		return append(b, digits...)
+	}
+
+	// negative values are ordered in reverse
+	b := binary.BigEndian.AppendUint32([]byte{0}, ^(uint32(exponent) ^ (1 << 31)))
+	for i := 0; i < len(digits); i++ {
+		b = append(b, 0xff-digits[i])
+	}
+	return append(b, 0xff)
+}
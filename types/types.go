@@ -0,0 +1,83 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the type of a column. The values are persisted in the catalog, so don't reorder them.
+type Type uint8
+
+const (
+	Unknown Type = iota
+	Text
+	Int
+	BigInt
+	Double
+	Decimal
+	Boolean
+	Date
+	Timestamp
+	Bytea
+)
+
+func (t Type) String() string {
+	switch t {
+	case Text:
+		return "text"
+	case Int:
+		return "int"
+	case BigInt:
+		return "bigint"
+	case Double:
+		return "double"
+	case Decimal:
+		return "decimal"
+	case Boolean:
+		return "boolean"
+	case Date:
+		return "date"
+	case Timestamp:
+		return "timestamp"
+	case Bytea:
+		return "bytea"
+	default:
+		return "unknown"
+	}
+}
+
+func (t Type) IsNumeric() bool {
+	return t == Int || t == BigInt || t == Double || t == Decimal
+}
+
+// IsBinaryCoercible reports whether the stored values and the index keys of from can be read as to,
+// so that the type of a column can be changed without rewriting the table
+func IsBinaryCoercible(from Type, to Type) bool {
+	return from == to || from == Int && to == BigInt
+}
+
+// ParseType maps a type name in SQL to Type
+func ParseType(name string) (Type, error) {
+	switch strings.ToLower(name) {
+	case "text", "varchar", "char":
+		return Text, nil
+	case "int", "integer", "smallint", "tinyint", "mediumint":
+		return Int, nil
+	case "bigint":
+		return BigInt, nil
+	case "double", "float", "real":
+		return Double, nil
+	case "decimal", "numeric":
+		return Decimal, nil
+	case "boolean", "bool":
+		return Boolean, nil
+	case "date":
+		return Date, nil
+	case "timestamp", "datetime":
+		return Timestamp, nil
+	case "bytea", "blob", "binary", "varbinary":
+		return Bytea, nil
+	default:
+		return Unknown, fmt.Errorf("unknown type: %s", name)
+	}
+}
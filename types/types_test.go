@@ -0,0 +1,191 @@
+package types_test
+
+import (
+	"garakutadb/types"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestParseAndEncode(t *testing.T) {
+	cases := []struct {
+		typ      types.Type
+		literal  string
+		expected string
+	}{
+		{types.Text, "alice", "alice"},
+		{types.Int, "-42", "-42"},
+		{types.BigInt, "9223372036854775807", "9223372036854775807"},
+		{types.Double, "1.25", "1.25"},
+		{types.Decimal, "-12.50", "-12.50"},
+		{types.Decimal, ".5", "0.5"},
+		{types.Boolean, "TRUE", "true"},
+		{types.Date, "1969-07-20", "1969-07-20"},
+		{types.Timestamp, "2024-01-02 03:04:05.123456", "2024-01-02 03:04:05.123456"},
+		{types.Bytea, `\x00ff`, `\x00ff`},
+	}
+
+	for _, c := range cases {
+		v, err := types.Parse(c.typ, c.literal)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, v.String())
+
+		decoded, err := types.Decode(c.typ, types.Encode(v))
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, decoded.String())
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for typ, literal := range map[types.Type]string{
+		types.Int:       "2147483648",
+		types.BigInt:    "1.5",
+		types.Double:    "abc",
+		types.Decimal:   "1e5",
+		types.Boolean:   "maybe",
+		types.Date:      "2024-02-30",
+		types.Timestamp: "yesterday",
+		types.Bytea:     `\xzz`,
+	} {
+		_, err := types.Parse(typ, literal)
+		assert.NotNil(t, err, "%s %s", typ, literal)
+	}
+}
+
+func TestEncodeKeyKeepsOrder(t *testing.T) {
+	literals := map[types.Type][]string{
+		types.Int:       {"-100", "-1", "0", "1", "100"},
+		types.Double:    {"-1e10", "-1.5", "0", "0.25", "3"},
+		types.Decimal:   {"-100", "-12.5", "-12.25", "-0.5", "0", "0.05", "1", "1.01", "10"},
+		types.Timestamp: {"1960-01-01", "2024-01-01 00:00:00", "2024-01-01 00:00:01"},
+	}
+
+	for typ, sorted := range literals {
+		keys := make([]string, 0)
+		for _, literal := range sorted {
+			v, err := types.Parse(typ, literal)
+			assert.Nil(t, err)
+			keys = append(keys, types.EncodeKey(v))
+		}
+		assert.True(t, sort.StringsAreSorted(keys), "%s", typ)
+	}
+
+	// equal decimals have the same key regardless of the scale
+	a, _ := types.Parse(types.Decimal, "1.5")
+	b, _ := types.Parse(types.Decimal, "1.50")
+	assert.Equal(t, types.EncodeKey(a), types.EncodeKey(b))
+	cmp, err := types.Compare(a, b)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cmp)
+}
+
+func TestEncodeKeyOfEqualDoubles(t *testing.T) {
+	zero := types.NewDouble(0)
+	assert.Equal(t, types.EncodeKey(zero), types.EncodeKey(types.NewDouble(math.Copysign(0, -1))))
+
+	nan := types.NewDouble(math.NaN())
+	assert.Equal(t, types.EncodeKey(nan), types.EncodeKey(types.NewDouble(-math.NaN())))
+	assert.Equal(t, types.EncodeKey(nan), types.EncodeKey(types.NewDouble(math.Float64frombits(0xfff0000000000001))))
+	assert.Less(t, types.EncodeKey(types.NewDouble(math.Inf(1))), types.EncodeKey(nan))
+}
+
+func TestEncodeKeysKeepsOrder(t *testing.T) {
+	rows := [][]string{{"a", "2"}, {"a", "10"}, {"ab", "1"}, {"b", "-1"}}
+	keys := make([]string, 0)
+	for _, row := range rows {
+		name := types.NewText(row[0])
+		n, err := types.Parse(types.Int, row[1])
+		assert.Nil(t, err)
+		keys = append(keys, types.EncodeKeys(name, n))
+	}
+	assert.True(t, sort.StringsAreSorted(keys))
+
+	one, _ := types.Parse(types.Int, "1")
+	assert.Equal(t, types.EncodeKey(one), types.EncodeKeys(one))
+
+	// a text with NUL neither collides nor breaks the order
+	text := types.NewText
+	assert.NotEqual(t, types.EncodeKeys(text("a\x00"), text("b")), types.EncodeKeys(text("a"), text("\x00b")))
+	sorted := [][]types.Value{{text("a"), text("\x00b")}, {text("a"), text("b")}, {text("a\x00"), text("b")}, {text("a\x01"), text("")}}
+	keys = keys[:0]
+	for _, row := range sorted {
+		keys = append(keys, types.EncodeKeys(row...))
+	}
+	assert.True(t, sort.StringsAreSorted(keys))
+}
+
+func TestNullIsLargest(t *testing.T) {
+	null := types.NewNull(types.Int)
+	one, _ := types.Parse(types.Int, "1")
+
+	cmp, err := types.Compare(null, one)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cmp)
+	cmp, err = types.Compare(null, types.NewNull(types.Int))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cmp)
+
+	assert.Less(t, types.EncodeKey(one), types.EncodeKey(null))
+	assert.Less(t, types.EncodeKey(types.NewText("zzz")), types.EncodeKey(types.NewNull(types.Text)))
+}
+
+func TestArithmetic(t *testing.T) {
+	parse := func(typ types.Type, literal string) types.Value {
+		v, err := types.Parse(typ, literal)
+		assert.Nil(t, err)
+		return v
+	}
+	cases := []struct {
+		operation func(a types.Value, b types.Value) (types.Value, error)
+		a         types.Value
+		b         types.Value
+		expected  string
+		typ       types.Type
+	}{
+		{types.Add, types.NewInt(1), types.NewInt(2), "3", types.Int},
+		{types.Subtract, types.NewInt(1), types.NewBigInt(5), "-4", types.BigInt},
+		{types.Divide, types.NewInt(-7), types.NewInt(2), "-3", types.Int},
+		{types.Modulo, types.NewInt(-7), types.NewInt(2), "-1", types.Int},
+		{types.Add, parse(types.Decimal, "1.50"), types.NewInt(2), "3.50", types.Decimal},
+		{types.Multiply, parse(types.Decimal, "1.5"), parse(types.Decimal, "0.25"), "0.375", types.Decimal},
+		{types.Divide, parse(types.Decimal, "1"), types.NewInt(3), "0.3333333333333333", types.Decimal},
+		{types.Modulo, parse(types.Decimal, "5.5"), types.NewInt(2), "1.5", types.Decimal},
+		{types.Multiply, types.NewDouble(0.5), parse(types.Decimal, "3"), "1.5", types.Double},
+		{types.Add, types.NewNull(types.Unknown), types.NewInt(1), "NULL", types.Int},
+	}
+	for _, c := range cases {
+		v, err := c.operation(c.a, c.b)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, v.String())
+		assert.Equal(t, c.typ, v.Type())
+	}
+
+	_, err := types.Add(types.NewInt(2147483647), types.NewInt(1))
+	assert.EqualError(t, err, "int out of range")
+	_, err = types.Divide(parse(types.Decimal, "1.0"), types.NewInt(0))
+	assert.EqualError(t, err, "division by zero")
+	_, err = types.Add(types.NewText("a"), types.NewInt(1))
+	assert.EqualError(t, err, "operator does not exist: text + int")
+
+	v, err := types.Cast(parse(types.Decimal, "-2.5"), types.Int)
+	assert.Nil(t, err)
+	assert.Equal(t, "-3", v.String())
+	assert.Equal(t, "a1", types.Concat(types.NewText("a"), types.NewInt(1)).String())
+
+	for _, c := range []struct {
+		v        types.Value
+		scale    int32
+		expected string
+	}{
+		{parse(types.Decimal, "2.345"), 2, "2.35"},
+		{parse(types.Decimal, "-2.5"), 0, "-3"},
+		{types.NewInt(1250), -2, "1300"},
+		{types.NewDouble(1.25), 1, "1.3"},
+	} {
+		v, err := types.Round(c.v, c.scale)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, v.String())
+		assert.Equal(t, c.v.Type(), v.Type())
+	}
+}
@@ -0,0 +1,316 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value is a typed value of a column.
+type Value struct {
+	typ Type
+	// string (Text), int64 (Int, BigInt), float64 (Double), decimal (Decimal), bool (Boolean),
+	// time.Time in UTC (Date, Timestamp), []byte (Bytea) or nil (NULL)
+	v interface{}
+}
+
+func NewNull(typ Type) Value {
+	return Value{typ: typ}
+}
+
+func NewText(s string) Value {
+	return Value{typ: Text, v: s}
+}
+
+func NewInt(i int64) Value {
+	return Value{typ: Int, v: i}
+}
+
+func NewBigInt(i int64) Value {
+	return Value{typ: BigInt, v: i}
+}
+
+func NewDouble(f float64) Value {
+	return Value{typ: Double, v: f}
+}
+
+func NewBoolean(b bool) Value {
+	return Value{typ: Boolean, v: b}
+}
+
+func NewDate(t time.Time) Value {
+	return Value{typ: Date, v: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func NewTimestamp(t time.Time) Value {
+	return Value{typ: Timestamp, v: t.UTC().Truncate(time.Microsecond)}
+}
+
+func NewBytea(b []byte) Value {
+	return Value{typ: Bytea, v: b}
+}
+
+func (v Value) Type() Type {
+	return v.typ
+}
+
+func (v Value) IsNull() bool {
+	return v.v == nil
+}
+
+// Int returns the value of an integer. It is 0 for NULL.
+func (v Value) Int() int64 {
+	i, _ := v.v.(int64)
+	return i
+}
+
+// Time returns the value of a date or a timestamp in UTC. It is the zero time for NULL.
+func (v Value) Time() time.Time {
+	t, _ := v.v.(time.Time)
+	return t
+}
+
+const (
+	dateLayout      = "2006-01-02"
+	timestampLayout = "2006-01-02 15:04:05.999999"
+)
+
+var timestampLayouts = []string{
+	timestampLayout,
+	"2006-01-02T15:04:05.999999",
+	time.RFC3339Nano,
+	dateLayout,
+}
+
+// Parse converts a literal written in SQL to a value of typ
+func Parse(typ Type, literal string) (Value, error) {
+	invalid := fmt.Errorf("invalid input syntax for type %s: \"%s\"", typ, literal)
+
+	switch typ {
+	case Text:
+		return NewText(literal), nil
+	case Int:
+		i, err := strconv.ParseInt(strings.TrimSpace(literal), 10, 32)
+		if err != nil {
+			if isOutOfRange(err) {
+				return Value{}, fmt.Errorf("value out of range for type %s: %s", typ, literal)
+			}
+			return Value{}, invalid
+		}
+		return NewInt(i), nil
+	case BigInt:
+		i, err := strconv.ParseInt(strings.TrimSpace(literal), 10, 64)
+		if err != nil {
+			if isOutOfRange(err) {
+				return Value{}, fmt.Errorf("value out of range for type %s: %s", typ, literal)
+			}
+			return Value{}, invalid
+		}
+		return NewBigInt(i), nil
+	case Double:
+		f, err := strconv.ParseFloat(strings.TrimSpace(literal), 64)
+		if err != nil {
+			return Value{}, invalid
+		}
+		return NewDouble(f), nil
+	case Decimal:
+		d, ok := parseDecimal(strings.TrimSpace(literal))
+		if !ok {
+			return Value{}, invalid
+		}
+		return Value{typ: Decimal, v: d}, nil
+	case Boolean:
+		switch strings.ToLower(strings.TrimSpace(literal)) {
+		case "true", "t", "yes", "y", "on", "1":
+			return NewBoolean(true), nil
+		case "false", "f", "no", "n", "off", "0":
+			return NewBoolean(false), nil
+		default:
+			return Value{}, invalid
+		}
+	case Date:
+		t, err := time.Parse(dateLayout, strings.TrimSpace(literal))
+		if err != nil {
+			return Value{}, invalid
+		}
+		return NewDate(t), nil
+	case Timestamp:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(literal)); err == nil {
+				return NewTimestamp(t), nil
+			}
+		}
+		return Value{}, invalid
+	case Bytea:
+		// hex format: \x0123abcd
+		if strings.HasPrefix(literal, `\x`) {
+			b, err := hex.DecodeString(literal[2:])
+			if err != nil {
+				return Value{}, invalid
+			}
+			return NewBytea(b), nil
+		}
+		return NewBytea([]byte(literal)), nil
+	default:
+		return Value{}, fmt.Errorf("unknown type: %d", typ)
+	}
+}
+
+func isOutOfRange(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+func (v Value) String() string {
+	switch x := v.v.(type) {
+	case string:
+		return x
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case decimal:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	case time.Time:
+		if v.typ == Date {
+			return x.Format(dateLayout)
+		}
+		return x.Format(timestampLayout)
+	case []byte:
+		return `\x` + hex.EncodeToString(x)
+	default:
+		return "NULL"
+	}
+}
+
+// Cast converts v to typ through its text representation, or by its value between numeric types. NULL is NULL of typ.
+func Cast(v Value, typ Type) (Value, error) {
+	if v.IsNull() {
+		return NewNull(typ), nil
+	}
+	if v.typ == typ {
+		return v, nil
+	}
+	if v.typ.IsNumeric() && typ.IsNumeric() {
+		return castNumeric(v, typ)
+	}
+	return Parse(typ, v.String())
+}
+
+// Compare returns -1, 0 or +1. Numeric values of different types are compared by their values.
+// NULL is larger than any other value and equal to NULL, which is the order used for sorting.
+// Comparisons in SQL must handle NULL before calling Compare (three-valued logic).
+func Compare(a Value, b Value) (int, error) {
+	if a.IsNull() || b.IsNull() {
+		switch {
+		case a.IsNull() && b.IsNull():
+			return 0, nil
+		case a.IsNull():
+			return 1, nil
+		default:
+			return -1, nil
+		}
+	}
+
+	if a.typ != b.typ {
+		if a.typ.IsNumeric() && b.typ.IsNumeric() {
+			return compareNumeric(a, b), nil
+		}
+		return 0, fmt.Errorf("cannot compare %s with %s", a.typ, b.typ)
+	}
+
+	switch x := a.v.(type) {
+	case string:
+		return strings.Compare(x, b.v.(string)), nil
+	case int64:
+		return compareOrdered(x, b.v.(int64)), nil
+	case float64:
+		return compareFloat(x, b.v.(float64)), nil
+	case decimal:
+		return x.rat().Cmp(b.v.(decimal).rat()), nil
+	case bool:
+		y := b.v.(bool)
+		if x == y {
+			return 0, nil
+		}
+		if !x {
+			return -1, nil
+		}
+		return 1, nil
+	case time.Time:
+		return x.Compare(b.v.(time.Time)), nil
+	case []byte:
+		return bytes.Compare(x, b.v.([]byte)), nil
+	default:
+		return 0, fmt.Errorf("cannot compare %s", a.typ)
+	}
+}
+
+func compareNumeric(a Value, b Value) int {
+	if a.typ == Double || b.typ == Double {
+		return compareFloat(a.float(), b.float())
+	}
+	return a.rat().Cmp(b.rat())
+}
+
+func compareOrdered[T int64 | string](x T, y T) int {
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
+// compareFloat orders NaN after all other values
+func compareFloat(x float64, y float64) int {
+	switch {
+	case math.IsNaN(x) && math.IsNaN(y):
+		return 0
+	case math.IsNaN(x):
+		return 1
+	case math.IsNaN(y):
+		return -1
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (v Value) float() float64 {
+	switch x := v.v.(type) {
+	case int64:
+		return float64(x)
+	case float64:
+		return x
+	case decimal:
+		f, _ := x.rat().Float64()
+		return f
+	default:
+		return 0
+	}
+}
+
+func (v Value) rat() *big.Rat {
+	switch x := v.v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(x)
+	case float64:
+		return new(big.Rat).SetFloat64(x)
+	case decimal:
+		return x.rat()
+	default:
+		return new(big.Rat)
+	}
+}